@@ -2,46 +2,212 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	netmail "net/mail"
 	"os"
+	"time"
 
 	"prmanager/internal/api"
 	"prmanager/internal/config"
-	"prmanager/internal/migration"
-	"prmanager/internal/repository/postgres"
+	"prmanager/internal/digest"
+	"prmanager/internal/events"
+	"prmanager/internal/jwtauth"
+	"prmanager/internal/mail"
+	"prmanager/internal/models"
+	"prmanager/internal/notify"
+	"prmanager/internal/outbox"
+	"prmanager/internal/repository"
+	"prmanager/internal/reviewsync"
 	"prmanager/internal/service"
-
-	"github.com/jackc/pgx/v5/pgxpool"
+	"prmanager/internal/sla"
+	"prmanager/internal/snapshot"
+	"prmanager/internal/tracing"
+	"prmanager/internal/vcs"
+	"prmanager/internal/vcs/github"
+	"prmanager/internal/vcs/gitlab"
+	"prmanager/internal/webhook"
 )
 
 func main() {
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	exportPath := flag.String("export", "", "write a JSON snapshot of all data to the given file (- for stdout) and exit")
+	restorePath := flag.String("restore", "", "load a JSON snapshot from the given file (- for stdin) into an empty database and exit")
+	purgeUserID := flag.Int("purge-user", 0, "anonymize the user with the given id, keeping their PR history, and exit")
+	purgeTeamID := flag.Int("purge-team", 0, "permanently remove the team with the given id (it must have no members) and exit")
+	var tokens tokenFlags
+	flag.StringVar(&tokens.issueRole, "issue-token", "", "issue an API token with the given role (admin, team-lead or user), print it and exit")
+	flag.IntVar(&tokens.userID, "token-user", 0, "the user a team-lead or user token acts as")
+	flag.StringVar(&tokens.org, "token-org", "", "the organization (id or name) an admin token is bound to; unbound admin tokens administer every organization")
+	flag.StringVar(&tokens.name, "token-name", "", "a name to recognize the issued token by")
+	flag.IntVar(&tokens.revokeID, "revoke-token", 0, "revoke the API token with the given id and exit")
+	flag.BoolVar(&tokens.list, "list-tokens", false, "list API tokens and exit")
+	flag.Parse()
+
+	logOut := os.Stdout
+	if *exportPath == "-" || tokens.set() {
+		logOut = os.Stderr
+	}
+	logger := slog.New(slog.NewJSONHandler(logOut, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
 
 	cfg := config.LoadFromEnv()
 	logger.Info("application starting", "config", map[string]interface{}{
 		"port":    cfg.Port,
+		"storage": cfg.Storage,
 		"db_host": os.Getenv("DB_HOST"),
 	})
 
+	movePolicy, err := service.ParseMovePolicy(cfg.UserMovePolicy)
+	if err != nil {
+		logger.Error("invalid USER_MOVE_POLICY", "error", err)
+		os.Exit(1)
+	}
+	chatTemplates, err := notify.LoadTemplates(cfg.ChatTemplatesFile)
+	if err != nil {
+		logger.Error("invalid CHAT_TEMPLATES_FILE", "error", err)
+		os.Exit(1)
+	}
+	digestCfg, err := digestConfig(cfg)
+	if err != nil {
+		logger.Error("invalid digest settings", "error", err)
+		os.Exit(1)
+	}
+	digestTemplates, err := digest.LoadTemplates(cfg.DigestTextTemplate, cfg.DigestHTMLTemplate)
+	if err != nil {
+		logger.Error("invalid DIGEST_TEXT_TEMPLATE or DIGEST_HTML_TEMPLATE", "error", err)
+		os.Exit(1)
+	}
+	routeLimits, err := api.ParseRouteLimits(cfg.RateLimitRoutes)
+	if err != nil {
+		logger.Error("invalid RATE_LIMIT_ROUTES", "error", err)
+		os.Exit(1)
+	}
+	svcOpts := []service.Option{service.WithMovePolicy(movePolicy)}
+	if jwt, err := jwtOption(cfg); err != nil {
+		logger.Error("invalid JWT settings", "error", err)
+		os.Exit(1)
+	} else if jwt != nil {
+		svcOpts = append(svcOpts, jwt)
+	}
+
 	ctx := context.Background()
-	pool, err := pgxpool.New(ctx, cfg.DBConn)
+	shutdownTracing, err := tracing.Setup(ctx, cfg.TracesExporter, "pr-manager")
 	if err != nil {
-		logger.Error("failed to connect to database", "error", err)
+		logger.Error("invalid OTEL_TRACES_EXPORTER", "error", err)
 		os.Exit(1)
 	}
-	defer pool.Close()
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("failed to flush traces", "error", err)
+		}
+	}()
 
-	if err := migration.Run(ctx, pool); err != nil {
-		logger.Error("migrations failed", "error", err)
+	repo, closeRepo, err := openRepository(ctx, cfg)
+	if err != nil {
+		logger.Error("failed to open storage", "error", err)
 		os.Exit(1)
 	}
+	defer closeRepo()
+
+	if *exportPath != "" {
+		if err := exportSnapshot(ctx, repo, *exportPath); err != nil {
+			logger.Error("export failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("export completed", "path", *exportPath)
+		return
+	}
+	if *restorePath != "" {
+		if err := restoreSnapshot(ctx, repo, *restorePath); err != nil {
+			logger.Error("restore failed", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("restore completed", "path", *restorePath)
+		return
+	}
+
+	webhookCfg := webhook.DefaultConfig()
+	webhookCfg.MaxAttempts = cfg.WebhookMaxAttempts
+	dispatcher := webhook.NewDispatcher(repo, logger, webhookCfg)
+
+	syncCfg := reviewsync.DefaultConfig()
+	syncCfg.MaxAttempts = cfg.ReviewerSyncMaxAttempts
+	providers := vcsProviders(cfg)
+	syncer := reviewsync.NewSyncer(repo, providers, logger, syncCfg)
+
+	chatCfg := notify.DefaultConfig()
+	chatCfg.MaxAttempts = cfg.ChatMaxAttempts
+	notifier := notify.NewNotifier(repo, chatTemplates, logger, chatCfg)
 
-	repo := postgres.NewRepo(pool)
-	svc := service.NewService(repo, logger)
-	h := api.NewHandler(svc, logger)
+	outboxCfg := outbox.DefaultConfig()
+	outboxCfg.Retention = cfg.OutboxRetention
+	outboxCfg.MaxAttempts = cfg.OutboxMaxAttempts
+	// the syncer only marks PRs pending and the notifier queues one message
+	// per event, so both are safe to repeat when the dispatcher fails and the
+	// relay retries the event
+	relay := outbox.NewRelay(repo, events.Fanout(syncer, notifier, dispatcher), logger, outboxCfg)
+
+	svc := service.NewService(repo, logger, svcOpts...)
+
+	if *purgeUserID != 0 || *purgeTeamID != 0 {
+		if err := purge(ctx, svc, *purgeUserID, *purgeTeamID); err != nil {
+			logger.Error("purge failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if tokens.set() {
+		if err := manageTokens(ctx, svc, tokens, os.Stdout); err != nil {
+			logger.Error("token command failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	opts := []api.Option{
+		api.WithIdempotency(repo, cfg.IdempotencyTTL),
+		api.WithGitHub(cfg.GitHubWebhookSecret),
+		api.WithGitLab(cfg.GitLabWebhookToken),
+		api.WithSlack(cfg.SlackSigningSecret),
+		api.WithMaxBodySize(int64(cfg.MaxBodyBytes)),
+	}
+	if gl, ok := providers[vcs.GitLab].(*gitlab.Client); ok {
+		opts = append(opts, api.WithGitLabUsers(gl))
+	}
+	if cfg.RateLimitRPS > 0 {
+		opts = append(opts, api.WithRateLimit(api.RateLimit{Rate: cfg.RateLimitRPS, Burst: max(cfg.RateLimitBurst, 1)}, routeLimits))
+	} else {
+		logger.Warn("API rate limiting is off: RATE_LIMIT_RPS is not positive")
+	}
+	if cfg.RateLimitIPRPS > 0 {
+		opts = append(opts, api.WithIPRateLimit(api.RateLimit{Rate: cfg.RateLimitIPRPS, Burst: max(cfg.RateLimitIPBurst, 1)}))
+	} else {
+		logger.Warn("per-address rate limiting is off: RATE_LIMIT_IP_RPS is not positive")
+	}
+	if cfg.AuthDisabled {
+		logger.Warn("API authentication is off: AUTH_DISABLED is set")
+	} else {
+		opts = append(opts, api.WithAuth())
+	}
+	h := api.NewHandler(svc, logger, opts...)
+
+	go purgeExpiredIdempotencyKeys(ctx, repo, cfg.IdempotencyTTL, logger)
+	go relay.Run(ctx)
+	go dispatcher.Run(ctx)
+	go syncer.Run(ctx)
+	go notifier.Run(ctx)
+	go sla.NewWorker(repo, svc, logger, sla.DefaultConfig()).Run(ctx)
+	if cfg.SMTPAddr != "" {
+		mailer := mail.NewSMTP(cfg.SMTPAddr, cfg.SMTPUsername, cfg.SMTPPassword, 30*time.Second)
+		go digest.NewScheduler(repo, mailer, digestTemplates, logger, digestCfg).Run(ctx)
+	} else {
+		logger.Info("review digests are off: SMTP_ADDR is not set")
+	}
 
 	srv := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -57,3 +223,132 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func exportSnapshot(ctx context.Context, repo repository.Repository, path string) error {
+	if path == "-" {
+		return snapshot.Export(ctx, repo, os.Stdout)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	if err := snapshot.Export(ctx, repo, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func restoreSnapshot(ctx context.Context, repo repository.Repository, path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("open %s: %w", path, err)
+		}
+		defer f.Close()
+		r = f
+	}
+	return snapshot.Restore(ctx, repo, r)
+}
+
+func purgeExpiredIdempotencyKeys(ctx context.Context, repo repository.Repository, ttl time.Duration, logger *slog.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		n, err := repo.DeleteIdempotencyKeysBefore(ctx, time.Now().Add(-ttl))
+		if err != nil {
+			logger.Error("failed to purge expired idempotency keys", "error", err)
+		} else if n > 0 {
+			logger.Info("purged expired idempotency keys", "count", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge is the admin-only hard delete. It is deliberately not exposed over
+// HTTP: only operators with access to the deployment can run it.
+func purge(ctx context.Context, svc *service.Service, userID, teamID int) error {
+	if userID != 0 {
+		if err := svc.PurgeUser(ctx, userID); err != nil {
+			return fmt.Errorf("purge user %d: %w", userID, err)
+		}
+	}
+	if teamID != 0 {
+		if err := svc.PurgeTeam(ctx, teamID); err != nil {
+			return fmt.Errorf("purge team %d: %w", teamID, err)
+		}
+	}
+	return nil
+}
+
+// vcsProviders returns a client for every code host an API token is
+// configured for; reviewers are only pushed to those.
+func vcsProviders(cfg *config.Config) map[string]vcs.Provider {
+	client := &http.Client{Timeout: 10 * time.Second}
+	providers := make(map[string]vcs.Provider)
+	if cfg.GitHubToken != "" {
+		providers[vcs.GitHub] = github.NewClient(cfg.GitHubAPIURL, cfg.GitHubToken, client)
+	}
+	if cfg.GitLabToken != "" {
+		providers[vcs.GitLab] = gitlab.NewClient(cfg.GitLabURL, cfg.GitLabToken, client)
+	}
+	return providers
+}
+
+func digestConfig(cfg *config.Config) (digest.Config, error) {
+	dc := digest.DefaultConfig()
+	dc.From = cfg.SMTPFrom
+	if _, err := time.Parse(models.DigestTimeLayout, cfg.DigestSendAt); err != nil {
+		return dc, fmt.Errorf("DIGEST_SEND_AT %q is not a time of day like 09:00", cfg.DigestSendAt)
+	}
+	dc.SendAt = cfg.DigestSendAt
+	loc, err := time.LoadLocation(cfg.DigestTimezone)
+	if err != nil {
+		return dc, fmt.Errorf("DIGEST_TIMEZONE: %w", err)
+	}
+	dc.Location = loc
+	if _, err := netmail.ParseAddress(dc.From); err != nil {
+		return dc, fmt.Errorf("SMTP_FROM: %w", err)
+	}
+	return dc, nil
+}
+
+// jwtOption returns the service option that accepts single sign-on tokens,
+// or nil if no JWKS is configured.
+func jwtOption(cfg *config.Config) (service.Option, error) {
+	var keys jwtauth.KeySource
+	switch {
+	case cfg.JWTJWKSURL != "" && cfg.JWTJWKSFile != "":
+		return nil, errors.New("set JWT_JWKS_URL or JWT_JWKS_FILE, not both")
+	case cfg.JWTJWKSURL != "":
+		keys = jwtauth.NewRemoteKeys(cfg.JWTJWKSURL, nil)
+	case cfg.JWTJWKSFile != "":
+		static, err := jwtauth.LoadJWKSFile(cfg.JWTJWKSFile)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_JWKS_FILE: %w", err)
+		}
+		keys = static
+	default:
+		return nil, nil
+	}
+	if cfg.JWTIssuer == "" || cfg.JWTAudience == "" {
+		return nil, errors.New("JWT_ISSUER and JWT_AUDIENCE are required with a JWKS")
+	}
+	v := jwtauth.NewVerifier(keys, jwtauth.Config{Issuer: cfg.JWTIssuer, Audience: cfg.JWTAudience, Leeway: time.Minute})
+	return service.WithJWT(v, service.JWTMapping{
+		UserClaim:           cfg.JWTUserClaim,
+		GroupsClaim:         cfg.JWTGroupsClaim,
+		OrgClaim:            cfg.JWTOrgClaim,
+		AdminGroups:         cfg.JWTAdminGroups,
+		InstanceAdminGroups: cfg.JWTInstanceAdminGroups,
+		TeamLeadGroups:      cfg.JWTTeamLeadGroups,
+	}), nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"prmanager/internal/config"
+	"prmanager/internal/migration"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/repository/postgres"
+	"prmanager/internal/repository/sqlite"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+)
+
+func openRepository(ctx context.Context, cfg *config.Config) (repository.Repository, func(), error) {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		return memory.NewRepo(), func() {}, nil
+	case config.StoragePostgres:
+		poolCfg, err := pgxpool.ParseConfig(cfg.DBConn)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse DB_CONN: %w", err)
+		}
+		poolCfg.ConnConfig.Tracer = postgres.NewQueryTracer(otel.GetTracerProvider())
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+		if err != nil {
+			return nil, nil, fmt.Errorf("connect to database: %w", err)
+		}
+		if err := migration.Run(ctx, pool); err != nil {
+			pool.Close()
+			return nil, nil, fmt.Errorf("run migrations: %w", err)
+		}
+		return postgres.NewRepo(pool), pool.Close, nil
+	case config.StorageSQLite:
+		path, err := sqlite.PathFromConn(cfg.DBConn)
+		if err != nil {
+			return nil, nil, err
+		}
+		db, err := sqlite.Open(ctx, path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := migration.RunSQLite(ctx, db); err != nil {
+			db.Close()
+			return nil, nil, fmt.Errorf("run migrations: %w", err)
+		}
+		return sqlite.NewRepo(db), func() { db.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown STORAGE %q", cfg.Storage)
+	}
+}
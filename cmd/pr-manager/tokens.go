@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/service"
+)
+
+type tokenFlags struct {
+	issueRole string
+	userID    int
+	org       string
+	name      string
+	revokeID  int
+	list      bool
+}
+
+func (f tokenFlags) set() bool {
+	return f.issueRole != "" || f.revokeID != 0 || f.list
+}
+
+// manageTokens issues, revokes or lists API tokens. Like purge it is only
+// available to operators: tokens cannot be issued over HTTP, so a leaked
+// token can never mint another. Admin tokens issued with an organization
+// administer only that organization.
+func manageTokens(ctx context.Context, svc *service.Service, f tokenFlags, out io.Writer) error {
+	switch {
+	case f.issueRole != "":
+		var userID *int
+		if f.userID != 0 {
+			userID = &f.userID
+		}
+		name := f.name
+		if name == "" {
+			name = f.issueRole
+		}
+		if f.org != "" {
+			o, err := svc.ResolveOrganization(ctx, f.org)
+			if err != nil {
+				return fmt.Errorf("issue token: %w", err)
+			}
+			ctx = repository.WithOrg(ctx, o.ID)
+		}
+		t, token, err := svc.IssueToken(ctx, name, models.Role(f.issueRole), userID)
+		if err != nil {
+			return fmt.Errorf("issue token: %w", err)
+		}
+		fmt.Fprintf(out, "%s\n", token)
+		fmt.Fprintf(out, "# token %d %q; it is shown only once\n", t.ID, t.Name)
+		return nil
+	case f.revokeID != 0:
+		if err := svc.RevokeToken(ctx, f.revokeID); err != nil {
+			return fmt.Errorf("revoke token %d: %w", f.revokeID, err)
+		}
+		fmt.Fprintf(out, "token %d revoked\n", f.revokeID)
+		return nil
+	}
+
+	ts, err := svc.ListTokens(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tROLE\tUSER\tORG\tCREATED\tREVOKED")
+	for _, t := range ts {
+		user, org, revoked := "-", "-", "-"
+		if t.UserID != nil {
+			user = fmt.Sprint(*t.UserID)
+		}
+		if t.OrgID != nil {
+			org = fmt.Sprint(*t.OrgID)
+		}
+		if t.RevokedAt != nil {
+			revoked = t.RevokedAt.Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Role, user, org, t.CreatedAt.Format(time.DateTime), revoked)
+	}
+	return w.Flush()
+}
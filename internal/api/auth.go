@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/service"
+)
+
+// unavailableRetryAfter is the Retry-After of requests that could not be
+// authenticated because the identity provider's keys could not be fetched.
+// It matches how often the keys are refetched.
+const unavailableRetryAfter = time.Minute
+
+// WithAuth requires an API token, or a single sign-on token if the service
+// accepts those, in the Authorization header of every API request. Integration endpoints authenticate with their own secrets and are
+// not affected.
+func WithAuth() Option {
+	return func(h *Handler) {
+		h.auth = true
+	}
+}
+
+// authenticate resolves the bearer token of r and runs next as its
+// principal, so the service can check what the token may do.
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pr-manager"`)
+			h.writeError(w, "UNAUTHORIZED", "bearer token required", http.StatusUnauthorized)
+			return
+		}
+
+		p, err := h.svc.Authenticate(r.Context(), strings.TrimSpace(token))
+		if errors.Is(err, service.ErrUnauthorized) {
+			h.log(r.Context()).Warn("rejected bearer token", "error", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="pr-manager", error="invalid_token"`)
+			h.writeError(w, "UNAUTHORIZED", "invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, service.ErrUnavailable) {
+			h.log(r.Context()).Warn("cannot authenticate request", "error", err)
+			w.Header().Set("Retry-After", strconv.Itoa(int(unavailableRetryAfter.Seconds())))
+			h.writeError(w, "UNAVAILABLE", "cannot verify bearer token, try again later", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			h.log(r.Context()).Error("failed to authenticate request", "error", err)
+			h.writeError(w, "INTERNAL_ERROR", "internal error", http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(service.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// adminOnly refuses requests whose principal is not an admin. Requests
+// without a principal only get here when authentication is off.
+func (h *Handler) adminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := service.PrincipalFrom(r.Context()); ok && p.Role != models.RoleAdmin {
+			h.writeError(w, "FORBIDDEN", "admin token required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
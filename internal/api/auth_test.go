@@ -0,0 +1,196 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"prmanager/internal/jwtauth"
+	"prmanager/internal/jwtauth/jwtauthtest"
+	"prmanager/internal/models"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doAs(h *Handler, token, method, path, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+type authFixture struct {
+	h                   *Handler
+	admin, lead, member string
+	outsider            string
+}
+
+func newAuthFixture(t *testing.T) authFixture {
+	t.Helper()
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewRepo()
+	svc := service.NewService(repo, logger)
+
+	backend, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	frontend, err := svc.CreateTeam(ctx, "frontend")
+	require.NoError(t, err)
+	var f authFixture
+	for _, m := range []struct {
+		name  string
+		team  int
+		role  models.Role
+		token *string
+	}{
+		{"lead", backend.ID, models.RoleTeamLead, &f.lead},
+		{"member", backend.ID, models.RoleUser, &f.member},
+		{"peer", backend.ID, "", nil},
+		{"outsider", frontend.ID, models.RoleUser, &f.outsider},
+	} {
+		u, err := svc.CreateUser(ctx, &m.team, m.name, true)
+		require.NoError(t, err)
+		if m.token != nil {
+			_, *m.token, err = svc.IssueToken(ctx, m.name, m.role, &u.ID)
+			require.NoError(t, err)
+		}
+	}
+	_, f.admin, err = svc.IssueToken(ctx, "ops", models.RoleAdmin, nil)
+	require.NoError(t, err)
+
+	f.h = NewHandler(svc, logger, WithAuth(), WithIdempotency(repo, time.Hour))
+	return f
+}
+
+func TestAuthRequiresToken(t *testing.T) {
+	f := newAuthFixture(t)
+
+	rr := doAs(f.h, "", http.MethodGet, "/teams", "", "")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Header().Get("WWW-Authenticate"), "Bearer")
+	assert.Equal(t, http.StatusUnauthorized, doAs(f.h, "prm_forged", http.MethodGet, "/teams", "", "").Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/teams", nil)
+	req.Header.Set("Authorization", "Basic "+f.admin)
+	rr = httptest.NewRecorder()
+	f.h.Router().ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "only bearer tokens are accepted")
+
+	assert.Equal(t, http.StatusOK, doAs(f.h, f.member, http.MethodGet, "/teams", "", "").Code)
+}
+
+func TestAuthRoles(t *testing.T) {
+	f := newAuthFixture(t)
+
+	// users 1-3 are lead, member and peer in backend, 4 is outsider in frontend
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.lead, http.MethodPost, "/teams", `{"name":"qa"}`, "").Code, "only admins manage teams")
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.member, http.MethodPatch, "/users/3", `{"is_active":false}`, "").Code)
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.member, http.MethodGet, "/webhooks", "", "").Code)
+	assert.Equal(t, http.StatusCreated, doAs(f.h, f.admin, http.MethodPost, "/teams", `{"name":"qa"}`, "").Code)
+
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.outsider, http.MethodPost, "/prs", `{"title":"Add search","author_id":2}`, "").Code)
+	rr := doAs(f.h, f.member, http.MethodPost, "/prs", `{"title":"Add search","author_id":2}`, "")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	pr := decode[models.PRWithReviewers](t, rr)
+	require.NotEmpty(t, pr.Reviewers)
+
+	assert.Equal(t, http.StatusOK, doAs(f.h, f.member, http.MethodGet, "/users/2/prs", "", "").Code, "own queue")
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.member, http.MethodGet, "/users/3/prs", "", "").Code)
+	assert.Equal(t, http.StatusOK, doAs(f.h, f.lead, http.MethodGet, "/users/3/prs", "", "").Code, "a lead sees their team's queues")
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.outsider, http.MethodGet, "/users/3/prs", "", "").Code)
+
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.outsider, http.MethodPost, "/prs/1/reassign", `{"old_user_id":3}`, "").Code)
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.outsider, http.MethodPost, "/prs/1/merge", "", "").Code)
+	assert.Equal(t, http.StatusOK, doAs(f.h, f.member, http.MethodPost, "/prs/1/merge", "", "").Code, "authors merge their own PRs")
+
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.lead, http.MethodPut, "/users/2/digest", `{"opt_out":true}`, "").Code)
+	assert.Equal(t, http.StatusOK, doAs(f.h, f.member, http.MethodPut, "/users/2/digest", `{"opt_out":true}`, "").Code)
+}
+
+func TestAuthOnlyAdminsLinkTrustedIdentities(t *testing.T) {
+	f := newAuthFixture(t)
+
+	for _, provider := range []string{"github", "gitlab", "oidc", "chat"} {
+		path := "/users/2/identities/" + provider
+		assert.Equal(t, http.StatusForbidden, doAs(f.h, f.member, http.MethodPut, path, `{"login":"victim"}`, "").Code, provider)
+		assert.Equal(t, http.StatusForbidden, doAs(f.h, f.lead, http.MethodPut, path, `{"login":"victim"}`, "").Code, provider)
+		assert.Equal(t, http.StatusOK, doAs(f.h, f.admin, http.MethodPut, path, `{"login":"member"}`, "").Code, provider)
+	}
+	assert.Equal(t, http.StatusOK, doAs(f.h, f.member, http.MethodPut, "/users/2/identities/email", `{"login":"member@example.com"}`, "").Code)
+	assert.Equal(t, http.StatusForbidden, doAs(f.h, f.member, http.MethodPut, "/users/3/identities/email", `{"login":"peer@example.com"}`, "").Code)
+	assert.Equal(t, http.StatusNoContent, doAs(f.h, f.member, http.MethodDelete, "/users/2/identities/github", "", "").Code, "users may still unlink themselves")
+}
+
+func TestAuthIdempotencyKeysArePerToken(t *testing.T) {
+	f := newAuthFixture(t)
+
+	first := doAs(f.h, f.member, http.MethodPost, "/prs", `{"title":"Add search","author_id":2}`, "k1")
+	require.Equal(t, http.StatusCreated, first.Code)
+	replay := doAs(f.h, f.member, http.MethodPost, "/prs", `{"title":"Add search","author_id":2}`, "k1")
+	assert.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeader))
+
+	own := doAs(f.h, f.outsider, http.MethodPost, "/prs", `{"title":"Add search","author_id":4}`, "k1")
+	assert.Equal(t, http.StatusCreated, own.Code, "keys of other tokens do not clash")
+	assert.Empty(t, own.Header().Get(IdempotentReplayedHeader))
+	other := doAs(f.h, f.lead, http.MethodPost, "/prs", `{"title":"Add search","author_id":2}`, "k1")
+	assert.Equal(t, http.StatusForbidden, other.Code, "another token cannot replay the response")
+	assert.Empty(t, other.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, http.StatusUnauthorized, doAs(f.h, "", http.MethodPost, "/prs", `{"title":"Add search","author_id":2}`, "k1").Code)
+}
+
+func TestAuthAcceptsJWT(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	idp := jwtauthtest.NewProvider(t)
+	keys := jwtauth.NewRemoteKeys(idp.JWKSURL, nil)
+	keys.MinRefresh = 0
+	verifier := jwtauth.NewVerifier(keys, jwtauth.Config{Issuer: idp.Issuer, Audience: "pr-manager"})
+	svc := service.NewService(memory.NewRepo(), logger, service.WithJWT(verifier, service.JWTMapping{
+		UserClaim:   "email",
+		GroupsClaim: "groups",
+		AdminGroups: []string{"pr-admins"},
+	}))
+	h := NewHandler(svc, logger, WithAuth())
+
+	claims := idp.Claims("00uOps", "pr-manager")
+	claims["groups"] = []string{"pr-admins"}
+	admin := idp.Token(claims)
+	require.Equal(t, http.StatusCreated, doAs(h, admin, http.MethodPost, "/teams", `{"name":"backend"}`, "").Code)
+	require.Equal(t, http.StatusCreated, doAs(h, admin, http.MethodPost, "/teams/1/users", `{"name":"alice"}`, "").Code)
+	require.Equal(t, http.StatusOK, doAs(h, admin, http.MethodPut, "/users/1/identities/oidc", `{"login":"alice@example.com"}`, "").Code)
+
+	claims = idp.Claims("00uA1ice", "pr-manager")
+	claims["email"] = "alice@example.com"
+	alice := idp.ECToken(claims)
+	assert.Equal(t, http.StatusOK, doAs(h, alice, http.MethodGet, "/users/1/prs", "", "").Code)
+	assert.Equal(t, http.StatusForbidden, doAs(h, alice, http.MethodPost, "/teams", `{"name":"qa"}`, "").Code)
+
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	assert.Equal(t, http.StatusUnauthorized, doAs(h, idp.Token(claims), http.MethodGet, "/users/1/prs", "", "").Code)
+
+	_, secret, err := svc.IssueToken(ctx, "ops", models.RoleAdmin, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, doAs(h, secret, http.MethodGet, "/webhooks", "", "").Code, "API tokens keep working")
+
+	idp.SetDown(true)
+	idp.Rotate()
+	rr := doAs(h, idp.Token(idp.Claims("00uOps", "pr-manager")), http.MethodGet, "/users/1/prs", "", "")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "a token that needs a key refresh while the provider is down")
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusOK, doAs(h, alice, http.MethodGet, "/users/1/prs", "", "").Code, "cached keys keep working")
+}
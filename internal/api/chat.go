@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) setTeamChannel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		WebhookURL string `json:"webhook_url"`
+		Channel    string `json:"channel"`
+	}
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		return
+	}
+
+	team := chi.URLParam(r, "team")
+	res, err := h.svc.SetTeamChannel(r.Context(), team, body.WebhookURL, body.Channel)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to set team channel", "error", err, "team", team)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) getTeamChannel(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetTeamChannel(r.Context(), chi.URLParam(r, "team"))
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) deleteTeamChannel(w http.ResponseWriter, r *http.Request) {
+	team := chi.URLParam(r, "team")
+	if err := h.svc.DeleteTeamChannel(r.Context(), team); err != nil {
+		h.log(r.Context()).Warn("failed to delete team channel", "error", err, "team", team)
+		h.writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
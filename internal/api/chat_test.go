@@ -0,0 +1,41 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamChannelEndpoints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "").Code)
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams/1/users", `{"name":"alice","is_active":true}`, "").Code)
+
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/teams/backend/chat-channel", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodPut, "/teams/frontend/chat-channel", `{"webhook_url":"https://chat.example.com/hooks/x"}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodPut, "/teams/backend/chat-channel", `{"webhook_url":"hooks/x"}`, "").Code)
+
+	rr := do(h, http.MethodPut, "/teams/backend/chat-channel", `{"webhook_url":"https://chat.example.com/hooks/x","channel":"reviews"}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = do(h, http.MethodGet, "/teams/1/chat-channel", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	got := decode[models.TeamChannel](t, rr)
+	assert.Equal(t, "https://chat.example.com/hooks/x", got.WebhookURL)
+	assert.Equal(t, "reviews", got.Channel)
+
+	rr = do(h, http.MethodPut, "/users/1/identities/chat", `{"login":"@U024BE7LH"}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "U024BE7LH", decode[models.Identity](t, rr).Login, "chat handles keep their case")
+
+	assert.Equal(t, http.StatusNoContent, do(h, http.MethodDelete, "/teams/backend/chat-channel", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodDelete, "/teams/backend/chat-channel", "", "").Code)
+}
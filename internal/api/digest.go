@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"prmanager/internal/models"
+)
+
+func (h *Handler) getDigestPrefs(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.GetDigestPrefs(r.Context(), userID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) setDigestPrefs(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		OptOut   bool   `json:"opt_out"`
+		SendAt   string `json:"send_at"`
+		Timezone string `json:"timezone"`
+	}
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		return
+	}
+
+	res, err := h.svc.SetDigestPrefs(r.Context(), models.DigestPrefs{UserID: userID, OptOut: body.OptOut, SendAt: body.SendAt, Timezone: body.Timezone})
+	if err != nil {
+		h.log(r.Context()).Warn("failed to set digest prefs", "error", err, "user_id", userID)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigestPrefsEndpoints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "").Code)
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams/1/users", `{"name":"alice","is_active":true}`, "").Code)
+
+	rr := do(h, http.MethodGet, "/users/1/digest", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, models.DigestPrefs{UserID: 1}, decode[models.DigestPrefs](t, rr), "users are subscribed by default")
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/users/2/digest", "", "").Code)
+
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodPut, "/users/1/digest", `{"send_at":"8am"}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodPut, "/users/1/digest", `{"timezone":"Mars/Olympus"}`, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodPut, "/users/2/digest", `{"opt_out":true}`, "").Code)
+
+	rr = do(h, http.MethodPut, "/users/1/digest", `{"send_at":"8:30","timezone":"Europe/Berlin"}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	got := decode[models.DigestPrefs](t, rr)
+	assert.Equal(t, "08:30", got.SendAt)
+	assert.Equal(t, "Europe/Berlin", got.Timezone)
+	assert.False(t, got.OptOut)
+
+	rr = do(h, http.MethodPut, "/users/1/digest", `{"opt_out":true}`, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = do(h, http.MethodGet, "/users/1/digest", "", "")
+	assert.True(t, decode[models.DigestPrefs](t, rr).OptOut)
+
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodPut, "/users/1/identities/email", `{"login":"alice"}`, "").Code)
+	rr = do(h, http.MethodPut, "/users/1/identities/email", `{"login":"Alice <Alice@Example.com>"}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.Equal(t, "alice@example.com", decode[models.Identity](t, rr).Login)
+}
@@ -2,11 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
+
+	"prmanager/internal/repository"
+	"prmanager/internal/service"
 
 	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ErrorResponse struct {
@@ -20,13 +28,30 @@ type Handler struct {
 	svc    ServiceInterface
 	r      *chi.Mux
 	logger *slog.Logger
+	tracer trace.Tracer
+
+	auth        bool
+	limiter     *limiter
+	ipLimiter   *limiter
+	maxBodySize int64
+
+	idempotency    IdempotencyStore
+	idempotencyTTL time.Duration
+
+	githubSecret string
+	gitlabToken  string
+	gitlabUsers  GitLabUsers
+	slackSecret  string
 }
 
-func NewHandler(s ServiceInterface, logger *slog.Logger) *Handler {
+func NewHandler(s ServiceInterface, logger *slog.Logger, opts ...Option) *Handler {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	h := &Handler{svc: s, r: chi.NewRouter(), logger: logger}
+	h := &Handler{svc: s, r: chi.NewRouter(), logger: logger, tracer: defaultTracer(), maxBodySize: defaultMaxBodySize}
+	for _, opt := range opts {
+		opt(h)
+	}
 	h.routes()
 	return h
 }
@@ -34,13 +59,134 @@ func NewHandler(s ServiceInterface, logger *slog.Logger) *Handler {
 func (h *Handler) Router() http.Handler { return h.r }
 
 func (h *Handler) routes() {
-	h.r.Post("/teams", h.createTeam)
-	h.r.Post("/teams/{team_id}/users", h.createUser)
-	h.r.Post("/prs", h.createPR)
-	h.r.Post("/prs/{pr_id}/reassign", h.reassign)
-	h.r.Post("/prs/{pr_id}/merge", h.merge)
-	h.r.Get("/users/{user_id}/prs", h.listPRsForUser)
-	h.r.Get("/stats", h.stats)
+	h.r.Use(h.requestID, h.trace, h.accessLog, h.recoverPanic)
+
+	h.r.Group(func(r chi.Router) {
+		r.Use(limitBody(h.maxBodySize))
+		if h.ipLimiter != nil {
+			r.Use(h.ipRateLimited)
+		}
+		// Authenticate first: an idempotent replay must not serve a stored
+		// response to a caller that could not have made the request.
+		if h.auth {
+			r.Use(h.authenticate)
+		}
+		if h.limiter != nil {
+			r.Use(h.rateLimited)
+		}
+		r.Use(h.tenant)
+		if h.idempotency != nil {
+			r.Use(h.idempotent)
+		}
+
+		r.Post("/prs", h.createPR)
+		r.Post("/prs/{pr_id}/reassign", h.reassign)
+		r.Post("/prs/{pr_id}/merge", h.merge)
+		r.Get("/users/{user_id}/prs", h.listPRsForUser)
+		r.Get("/stats", h.stats)
+
+		r.Get("/prs/{pr_id}", h.getPR)
+		r.Get("/prs/{pr_id}/reviewer-sync", h.getReviewerSync)
+		r.Get("/teams", h.listTeams)
+		r.Get("/teams/{team}", h.getTeam)
+		r.Get("/users", h.listUsers)
+		r.Get("/users/{user_id}", h.getUser)
+		r.Get("/teams/{team}/chat-channel", h.getTeamChannel)
+		r.Get("/teams/{team}/sla", h.getTeamSLA)
+
+		r.Get("/users/{user_id}/identities", h.listIdentities)
+		r.Put("/users/{user_id}/identities/{provider}", h.setIdentity)
+		r.Delete("/users/{user_id}/identities/{provider}", h.deleteIdentity)
+		r.Get("/users/{user_id}/digest", h.getDigestPrefs)
+		r.Put("/users/{user_id}/digest", h.setDigestPrefs)
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.adminOnly)
+
+			r.Post("/teams", h.createTeam)
+			r.Post("/teams/{team_id}/users", h.createUser)
+			r.Patch("/users/{user_id}", h.updateUser)
+			r.Delete("/users/{user_id}", h.deleteUser)
+			r.Delete("/teams/{team}", h.deleteTeam)
+			r.Put("/teams/{team}/chat-channel", h.setTeamChannel)
+			r.Delete("/teams/{team}/chat-channel", h.deleteTeamChannel)
+			r.Put("/teams/{team}/sla", h.setTeamSLA)
+			r.Delete("/teams/{team}/sla", h.deleteTeamSLA)
+
+			r.Post("/webhooks", h.createWebhook)
+			r.Get("/webhooks", h.listWebhooks)
+			r.Get("/webhooks/{webhook_id}", h.getWebhook)
+			r.Delete("/webhooks/{webhook_id}", h.deleteWebhook)
+			r.Get("/webhooks/{webhook_id}/deliveries", h.listWebhookDeliveries)
+
+			r.Post("/organizations", h.createOrganization)
+			r.Get("/organizations", h.listOrganizations)
+		})
+	})
+
+	h.r.Group(func(r chi.Router) {
+		r.Use(limitBody(maxIntegrationPayload))
+		if h.limiter != nil {
+			r.Use(h.rateLimited)
+		}
+		if h.idempotency != nil {
+			r.Use(h.idempotent)
+		}
+		if h.githubSecret != "" {
+			r.Post("/integrations/github", h.githubWebhook)
+		}
+		if h.gitlabToken != "" {
+			r.Post("/integrations/gitlab", h.gitlabWebhook)
+		}
+		if h.slackSecret != "" {
+			r.Post("/integrations/slack/commands", h.slashCommand)
+		}
+	})
+}
+
+// defaultMaxBodySize caps API request bodies unless WithMaxBodySize says
+// otherwise; the largest legitimate body is a few hundred bytes.
+const defaultMaxBodySize = 1 << 20
+
+// WithMaxBodySize caps API request bodies at n bytes. Larger bodies are
+// refused with 413.
+func WithMaxBodySize(n int64) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.maxBodySize = n
+		}
+	}
+}
+
+// limitBody caps request bodies at n bytes, so that neither the idempotency
+// middleware nor a handler reads more.
+func limitBody(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// decodeJSON decodes the body of r into v, rejecting unknown fields and
+// anything after the JSON value, and writes the error response if it cannot.
+func (h *Handler) decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(v)
+	if err == nil && !errors.Is(dec.Decode(&struct{}{}), io.EOF) {
+		err = errors.New("unexpected data after JSON value")
+	}
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+	case errors.As(err, &tooLarge):
+		h.writeError(w, "PAYLOAD_TOO_LARGE", fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+	default:
+		h.writeError(w, "BAD_REQUEST", "invalid JSON: "+err.Error(), http.StatusBadRequest)
+	}
+	return err
 }
 
 func (h *Handler) writeJSON(w http.ResponseWriter, v interface{}, code int) {
@@ -60,15 +206,34 @@ func (h *Handler) writeError(w http.ResponseWriter, code, message string, status
 	json.NewEncoder(w).Encode(errorResp)
 }
 
+// writeServiceError maps the service error sentinels to HTTP statuses.
+func (h *Handler) writeServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrUnauthorized):
+		h.writeError(w, "UNAUTHORIZED", err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrForbidden):
+		h.writeError(w, "FORBIDDEN", err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrNotFound):
+		h.writeError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrTeamNotEmpty):
+		h.writeError(w, "TEAM_NOT_EMPTY", err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrConflict):
+		h.writeError(w, "CONFLICT", err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrBadRequest):
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+	default:
+		h.writeError(w, "INTERNAL_ERROR", "internal error", http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) createTeam(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("createTeam request")
+	h.log(r.Context()).Info("createTeam request")
 
 	var body struct {
 		Name string `json:"name"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		h.logger.Warn("invalid JSON in createTeam request", "error", err)
-		h.writeError(w, "BAD_REQUEST", "invalid JSON", http.StatusBadRequest)
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in createTeam request", "error", err)
 		return
 	}
 
@@ -78,18 +243,22 @@ func (h *Handler) createTeam(w http.ResponseWriter, r *http.Request) {
 	}
 
 	t, err := h.svc.CreateTeam(r.Context(), body.Name)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		h.writeError(w, "TEAM_EXISTS", fmt.Sprintf("team %q already exists", body.Name), http.StatusConflict)
+		return
+	}
 	if err != nil {
-		h.logger.Error("failed to create team", "error", err, "name", body.Name)
+		h.log(r.Context()).Error("failed to create team", "error", err, "name", body.Name)
 		h.writeError(w, "INTERNAL_ERROR", "failed to create team", http.StatusInternalServerError)
 		return
 	}
 
-	h.logger.Info("team created successfully", "team_id", t.ID, "name", t.Name)
+	h.log(r.Context()).Info("team created successfully", "team_id", t.ID, "name", t.Name)
 	h.writeJSON(w, t, http.StatusCreated)
 }
 
 func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("createUser request")
+	h.log(r.Context()).Info("createUser request")
 
 	teamIDStr := chi.URLParam(r, "team_id")
 	teamID, err := strconv.Atoi(teamIDStr)
@@ -103,9 +272,8 @@ func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
 		IsActive *bool  `json:"is_active"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		h.logger.Warn("invalid JSON in createUser request", "error", err)
-		h.writeError(w, "BAD_REQUEST", "invalid JSON", http.StatusBadRequest)
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in createUser request", "error", err)
 		return
 	}
 
@@ -121,26 +289,89 @@ func (h *Handler) createUser(w http.ResponseWriter, r *http.Request) {
 
 	u, err := h.svc.CreateUser(r.Context(), &teamID, body.Name, isActive)
 	if err != nil {
-		h.logger.Error("failed to create user", "error", err, "team_id", teamID, "name", body.Name)
+		h.log(r.Context()).Error("failed to create user", "error", err, "team_id", teamID, "name", body.Name)
 		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	h.logger.Info("user created successfully", "user_id", u.ID, "name", u.Name, "team_id", teamID)
+	h.log(r.Context()).Info("user created successfully", "user_id", u.ID, "name", u.Name, "team_id", teamID)
 	h.writeJSON(w, u, http.StatusCreated)
 }
 
+func (h *Handler) updateUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Name     *string         `json:"name"`
+		TeamID   json.RawMessage `json:"team_id"`
+		IsActive *bool           `json:"is_active"`
+	}
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in updateUser request", "error", err)
+		return
+	}
+
+	upd := service.UserUpdate{Name: body.Name, IsActive: body.IsActive}
+	if len(body.TeamID) > 0 {
+		upd.SetTeam = true
+		if err := json.Unmarshal(body.TeamID, &upd.TeamID); err != nil {
+			h.writeError(w, "BAD_REQUEST", "team_id must be an integer or null", http.StatusBadRequest)
+			return
+		}
+	}
+
+	res, err := h.svc.UpdateUser(r.Context(), userID, upd)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to update user", "error", err, "user_id", userID)
+		h.writeServiceError(w, err)
+		return
+	}
+
+	h.log(r.Context()).Info("user updated successfully", "user_id", userID, "reassignments", len(res.Reassignments))
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.DeleteUser(r.Context(), userID)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to delete user", "error", err, "user_id", userID)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) deleteTeam(w http.ResponseWriter, r *http.Request) {
+	ref := chi.URLParam(r, "team")
+
+	if err := h.svc.DeleteTeam(r.Context(), ref); err != nil {
+		h.log(r.Context()).Warn("failed to delete team", "error", err, "team", ref)
+		h.writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *Handler) createPR(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("createPR request")
+	h.log(r.Context()).Info("createPR request")
 
 	var body struct {
 		Title    string `json:"title"`
 		AuthorID int    `json:"author_id"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		h.logger.Warn("invalid JSON in createPR request", "error", err)
-		h.writeError(w, "BAD_REQUEST", "invalid JSON", http.StatusBadRequest)
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in createPR request", "error", err)
 		return
 	}
 
@@ -156,7 +387,11 @@ func (h *Handler) createPR(w http.ResponseWriter, r *http.Request) {
 
 	pr, err := h.svc.CreatePR(r.Context(), body.Title, body.AuthorID)
 	if err != nil {
-		h.logger.Error("failed to create PR", "error", err, "title", body.Title, "author_id", body.AuthorID)
+		h.log(r.Context()).Error("failed to create PR", "error", err, "title", body.Title, "author_id", body.AuthorID)
+		if errors.Is(err, service.ErrForbidden) {
+			h.writeServiceError(w, err)
+			return
+		}
 		switch err.Error() {
 		case "bad request: author not found", "bad request: author is not active":
 			h.writeError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
@@ -166,12 +401,12 @@ func (h *Handler) createPR(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("PR created successfully", "pr_id", pr.ID, "reviewers_count", len(pr.Reviewers))
+	h.log(r.Context()).Info("PR created successfully", "pr_id", pr.ID, "reviewers_count", len(pr.Reviewers))
 	h.writeJSON(w, pr, http.StatusCreated)
 }
 
 func (h *Handler) reassign(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("reassign reviewer request")
+	h.log(r.Context()).Info("reassign reviewer request")
 
 	prIDStr := chi.URLParam(r, "pr_id")
 	prID, err := strconv.Atoi(prIDStr)
@@ -183,9 +418,8 @@ func (h *Handler) reassign(w http.ResponseWriter, r *http.Request) {
 	var body struct {
 		OldUserID int `json:"old_user_id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		h.logger.Warn("invalid JSON in reassign request", "error", err)
-		h.writeError(w, "BAD_REQUEST", "invalid JSON", http.StatusBadRequest)
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in reassign request", "error", err)
 		return
 	}
 
@@ -196,10 +430,16 @@ func (h *Handler) reassign(w http.ResponseWriter, r *http.Request) {
 
 	res, err := h.svc.ReassignReviewer(r.Context(), prID, body.OldUserID)
 	if err != nil {
-		h.logger.Error("failed to reassign reviewer", "error", err, "pr_id", prID, "old_user_id", body.OldUserID)
+		h.log(r.Context()).Error("failed to reassign reviewer", "error", err, "pr_id", prID, "old_user_id", body.OldUserID)
+		if errors.Is(err, service.ErrForbidden) {
+			h.writeServiceError(w, err)
+			return
+		}
 		switch err.Error() {
 		case "bad request: cannot reassign merged pr":
 			h.writeError(w, "PR_MERGED", err.Error(), http.StatusConflict)
+		case "bad request: cannot reassign closed pr":
+			h.writeError(w, "PR_CLOSED", err.Error(), http.StatusConflict)
 		case "bad request: no active candidates to reassign":
 			h.writeError(w, "NO_CANDIDATE", err.Error(), http.StatusConflict)
 		case "bad request: reviewer is not assigned to this PR":
@@ -212,12 +452,12 @@ func (h *Handler) reassign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("reviewer reassigned successfully", "pr_id", prID, "old_user_id", body.OldUserID)
+	h.log(r.Context()).Info("reviewer reassigned successfully", "pr_id", prID, "old_user_id", body.OldUserID)
 	h.writeJSON(w, res, http.StatusOK)
 }
 
 func (h *Handler) merge(w http.ResponseWriter, r *http.Request) {
-	h.logger.Info("merge PR request")
+	h.log(r.Context()).Info("merge PR request")
 
 	prIDStr := chi.URLParam(r, "pr_id")
 	prID, err := strconv.Atoi(prIDStr)
@@ -228,7 +468,11 @@ func (h *Handler) merge(w http.ResponseWriter, r *http.Request) {
 
 	res, err := h.svc.MergePR(r.Context(), prID)
 	if err != nil {
-		h.logger.Error("failed to merge PR", "error", err, "pr_id", prID)
+		h.log(r.Context()).Error("failed to merge PR", "error", err, "pr_id", prID)
+		if errors.Is(err, service.ErrForbidden) {
+			h.writeServiceError(w, err)
+			return
+		}
 		if err.Error() == "bad request: pr not found" {
 			h.writeError(w, "NOT_FOUND", err.Error(), http.StatusNotFound)
 		} else {
@@ -237,7 +481,7 @@ func (h *Handler) merge(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.logger.Info("PR merged successfully", "pr_id", prID)
+	h.log(r.Context()).Info("PR merged successfully", "pr_id", prID)
 	h.writeJSON(w, res, http.StatusOK)
 }
 
@@ -249,21 +493,31 @@ func (h *Handler) listPRsForUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.svc.ListPRsAssignedToUser(r.Context(), userID)
+	filter, err := parseReviewQueueFilter(r.URL.Query())
 	if err != nil {
-		h.logger.Error("failed to list PRs for user", "error", err, "user_id", userID)
 		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	h.logger.Debug("retrieved PRs for user", "user_id", userID, "prs_count", len(res))
+	res, err := h.svc.ListPRsAssignedToUser(r.Context(), userID, filter)
+	if err != nil {
+		h.log(r.Context()).Error("failed to list PRs for user", "error", err, "user_id", userID)
+		if errors.Is(err, service.ErrForbidden) {
+			h.writeServiceError(w, err)
+			return
+		}
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.log(r.Context()).Debug("retrieved PRs for user", "user_id", userID, "prs_count", len(res.Items))
 	h.writeJSON(w, res, http.StatusOK)
 }
 
 func (h *Handler) stats(w http.ResponseWriter, r *http.Request) {
 	c, err := h.svc.StatsAssignments(r.Context())
 	if err != nil {
-		h.logger.Error("failed to get stats", "error", err)
+		h.log(r.Context()).Error("failed to get stats", "error", err)
 		h.writeError(w, "INTERNAL_ERROR", "failed to get statistics", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStrictJSONBodies(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewRepo()
+	h := NewHandler(service.NewService(repo, logger), logger, WithMaxBodySize(64), WithIdempotency(repo, time.Hour))
+
+	rr := do(h, http.MethodPost, "/teams", `{"name":"backend","nmae":"typo"}`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `unknown field \"nmae\"`)
+
+	rr = do(h, http.MethodPost, "/teams", `{"name":"backend"}{"name":"frontend"}`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "only one JSON value is accepted")
+
+	big := `{"name":"` + strings.Repeat("a", 100) + `"}`
+	rr = do(h, http.MethodPost, "/teams", big, "")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+	assert.Contains(t, rr.Body.String(), "PAYLOAD_TOO_LARGE")
+	rr = do(h, http.MethodPost, "/teams", big, "k")
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rr.Code, "the idempotency middleware does not read past the limit either")
+
+	assert.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", ` {"name":"backend"} `+"\n", "").Code)
+}
+
+func TestCreateTeamRejectsDuplicateName(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+
+	assert.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "").Code)
+	rr := do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "TEAM_EXISTS")
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/service"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	defaultIdempotencyTTL     = 24 * time.Hour
+	idempotencyReserveRetries = 2
+	// idempotencyPendingLease is how long a request that never finished,
+	// because the process died mid-request, keeps its key from being retried.
+	idempotencyPendingLease = time.Minute
+)
+
+type IdempotencyStore interface {
+	CreateIdempotencyKey(ctx context.Context, rec models.IdempotencyRecord) error
+	GetIdempotencyKey(ctx context.Context, key string) (models.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, body []byte) error
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+}
+
+type Option func(*Handler)
+
+// WithIdempotency makes mutating requests honour the Idempotency-Key header.
+// Responses are stored in the given store, so a retry that lands on another
+// replica is still answered from the first attempt.
+func WithIdempotency(store IdempotencyStore, ttl time.Duration) Option {
+	return func(h *Handler) {
+		if ttl <= 0 {
+			ttl = defaultIdempotencyTTL
+		}
+		h.idempotency = store
+		h.idempotencyTTL = ttl
+	}
+}
+
+type capturingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *capturingWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	fmt.Fprintf(sum, "%s\n%s\n", r.Method, r.URL.RequestURI())
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+// storedIdempotencyKey namespaces key by the organization and the caller of
+// r, so callers that happen to pick the same key neither clash nor learn that
+// someone else used it.
+func storedIdempotencyKey(r *http.Request, key string) string {
+	var b strings.Builder
+	if id, ok := repository.OrgFrom(r.Context()); ok {
+		fmt.Fprintf(&b, "org:%d|", id)
+	}
+	if p, ok := service.PrincipalFrom(r.Context()); ok {
+		if p.Subject != "" {
+			fmt.Fprintf(&b, "sub:%q|", p.Subject)
+		} else {
+			fmt.Fprintf(&b, "token:%d|", p.TokenID)
+		}
+	}
+	b.WriteString(key)
+	return b.String()
+}
+
+func (h *Handler) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			h.writeError(w, "BAD_REQUEST", "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, "PAYLOAD_TOO_LARGE", fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			h.writeError(w, "BAD_REQUEST", "failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		fingerprint := requestFingerprint(r, body)
+		stored := storedIdempotencyKey(r, key)
+
+		existing, reserved, err := h.reserveIdempotencyKey(r.Context(), stored, fingerprint)
+		if err != nil {
+			h.log(r.Context()).Error("failed to reserve idempotency key", "error", err, "key", key)
+			h.writeError(w, "INTERNAL_ERROR", "failed to process Idempotency-Key", http.StatusInternalServerError)
+			return
+		}
+
+		if !reserved {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				h.writeError(w, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used with a different request", http.StatusConflict)
+			case existing.CompletedAt == nil:
+				h.writeError(w, "IDEMPOTENCY_KEY_IN_PROGRESS", "a request with this Idempotency-Key is still being processed", http.StatusConflict)
+			default:
+				h.log(r.Context()).Info("replaying idempotent response", "key", key, "status", existing.StatusCode)
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(existing.StatusCode)
+				w.Write(existing.ResponseBody)
+			}
+			return
+		}
+
+		// The outcome must be recorded even if the client has already gone away,
+		// that is exactly the case a retry will come back for.
+		ctx := context.WithoutCancel(r.Context())
+		completed := false
+		// A failed or panicking request releases the key, so that a retry is
+		// processed again; the deferred call also runs while a panic unwinds.
+		defer func() {
+			if completed {
+				return
+			}
+			if err := h.idempotency.DeleteIdempotencyKey(ctx, stored); err != nil {
+				h.log(r.Context()).Error("failed to release idempotency key", "error", err, "key", key)
+			}
+		}()
+
+		cw := &capturingWriter{ResponseWriter: w}
+		next.ServeHTTP(cw, r)
+
+		if cw.status == 0 || cw.status >= http.StatusInternalServerError {
+			return
+		}
+		completed = true
+		if err := h.idempotency.CompleteIdempotencyKey(ctx, stored, cw.status, cw.body.Bytes()); err != nil {
+			h.log(r.Context()).Error("failed to store idempotent response", "error", err, "key", key)
+		}
+	})
+}
+
+// reserveIdempotencyKey inserts a pending record for key. If the key is taken
+// it returns the stored record instead; expired records, and pending ones
+// whose lease ran out, are dropped and the reservation is retried.
+func (h *Handler) reserveIdempotencyKey(ctx context.Context, key, fingerprint string) (models.IdempotencyRecord, bool, error) {
+	for range idempotencyReserveRetries {
+		now := time.Now()
+		err := h.idempotency.CreateIdempotencyKey(ctx, models.IdempotencyRecord{Key: key, Fingerprint: fingerprint, CreatedAt: now})
+		if err == nil {
+			return models.IdempotencyRecord{}, true, nil
+		}
+		if !errors.Is(err, repository.ErrAlreadyExists) {
+			return models.IdempotencyRecord{}, false, err
+		}
+
+		existing, err := h.idempotency.GetIdempotencyKey(ctx, key)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return models.IdempotencyRecord{}, false, err
+		}
+
+		expired := existing.CreatedAt.Before(now.Add(-h.idempotencyTTL))
+		abandoned := existing.CompletedAt == nil && existing.CreatedAt.Before(now.Add(-idempotencyPendingLease))
+		if expired || abandoned {
+			if err := h.idempotency.DeleteIdempotencyKey(ctx, key); err != nil {
+				return models.IdempotencyRecord{}, false, err
+			}
+			continue
+		}
+		return existing, false, nil
+	}
+	return models.IdempotencyRecord{}, false, fmt.Errorf("reserve idempotency key %q: too much contention", key)
+}
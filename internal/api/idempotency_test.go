@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newIdempotentHandler(t *testing.T, ttl time.Duration) (*Handler, repository.Repository) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewRepo()
+	svc := service.NewService(repo, logger)
+	return NewHandler(svc, logger, WithIdempotency(repo, ttl)), repo
+}
+
+func do(h *Handler, method, path, body, key string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestIdempotentRetryIsReplayed(t *testing.T) {
+	h, repo := newIdempotentHandler(t, time.Hour)
+
+	first := do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "retry-1")
+	require.Equal(t, http.StatusCreated, first.Code)
+
+	second := do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "retry-1")
+	assert.Equal(t, http.StatusCreated, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+
+	teams := 0
+	require.NoError(t, repo.ForEachTeam(context.Background(), func(models.Team) error {
+		teams++
+		return nil
+	}))
+	assert.Equal(t, 1, teams)
+}
+
+func TestIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	h, _ := newIdempotentHandler(t, time.Hour)
+
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "k").Code)
+
+	rr := do(h, http.MethodPost, "/teams", `{"name":"frontend"}`, "k")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "IDEMPOTENCY_KEY_REUSED")
+
+	rr = do(h, http.MethodPost, "/prs", `{"name":"backend"}`, "k")
+	assert.Equal(t, http.StatusConflict, rr.Code, "same key on another endpoint is a different request")
+}
+
+func TestIdempotencyKeyInProgress(t *testing.T) {
+	h, repo := newIdempotentHandler(t, time.Hour)
+
+	body := `{"name":"backend"}`
+	req := httptest.NewRequest(http.MethodPost, "/teams", strings.NewReader(body))
+	req = req.WithContext(repository.WithOrg(req.Context(), models.DefaultOrgID))
+	require.NoError(t, repo.CreateIdempotencyKey(context.Background(), models.IdempotencyRecord{
+		Key:         storedIdempotencyKey(req, "busy"),
+		Fingerprint: requestFingerprint(req, []byte(body)),
+		CreatedAt:   time.Now(),
+	}))
+
+	rr := do(h, http.MethodPost, "/teams", body, "busy")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "IDEMPOTENCY_KEY_IN_PROGRESS")
+}
+
+func TestAbandonedIdempotencyKeyIsReprocessed(t *testing.T) {
+	h, repo := newIdempotentHandler(t, time.Hour)
+
+	body := `{"name":"backend"}`
+	req := httptest.NewRequest(http.MethodPost, "/teams", strings.NewReader(body))
+	req = req.WithContext(repository.WithOrg(req.Context(), models.DefaultOrgID))
+	require.NoError(t, repo.CreateIdempotencyKey(context.Background(), models.IdempotencyRecord{
+		Key:         storedIdempotencyKey(req, "crashed"),
+		Fingerprint: requestFingerprint(req, []byte(body)),
+		CreatedAt:   time.Now().Add(-2 * idempotencyPendingLease),
+	}))
+
+	rr := do(h, http.MethodPost, "/teams", body, "crashed")
+	assert.Equal(t, http.StatusCreated, rr.Code, "a request that never finished does not hold its key for the whole TTL")
+}
+
+func TestPanickingRequestReleasesIdempotencyKey(t *testing.T) {
+	h, _ := newIdempotentHandler(t, time.Hour)
+	calls := 0
+	h.r.With(h.idempotent).Post("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		h.writeJSON(w, map[string]int{"calls": calls}, http.StatusCreated)
+	})
+
+	rr := do(h, http.MethodPost, "/flaky", `{}`, "ci-retry")
+	require.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	rr = do(h, http.MethodPost, "/flaky", `{}`, "ci-retry")
+	assert.Equal(t, http.StatusCreated, rr.Code, "the retry is processed instead of reported in progress")
+	assert.Equal(t, 2, calls)
+	rr = do(h, http.MethodPost, "/flaky", `{}`, "ci-retry")
+	assert.Equal(t, "true", rr.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, 2, calls)
+}
+
+func TestExpiredIdempotencyKeyIsReprocessed(t *testing.T) {
+	h, repo := newIdempotentHandler(t, time.Minute)
+
+	req := httptest.NewRequest(http.MethodPost, "/teams", nil)
+	req = req.WithContext(repository.WithOrg(req.Context(), models.DefaultOrgID))
+	require.NoError(t, repo.CreateIdempotencyKey(context.Background(), models.IdempotencyRecord{
+		Key:         storedIdempotencyKey(req, "stale"),
+		Fingerprint: "whatever",
+		CreatedAt:   time.Now().Add(-time.Hour),
+	}))
+
+	rr := do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "stale")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Empty(t, rr.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestClientErrorsAreReplayedToo(t *testing.T) {
+	h, _ := newIdempotentHandler(t, time.Hour)
+
+	first := do(h, http.MethodPost, "/teams", `{"name":""}`, "bad")
+	require.Equal(t, http.StatusBadRequest, first.Code)
+
+	second := do(h, http.MethodPost, "/teams", `{"name":""}`, "bad")
+	assert.Equal(t, http.StatusBadRequest, second.Code)
+	assert.Equal(t, "true", second.Header().Get(IdempotentReplayedHeader))
+}
+
+func TestRequestsWithoutKeyAreNotDeduplicated(t *testing.T) {
+	h, _ := newIdempotentHandler(t, time.Hour)
+
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "").Code)
+	rr := do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "")
+	assert.NotEqual(t, http.StatusCreated, rr.Code)
+	assert.Empty(t, rr.Header().Get(IdempotentReplayedHeader))
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"prmanager/internal/models"
+	"prmanager/internal/vcs"
+	"prmanager/internal/vcs/github"
+	"prmanager/internal/vcs/gitlab"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxIntegrationPayload caps the body read from code host webhooks; pull and
+// merge request events are far smaller.
+const maxIntegrationPayload = 5 << 20
+
+// WithGitHub enables POST /integrations/github, authenticating deliveries
+// with the webhook secret configured on the GitHub side.
+func WithGitHub(secret string) Option {
+	return func(h *Handler) {
+		h.githubSecret = secret
+	}
+}
+
+// WithGitLab enables POST /integrations/gitlab, authenticating deliveries
+// with the secret token configured on the GitLab webhook.
+func WithGitLab(token string) Option {
+	return func(h *Handler) {
+		h.gitlabToken = token
+	}
+}
+
+// GitLabUsers looks up GitLab usernames by user id.
+type GitLabUsers interface {
+	Username(ctx context.Context, id int) (string, error)
+}
+
+// WithGitLabUsers lets the GitLab integration find the author of a merge
+// request that someone else marked ready or reopened. Without it such merge
+// requests are not imported.
+func WithGitLabUsers(users GitLabUsers) Option {
+	return func(h *Handler) {
+		h.gitlabUsers = users
+	}
+}
+
+func (h *Handler) readIntegrationPayload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIntegrationPayload))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.writeError(w, "PAYLOAD_TOO_LARGE", "payload too large", http.StatusRequestEntityTooLarge)
+			return nil, false
+		}
+		h.writeError(w, "BAD_REQUEST", "failed to read body", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+func (h *Handler) writeSyncResult(w http.ResponseWriter, res models.ExternalSyncResult) {
+	code := http.StatusOK
+	switch res.Status {
+	case models.ExternalSyncCreated:
+		code = http.StatusCreated
+	case models.ExternalSyncIgnored:
+		code = http.StatusAccepted
+	}
+	h.writeJSON(w, res, code)
+}
+
+func (h *Handler) githubWebhook(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readIntegrationPayload(w, r)
+	if !ok {
+		return
+	}
+	delivery := r.Header.Get(github.DeliveryHeader)
+	if err := github.VerifySignature(h.githubSecret, body, r.Header.Get(github.SignatureHeader)); err != nil {
+		h.log(r.Context()).Warn("rejected GitHub delivery", "error", err, "delivery", delivery)
+		h.writeError(w, "UNAUTHORIZED", "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	switch event := r.Header.Get(github.EventHeader); event {
+	case github.EventPing:
+		h.writeJSON(w, map[string]string{"status": "pong"}, http.StatusOK)
+		return
+	case github.EventPullRequest:
+	default:
+		h.writeSyncResult(w, models.ExternalSyncResult{Status: models.ExternalSyncIgnored, Reason: "unsupported event " + strconv.Quote(event)})
+		return
+	}
+
+	var e github.PullRequestEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in GitHub delivery", "error", err, "delivery", delivery)
+		h.writeError(w, "BAD_REQUEST", "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	action, pr, ok := e.Translate()
+	if !ok {
+		h.writeSyncResult(w, models.ExternalSyncResult{Status: models.ExternalSyncIgnored, Reason: "unsupported action " + strconv.Quote(e.Action)})
+		return
+	}
+	h.syncExternalPR(w, r, delivery, action, pr)
+}
+
+func (h *Handler) gitlabWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery := r.Header.Get(gitlab.UUIDHeader)
+	if err := gitlab.VerifyToken(h.gitlabToken, r.Header.Get(gitlab.TokenHeader)); err != nil {
+		h.log(r.Context()).Warn("rejected GitLab delivery", "error", err, "delivery", delivery)
+		h.writeError(w, "UNAUTHORIZED", "invalid token", http.StatusUnauthorized)
+		return
+	}
+	body, ok := h.readIntegrationPayload(w, r)
+	if !ok {
+		return
+	}
+
+	if event := r.Header.Get(gitlab.EventHeader); event != gitlab.EventMergeRequest {
+		h.writeSyncResult(w, models.ExternalSyncResult{Status: models.ExternalSyncIgnored, Reason: "unsupported event " + strconv.Quote(event)})
+		return
+	}
+
+	var e gitlab.MergeRequestEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in GitLab delivery", "error", err, "delivery", delivery)
+		h.writeError(w, "BAD_REQUEST", "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	action, pr, ok := e.Translate()
+	if !ok {
+		h.writeSyncResult(w, models.ExternalSyncResult{Status: models.ExternalSyncIgnored, Reason: "unsupported action " + strconv.Quote(e.ObjectAttributes.Action)})
+		return
+	}
+	if pr.AuthorLogin == "" && action != vcs.ActionClosed && action != vcs.ActionMerged && h.gitlabUsers != nil {
+		login, err := h.gitlabUsers.Username(r.Context(), e.ObjectAttributes.AuthorID)
+		if err != nil {
+			h.log(r.Context()).Error("failed to look up GitLab merge request author", "error", err, "delivery", delivery, "author_id", e.ObjectAttributes.AuthorID)
+			h.writeError(w, "BAD_GATEWAY", "failed to look up the merge request author", http.StatusBadGateway)
+			return
+		}
+		pr.AuthorLogin = login
+	}
+	h.syncExternalPR(w, r, delivery, action, pr)
+}
+
+func (h *Handler) syncExternalPR(w http.ResponseWriter, r *http.Request, delivery string, action vcs.Action, pr vcs.PullRequest) {
+	ref := pr.Ref
+	if ref.Repo == "" || ref.Number <= 0 {
+		h.writeError(w, "BAD_REQUEST", "repository and pull request number are required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.SyncExternalPR(r.Context(), action, pr)
+	if err != nil {
+		h.log(r.Context()).Error("failed to sync external pull request", "error", err, "provider", ref.Provider, "delivery", delivery, "repo", ref.Repo, "number", ref.Number)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.log(r.Context()).Info("external pull request synced", "provider", ref.Provider, "delivery", delivery, "repo", ref.Repo, "number", ref.Number, "action", action, "status", res.Status)
+	h.writeSyncResult(w, res)
+}
+
+func (h *Handler) setIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+	var body struct {
+		Login string `json:"login"`
+	}
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		return
+	}
+
+	res, err := h.svc.SetIdentity(r.Context(), userID, chi.URLParam(r, "provider"), body.Login)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to set identity", "error", err, "user_id", userID)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) listIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.ListIdentities(r.Context(), userID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) deleteIdentity(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteIdentity(r.Context(), userID, chi.URLParam(r, "provider")); err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
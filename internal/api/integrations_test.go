@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+	"prmanager/internal/vcs/github"
+	"prmanager/internal/vcs/gitlab"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const githubSecret = "It's a Secret to Everybody"
+
+// deliverGitHub replays a recorded payload from the github package's
+// testdata the way GitHub would send it.
+func deliverGitHub(t *testing.T, h *Handler, event, fixture, secret string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("..", "vcs", "github", "testdata", fixture))
+	require.NoError(t, err)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	req := httptest.NewRequest(http.MethodPost, "/integrations/github", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(github.EventHeader, event)
+	req.Header.Set(github.DeliveryHeader, "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	req.Header.Set(github.SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGitHubWebhook(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger, WithGitHub(githubSecret))
+
+	for _, req := range []struct{ path, body string }{
+		{"/teams", `{"name":"backend"}`},
+		{"/teams/1/users", `{"name":"alice","is_active":true}`},
+		{"/teams/1/users", `{"name":"bob","is_active":true}`},
+	} {
+		require.Equal(t, http.StatusCreated, do(h, http.MethodPost, req.path, req.body, "").Code)
+	}
+
+	rr := deliverGitHub(t, h, "pull_request", "opened.json", "wrong secret")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	rr = deliverGitHub(t, h, "ping", "ping.json", githubSecret)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	rr = deliverGitHub(t, h, "issues", "ping.json", githubSecret)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	rr = deliverGitHub(t, h, "pull_request", "opened.json", githubSecret)
+	require.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Contains(t, decode[models.ExternalSyncResult](t, rr).Reason, `"octo-alice" is not linked`)
+
+	rr = do(h, http.MethodPut, "/users/1/identities/github", `{"login":"Octo-Alice"}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = do(h, http.MethodPut, "/users/2/identities/github", `{"login":"octo-alice"}`, "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	rr = do(h, http.MethodGet, "/users/1/identities", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, decode[[]models.Identity](t, rr), 1)
+
+	rr = deliverGitHub(t, h, "pull_request", "opened_draft.json", githubSecret)
+	assert.Equal(t, http.StatusAccepted, rr.Code, "drafts are not tracked")
+	rr = deliverGitHub(t, h, "pull_request", "ready_for_review.json", githubSecret)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	created := decode[models.ExternalSyncResult](t, rr)
+	assert.Equal(t, "Add rate limiting to the public API", created.PR.Title)
+	require.Len(t, created.PR.Reviewers, 1)
+	assert.Equal(t, "bob", created.PR.Reviewers[0].Name)
+
+	rr = deliverGitHub(t, h, "pull_request", "opened.json", githubSecret)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, models.ExternalSyncUnchanged, decode[models.ExternalSyncResult](t, rr).Status)
+	rr = deliverGitHub(t, h, "pull_request", "synchronize.json", githubSecret)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+
+	for _, step := range []struct {
+		fixture string
+		status  models.PRStatus
+	}{
+		{"closed.json", models.PRStatusClosed},
+		{"reopened.json", models.PRStatusOpen},
+		{"closed_merged.json", models.PRStatusMerged},
+	} {
+		rr = deliverGitHub(t, h, "pull_request", step.fixture, githubSecret)
+		require.Equal(t, http.StatusOK, rr.Code, step.fixture)
+		assert.Equal(t, step.status, decode[models.ExternalSyncResult](t, rr).PR.Status, step.fixture)
+	}
+
+	rr = do(h, http.MethodGet, "/prs/1", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, models.PRStatusMerged, decode[models.PRWithReviewers](t, rr).Status)
+}
+
+func TestGitHubWebhookDisabledWithoutSecret(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+
+	rr := deliverGitHub(t, h, "ping", "ping.json", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+const gitlabToken = "glwt-7f3a9c"
+
+func deliverGitLab(t *testing.T, h *Handler, fixture, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("..", "vcs", "gitlab", "testdata", fixture))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/integrations/gitlab", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(gitlab.EventHeader, gitlab.EventMergeRequest)
+	req.Header.Set(gitlab.UUIDHeader, "3a2ad6ee-6c5f-4e0b-9c5f-2f4d6d1b8c11")
+	req.Header.Set(gitlab.TokenHeader, token)
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestGitLabWebhook(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger, WithGitLab(gitlabToken))
+
+	for _, req := range []struct{ path, body string }{
+		{"/teams", `{"name":"payments"}`},
+		{"/teams/1/users", `{"name":"dana","is_active":true}`},
+		{"/teams/1/users", `{"name":"kim","is_active":true}`},
+	} {
+		require.Equal(t, http.StatusCreated, do(h, http.MethodPost, req.path, req.body, "").Code)
+	}
+	require.Equal(t, http.StatusOK, do(h, http.MethodPut, "/users/1/identities/gitlab", `{"login":"dana.builder"}`, "").Code)
+
+	assert.Equal(t, http.StatusUnauthorized, deliverGitLab(t, h, "open.json", "wrong").Code)
+	assert.Equal(t, http.StatusAccepted, deliverGitLab(t, h, "open_draft.json", gitlabToken).Code)
+	assert.Equal(t, http.StatusAccepted, deliverGitLab(t, h, "update.json", gitlabToken).Code)
+
+	rr := deliverGitLab(t, h, "ready.json", gitlabToken)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	created := decode[models.ExternalSyncResult](t, rr)
+	assert.Equal(t, "Retry failed invoice webhooks", created.PR.Title)
+	assert.Equal(t, 1, created.PR.AuthorID)
+
+	rr = deliverGitLab(t, h, "open.json", gitlabToken)
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, models.ExternalSyncUnchanged, decode[models.ExternalSyncResult](t, rr).Status)
+
+	for _, step := range []struct {
+		fixture string
+		status  models.PRStatus
+	}{
+		{"close.json", models.PRStatusClosed},
+		{"reopen.json", models.PRStatusOpen},
+		{"merge.json", models.PRStatusMerged},
+	} {
+		rr = deliverGitLab(t, h, step.fixture, gitlabToken)
+		require.Equal(t, http.StatusOK, rr.Code, step.fixture)
+		assert.Equal(t, step.status, decode[models.ExternalSyncResult](t, rr).PR.Status, step.fixture)
+	}
+
+	rr = do(h, http.MethodGet, "/prs/2", "", "")
+	assert.Equal(t, http.StatusNotFound, rr.Code, "redeliveries never create a second PR")
+}
+
+type gitlabUsers map[int]string
+
+func (u gitlabUsers) Username(_ context.Context, id int) (string, error) {
+	if login, ok := u[id]; ok {
+		return login, nil
+	}
+	return "", errors.New("user not found")
+}
+
+func TestGitLabWebhookFindsTheAuthor(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	newHandler := func(opts ...Option) *Handler {
+		h := NewHandler(service.NewService(memory.NewRepo(), logger), logger, append(opts, WithGitLab(gitlabToken))...)
+		for _, req := range []struct{ path, body string }{
+			{"/teams", `{"name":"payments"}`},
+			{"/teams/1/users", `{"name":"dana","is_active":true}`},
+			{"/teams/1/users", `{"name":"kim","is_active":true}`},
+		} {
+			require.Equal(t, http.StatusCreated, do(h, http.MethodPost, req.path, req.body, "").Code)
+		}
+		require.Equal(t, http.StatusOK, do(h, http.MethodPut, "/users/1/identities/gitlab", `{"login":"dana.builder"}`, "").Code)
+		require.Equal(t, http.StatusOK, do(h, http.MethodPut, "/users/2/identities/gitlab", `{"login":"kim.lee"}`, "").Code)
+		return h
+	}
+
+	for _, fixture := range []string{"ready_by_reviewer.json", "reopen_by_reviewer.json"} {
+		t.Run(fixture, func(t *testing.T) {
+			rr := deliverGitLab(t, newHandler(), fixture, gitlabToken)
+			require.Equal(t, http.StatusAccepted, rr.Code, "without the users API the author is unknown")
+			assert.Equal(t, "author of the pull request is unknown", decode[models.ExternalSyncResult](t, rr).Reason)
+
+			rr = deliverGitLab(t, newHandler(WithGitLabUsers(gitlabUsers{21: "Dana.Builder"})), fixture, gitlabToken)
+			require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+			assert.Equal(t, 1, decode[models.ExternalSyncResult](t, rr).PR.AuthorID, "the author, not whoever acted on the merge request")
+
+			rr = deliverGitLab(t, newHandler(WithGitLabUsers(gitlabUsers{})), fixture, gitlabToken)
+			assert.Equal(t, http.StatusBadGateway, rr.Code)
+		})
+	}
+}
@@ -4,14 +4,57 @@ import (
 	"context"
 
 	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository"
+	"prmanager/internal/service"
+	"prmanager/internal/vcs"
 )
 
 type ServiceInterface interface {
 	CreateTeam(ctx context.Context, name string) (models.Team, error)
 	CreateUser(ctx context.Context, teamID *int, name string, isActive bool) (models.User, error)
+	UpdateUser(ctx context.Context, id int, upd service.UserUpdate) (models.UserUpdateResult, error)
+	DeleteUser(ctx context.Context, id int) (models.UserUpdateResult, error)
+	DeleteTeam(ctx context.Context, ref string) error
 	CreatePR(ctx context.Context, title string, authorID int) (models.PRWithReviewers, error)
 	ReassignReviewer(ctx context.Context, prID int, oldUserID int) (models.PRWithReviewers, error)
 	MergePR(ctx context.Context, prID int) (models.PRWithReviewers, error)
-	ListPRsAssignedToUser(ctx context.Context, userID int) ([]models.PRWithReviewers, error)
+	ListPRsAssignedToUser(ctx context.Context, userID int, f repository.ReviewQueueFilter) (pagination.Page[models.AssignedPR], error)
 	StatsAssignments(ctx context.Context) (int, error)
+
+	GetPR(ctx context.Context, id int) (models.PRWithReviewers, error)
+	GetTeam(ctx context.Context, ref string) (models.TeamWithMembers, error)
+	ListTeams(ctx context.Context, f repository.TeamFilter) (pagination.Page[models.Team], error)
+	GetUser(ctx context.Context, id int) (models.UserWithTeam, error)
+	ListUsers(ctx context.Context, teamRef string, f repository.UserFilter) (pagination.Page[models.User], error)
+
+	CreateWebhook(ctx context.Context, url, secret string, eventTypes []string) (models.Webhook, error)
+	GetWebhook(ctx context.Context, id int) (models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+	ListWebhookDeliveries(ctx context.Context, webhookID int, f repository.DeliveryFilter) (pagination.Page[models.WebhookDelivery], error)
+
+	SetIdentity(ctx context.Context, userID int, provider, login string) (models.Identity, error)
+	ListIdentities(ctx context.Context, userID int) ([]models.Identity, error)
+	DeleteIdentity(ctx context.Context, userID int, provider string) error
+	UserByIdentity(ctx context.Context, provider, login string) (models.User, error)
+	SyncExternalPR(ctx context.Context, action vcs.Action, pr vcs.PullRequest) (models.ExternalSyncResult, error)
+	GetReviewerSync(ctx context.Context, prID int) (models.ReviewerSync, error)
+
+	SetTeamChannel(ctx context.Context, teamRef, webhookURL, channel string) (models.TeamChannel, error)
+	GetTeamChannel(ctx context.Context, teamRef string) (models.TeamChannel, error)
+	DeleteTeamChannel(ctx context.Context, teamRef string) error
+
+	SetTeamSLA(ctx context.Context, teamRef string, sla models.TeamSLA) (models.TeamSLA, error)
+	GetTeamSLA(ctx context.Context, teamRef string) (models.TeamSLA, error)
+	DeleteTeamSLA(ctx context.Context, teamRef string) error
+
+	GetDigestPrefs(ctx context.Context, userID int) (models.DigestPrefs, error)
+	SetDigestPrefs(ctx context.Context, p models.DigestPrefs) (models.DigestPrefs, error)
+
+	Authenticate(ctx context.Context, token string) (service.Principal, error)
+
+	CreateOrganization(ctx context.Context, name string) (models.Organization, error)
+	ListOrganizations(ctx context.Context) ([]models.Organization, error)
+	ResolveOrganization(ctx context.Context, ref string) (models.Organization, error)
 }
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"prmanager/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	RequestIDHeader     = "X-Request-ID"
+	maxRequestIDLength  = 128
+	generatedIDByteSize = 16
+)
+
+// log returns the logger of the request ctx belongs to.
+func (h *Handler) log(ctx context.Context) *slog.Logger {
+	return service.LoggerFrom(ctx, h.logger)
+}
+
+// validRequestID reports whether a caller's request ID is short, printable
+// ASCII, so it can be logged and echoed back as is.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, generatedIDByteSize)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requestID keeps the caller's X-Request-ID, or makes one up, returns it in
+// the response and tags every log line written for the request with it,
+// including those of the service.
+func (h *Handler) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := service.WithLogger(r.Context(), h.logger.With("request_id", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// accessLog writes one line per request once it has been served.
+func (h *Handler) accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if sw.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		h.log(r.Context()).Log(r.Context(), level, "request served",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"route", chi.RouteContext(r.Context()).RoutePattern(),
+			"status", sw.status,
+			"bytes", sw.bytes,
+			"latency", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
+
+// recoverPanic turns a panicking handler into a 500 response instead of a
+// dropped connection, and logs the panic with its stack.
+func (h *Handler) recoverPanic(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			h.log(r.Context()).Error("handler panicked", "panic", rec, "stack", string(debug.Stack()))
+			if sw, ok := w.(*statusWriter); ok && sw.status != 0 {
+				return
+			}
+			h.writeError(w, "INTERNAL_ERROR", "internal error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
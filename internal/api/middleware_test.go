@@ -0,0 +1,100 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var lines []map[string]any
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestRequestIDReachesServiceLogs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/teams", strings.NewReader(`{"name":"backend"}`))
+	req.Header.Set(RequestIDHeader, "ci-4411")
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "ci-4411", rr.Header().Get(RequestIDHeader))
+
+	lines := logLines(t, &buf)
+	msgs := make(map[string]map[string]any)
+	for _, l := range lines {
+		assert.Equal(t, "ci-4411", l["request_id"], l["msg"])
+		msg, _ := l["msg"].(string)
+		msgs[msg] = l
+	}
+	require.Contains(t, msgs, "creating team", "service logs carry the request ID")
+	access := msgs["request served"]
+	require.NotNil(t, access, "one access line per request")
+	assert.Equal(t, "POST", access["method"])
+	assert.Equal(t, "/teams", access["route"])
+	assert.EqualValues(t, http.StatusCreated, access["status"])
+	assert.Contains(t, access, "latency")
+}
+
+func TestRequestIDIsGenerated(t *testing.T) {
+	h := newReadHandler(t)
+
+	for _, given := range []string{"", "bad id", strings.Repeat("x", maxRequestIDLength+1)} {
+		req := httptest.NewRequest(http.MethodGet, "/teams", nil)
+		req.Header.Set(RequestIDHeader, given)
+		rr := httptest.NewRecorder()
+		h.Router().ServeHTTP(rr, req)
+		id := rr.Header().Get(RequestIDHeader)
+		assert.Len(t, id, 2*generatedIDByteSize, "%q", given)
+		assert.NotEqual(t, given, id)
+	}
+}
+
+func TestPanicsBecome500(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+	h.r.Get("/boom", func(http.ResponseWriter, *http.Request) { panic("boom") })
+
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	resp := decode[ErrorResponse](t, rr)
+	assert.Equal(t, "INTERNAL_ERROR", resp.Error.Code)
+
+	var panicked, served bool
+	for _, l := range logLines(t, &buf) {
+		switch l["msg"] {
+		case "handler panicked":
+			panicked = true
+			assert.Equal(t, "boom", l["panic"])
+			assert.Contains(t, l["stack"], "TestPanicsBecome500")
+		case "request served":
+			served = true
+			assert.EqualValues(t, http.StatusInternalServerError, l["status"])
+		}
+	}
+	assert.True(t, panicked)
+	assert.True(t, served)
+}
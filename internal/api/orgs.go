@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/service"
+)
+
+// OrganizationHeader selects, by id or name, the organization a request acts
+// in. Principals bound to an organization may only name their own.
+const OrganizationHeader = "X-Organization"
+
+// tenant scopes the repository calls of a request to one organization: the
+// one the principal is bound to, else the one named by OrganizationHeader,
+// else the default organization.
+func (h *Handler) tenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgID := models.DefaultOrgID
+		if ref := r.Header.Get(OrganizationHeader); ref != "" {
+			o, err := h.svc.ResolveOrganization(r.Context(), ref)
+			if err != nil {
+				h.writeServiceError(w, err)
+				return
+			}
+			orgID = o.ID
+		}
+		if p, ok := service.PrincipalFrom(r.Context()); ok && p.OrgID != nil {
+			if r.Header.Get(OrganizationHeader) != "" && orgID != *p.OrgID {
+				h.writeError(w, "FORBIDDEN", "token is bound to another organization", http.StatusForbidden)
+				return
+			}
+			orgID = *p.OrgID
+		}
+		next.ServeHTTP(w, r.WithContext(repository.WithOrg(r.Context(), orgID)))
+	})
+}
+
+func (h *Handler) createOrganization(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in createOrganization request", "error", err)
+		return
+	}
+
+	res, err := h.svc.CreateOrganization(r.Context(), body.Name)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to create organization", "error", err, "name", body.Name)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusCreated)
+}
+
+func (h *Handler) listOrganizations(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListOrganizations(r.Context())
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
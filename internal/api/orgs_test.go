@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func doInOrg(h *Handler, token, org, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	if org != "" {
+		req.Header.Set(OrganizationHeader, org)
+	}
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+func TestOrganizationIsolation(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := service.NewService(memory.NewRepo(), logger)
+	h := NewHandler(svc, logger, WithAuth())
+
+	acme, err := svc.CreateOrganization(ctx, "acme")
+	require.NoError(t, err)
+	acmeCtx := repository.WithOrg(ctx, acme.ID)
+
+	// Both organizations have a team called backend.
+	home, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	away, err := svc.CreateTeam(acmeCtx, "backend")
+	require.NoError(t, err)
+	alice, err := svc.CreateUser(ctx, &home.ID, "Alice", true)
+	require.NoError(t, err)
+	bob, err := svc.CreateUser(ctx, &home.ID, "Bob", true)
+	require.NoError(t, err)
+	carol, err := svc.CreateUser(acmeCtx, &away.ID, "Carol", true)
+	require.NoError(t, err)
+	dave, err := svc.CreateUser(acmeCtx, &away.ID, "Dave", true)
+	require.NoError(t, err)
+
+	_, root, err := svc.IssueToken(ctx, "root", models.RoleAdmin, nil)
+	require.NoError(t, err)
+	_, acmeAdmin, err := svc.IssueToken(acmeCtx, "acme-ops", models.RoleAdmin, nil)
+	require.NoError(t, err)
+	_, carolToken, err := svc.IssueToken(ctx, "carol", models.RoleUser, &carol.ID)
+	require.NoError(t, err)
+
+	rr := doInOrg(h, acmeAdmin, "", http.MethodGet, "/users", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var ids []int
+	for _, u := range decode[pagination.Page[models.User]](t, rr).Items {
+		ids = append(ids, u.ID)
+	}
+	assert.ElementsMatch(t, []int{carol.ID, dave.ID}, ids)
+
+	rr = doInOrg(h, acmeAdmin, "", http.MethodGet, "/teams/backend", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, away.ID, decode[models.TeamWithMembers](t, rr).ID)
+
+	for _, path := range []string{
+		fmt.Sprintf("/users/%d", alice.ID),
+		fmt.Sprintf("/teams/%d", home.ID),
+	} {
+		assert.Equal(t, http.StatusNotFound, doInOrg(h, acmeAdmin, "", http.MethodGet, path, "").Code, path)
+	}
+	assert.Equal(t, http.StatusForbidden, doInOrg(h, acmeAdmin, "default", http.MethodGet, "/users", "").Code,
+		"an organization token cannot switch organizations")
+	assert.Equal(t, http.StatusForbidden, doInOrg(h, carolToken, "1", http.MethodGet, "/users", "").Code)
+
+	rr = doInOrg(h, acmeAdmin, "", http.MethodPost, "/prs", fmt.Sprintf(`{"title":"Steal","author_id":%d}`, alice.ID))
+	assert.Equal(t, http.StatusNotFound, rr.Code, rr.Body.String())
+	rr = doInOrg(h, acmeAdmin, "", http.MethodPatch, fmt.Sprintf("/users/%d", dave.ID), fmt.Sprintf(`{"team_id":%d}`, home.ID))
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "users cannot move into another organization's team")
+
+	rr = doInOrg(h, carolToken, "", http.MethodPost, "/prs", fmt.Sprintf(`{"title":"Add search","author_id":%d}`, carol.ID))
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	pr := decode[models.PRWithReviewers](t, rr)
+	assert.Equal(t, acme.ID, pr.PR.OrgID)
+	require.Len(t, pr.Reviewers, 1)
+	assert.Equal(t, dave.ID, pr.Reviewers[0].ID, "reviewers come from the author's organization only")
+
+	rr = doInOrg(h, acmeAdmin, "", http.MethodPost, fmt.Sprintf("/prs/%d/reassign", pr.PR.ID), fmt.Sprintf(`{"old_user_id":%d}`, dave.ID))
+	assert.Contains(t, rr.Body.String(), "no active candidates", "no one else in acme can review, and default users are not candidates")
+	assert.Equal(t, http.StatusNotFound, doInOrg(h, root, "", http.MethodGet, fmt.Sprintf("/prs/%d", pr.PR.ID), "").Code,
+		"the root token works in the default organization unless told otherwise")
+	assert.Equal(t, http.StatusOK, doInOrg(h, root, "acme", http.MethodGet, fmt.Sprintf("/prs/%d", pr.PR.ID), "").Code)
+	assert.Equal(t, http.StatusNotFound, doInOrg(h, root, "globex", http.MethodGet, "/users", "").Code)
+
+	rr = doInOrg(h, root, "", http.MethodGet, "/users", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	ids = nil
+	for _, u := range decode[pagination.Page[models.User]](t, rr).Items {
+		ids = append(ids, u.ID)
+	}
+	assert.ElementsMatch(t, []int{alice.ID, bob.ID}, ids)
+
+	assert.Equal(t, http.StatusForbidden, doInOrg(h, acmeAdmin, "", http.MethodPost, "/organizations", `{"name":"globex"}`).Code)
+	assert.Equal(t, http.StatusForbidden, doInOrg(h, acmeAdmin, "", http.MethodGet, "/webhooks", "").Code,
+		"webhooks see every organization's events")
+	assert.Equal(t, http.StatusCreated, doInOrg(h, root, "", http.MethodPost, "/organizations", `{"name":"globex"}`).Code)
+	assert.Equal(t, http.StatusConflict, doInOrg(h, root, "", http.MethodPost, "/organizations", `{"name":"globex"}`).Code)
+	rr = doInOrg(h, root, "", http.MethodGet, "/organizations", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, decode[[]models.Organization](t, rr), 3)
+}
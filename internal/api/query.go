@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository"
+)
+
+// queryList returns every value of a query parameter, accepting both
+// repeated parameters (?status=OPEN&status=MERGED) and comma-separated ones.
+func queryList(q url.Values, name string) []string {
+	var out []string
+	for _, v := range q[name] {
+		for _, part := range strings.Split(v, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+func queryTime(q url.Values, name string) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+func queryOrder(q url.Values) (bool, error) {
+	switch q.Get("order") {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	}
+	return false, fmt.Errorf("order must be asc or desc")
+}
+
+func queryBool(q url.Values, name string) (*bool, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", name)
+	}
+	return &b, nil
+}
+
+// queryIDPage parses limit and cursor for lists ordered by id.
+func queryIDPage(q url.Values) (int, *pagination.Cursor, error) {
+	limit, err := pagination.ParseLimit(q.Get("limit"))
+	if err != nil {
+		return 0, nil, err
+	}
+	after, err := pagination.DecodeFor(q.Get("cursor"), pagination.SortByID, false)
+	if err != nil {
+		return 0, nil, err
+	}
+	return limit, after, nil
+}
+
+func queryPRStatuses(q url.Values) ([]models.PRStatus, error) {
+	var out []models.PRStatus
+	for _, v := range queryList(q, "status") {
+		st := models.PRStatus(strings.ToUpper(v))
+		switch st {
+		case models.PRStatusOpen, models.PRStatusMerged, models.PRStatusClosed:
+			out = append(out, st)
+		default:
+			return nil, fmt.Errorf("unknown status %q", v)
+		}
+	}
+	return out, nil
+}
+
+func parseReviewQueueFilter(q url.Values) (repository.ReviewQueueFilter, error) {
+	var f repository.ReviewQueueFilter
+	var err error
+
+	if f.Statuses, err = queryPRStatuses(q); err != nil {
+		return f, err
+	}
+	if f.CreatedAfter, err = queryTime(q, "created_after"); err != nil {
+		return f, err
+	}
+	if f.CreatedBefore, err = queryTime(q, "created_before"); err != nil {
+		return f, err
+	}
+
+	switch sort := repository.ReviewQueueSort(q.Get("sort")); sort {
+	case "", repository.SortByCreatedAt:
+		f.SortBy = repository.SortByCreatedAt
+	case repository.SortByAssignedAt:
+		f.SortBy = sort
+	default:
+		return f, fmt.Errorf("sort must be created_at or assigned_at")
+	}
+	if f.Desc, err = queryOrder(q); err != nil {
+		return f, err
+	}
+
+	if f.Limit, err = pagination.ParseLimit(q.Get("limit")); err != nil {
+		return f, err
+	}
+	if f.After, err = pagination.DecodeFor(q.Get("cursor"), string(f.SortBy), f.Desc); err != nil {
+		return f, err
+	}
+	if f.After != nil && f.After.Time == nil {
+		return f, pagination.ErrInvalidCursor
+	}
+	return f, nil
+}
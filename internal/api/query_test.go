@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPRsForUserPagination(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "").Code)
+	for _, name := range []string{"author", "reviewer"} {
+		require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams/1/users", fmt.Sprintf(`{"name":%q,"is_active":true}`, name), "").Code)
+	}
+	for i := range 5 {
+		require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/prs", fmt.Sprintf(`{"title":"pr%d","author_id":1}`, i), "").Code)
+	}
+	require.Equal(t, http.StatusOK, do(h, http.MethodPost, "/prs/2/merge", "", "").Code)
+
+	var walked []int
+	path := "/users/2/prs?limit=2&order=desc&status=OPEN"
+	for {
+		rr := do(h, http.MethodGet, path, "", "")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var page pagination.Page[models.AssignedPR]
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+		for _, p := range page.Items {
+			walked = append(walked, p.ID)
+			assert.Equal(t, models.PRStatusOpen, p.Status)
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		path = "/users/2/prs?limit=2&order=desc&status=OPEN&cursor=" + *page.NextCursor
+	}
+	assert.Equal(t, []int{5, 4, 3, 1}, walked)
+}
+
+func TestListPRsForUserRejectsBadQuery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams", `{"name":"backend"}`, "").Code)
+	require.Equal(t, http.StatusCreated, do(h, http.MethodPost, "/teams/1/users", `{"name":"reviewer","is_active":true}`, "").Code)
+	require.Equal(t, http.StatusOK, do(h, http.MethodGet, "/users/1/prs", "", "").Code)
+
+	ascCursor := pagination.Cursor{Sort: "created_at", ID: 1}.Encode()
+	for _, q := range []string{
+		"status=DRAFT",
+		"created_after=yesterday",
+		"sort=title",
+		"order=up",
+		"limit=0",
+		"limit=1000",
+		"cursor=not-a-cursor!",
+		"order=desc&cursor=" + ascCursor,
+	} {
+		rr := do(h, http.MethodGet, "/users/1/prs?"+q, "", "")
+		assert.Equal(t, http.StatusBadRequest, rr.Code, q)
+	}
+}
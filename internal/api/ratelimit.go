@@ -0,0 +1,199 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"prmanager/internal/service"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// RateLimit is a token bucket: a client may make Burst requests at once, and
+// regains Rate requests per second up to Burst.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// WithRateLimit limits how fast each client calls the API. Clients are told
+// apart by their token, or by IP address when they have none. Requests to a
+// route in routes, keyed like "POST /prs/{pr_id}/merge", draw from a bucket of
+// their own; all other requests of a client share one bucket of limit.
+func WithRateLimit(limit RateLimit, routes map[string]RateLimit) Option {
+	return func(h *Handler) {
+		h.limiter = newLimiter(limit, routes, time.Now)
+	}
+}
+
+// WithIPRateLimit limits how fast requests come from each IP address,
+// whether or not they carry a valid token. It is charged before
+// authentication, so guessing tokens costs the guesser, and should be looser
+// than the per-token limit since many clients may share an address.
+func WithIPRateLimit(limit RateLimit) Option {
+	return func(h *Handler) {
+		h.ipLimiter = newLimiter(limit, nil, time.Now)
+	}
+}
+
+// ParseRouteLimits parses route limits written as "METHOD /pattern=rate:burst",
+// e.g. "POST /prs=1:5".
+func ParseRouteLimits(specs []string) (map[string]RateLimit, error) {
+	res := make(map[string]RateLimit, len(specs))
+	for _, spec := range specs {
+		route, limit, ok := strings.Cut(spec, "=")
+		method, pattern, okRoute := strings.Cut(strings.TrimSpace(route), " ")
+		rate, burst, okLimit := strings.Cut(limit, ":")
+		if !ok || !okRoute || !okLimit || !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("route limit %q: want \"METHOD /pattern=rate:burst\"", spec)
+		}
+		l, err := parseRateLimit(rate, burst)
+		if err != nil {
+			return nil, fmt.Errorf("route limit %q: %w", spec, err)
+		}
+		res[strings.ToUpper(method)+" "+pattern] = l
+	}
+	return res, nil
+}
+
+func parseRateLimit(rate, burst string) (RateLimit, error) {
+	r, err := strconv.ParseFloat(rate, 64)
+	if err != nil || r <= 0 || math.IsInf(r, 0) {
+		return RateLimit{}, fmt.Errorf("rate %q is not a positive number", rate)
+	}
+	b, err := strconv.Atoi(burst)
+	if err != nil || b < 1 {
+		return RateLimit{}, fmt.Errorf("burst %q is not a positive integer", burst)
+	}
+	return RateLimit{Rate: r, Burst: b}, nil
+}
+
+// sweepInterval is how often buckets that have refilled, and so are no
+// different from new ones, are dropped.
+const sweepInterval = time.Minute
+
+type bucketKey struct {
+	client string
+	route  string
+}
+
+type bucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+type limiter struct {
+	limit  RateLimit
+	routes map[string]RateLimit
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[bucketKey]*bucket
+	lastSweep time.Time
+}
+
+func newLimiter(limit RateLimit, routes map[string]RateLimit, now func() time.Time) *limiter {
+	return &limiter{limit: limit, routes: routes, now: now, buckets: make(map[bucketKey]*bucket), lastSweep: now()}
+}
+
+// allow takes a token from the bucket of client for route. If there is none
+// it returns how long until there is.
+func (l *limiter) allow(client, route string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		for k, b := range l.buckets {
+			b.refill(now)
+			if b.tokens >= float64(b.limit.Burst) {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	limit, ok := l.routes[route]
+	if !ok {
+		limit, route = l.limit, ""
+	}
+	k := bucketKey{client: client, route: route}
+	b, ok := l.buckets[k]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[k] = b
+	}
+	b.refill(now)
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// clientKey identifies who makes r: its principal if it has one, else its
+// IP address.
+func clientKey(r *http.Request) string {
+	if p, ok := service.PrincipalFrom(r.Context()); ok {
+		if p.TokenID != 0 {
+			return "token:" + strconv.Itoa(p.TokenID)
+		}
+		return "subject:" + p.Subject
+	}
+	return ipKey(r)
+}
+
+func ipKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimited refuses requests of clients that are over their limit with 429
+// and a Retry-After header. It runs after authentication so that clients
+// behind one address are limited per token.
+func (h *Handler) rateLimited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.Method + " " + chi.RouteContext(r.Context()).RoutePattern()
+		if h.throttle(w, h.limiter, clientKey(r), route) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ipRateLimited is rateLimited for the IP address of the request. It runs
+// before authentication, so requests with a bad token are counted too.
+func (h *Handler) ipRateLimited(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.throttle(w, h.ipLimiter, ipKey(r), "") {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// throttle answers 429 and reports true if client is over its limit in l.
+func (h *Handler) throttle(w http.ResponseWriter, l *limiter, client, route string) bool {
+	ok, wait := l.allow(client, route)
+	if ok {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	h.writeError(w, "RATE_LIMITED", "too many requests", http.StatusTooManyRequests)
+	return true
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestLimiterRefills(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	l := newLimiter(RateLimit{Rate: 2, Burst: 3}, map[string]RateLimit{"POST /prs": {Rate: 0.5, Burst: 1}}, clock.now)
+
+	for range 3 {
+		ok, _ := l.allow("a", "GET /teams")
+		require.True(t, ok)
+	}
+	ok, wait := l.allow("a", "GET /users")
+	assert.False(t, ok, "routes without a limit of their own share the client's bucket")
+	assert.Equal(t, 500*time.Millisecond, wait)
+	ok, _ = l.allow("b", "GET /teams")
+	assert.True(t, ok, "clients have buckets of their own")
+
+	ok, _ = l.allow("a", "POST /prs")
+	assert.True(t, ok)
+	ok, wait = l.allow("a", "POST /prs")
+	assert.False(t, ok)
+	assert.Equal(t, 2*time.Second, wait)
+
+	clock.advance(500 * time.Millisecond)
+	ok, _ = l.allow("a", "GET /teams")
+	assert.True(t, ok)
+	ok, _ = l.allow("a", "POST /prs")
+	assert.False(t, ok)
+
+	clock.advance(sweepInterval)
+	ok, _ = l.allow("c", "GET /teams")
+	assert.True(t, ok)
+	assert.Len(t, l.buckets, 1, "refilled buckets are dropped")
+}
+
+func TestParseRouteLimits(t *testing.T) {
+	limits, err := ParseRouteLimits([]string{"post /prs=1:5", "POST /prs/{pr_id}/reassign=0.5:2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]RateLimit{
+		"POST /prs":                  {Rate: 1, Burst: 5},
+		"POST /prs/{pr_id}/reassign": {Rate: 0.5, Burst: 2},
+	}, limits)
+
+	for _, spec := range []string{"POST /prs", "/prs=1:5", "POST prs=1:5", "POST /prs=0:5", "POST /prs=1:0", "POST /prs=x:1"} {
+		_, err := ParseRouteLimits([]string{spec})
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestRateLimitedRequests(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := service.NewService(memory.NewRepo(), logger)
+	alice, err := svc.CreateUser(ctx, nil, "Alice", true)
+	require.NoError(t, err)
+	_, bot, err := svc.IssueToken(ctx, "bot", models.RoleUser, &alice.ID)
+	require.NoError(t, err)
+	_, human, err := svc.IssueToken(ctx, "laptop", models.RoleUser, &alice.ID)
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Now()}
+	h := NewHandler(svc, logger, WithAuth(), func(h *Handler) {
+		h.limiter = newLimiter(RateLimit{Rate: 10, Burst: 10}, map[string]RateLimit{"POST /prs": {Rate: 0.2, Burst: 2}}, clock.now)
+	})
+
+	for range 2 {
+		rr := doAs(h, bot, http.MethodPost, "/prs", `{"title":"Spam","author_id":1}`, "")
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	rr := doAs(h, bot, http.MethodPost, "/prs", `{"title":"Spam","author_id":1}`, "")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "5", rr.Header().Get("Retry-After"))
+	assert.Contains(t, rr.Body.String(), "RATE_LIMITED")
+
+	assert.Equal(t, http.StatusOK, doAs(h, bot, http.MethodGet, "/users", "", "").Code, "other routes are limited separately")
+	assert.Equal(t, http.StatusCreated, doAs(h, human, http.MethodPost, "/prs", `{"title":"Real","author_id":1}`, "").Code,
+		"another token of the same user has its own budget")
+
+	clock.advance(5 * time.Second)
+	assert.Equal(t, http.StatusCreated, doAs(h, bot, http.MethodPost, "/prs", `{"title":"Spam","author_id":1}`, "").Code)
+}
+
+func TestFailedAuthenticationCountsAgainstAddress(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := service.NewService(memory.NewRepo(), logger)
+	alice, err := svc.CreateUser(ctx, nil, "Alice", true)
+	require.NoError(t, err)
+	_, secret, err := svc.IssueToken(ctx, "laptop", models.RoleUser, &alice.ID)
+	require.NoError(t, err)
+
+	clock := &fakeClock{t: time.Now()}
+	h := NewHandler(svc, logger, WithAuth(), func(h *Handler) {
+		h.limiter = newLimiter(RateLimit{Rate: 10, Burst: 10}, nil, clock.now)
+		h.ipLimiter = newLimiter(RateLimit{Rate: 1, Burst: 3}, nil, clock.now)
+	})
+
+	for i := range 3 {
+		rr := doAs(h, "guess-"+strconv.Itoa(i), http.MethodGet, "/users", "", "")
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+	}
+	rr := doAs(h, "guess-3", http.MethodGet, "/users", "", "")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "rejected tokens drain the address's bucket")
+	assert.Equal(t, "1", rr.Header().Get("Retry-After"))
+	assert.Equal(t, http.StatusTooManyRequests, doAs(h, secret, http.MethodGet, "/users", "", "").Code)
+
+	clock.advance(time.Second)
+	assert.Equal(t, http.StatusOK, doAs(h, secret, http.MethodGet, "/users", "", "").Code)
+}
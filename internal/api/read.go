@@ -0,0 +1,107 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"prmanager/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) getPR(w http.ResponseWriter, r *http.Request) {
+	prID, err := strconv.Atoi(chi.URLParam(r, "pr_id"))
+	if err != nil || prID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid pr_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.GetPR(r.Context(), prID)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to get PR", "error", err, "pr_id", prID)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) getTeam(w http.ResponseWriter, r *http.Request) {
+	ref := chi.URLParam(r, "team")
+
+	res, err := h.svc.GetTeam(r.Context(), ref)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to get team", "error", err, "team", ref)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) listTeams(w http.ResponseWriter, r *http.Request) {
+	var f repository.TeamFilter
+	var err error
+	if f.Limit, f.After, err = queryIDPage(r.URL.Query()); err != nil {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.ListTeams(r.Context(), f)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "user_id"))
+	if err != nil || userID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.GetUser(r.Context(), userID)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to get user", "error", err, "user_id", userID)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var f repository.UserFilter
+	var err error
+	if f.Active, err = queryBool(q, "active"); err != nil {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.Limit, f.After, err = queryIDPage(q); err != nil {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.ListUsers(r.Context(), q.Get("team"), f)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to list users", "error", err)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) getReviewerSync(w http.ResponseWriter, r *http.Request) {
+	prID, err := strconv.Atoi(chi.URLParam(r, "pr_id"))
+	if err != nil || prID <= 0 {
+		h.writeError(w, "BAD_REQUEST", "valid pr_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.GetReviewerSync(r.Context(), prID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
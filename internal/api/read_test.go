@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newReadHandler(t *testing.T) *Handler {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+
+	for _, req := range []struct{ path, body string }{
+		{"/teams", `{"name":"backend"}`},
+		{"/teams", `{"name":"2024"}`},
+		{"/teams/1/users", `{"name":"alice","is_active":true}`},
+		{"/teams/1/users", `{"name":"bob","is_active":true}`},
+		{"/teams/1/users", `{"name":"carol","is_active":false}`},
+		{"/teams/2/users", `{"name":"dave","is_active":true}`},
+		{"/prs", `{"title":"Add login","author_id":1}`},
+	} {
+		rr := do(h, http.MethodPost, req.path, req.body, "")
+		require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	return h
+}
+
+func decode[T any](t *testing.T, rr *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &v))
+	return v
+}
+
+func TestGetPR(t *testing.T) {
+	h := newReadHandler(t)
+
+	rr := do(h, http.MethodGet, "/prs/1", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	pr := decode[models.PRWithReviewers](t, rr)
+	assert.Equal(t, "Add login", pr.Title)
+	assert.Len(t, pr.Reviewers, 1, "only bob is an active teammate of the author")
+
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/prs/42", "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodGet, "/prs/abc", "", "").Code)
+}
+
+func TestGetTeamByIDOrName(t *testing.T) {
+	h := newReadHandler(t)
+
+	for _, ref := range []string{"1", "backend"} {
+		rr := do(h, http.MethodGet, "/teams/"+ref, "", "")
+		require.Equal(t, http.StatusOK, rr.Code)
+		team := decode[models.TeamWithMembers](t, rr)
+		assert.Equal(t, "backend", team.Name)
+		require.Len(t, team.Members, 3, "inactive members are listed too")
+		assert.False(t, team.Members[2].IsActive)
+	}
+
+	rr := do(h, http.MethodGet, "/teams/2024", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "2024", decode[models.TeamWithMembers](t, rr).Name, "numeric names fall back to a name lookup")
+
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/teams/mobile", "", "").Code)
+}
+
+func TestListTeams(t *testing.T) {
+	h := newReadHandler(t)
+
+	rr := do(h, http.MethodGet, "/teams?limit=1", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	page := decode[pagination.Page[models.Team]](t, rr)
+	require.Len(t, page.Items, 1)
+	require.NotNil(t, page.NextCursor)
+
+	rr = do(h, http.MethodGet, "/teams?limit=1&cursor="+*page.NextCursor, "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	page = decode[pagination.Page[models.Team]](t, rr)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "2024", page.Items[0].Name)
+	assert.Nil(t, page.NextCursor)
+}
+
+func TestGetUser(t *testing.T) {
+	h := newReadHandler(t)
+
+	rr := do(h, http.MethodGet, "/users/3", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	u := decode[models.UserWithTeam](t, rr)
+	assert.Equal(t, "carol", u.Name)
+	assert.False(t, u.IsActive)
+	require.NotNil(t, u.Team)
+	assert.Equal(t, "backend", u.Team.Name)
+
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/users/42", "", "").Code)
+}
+
+func TestListUsers(t *testing.T) {
+	h := newReadHandler(t)
+
+	names := func(path string) []string {
+		t.Helper()
+		rr := do(h, http.MethodGet, path, "", "")
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var res []string
+		for _, u := range decode[pagination.Page[models.User]](t, rr).Items {
+			res = append(res, u.Name)
+		}
+		return res
+	}
+
+	assert.Equal(t, []string{"alice", "bob", "carol", "dave"}, names("/users"))
+	assert.Equal(t, []string{"alice", "bob", "carol"}, names("/users?team=backend"))
+	assert.Equal(t, []string{"alice", "bob"}, names("/users?team=1&active=true"))
+	assert.Equal(t, []string{"carol"}, names("/users?active=false"))
+
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/users?team=mobile", "", "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodGet, "/users?active=maybe", "", "").Code)
+}
+
+func TestUpdateUser(t *testing.T) {
+	h := newReadHandler(t)
+
+	rr := do(h, http.MethodPatch, "/users/3", `{"name":"Carol","team_id":2,"is_active":true}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	res := decode[models.UserUpdateResult](t, rr)
+	assert.Equal(t, "Carol", res.User.Name)
+	assert.Equal(t, 2, *res.User.TeamID)
+	assert.True(t, res.User.IsActive)
+	assert.NotNil(t, res.Reassignments)
+
+	rr = do(h, http.MethodPatch, "/users/3", `{"team_id":null}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	res = decode[models.UserUpdateResult](t, rr)
+	assert.Nil(t, res.User.TeamID)
+	assert.Equal(t, "Carol", res.User.Name, "omitted fields are unchanged")
+
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodPatch, "/users/3", `{"team_id":"backend"}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodPatch, "/users/3", `{"team_id":99}`, "").Code)
+	assert.Equal(t, http.StatusBadRequest, do(h, http.MethodPatch, "/users/3", `{"name":""}`, "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodPatch, "/users/99", `{"name":"x"}`, "").Code)
+}
+
+func TestDeleteUserAndTeam(t *testing.T) {
+	h := newReadHandler(t)
+
+	rr := do(h, http.MethodDelete, "/teams/2024", "", "")
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "TEAM_NOT_EMPTY")
+
+	rr = do(h, http.MethodDelete, "/users/4", "", "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	assert.NotNil(t, decode[models.UserUpdateResult](t, rr).User.DeletedAt)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodDelete, "/users/4", "", "").Code)
+
+	assert.Equal(t, http.StatusNoContent, do(h, http.MethodDelete, "/teams/2024", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/teams/2024", "", "").Code)
+
+	rr = do(h, http.MethodGet, "/users", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, decode[pagination.Page[models.User]](t, rr).Items, 3, "deleted users are not listed")
+
+	rr = do(h, http.MethodGet, "/users/4", "", "")
+	require.Equal(t, http.StatusOK, rr.Code, "deleted users can still be fetched by id")
+	assert.Equal(t, "dave", decode[models.UserWithTeam](t, rr).Name)
+}
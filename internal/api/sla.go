@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"prmanager/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) setTeamSLA(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RemindAfterHours   int    `json:"remind_after_hours"`
+		ReassignAfterHours int    `json:"reassign_after_hours"`
+		EscalateAfterHours int    `json:"escalate_after_hours"`
+		WorkdayStart       string `json:"workday_start"`
+		WorkdayEnd         string `json:"workday_end"`
+		Timezone           string `json:"timezone"`
+		LeadID             *int   `json:"lead_id"`
+	}
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		return
+	}
+
+	team := chi.URLParam(r, "team")
+	res, err := h.svc.SetTeamSLA(r.Context(), team, models.TeamSLA{
+		RemindAfterHours:   body.RemindAfterHours,
+		ReassignAfterHours: body.ReassignAfterHours,
+		EscalateAfterHours: body.EscalateAfterHours,
+		WorkdayStart:       body.WorkdayStart,
+		WorkdayEnd:         body.WorkdayEnd,
+		Timezone:           body.Timezone,
+		LeadID:             body.LeadID,
+	})
+	if err != nil {
+		h.log(r.Context()).Warn("failed to set team SLA", "error", err, "team", team)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) getTeamSLA(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.GetTeamSLA(r.Context(), chi.URLParam(r, "team"))
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) deleteTeamSLA(w http.ResponseWriter, r *http.Request) {
+	team := chi.URLParam(r, "team")
+	if err := h.svc.DeleteTeamSLA(r.Context(), team); err != nil {
+		h.log(r.Context()).Warn("failed to delete team SLA", "error", err, "team", team)
+		h.writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamSLAEndpoints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+	for _, req := range []struct{ path, body string }{
+		{"/teams", `{"name":"backend"}`},
+		{"/teams", `{"name":"frontend"}`},
+		{"/teams/1/users", `{"name":"alice","is_active":true}`},
+		{"/teams/2/users", `{"name":"bob","is_active":true}`},
+	} {
+		require.Equal(t, http.StatusCreated, do(h, http.MethodPost, req.path, req.body, "").Code)
+	}
+
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/teams/backend/sla", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodPut, "/teams/ops/sla", `{"remind_after_hours":4}`, "").Code)
+	for _, body := range []string{
+		`{}`,
+		`{"remind_after_hours":-1}`,
+		`{"remind_after_hours":8,"reassign_after_hours":4}`,
+		`{"reassign_after_hours":8,"escalate_after_hours":8}`,
+		`{"remind_after_hours":4,"workday_start":"9am"}`,
+		`{"remind_after_hours":4,"workday_start":"18:00","workday_end":"09:00"}`,
+		`{"remind_after_hours":4,"timezone":"Mars/Olympus"}`,
+		`{"remind_after_hours":4,"lead_id":2}`,
+		`{"remind_after_hours":4,"lead_id":99}`,
+	} {
+		rr := do(h, http.MethodPut, "/teams/backend/sla", body, "")
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+	}
+
+	rr := do(h, http.MethodPut, "/teams/backend/sla", `{"remind_after_hours":4,"reassign_after_hours":8,"escalate_after_hours":16,"lead_id":1}`, "")
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	rr = do(h, http.MethodGet, "/teams/1/sla", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	got := decode[models.TeamSLA](t, rr)
+	assert.Equal(t, 1, got.TeamID)
+	assert.Equal(t, 8, got.ReassignAfterHours)
+	assert.Equal(t, "09:00", got.WorkdayStart, "working hours default to 09:00 to 18:00 UTC")
+	assert.Equal(t, "18:00", got.WorkdayEnd)
+	assert.Equal(t, "UTC", got.Timezone)
+	require.NotNil(t, got.LeadID)
+	assert.Equal(t, 1, *got.LeadID)
+
+	assert.Equal(t, http.StatusNoContent, do(h, http.MethodDelete, "/teams/backend/sla", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodDelete, "/teams/backend/sla", "", "").Code)
+}
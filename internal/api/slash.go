@@ -0,0 +1,147 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/notify"
+	"prmanager/internal/repository"
+	"prmanager/internal/service"
+	"prmanager/internal/slack"
+)
+
+// slashQueueLimit caps the reviews listed by "/reviews".
+const slashQueueLimit = 10
+
+const slashUsage = "Usage:\n" +
+	"• `/reviews` - your open reviews\n" +
+	"• `/reviews reassign <pr_id>` - hand a review over to someone else in your team\n" +
+	"• `/reviews stats` - assignment statistics"
+
+// WithSlack enables POST /integrations/slack/commands, authenticating slash
+// command requests with the app's signing secret.
+func WithSlack(signingSecret string) Option {
+	return func(h *Handler) {
+		h.slackSecret = signingSecret
+	}
+}
+
+// slashCommand answers "/reviews" and its subcommands. Slack shows anything
+// but a 200 as a failed command, so problems with the command itself are
+// reported in the reply; only unauthenticated requests are refused.
+func (h *Handler) slashCommand(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.readIntegrationPayload(w, r)
+	if !ok {
+		return
+	}
+	if err := slack.VerifyRequest(h.slackSecret, body, r.Header.Get(slack.TimestampHeader), r.Header.Get(slack.SignatureHeader), time.Now()); err != nil {
+		h.log(r.Context()).Warn("rejected slash command", "error", err)
+		h.writeError(w, "UNAUTHORIZED", "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	cmd, err := slack.ParseSlashCommand(body)
+	if err != nil {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.writeJSON(w, slack.Ephemeral(h.runSlashCommand(r.Context(), cmd)), http.StatusOK)
+}
+
+func (h *Handler) runSlashCommand(ctx context.Context, cmd slack.SlashCommand) string {
+	user, err := h.svc.UserByIdentity(ctx, models.ChatIdentityProvider, cmd.UserID)
+	if errors.Is(err, service.ErrNotFound) {
+		return "Your chat account is not linked to a reviewer. Ask an admin to link it with " +
+			"`PUT /users/{id}/identities/chat` and your member ID `" + cmd.UserID + "`."
+	}
+	if err != nil {
+		return h.slashFailed(ctx, err, cmd)
+	}
+	// The command acts as the Slack user, within their organization, whatever
+	// token-less access the server otherwise allows.
+	ctx = repository.WithOrg(ctx, user.OrgID)
+	ctx = service.WithPrincipal(ctx, service.Principal{Role: models.RoleUser, UserID: &user.ID, TeamID: user.TeamID, OrgID: &user.OrgID})
+
+	args := strings.Fields(cmd.Text)
+	switch {
+	case len(args) == 0 || len(args) == 1 && args[0] == "list":
+		return h.slashQueue(ctx, user, cmd)
+	case len(args) == 2 && args[0] == "reassign":
+		prID, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil || prID <= 0 {
+			return "`" + args[1] + "` is not a PR id.\n" + slashUsage
+		}
+		return h.slashReassign(ctx, user, prID, cmd)
+	case len(args) == 1 && args[0] == "stats":
+		return h.slashStats(ctx, user, cmd)
+	}
+	return slashUsage
+}
+
+func (h *Handler) slashQueue(ctx context.Context, user models.User, cmd slack.SlashCommand) string {
+	page, err := h.svc.ListPRsAssignedToUser(ctx, user.ID, repository.ReviewQueueFilter{
+		Statuses: []models.PRStatus{models.PRStatusOpen},
+		SortBy:   repository.SortByAssignedAt,
+		Limit:    slashQueueLimit,
+	})
+	if err != nil {
+		return h.slashFailed(ctx, err, cmd)
+	}
+	if len(page.Items) == 0 {
+		return "You have no open reviews."
+	}
+
+	var b strings.Builder
+	b.WriteString("Your open reviews, oldest first:")
+	for _, pr := range page.Items {
+		fmt.Fprintf(&b, "\n• #%d *%s*, assigned %s", pr.ID, notify.Escape(pr.Title), pr.AssignedAt.Format(time.DateOnly))
+	}
+	if page.NextCursor != nil {
+		fmt.Fprintf(&b, "\n…and more. The first %d are shown.", slashQueueLimit)
+	}
+	return b.String()
+}
+
+func (h *Handler) slashReassign(ctx context.Context, user models.User, prID int, cmd slack.SlashCommand) string {
+	res, err := h.svc.ReassignReviewer(ctx, prID, user.ID)
+	switch {
+	case err == nil:
+	case errors.Is(err, service.ErrPRMerged):
+		return fmt.Sprintf("PR #%d is already merged.", prID)
+	case errors.Is(err, service.ErrNoCandidate):
+		return fmt.Sprintf("Nobody else in your team can take over PR #%d.", prID)
+	case errors.Is(err, service.ErrBadRequest):
+		return fmt.Sprintf("Cannot reassign PR #%d: %s.", prID, strings.TrimPrefix(err.Error(), service.ErrBadRequest.Error()+": "))
+	default:
+		return h.slashFailed(ctx, err, cmd)
+	}
+
+	names := make([]string, 0, len(res.Reviewers))
+	for _, r := range res.Reviewers {
+		names = append(names, notify.Escape(r.Name))
+	}
+	return fmt.Sprintf("You are no longer reviewing #%d *%s*. Reviewers now: %s.", res.ID, notify.Escape(res.Title), strings.Join(names, ", "))
+}
+
+func (h *Handler) slashStats(ctx context.Context, user models.User, cmd slack.SlashCommand) string {
+	total, err := h.svc.StatsAssignments(ctx)
+	if err != nil {
+		return h.slashFailed(ctx, err, cmd)
+	}
+	page, err := h.svc.ListPRsAssignedToUser(ctx, user.ID, repository.ReviewQueueFilter{Statuses: []models.PRStatus{models.PRStatusOpen}})
+	if err != nil {
+		return h.slashFailed(ctx, err, cmd)
+	}
+	return fmt.Sprintf("%d review assignments in total. You have %d open.", total, len(page.Items))
+}
+
+func (h *Handler) slashFailed(ctx context.Context, err error, cmd slack.SlashCommand) string {
+	h.log(ctx).Error("slash command failed", "error", err, "text", cmd.Text, "chat_user_id", cmd.UserID)
+	return "Something went wrong, please try again later."
+}
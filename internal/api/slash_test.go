@@ -0,0 +1,154 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+	"prmanager/internal/slack"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const slackSecret = "8f742231b10e8888abcd99yyyzzz85a5"
+
+func slash(h *Handler, memberID, text string) *httptest.ResponseRecorder {
+	body := url.Values{"command": {"/reviews"}, "text": {text}, "user_id": {memberID}}.Encode()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	req := httptest.NewRequest(http.MethodPost, "/integrations/slack/commands", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set(slack.TimestampHeader, ts)
+	req.Header.Set(slack.SignatureHeader, slack.Sign(slackSecret, ts, []byte(body)))
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	return rr
+}
+
+func slashReply(t *testing.T, rr *httptest.ResponseRecorder) string {
+	t.Helper()
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	res := decode[slack.Response](t, rr)
+	assert.Equal(t, "ephemeral", res.ResponseType)
+	return res.Text
+}
+
+func TestSlashCommands(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := service.NewService(memory.NewRepo(), logger)
+	h := NewHandler(svc, logger, WithSlack(slackSecret))
+
+	team, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	users := make(map[string]models.User)
+	for _, name := range []string{"author", "alice", "bob", "carol"} {
+		u, err := svc.CreateUser(ctx, &team.ID, name, true)
+		require.NoError(t, err)
+		_, err = svc.SetIdentity(ctx, u.ID, models.ChatIdentityProvider, "U"+strings.ToUpper(name))
+		require.NoError(t, err)
+		users[name] = u
+	}
+	pr, err := svc.CreatePR(ctx, "Add <search>", users["author"].ID)
+	require.NoError(t, err)
+	reviewer := pr.Reviewers[0]
+	member := "U" + strings.ToUpper(reviewer.Name)
+
+	assert.Contains(t, slashReply(t, slash(h, "UNOBODY", "")), "not linked")
+	assert.Equal(t, "You have no open reviews.", slashReply(t, slash(h, "UAUTHOR", "")))
+
+	queue := slashReply(t, slash(h, member, ""))
+	assert.Contains(t, queue, "#"+strconv.Itoa(pr.ID)+" *Add &lt;search&gt;*, assigned ")
+
+	assert.Equal(t, "2 review assignments in total. You have 1 open.", slashReply(t, slash(h, member, "stats")))
+	assert.Contains(t, slashReply(t, slash(h, member, "help")), "Usage:")
+	assert.Contains(t, slashReply(t, slash(h, member, "reassign forty-two")), "is not a PR id")
+
+	reply := slashReply(t, slash(h, member, "reassign #"+strconv.Itoa(pr.ID)))
+	assert.Contains(t, reply, "You are no longer reviewing #"+strconv.Itoa(pr.ID))
+	assert.NotContains(t, reply, reviewer.Name+",")
+	assert.Equal(t, "You have no open reviews.", slashReply(t, slash(h, member, "")))
+	assert.Contains(t, slashReply(t, slash(h, member, "reassign "+strconv.Itoa(pr.ID))), "reviewer is not assigned to this PR")
+
+	_, err = svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "PR #"+strconv.Itoa(pr.ID)+" is already merged.", slashReply(t, slash(h, "UAUTHOR", "reassign "+strconv.Itoa(pr.ID))))
+}
+
+func TestSlashCommandsStayInTheUsersOrganization(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := service.NewService(memory.NewRepo(), logger)
+	h := NewHandler(svc, logger, WithSlack(slackSecret))
+
+	acme, err := svc.CreateOrganization(ctx, "acme")
+	require.NoError(t, err)
+	prs := make(map[int]models.PRWithReviewers)
+	for _, orgID := range []int{models.DefaultOrgID, acme.ID} {
+		ctx := repository.WithOrg(ctx, orgID)
+		team, err := svc.CreateTeam(ctx, "backend")
+		require.NoError(t, err)
+		var author models.User
+		for _, name := range []string{"author", "alice", "bob"} {
+			u, err := svc.CreateUser(ctx, &team.ID, name, true)
+			require.NoError(t, err)
+			_, err = svc.SetIdentity(ctx, u.ID, models.ChatIdentityProvider, fmt.Sprintf("U%d%s", orgID, strings.ToUpper(name)))
+			require.NoError(t, err)
+			if name == "author" {
+				author = u
+			}
+		}
+		prs[orgID], err = svc.CreatePR(ctx, "Add search", author.ID)
+		require.NoError(t, err)
+	}
+	member := func(orgID int) string {
+		return fmt.Sprintf("U%d%s", orgID, strings.ToUpper(prs[orgID].Reviewers[0].Name))
+	}
+
+	assert.Equal(t, "2 review assignments in total. You have 1 open.", slashReply(t, slash(h, member(acme.ID), "stats")),
+		"totals only count the user's organization")
+	other := strconv.Itoa(prs[models.DefaultOrgID].ID)
+	assert.NotContains(t, slashReply(t, slash(h, member(acme.ID), "reassign "+other)), "no longer reviewing")
+	pr, err := svc.GetPR(ctx, prs[models.DefaultOrgID].ID)
+	require.NoError(t, err)
+	assert.Equal(t, prs[models.DefaultOrgID].Reviewers, pr.Reviewers)
+}
+
+func TestSlashCommandsAreAuthenticated(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	h := NewHandler(service.NewService(memory.NewRepo(), logger), logger, WithSlack(slackSecret))
+
+	body := "command=%2Freviews&text=stats&user_id=U1"
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	for name, headers := range map[string][2]string{
+		"unsigned":      {now, ""},
+		"wrong secret":  {now, slack.Sign("other", now, []byte(body))},
+		"replayed":      {stale, slack.Sign(slackSecret, stale, []byte(body))},
+		"no timestamps": {"", slack.Sign(slackSecret, "", []byte(body))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/integrations/slack/commands", strings.NewReader(body))
+			req.Header.Set(slack.TimestampHeader, headers[0])
+			req.Header.Set(slack.SignatureHeader, headers[1])
+			rr := httptest.NewRecorder()
+			h.Router().ServeHTTP(rr, req)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		})
+	}
+
+	h = NewHandler(service.NewService(memory.NewRepo(), logger), logger)
+	assert.Equal(t, http.StatusNotFound, slash(h, "U1", "").Code, "the endpoint is off without a signing secret")
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func spanAttr(s tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestRequestsAreTraced(t *testing.T) {
+	ctx := context.Background()
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	svc := service.NewService(memory.NewRepo(), logger, service.WithTracerProvider(tp))
+	h := NewHandler(svc, logger, WithTracerProvider(tp))
+
+	team, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	for _, name := range []string{"alice", "bob", "carol", "dave"} {
+		_, err := svc.CreateUser(ctx, &team.ID, name, true)
+		require.NoError(t, err)
+	}
+	pr, err := svc.CreatePR(ctx, "Add login", 1)
+	require.NoError(t, err)
+	exp.Reset()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/prs/%d/reassign", pr.PR.ID),
+		strings.NewReader(fmt.Sprintf(`{"old_user_id":%d}`, pr.Reviewers[0].ID)))
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	h.Router().ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+	byName := make(map[string]tracetest.SpanStub)
+	for _, s := range exp.GetSpans() {
+		assert.Equal(t, traceID, s.SpanContext.TraceID().String(), "%s joins the caller's trace", s.Name)
+		byName[s.Name] = s
+	}
+	server, ok := byName["POST /prs/{pr_id}/reassign"]
+	require.True(t, ok, "the server span is named after the route")
+	assert.True(t, server.Parent.IsRemote())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.EqualValues(t, http.StatusOK, spanAttr(server, "http.response.status_code").AsInt64())
+	assert.Equal(t, rr.Header().Get(RequestIDHeader), spanAttr(server, "http.request.header.x-request-id").AsStringSlice()[0])
+
+	parent := server
+	for _, name := range []string{"Service.ReassignReviewer", "Service.transaction", "Service.pickReplacement"} {
+		s, ok := byName[name]
+		require.True(t, ok, name)
+		assert.Equal(t, parent.SpanContext.SpanID(), s.Parent.SpanID(), "%s is a child of %s", name, parent.Name)
+		parent = s
+	}
+	pick := byName["Service.pickReplacement"]
+	assert.EqualValues(t, pr.PR.ID, spanAttr(pick, "pr_id").AsInt64())
+	assert.EqualValues(t, team.ID, spanAttr(pick, "team_id").AsInt64())
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (h *Handler) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"`
+		Events []string `json:"events"`
+	}
+	if err := h.decodeJSON(w, r, &body); err != nil {
+		h.log(r.Context()).Warn("invalid JSON in createWebhook request", "error", err)
+		return
+	}
+
+	res, err := h.svc.CreateWebhook(r.Context(), body.URL, body.Secret, body.Events)
+	if err != nil {
+		h.log(r.Context()).Warn("failed to create webhook", "error", err, "url", body.URL)
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusCreated)
+}
+
+func (h *Handler) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	res, err := h.svc.ListWebhooks(r.Context())
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func webhookID(r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "webhook_id"))
+	return id, err == nil && id > 0
+}
+
+func (h *Handler) getWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(r)
+	if !ok {
+		h.writeError(w, "BAD_REQUEST", "valid webhook_id is required", http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.GetWebhook(r.Context(), id)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
+
+func (h *Handler) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(r)
+	if !ok {
+		h.writeError(w, "BAD_REQUEST", "valid webhook_id is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.svc.DeleteWebhook(r.Context(), id); err != nil {
+		h.log(r.Context()).Warn("failed to delete webhook", "error", err, "webhook_id", id)
+		h.writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookID(r)
+	if !ok {
+		h.writeError(w, "BAD_REQUEST", "valid webhook_id is required", http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	var f repository.DeliveryFilter
+	var err error
+	if f.Limit, err = pagination.ParseLimit(q.Get("limit")); err != nil {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.After, err = pagination.DecodeFor(q.Get("cursor"), pagination.SortByID, true); err != nil {
+		h.writeError(w, "BAD_REQUEST", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	res, err := h.svc.ListWebhookDeliveries(r.Context(), id, f)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, res, http.StatusOK)
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/outbox"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+	"prmanager/internal/webhook"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookEndpoints(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewRepo()
+	relay := outbox.NewRelay(repo, webhook.NewDispatcher(repo, logger, webhook.DefaultConfig()), logger, outbox.DefaultConfig())
+	h := NewHandler(service.NewService(repo, logger), logger)
+
+	rr := do(h, http.MethodPost, "/webhooks", `{"url":"ftp://example.com"}`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	rr = do(h, http.MethodPost, "/webhooks", `{"url":"http://example.com","events":["pr.exploded"]}`, "")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = do(h, http.MethodPost, "/webhooks", `{"url":"http://example.com/hook","events":["pr.created"]}`, "")
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	created := decode[models.Webhook](t, rr)
+	assert.NotEmpty(t, created.Secret, "a secret is generated and returned once")
+	assert.Equal(t, []string{"pr.created"}, created.Events)
+
+	rr = do(h, http.MethodGet, "/webhooks/1", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, decode[models.Webhook](t, rr).Secret)
+	rr = do(h, http.MethodGet, "/webhooks", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Len(t, decode[[]models.Webhook](t, rr), 1)
+
+	for _, req := range []struct{ path, body string }{
+		{"/teams", `{"name":"backend"}`},
+		{"/teams/1/users", `{"name":"alice","is_active":true}`},
+		{"/prs", `{"title":"one","author_id":1}`},
+		{"/prs", `{"title":"two","author_id":1}`},
+	} {
+		require.Equal(t, http.StatusCreated, do(h, http.MethodPost, req.path, req.body, "").Code)
+	}
+	n, err := relay.RelayDue(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	rr = do(h, http.MethodGet, "/webhooks/1/deliveries?limit=1", "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	page := decode[pagination.Page[models.WebhookDelivery]](t, rr)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "pr.created", page.Items[0].EventType)
+	assert.Equal(t, models.DeliveryPending, page.Items[0].Status)
+	require.NotNil(t, page.NextCursor)
+	rr = do(h, http.MethodGet, "/webhooks/1/deliveries?cursor="+*page.NextCursor, "", "")
+	require.Equal(t, http.StatusOK, rr.Code)
+	next := decode[pagination.Page[models.WebhookDelivery]](t, rr)
+	require.Len(t, next.Items, 1)
+	assert.Less(t, next.Items[0].ID, page.Items[0].ID, "newest first")
+	assert.Nil(t, next.NextCursor)
+
+	assert.Equal(t, http.StatusNoContent, do(h, http.MethodDelete, "/webhooks/1", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodDelete, "/webhooks/1", "", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/webhooks/1/deliveries", "", "").Code)
+}
@@ -0,0 +1,262 @@
+// Package digest emails every active user a daily summary of the reviews
+// waiting for them and of their own PRs waiting on reviewers.
+//
+// Run checks once per PollInterval whose digest is due: a user's digest is
+// due at their send time in their time zone and is sent once per day, as
+// long as the scheduler gets to it within MaxDelay. Users opt out through
+// their digest preferences and are skipped while they have no email
+// identity. A digest is claimed before it is sent, so several replicas never
+// send the same one twice; in exchange a digest the SMTP server refuses is
+// logged and not retried.
+package digest
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	_ "time/tzdata" // users pick their time zone; the image may have no zoneinfo
+
+	"prmanager/internal/mail"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+type Config struct {
+	// From is the sender address of digest emails.
+	From string
+	// SendAt and Location are the send time of users who did not choose
+	// their own.
+	SendAt       string
+	Location     *time.Location
+	MaxDelay     time.Duration
+	PollInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		SendAt:       "09:00",
+		Location:     time.UTC,
+		MaxDelay:     2 * time.Hour,
+		PollInterval: time.Minute,
+	}
+}
+
+type Scheduler struct {
+	repo      repository.Repository
+	mailer    mail.Sender
+	templates Templates
+	logger    *slog.Logger
+	cfg       Config
+	now       func() time.Time
+}
+
+func NewScheduler(r repository.Repository, mailer mail.Sender, templates Templates, logger *slog.Logger, cfg Config) *Scheduler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Scheduler{
+		repo:      r,
+		mailer:    mailer,
+		templates: templates,
+		logger:    logger,
+		cfg:       cfg,
+		now:       time.Now,
+	}
+}
+
+// Run sends due digests until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.SendDue(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("failed to send digests", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue sends every digest that is due and returns how many were sent.
+func (s *Scheduler) SendDue(ctx context.Context) (int, error) {
+	now := s.now()
+	stored, err := s.repo.ListDigestPrefs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list digest prefs: %w", err)
+	}
+	prefs := make(map[int]models.DigestPrefs, len(stored))
+	for _, p := range stored {
+		prefs[p.UserID] = p
+	}
+	active := true
+	users, err := s.repo.ListUsers(ctx, repository.UserFilter{Active: &active})
+	if err != nil {
+		return 0, fmt.Errorf("list active users: %w", err)
+	}
+
+	sent := 0
+	for _, u := range users {
+		p := prefs[u.ID]
+		if p.OptOut {
+			continue
+		}
+		due, loc, err := s.dueAt(p, now)
+		if err != nil {
+			s.logger.Warn("invalid digest preferences", "user_id", u.ID, "error", err)
+			continue
+		}
+		if now.Sub(due) > s.cfg.MaxDelay || p.LastSentAt != nil && !p.LastSentAt.Before(due) {
+			continue
+		}
+
+		ok, err := s.send(ctx, u, due, now.In(loc))
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// dueAt returns the latest send time of p at or before now, and p's time
+// zone.
+func (s *Scheduler) dueAt(p models.DigestPrefs, now time.Time) (time.Time, *time.Location, error) {
+	loc := s.cfg.Location
+	if p.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(p.Timezone); err != nil {
+			return time.Time{}, nil, err
+		}
+	}
+	at, err := time.Parse(models.DigestTimeLayout, cmp.Or(p.SendAt, s.cfg.SendAt))
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	local := now.In(loc)
+	due := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if due.After(now) {
+		due = time.Date(local.Year(), local.Month(), local.Day()-1, at.Hour(), at.Minute(), 0, 0, loc)
+	}
+	return due, loc, nil
+}
+
+// send mails u the digest due at due, unless they have no email address,
+// nothing to review and nothing waiting, or another replica claimed it first.
+// It reports whether an email went out; only repository failures are errors.
+func (s *Scheduler) send(ctx context.Context, u models.User, due, localNow time.Time) (bool, error) {
+	ids, err := s.repo.ListIdentities(ctx, u.ID)
+	if err != nil {
+		return false, fmt.Errorf("list identities of user %d: %w", u.ID, err)
+	}
+	i := slices.IndexFunc(ids, func(id models.Identity) bool { return id.Provider == models.EmailIdentityProvider })
+	if i < 0 {
+		return false, nil
+	}
+	to := ids[i].Login
+
+	claimed, err := s.repo.ClaimDigest(ctx, u.ID, due, localNow)
+	if err != nil {
+		return false, fmt.Errorf("claim digest of user %d: %w", u.ID, err)
+	}
+	if !claimed {
+		return false, nil
+	}
+
+	d, err := s.build(ctx, u, localNow)
+	if err != nil || d.empty() {
+		return false, err
+	}
+	text, html, err := s.templates.render(d)
+	if err != nil {
+		return false, err
+	}
+	err = s.mailer.Send(ctx, mail.Message{
+		From:    s.cfg.From,
+		To:      to,
+		Subject: fmt.Sprintf("Review digest: %d to review, %d waiting on others", len(d.Reviews), len(d.Waiting)),
+		Date:    localNow,
+		Text:    text,
+		HTML:    html,
+	})
+	if err != nil {
+		s.logger.Error("failed to send digest", "user_id", u.ID, "error", err)
+		return false, nil
+	}
+	s.logger.Info("digest sent", "user_id", u.ID, "reviews", len(d.Reviews), "waiting", len(d.Waiting))
+	return true, nil
+}
+
+func (s *Scheduler) build(ctx context.Context, u models.User, now time.Time) (Digest, error) {
+	d := Digest{User: u, Date: now}
+	names := map[int]string{u.ID: u.Name}
+
+	assigned, err := s.repo.ListPRsAssignedToUser(ctx, u.ID, repository.ReviewQueueFilter{
+		Statuses: []models.PRStatus{models.PRStatusOpen},
+		SortBy:   repository.SortByAssignedAt,
+	})
+	if err != nil {
+		return Digest{}, fmt.Errorf("list reviews of user %d: %w", u.ID, err)
+	}
+	for _, pr := range assigned {
+		item, err := s.item(ctx, pr.PRWithReviewers, pr.AssignedAt, now, names)
+		if err != nil {
+			return Digest{}, err
+		}
+		d.Reviews = append(d.Reviews, item)
+	}
+
+	own, err := s.repo.ListPRsByAuthor(ctx, u.ID, []models.PRStatus{models.PRStatusOpen})
+	if err != nil {
+		return Digest{}, fmt.Errorf("list PRs of user %d: %w", u.ID, err)
+	}
+	for _, pr := range own {
+		if len(pr.Reviewers) == 0 {
+			continue
+		}
+		item, err := s.item(ctx, pr, pr.CreatedAt, now, names)
+		if err != nil {
+			return Digest{}, err
+		}
+		d.Waiting = append(d.Waiting, item)
+	}
+	return d, nil
+}
+
+// item describes pr as of now. names caches user names by id.
+func (s *Scheduler) item(ctx context.Context, pr models.PRWithReviewers, since, now time.Time, names map[int]string) (Item, error) {
+	item := Item{PR: pr.PR, Since: since, Age: age(now.Sub(since))}
+	author, ok := names[pr.AuthorID]
+	if !ok {
+		u, err := s.repo.GetUserByID(ctx, pr.AuthorID)
+		if err != nil {
+			return Item{}, fmt.Errorf("get author of PR %d: %w", pr.ID, err)
+		}
+		author = u.Name
+		names[u.ID] = author
+	}
+	item.Author = author
+	for _, r := range pr.Reviewers {
+		item.Reviewers = append(item.Reviewers, r.Name)
+	}
+
+	ref, err := s.repo.GetExternalRef(ctx, pr.ID)
+	switch {
+	case err == nil:
+		item.URL = ref.URL
+	case !errors.Is(err, repository.ErrNotFound):
+		return Item{}, fmt.Errorf("get external ref of PR %d: %w", pr.ID, err)
+	}
+	return item, nil
+}
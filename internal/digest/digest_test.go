@@ -0,0 +1,183 @@
+package digest
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+
+	"prmanager/internal/mail"
+	"prmanager/internal/mail/mailtest"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	repo  repository.Repository
+	smtp  *mailtest.Server
+	sched *Scheduler
+	clock time.Time
+	users map[string]models.User
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &fixture{
+		repo:  memory.NewRepo(),
+		smtp:  mailtest.NewServer(t),
+		users: make(map[string]models.User),
+		// 09:05 UTC tomorrow, so everything created by the test is in the past
+		clock: time.Now().UTC().Truncate(24 * time.Hour).Add(33*time.Hour + 5*time.Minute),
+	}
+	cfg := DefaultConfig()
+	cfg.From = "PR Manager <reviews@example.com>"
+	f.sched = NewScheduler(f.repo, mail.NewSMTP(f.smtp.Addr, "", "", 5*time.Second), DefaultTemplates(), slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+	f.sched.now = func() time.Time { return f.clock }
+
+	team, err := f.repo.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	for _, name := range []string{"alice", "bob", "carol", "dave", "erin"} {
+		u, err := f.repo.CreateUser(ctx, models.User{TeamID: &team.ID, Name: name, IsActive: true})
+		require.NoError(t, err)
+		f.users[name] = u
+		if name != "dave" {
+			_, err = f.repo.SetIdentity(ctx, models.Identity{UserID: u.ID, Provider: models.EmailIdentityProvider, Login: name + "@example.com"})
+			require.NoError(t, err)
+		}
+	}
+	return f
+}
+
+func (f *fixture) pr(t *testing.T, title, author string, reviewers ...string) models.PR {
+	t.Helper()
+	pr, err := f.repo.CreatePR(context.Background(), models.PR{Title: title, AuthorID: f.users[author].ID, Status: models.PRStatusOpen})
+	require.NoError(t, err)
+	ids := make([]int, 0, len(reviewers))
+	for _, r := range reviewers {
+		ids = append(ids, f.users[r].ID)
+	}
+	require.NoError(t, f.repo.AssignReviewers(context.Background(), pr.ID, ids))
+	return pr
+}
+
+func (f *fixture) sendDue(t *testing.T) int {
+	t.Helper()
+	n, err := f.sched.SendDue(context.Background())
+	require.NoError(t, err)
+	return n
+}
+
+func (f *fixture) recipients() []string {
+	var res []string
+	for _, m := range f.smtp.Messages() {
+		res = append(res, m.To...)
+	}
+	return res
+}
+
+func TestSendDue(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	fix := f.pr(t, "Fix <login> redirect", "bob", "alice", "carol")
+	search := f.pr(t, "Add search", "alice", "bob")
+	f.pr(t, "Draft without reviewers", "alice")
+	f.pr(t, "Bump deps", "bob", "dave")
+	merged := f.pr(t, "Merged already", "bob", "alice")
+	require.NoError(t, f.repo.SetPRStatus(ctx, merged.ID, string(models.PRStatusMerged)))
+
+	_, err := f.repo.SetDigestPrefs(ctx, models.DigestPrefs{UserID: f.users["bob"].ID, SendAt: "19:30", Timezone: "Asia/Tokyo"})
+	require.NoError(t, err)
+	_, err = f.repo.SetDigestPrefs(ctx, models.DigestPrefs{UserID: f.users["carol"].ID, OptOut: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, f.sendDue(t), "only alice is due at 09:05 UTC; bob is due at 10:30 UTC")
+	assert.Equal(t, []string{"alice@example.com"}, f.recipients())
+	assert.Zero(t, f.sendDue(t), "a digest is sent once")
+
+	msgs := f.smtp.Messages()
+	require.Len(t, msgs, 1)
+	assert.Equal(t, "reviews@example.com", msgs[0].From)
+	parsed, err := msgs[0].Parse()
+	require.NoError(t, err)
+	assert.Equal(t, "Review digest: 1 to review, 1 waiting on others", parsed.Header.Get("Subject"))
+	text := parsed.Bodies["text/plain"]
+	assert.Contains(t, text, "Good morning, alice!")
+	assert.Contains(t, text, "#"+strconv.Itoa(fix.ID)+" Fix <login> redirect by bob, assigned "+age(f.clock.Sub(fix.CreatedAt))+" ago")
+	assert.Contains(t, text, "#"+strconv.Itoa(search.ID)+" Add search, open for "+age(f.clock.Sub(search.CreatedAt))+", with bob")
+	assert.NotContains(t, text, "Draft without reviewers")
+	assert.NotContains(t, text, "Merged already")
+	assert.Contains(t, parsed.Bodies["text/html"], "Fix &lt;login&gt; redirect")
+
+	f.clock = f.clock.Add(90 * time.Minute)
+	assert.Equal(t, 1, f.sendDue(t))
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, f.recipients())
+
+	prefs, err := f.repo.GetDigestPrefs(ctx, f.users["erin"].ID)
+	require.NoError(t, err, "erin's digest was claimed although there was nothing in it")
+	assert.NotNil(t, prefs.LastSentAt)
+
+	// The next day the scheduler only gets going at 11:30 UTC: alice's digest
+	// is too late to send, bob's is not.
+	f.clock = f.clock.Add(24*time.Hour + 55*time.Minute)
+	assert.Equal(t, 1, f.sendDue(t))
+	assert.Equal(t, []string{"alice@example.com", "bob@example.com", "bob@example.com"}, f.recipients())
+}
+
+func TestSendDueSkipsRejectedDigest(t *testing.T) {
+	f := newFixture(t)
+	f.pr(t, "Add search", "bob", "alice")
+	f.smtp.RejectRecipient("alice@example.com")
+
+	assert.Equal(t, 1, f.sendDue(t), "bob's digest goes out although alice's failed")
+	assert.Equal(t, []string{"bob@example.com"}, f.recipients())
+	assert.Zero(t, f.sendDue(t), "failed digests are not retried")
+}
+
+func TestDueAt(t *testing.T) {
+	s := NewScheduler(nil, nil, DefaultTemplates(), nil, DefaultConfig())
+	now := time.Date(2026, 3, 29, 7, 0, 0, 0, time.UTC)
+
+	due, _, err := s.dueAt(models.DigestPrefs{}, now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 3, 28, 9, 0, 0, 0, time.UTC), due, "before today's send time the latest is yesterday's")
+
+	due, loc, err := s.dueAt(models.DigestPrefs{SendAt: "08:30", Timezone: "Europe/Berlin"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", loc.String())
+	assert.True(t, time.Date(2026, 3, 29, 6, 30, 0, 0, time.UTC).Equal(due), "summer time started that night")
+
+	_, _, err = s.dueAt(models.DigestPrefs{Timezone: "Mars/Olympus"}, now)
+	assert.Error(t, err)
+	_, _, err = s.dueAt(models.DigestPrefs{SendAt: "25:00"}, now)
+	assert.Error(t, err)
+}
+
+func TestParseTemplates(t *testing.T) {
+	_, err := ParseTemplates(defaultText, `{{.Nope}}`)
+	assert.ErrorContains(t, err, "check HTML template")
+	_, err = ParseTemplates(`{{range}}`, defaultHTML)
+	assert.ErrorContains(t, err, "parse text template")
+
+	tmpl, err := ParseTemplates(`{{len .Reviews}} for {{.User.Name}}`, `<b>{{.User.Name}}</b>`)
+	require.NoError(t, err)
+	text, html, err := tmpl.render(Digest{User: models.User{Name: "<Al>"}})
+	require.NoError(t, err)
+	assert.Equal(t, "0 for <Al>", text)
+	assert.Equal(t, "<b>&lt;Al&gt;</b>", html)
+}
+
+func TestAge(t *testing.T) {
+	assert.Equal(t, "less than an hour", age(59*time.Minute))
+	assert.Equal(t, "1 hour", age(time.Hour))
+	assert.Equal(t, "23 hours", age(24*time.Hour-time.Second))
+	assert.Equal(t, "1 day", age(47*time.Hour))
+	assert.Equal(t, "3 days", age(72*time.Hour))
+}
@@ -0,0 +1,157 @@
+package digest
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+var (
+	//go:embed templates/digest.txt
+	defaultText string
+	//go:embed templates/digest.html
+	defaultHTML string
+)
+
+var funcs = map[string]any{"join": strings.Join}
+
+// Templates render the plain text and HTML bodies of a digest email.
+type Templates struct {
+	Text *texttemplate.Template
+	HTML *htmltemplate.Template
+}
+
+// DefaultTemplates returns the built-in templates.
+func DefaultTemplates() Templates {
+	t, err := ParseTemplates(defaultText, defaultHTML)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ParseTemplates parses both bodies and executes them against sample data,
+// so a reference to a field that does not exist fails here rather than when
+// the first digest is sent.
+func ParseTemplates(text, html string) (Templates, error) {
+	var t Templates
+	var err error
+	if t.Text, err = texttemplate.New("digest.txt").Option("missingkey=error").Funcs(funcs).Parse(text); err != nil {
+		return Templates{}, fmt.Errorf("parse text template: %w", err)
+	}
+	if t.HTML, err = htmltemplate.New("digest.html").Option("missingkey=error").Funcs(funcs).Parse(html); err != nil {
+		return Templates{}, fmt.Errorf("parse HTML template: %w", err)
+	}
+	if err := t.Text.Execute(io.Discard, sampleDigest()); err != nil {
+		return Templates{}, fmt.Errorf("check text template: %w", err)
+	}
+	if err := t.HTML.Execute(io.Discard, sampleDigest()); err != nil {
+		return Templates{}, fmt.Errorf("check HTML template: %w", err)
+	}
+	return t, nil
+}
+
+// LoadTemplates reads the text and HTML templates from files. An empty path
+// keeps the built-in template for that body.
+func LoadTemplates(textPath, htmlPath string) (Templates, error) {
+	text, html := defaultText, defaultHTML
+	for _, f := range []struct {
+		path string
+		dst  *string
+	}{{textPath, &text}, {htmlPath, &html}} {
+		if f.path == "" {
+			continue
+		}
+		raw, err := os.ReadFile(f.path)
+		if err != nil {
+			return Templates{}, fmt.Errorf("read digest template: %w", err)
+		}
+		*f.dst = string(raw)
+	}
+	return ParseTemplates(text, html)
+}
+
+func (t Templates) render(d Digest) (string, string, error) {
+	var text, html bytes.Buffer
+	if err := t.Text.Execute(&text, d); err != nil {
+		return "", "", fmt.Errorf("render text digest: %w", err)
+	}
+	if err := t.HTML.Execute(&html, d); err != nil {
+		return "", "", fmt.Errorf("render HTML digest: %w", err)
+	}
+	return text.String(), html.String(), nil
+}
+
+// Digest is the data the templates are executed with.
+type Digest struct {
+	User models.User
+	// Date is the recipient's local date the digest is for.
+	Date time.Time
+	// Reviews are the open PRs the user reviews, longest assigned first.
+	Reviews []Item
+	// Waiting are the user's own open PRs that have reviewers, oldest first.
+	Waiting []Item
+}
+
+func (d Digest) empty() bool {
+	return len(d.Reviews) == 0 && len(d.Waiting) == 0
+}
+
+// Item is one PR in a digest. Since is when the review was assigned for
+// Reviews and when the PR was opened for Waiting; Age is the time since then
+// in words.
+type Item struct {
+	PR        models.PR
+	URL       string // of the linked pull request, if any
+	Author    string
+	Reviewers []string
+	Since     time.Time
+	Age       string
+}
+
+// age describes d in whole hours or days.
+func age(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return "less than an hour"
+	case d < 2*time.Hour:
+		return "1 hour"
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%d hours", int(d/time.Hour))
+	case d < 48*time.Hour:
+		return "1 day"
+	default:
+		return fmt.Sprintf("%d days", int(d/(24*time.Hour)))
+	}
+}
+
+func sampleDigest() Digest {
+	opened := time.Date(2026, 1, 5, 9, 30, 0, 0, time.UTC)
+	return Digest{
+		User: models.User{ID: 1, Name: "Alice", IsActive: true},
+		Date: opened.Add(72 * time.Hour),
+		Reviews: []Item{{
+			PR:        models.PR{ID: 2, Title: "Fix <login> redirect", AuthorID: 2, Status: models.PRStatusOpen, CreatedAt: opened},
+			URL:       "https://github.com/acme/web/pull/2",
+			Author:    "Bob",
+			Reviewers: []string{"Alice", "Carol"},
+			Since:     opened,
+			Age:       "3 days",
+		}},
+		Waiting: []Item{{
+			PR:        models.PR{ID: 3, Title: "Add search", AuthorID: 1, Status: models.PRStatusOpen, CreatedAt: opened},
+			Author:    "Alice",
+			Reviewers: []string{"Bob"},
+			Since:     opened,
+			Age:       "3 days",
+		}},
+	}
+}
@@ -0,0 +1,115 @@
+// Package events defines the domain events the service emits when reviews
+// are assigned and pull requests change state, and the Publisher they are
+// handed to.
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+type Type string
+
+const (
+	PRCreated          Type = "pr.created"
+	ReviewerAssigned   Type = "reviewer.assigned"
+	ReviewerReassigned Type = "reviewer.reassigned"
+	PRMerged           Type = "pr.merged"
+	PRClosed           Type = "pr.closed"
+	PRReopened         Type = "pr.reopened"
+	ReviewReminder     Type = "review.reminder"
+	ReviewEscalated    Type = "review.escalated"
+)
+
+var Types = []Type{PRCreated, ReviewerAssigned, ReviewerReassigned, PRMerged, PRClosed, PRReopened, ReviewReminder, ReviewEscalated}
+
+func (t Type) Valid() bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the envelope delivered to subscribers. Data holds one of the
+// *Data payloads below, depending on Type.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       Type            `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+type PRData struct {
+	PR models.PRWithReviewers `json:"pr"`
+}
+
+type ReviewerAssignedData struct {
+	PRID       int `json:"pr_id"`
+	ReviewerID int `json:"reviewer_id"`
+}
+
+type ReviewerReassignedData struct {
+	PRID          int `json:"pr_id"`
+	OldReviewerID int `json:"old_reviewer_id"`
+	NewReviewerID int `json:"new_reviewer_id"`
+}
+
+// ReviewOverdueData is the payload of ReviewReminder and ReviewEscalated:
+// ReviewerID has not reviewed PRID within the SLA of TeamID. LeadID is set
+// on escalations of teams that have a lead.
+type ReviewOverdueData struct {
+	PRID       int       `json:"pr_id"`
+	ReviewerID int       `json:"reviewer_id"`
+	TeamID     int       `json:"team_id"`
+	LeadID     *int      `json:"lead_id,omitempty"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+func New(typ Type, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("marshal %s event: %w", typ, err)
+	}
+	return Event{
+		ID:         newID(),
+		Type:       typ,
+		OccurredAt: time.Now().UTC(),
+		Data:       raw,
+	}, nil
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+type fanout []Publisher
+
+// Fanout publishes every event to each of pubs in order. The first error
+// stops the fan-out and is returned, so a retried event reaches the earlier
+// publishers again: put idempotent publishers first.
+func Fanout(pubs ...Publisher) Publisher {
+	return fanout(pubs)
+}
+
+func (f fanout) Publish(ctx context.Context, e Event) error {
+	for _, p := range f {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
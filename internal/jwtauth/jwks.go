@@ -0,0 +1,263 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySource returns the public key with id kid. An empty kid asks for the
+// only key of the set.
+type KeySource interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+var (
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrKeysUnavailable means the key set could not be fetched, so tokens
+	// cannot be checked for now. It says nothing about the token itself.
+	ErrKeysUnavailable = errors.New("signing keys unavailable")
+)
+
+// JWK is a public key as in RFC 7517. Only RSA and EC signing keys are
+// supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid,omitempty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// ParseJWKS parses a JWK set and returns its signing keys by id. Keys of
+// unsupported types and encryption keys are skipped, so a set shared with
+// other consumers still loads.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.PublicKey()
+		if errors.Is(err, errUnsupportedKey) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse JWK %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("parse JWKS: no signing keys")
+	}
+	return keys, nil
+}
+
+var errUnsupportedKey = errors.New("unsupported key type")
+
+// PublicKey decodes k.
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("modulus: %w", err)
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("exponent: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: curve %q", errUnsupportedKey, k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", errUnsupportedKey, k.Kty)
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// StaticKeys is a key set that never changes, e.g. one read from a file.
+type StaticKeys map[string]crypto.PublicKey
+
+// LoadJWKSFile reads a JWK set from path.
+func LoadJWKSFile(path string) (StaticKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read JWKS: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return nil, err
+	}
+	return StaticKeys(keys), nil
+}
+
+func (s StaticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	return lookup(s, kid)
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, error) {
+	if kid == "" && len(keys) == 1 {
+		for _, k := range keys {
+			return k, nil
+		}
+	}
+	if k, ok := keys[kid]; ok && kid != "" {
+		return k, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+}
+
+// RemoteKeys fetches a JWK set from the identity provider's jwks_uri. The
+// set is refetched once it is older than MaxAge, and early when a token is
+// signed with a key it does not know, which is how providers roll keys; at
+// most once per MinRefresh, so tokens with made-up key ids cannot make the
+// service hammer the provider. While the provider is down the cached keys
+// keep being served; only tokens that need a refresh fail, with
+// ErrKeysUnavailable.
+type RemoteKeys struct {
+	URL        string
+	Client     *http.Client
+	MaxAge     time.Duration
+	MinRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	triedAt   time.Time
+	failed    bool
+	now       func() time.Time
+}
+
+func NewRemoteKeys(url string, client *http.Client) *RemoteKeys {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteKeys{URL: url, Client: client, MaxAge: time.Hour, MinRefresh: time.Minute, now: time.Now}
+}
+
+func (r *RemoteKeys) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keys == nil || r.now().Sub(r.fetchedAt) > r.MaxAge {
+		if err := r.refresh(ctx); err != nil && r.keys == nil {
+			return nil, err
+		}
+	}
+	k, err := lookup(r.keys, kid)
+	if errors.Is(err, ErrUnknownKey) {
+		if err := r.refresh(ctx); err != nil {
+			return nil, err
+		}
+		return lookup(r.keys, kid)
+	}
+	return k, err
+}
+
+// refresh fetches the keys unless the last attempt was less than MinRefresh
+// ago. A failed fetch keeps the cached keys and is reported as
+// ErrKeysUnavailable until a later fetch succeeds.
+func (r *RemoteKeys) refresh(ctx context.Context) error {
+	if !r.triedAt.IsZero() && r.now().Sub(r.triedAt) < r.MinRefresh {
+		if r.failed || r.keys == nil {
+			return fmt.Errorf("%w: provider unavailable, retrying later", ErrKeysUnavailable)
+		}
+		return nil
+	}
+	r.triedAt = r.now()
+	if err := r.fetch(ctx); err != nil {
+		r.failed = true
+		return fmt.Errorf("%w: %w", ErrKeysUnavailable, err)
+	}
+	r.failed = false
+	return nil
+}
+
+// fetch replaces the cached keys. It holds r.mu, so concurrent requests
+// wait for one fetch rather than each doing their own.
+func (r *RemoteKeys) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.URL, nil)
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch JWKS: unexpected status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("fetch JWKS: %w", err)
+	}
+	keys, err := ParseJWKS(data)
+	if err != nil {
+		return err
+	}
+	r.keys = keys
+	r.fetchedAt = r.now()
+	return nil
+}
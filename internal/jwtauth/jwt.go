@@ -0,0 +1,200 @@
+// Package jwtauth verifies JSON Web Tokens issued by an OpenID Connect
+// identity provider against the provider's published keys (JWKS).
+//
+// Only asymmetric signatures are accepted: RS256, RS384, RS512, ES256, ES384
+// and ES512. "none" and HMAC tokens are rejected, since the service never
+// shares a secret with the provider.
+package jwtauth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	_ "crypto/sha256" // register the hashes used by the algorithms
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the decoded claims of a verified token.
+type Claims map[string]any
+
+// String returns the string claim name, or "" if it is missing or not a
+// string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Strings returns a claim that is a list of strings. A single string is a
+// list of one, as some providers send single-valued groups that way.
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, e := range v {
+			if s, ok := e.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+func (c Claims) time(name string) (time.Time, bool) {
+	v, ok := c[name].(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := v.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+type Config struct {
+	// Issuer must equal the iss claim.
+	Issuer string
+	// Audience must be one of the aud claim.
+	Audience string
+	// Leeway absorbs clock skew between the provider and the service.
+	Leeway time.Duration
+}
+
+type Verifier struct {
+	keys KeySource
+	cfg  Config
+	now  func() time.Time
+}
+
+func NewVerifier(keys KeySource, cfg Config) *Verifier {
+	return &Verifier{keys: keys, cfg: cfg, now: time.Now}
+}
+
+// Verify checks the signature, issuer, audience and validity period of
+// token and returns its claims. A token without exp is rejected.
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	key, err := v.keys.Key(ctx, header.Kid)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.check(claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+func (v *Verifier) check(c Claims) error {
+	if iss := c.String("iss"); iss != v.cfg.Issuer {
+		return fmt.Errorf("issuer %q is not trusted", iss)
+	}
+	if !slices.Contains(c.Strings("aud"), v.cfg.Audience) {
+		return fmt.Errorf("token is not for audience %q", v.cfg.Audience)
+	}
+	now := v.now()
+	exp, ok := c.time("exp")
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if !now.Before(exp.Add(v.cfg.Leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := c.time("nbf"); ok && now.Add(v.cfg.Leeway).Before(nbf) {
+		return errors.New("token not valid yet")
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// ecBits is the curve each ECDSA algorithm is defined for.
+var ecBits = map[string]int{"ES256": 256, "ES384": 384, "ES512": 521}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) error {
+	var hash crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hash = crypto.SHA256
+	case "RS384", "ES384":
+		hash = crypto.SHA384
+	case "RS512", "ES512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("algorithm %q is not accepted", alg)
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		if alg[:2] != "RS" {
+			return fmt.Errorf("algorithm %s does not match an RSA key", alg)
+		}
+		return rsa.VerifyPKCS1v15(k, hash, digest, sig)
+	case *ecdsa.PublicKey:
+		bits := k.Curve.Params().BitSize
+		if alg[:2] != "ES" || ecBits[alg] != bits {
+			return fmt.Errorf("algorithm %s does not match a P-%d key", alg, bits)
+		}
+		size := (bits + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("malformed EC signature")
+		}
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+		if !ecdsa.Verify(k, digest, r, s) {
+			return errors.New("signature mismatch")
+		}
+		return nil
+	}
+	return fmt.Errorf("unsupported key type %T", key)
+}
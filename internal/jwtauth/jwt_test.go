@@ -0,0 +1,154 @@
+package jwtauth_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"prmanager/internal/jwtauth"
+	"prmanager/internal/jwtauth/jwtauthtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newVerifier(p *jwtauthtest.Provider) (*jwtauth.Verifier, *jwtauth.RemoteKeys) {
+	keys := jwtauth.NewRemoteKeys(p.JWKSURL, nil)
+	return jwtauth.NewVerifier(keys, jwtauth.Config{Issuer: p.Issuer, Audience: "pr-manager", Leeway: time.Minute}), keys
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	p := jwtauthtest.NewProvider(t)
+	v, _ := newVerifier(p)
+
+	claims := p.Claims("alice", "pr-manager")
+	claims["groups"] = []string{"eng", "pr-admins"}
+	got, err := v.Verify(ctx, p.Token(claims))
+	require.NoError(t, err)
+	assert.Equal(t, "alice", got.String("sub"))
+	assert.Equal(t, []string{"eng", "pr-admins"}, got.Strings("groups"))
+
+	claims = p.Claims("bob", "pr-manager")
+	claims["aud"] = []string{"other", "pr-manager"}
+	got, err = v.Verify(ctx, p.ECToken(claims))
+	require.NoError(t, err, "ES256 and a list audience")
+	assert.Equal(t, "bob", got.String("sub"))
+}
+
+func TestVerifyRejects(t *testing.T) {
+	ctx := context.Background()
+	p := jwtauthtest.NewProvider(t)
+	v, _ := newVerifier(p)
+
+	claims := func(change func(map[string]any)) map[string]any {
+		c := p.Claims("alice", "pr-manager")
+		change(c)
+		return c
+	}
+	valid := p.Token(p.Claims("alice", "pr-manager"))
+	parts := strings.Split(valid, ".")
+	forge := func(header string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + parts[1] + "." + parts[2]
+	}
+	tampered, _ := json.Marshal(claims(func(c map[string]any) { c["sub"] = "mallory" }))
+
+	for name, token := range map[string]string{
+		"issuer":       p.Token(claims(func(c map[string]any) { c["iss"] = "https://evil.example" })),
+		"audience":     p.Token(claims(func(c map[string]any) { c["aud"] = "someone-else" })),
+		"expired":      p.Token(claims(func(c map[string]any) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() })),
+		"not yet":      p.Token(claims(func(c map[string]any) { c["nbf"] = time.Now().Add(5 * time.Minute).Unix() })),
+		"no expiry":    p.Token(claims(func(c map[string]any) { delete(c, "exp") })),
+		"alg none":     forge(`{"alg":"none"}`),
+		"hmac":         forge(`{"alg":"HS256","kid":"` + p.JWKS().Keys[0].Kid + `"}`),
+		"alg mismatch": forge(`{"alg":"ES256","kid":"` + p.JWKS().Keys[0].Kid + `"}`),
+		"unknown kid":  forge(`{"alg":"RS256","kid":"nope"}`),
+		"tampered":     parts[0] + "." + base64.RawURLEncoding.EncodeToString(tampered) + "." + parts[2],
+		"malformed":    "not.a-jwt",
+	} {
+		_, err := v.Verify(ctx, token)
+		assert.ErrorIs(t, err, jwtauth.ErrInvalidToken, name)
+	}
+
+	_, err := v.Verify(ctx, p.Token(claims(func(c map[string]any) { c["exp"] = time.Now().Add(-30 * time.Second).Unix() })))
+	assert.NoError(t, err, "within the leeway")
+}
+
+func TestRemoteKeysRefetchOnRotation(t *testing.T) {
+	ctx := context.Background()
+	p := jwtauthtest.NewProvider(t)
+	v, keys := newVerifier(p)
+	keys.MinRefresh = 0
+
+	_, err := v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	require.NoError(t, err)
+	_, err = v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Fetches(), "keys are cached")
+
+	p.Rotate()
+	_, err = v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	require.NoError(t, err, "a new key id is fetched")
+	assert.Equal(t, 2, p.Fetches())
+
+	keys.MinRefresh = time.Hour
+	p.Rotate()
+	_, err = v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	assert.ErrorIs(t, err, jwtauth.ErrInvalidToken)
+	assert.Equal(t, 2, p.Fetches(), "refetches are rate limited")
+}
+
+func TestRemoteKeysProviderDown(t *testing.T) {
+	ctx := context.Background()
+	p := jwtauthtest.NewProvider(t)
+	v, keys := newVerifier(p)
+	keys.MinRefresh = 0
+
+	p.SetDown(true)
+	_, err := v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	assert.ErrorIs(t, err, jwtauth.ErrKeysUnavailable, "no keys yet")
+	assert.NotErrorIs(t, err, jwtauth.ErrInvalidToken)
+
+	p.SetDown(false)
+	_, err = v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	require.NoError(t, err)
+
+	p.SetDown(true)
+	keys.MaxAge = 0
+	_, err = v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	assert.NoError(t, err, "stale keys are served while the provider is down")
+
+	p.Rotate()
+	_, err = v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	assert.ErrorIs(t, err, jwtauth.ErrKeysUnavailable, "a new key id cannot be fetched")
+
+	keys.MinRefresh = time.Hour
+	_, err = v.Verify(ctx, p.Token(p.Claims("alice", "pr-manager")))
+	assert.ErrorIs(t, err, jwtauth.ErrKeysUnavailable, "still unavailable while refetches are rate limited")
+}
+
+func TestLoadJWKSFile(t *testing.T) {
+	p := jwtauthtest.NewProvider(t)
+	set := p.JWKS()
+	set.Keys = append(set.Keys, jwtauth.JWK{Kty: "oct", Kid: "shared"}, jwtauth.JWK{Kty: "RSA", Kid: "enc", Use: "enc"})
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	keys, err := jwtauth.LoadJWKSFile(path)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2, "symmetric and encryption keys are skipped")
+
+	v := jwtauth.NewVerifier(keys, jwtauth.Config{Issuer: p.Issuer, Audience: "pr-manager"})
+	_, err = v.Verify(context.Background(), p.ECToken(p.Claims("alice", "pr-manager")))
+	assert.NoError(t, err)
+
+	_, err = jwtauth.ParseJWKS([]byte(`{"keys":[]}`))
+	assert.Error(t, err)
+}
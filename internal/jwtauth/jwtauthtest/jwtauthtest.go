@@ -0,0 +1,158 @@
+// Package jwtauthtest is an identity provider for tests: it signs tokens with
+// keys generated on the fly and serves their JWKS over a local HTTP server.
+package jwtauthtest
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"prmanager/internal/jwtauth"
+)
+
+type Provider struct {
+	// JWKSURL serves the public keys of the provider.
+	JWKSURL string
+	// Issuer is the iss of the tokens Token signs.
+	Issuer string
+
+	t       testing.TB
+	mu      sync.Mutex
+	rsa     *rsa.PrivateKey
+	rsaKid  string
+	ec      *ecdsa.PrivateKey
+	fetches int
+	down    bool
+}
+
+// NewProvider generates an RSA and a P-256 key and starts serving them. The
+// server stops when the test ends.
+func NewProvider(t testing.TB) *Provider {
+	t.Helper()
+	ec, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("jwtauthtest: generate EC key: %v", err)
+	}
+	p := &Provider{t: t, ec: ec}
+	p.Rotate()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := json.Marshal(p.JWKS())
+		p.mu.Lock()
+		p.fetches++
+		down := p.down
+		p.mu.Unlock()
+		if down {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	p.JWKSURL = srv.URL + "/jwks.json"
+	p.Issuer = srv.URL
+	return p
+}
+
+// Rotate replaces the RSA key with a new one under a new key id.
+func (p *Provider) Rotate() {
+	p.t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		p.t.Fatalf("jwtauthtest: generate RSA key: %v", err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rsa = key
+	p.rsaKid = fmt.Sprintf("rsa-%d", time.Now().UnixNano())
+}
+
+// SetDown makes the JWKS endpoint answer 503 until it is called with false.
+func (p *Provider) SetDown(down bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.down = down
+}
+
+// Fetches returns how often the JWKS was requested.
+func (p *Provider) Fetches() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.fetches
+}
+
+// JWKS returns the provider's current public keys.
+func (p *Provider) JWKS() jwtauth.JWKS {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	return jwtauth.JWKS{Keys: []jwtauth.JWK{
+		{Kty: "RSA", Kid: p.rsaKid, Use: "sig", Alg: "RS256", N: b64(p.rsa.N.Bytes()), E: b64(big.NewInt(int64(p.rsa.E)).Bytes())},
+		{Kty: "EC", Kid: "ec-1", Use: "sig", Alg: "ES256", Crv: "P-256", X: b64(p.ec.X.FillBytes(make([]byte, 32))), Y: b64(p.ec.Y.FillBytes(make([]byte, 32)))},
+	}}
+}
+
+// Claims returns valid claims for sub and aud that expire in an hour.
+func (p *Provider) Claims(sub, aud string) map[string]any {
+	now := time.Now()
+	return map[string]any{
+		"iss": p.Issuer,
+		"sub": sub,
+		"aud": aud,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+// Token signs claims with the RSA key (RS256).
+func (p *Provider) Token(claims map[string]any) string {
+	p.mu.Lock()
+	key, kid := p.rsa, p.rsaKid
+	p.mu.Unlock()
+	return p.sign("RS256", kid, claims, func(digest []byte) ([]byte, error) {
+		return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest)
+	})
+}
+
+// ECToken signs claims with the P-256 key (ES256).
+func (p *Provider) ECToken(claims map[string]any) string {
+	return p.sign("ES256", "ec-1", claims, func(digest []byte) ([]byte, error) {
+		r, s, err := ecdsa.Sign(rand.Reader, p.ec, digest)
+		if err != nil {
+			return nil, err
+		}
+		return append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...), nil
+	})
+}
+
+func (p *Provider) sign(alg, kid string, claims map[string]any, sign func([]byte) ([]byte, error)) string {
+	p.t.Helper()
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	if err != nil {
+		p.t.Fatalf("jwtauthtest: %v", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		p.t.Fatalf("jwtauthtest: %v", err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := sign(digest[:])
+	if err != nil {
+		p.t.Fatalf("jwtauthtest: sign: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
@@ -0,0 +1,73 @@
+package mail
+
+import (
+	"context"
+	"mime"
+	"strings"
+	"testing"
+	"time"
+
+	"prmanager/internal/mail/mailtest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPSend(t *testing.T) {
+	srv := mailtest.NewServer(t)
+	s := NewSMTP(srv.Addr, "", "", 5*time.Second)
+
+	msg := Message{
+		From:    "PR Manager <reviews@example.com>",
+		To:      "Bob <bob@example.com>",
+		Subject: "Reviews for Wednesday – 3 open",
+		Date:    time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+		Text:    "You have 3 open reviews. " + strings.Repeat("long line ", 20),
+		HTML:    `<p class="x">You have <b>3</b> open reviews.</p>`,
+	}
+	require.NoError(t, s.Send(context.Background(), msg))
+
+	got := srv.Messages()
+	require.Len(t, got, 1)
+	assert.Equal(t, "reviews@example.com", got[0].From)
+	assert.Equal(t, []string{"bob@example.com"}, got[0].To)
+
+	parsed, err := got[0].Parse()
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, msg.Subject, subject)
+	assert.Equal(t, msg.Text, parsed.Bodies["text/plain"])
+	assert.Equal(t, msg.HTML, parsed.Bodies["text/html"])
+
+	date, err := parsed.Header.Date()
+	require.NoError(t, err)
+	assert.True(t, msg.Date.Equal(date))
+}
+
+func TestSMTPSendTextOnly(t *testing.T) {
+	srv := mailtest.NewServer(t)
+	s := NewSMTP(srv.Addr, "", "", 5*time.Second)
+
+	require.NoError(t, s.Send(context.Background(), Message{From: "a@example.com", To: "b@example.com", Text: "hi"}))
+	got := srv.Messages()
+	require.Len(t, got, 1)
+	parsed, err := got[0].Parse()
+	require.NoError(t, err)
+	require.Len(t, parsed.Bodies, 1)
+	assert.Equal(t, "hi\n", parsed.Bodies["text/plain"], "a single part ends with a line break")
+}
+
+func TestSMTPSendErrors(t *testing.T) {
+	srv := mailtest.NewServer(t)
+	srv.RejectRecipient("gone@example.com")
+	s := NewSMTP(srv.Addr, "", "", 5*time.Second)
+	ctx := context.Background()
+
+	assert.ErrorContains(t, s.Send(ctx, Message{From: "a@example.com", To: "gone@example.com"}), "RCPT TO")
+	assert.ErrorContains(t, s.Send(ctx, Message{From: "a@example.com", To: "not an address"}), "parse recipient")
+	assert.Empty(t, srv.Messages())
+
+	down := NewSMTP("127.0.0.1:1", "", "", time.Second)
+	assert.ErrorContains(t, down.Send(ctx, Message{From: "a@example.com", To: "b@example.com"}), "connect")
+}
@@ -0,0 +1,179 @@
+// Package mailtest runs a minimal in-process SMTP server that records what it
+// is sent, for tests of code that mails.
+package mailtest
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Message is a message the server accepted.
+type Message struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+type Server struct {
+	Addr string
+
+	ln     net.Listener
+	mu     sync.Mutex
+	msgs   []Message
+	reject string
+}
+
+// NewServer starts a server on a loopback port. It stops when the test ends.
+func NewServer(t testing.TB) *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("mailtest: listen: %v", err)
+	}
+	s := &Server{Addr: ln.Addr().String(), ln: ln}
+	t.Cleanup(func() { ln.Close() })
+	go s.serve()
+	return s
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.msgs...)
+}
+
+// RejectRecipient makes the server refuse RCPT TO for addr.
+func (s *Server) RejectRecipient(addr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = addr
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	tc := textproto.NewConn(conn)
+	reply := func(line string) { _ = tc.PrintfLine("%s", line) }
+
+	reply("220 mailtest ESMTP")
+	var msg Message
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250 mailtest")
+		case "MAIL":
+			msg = Message{From: address(arg)}
+			reply("250 OK")
+		case "RCPT":
+			to := address(arg)
+			s.mu.Lock()
+			rejected := to == s.reject
+			s.mu.Unlock()
+			if rejected {
+				reply("550 no such mailbox")
+				continue
+			}
+			msg.To = append(msg.To, to)
+			reply("250 OK")
+		case "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.msgs = append(s.msgs, msg)
+			s.mu.Unlock()
+			reply("250 OK")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 command not implemented")
+		}
+	}
+}
+
+// address extracts the address from "FROM:<a@b>" or "TO:<a@b>".
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(addr, " ")
+	return strings.Trim(addr, "<>")
+}
+
+// Parsed is a message's headers and its decoded body for every content type.
+type Parsed struct {
+	Header mail.Header
+	Bodies map[string]string
+}
+
+// Parse decodes m, looking into one level of multipart.
+func (m Message) Parse() (Parsed, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(string(m.Data)))
+	if err != nil {
+		return Parsed{}, err
+	}
+	p := Parsed{Header: msg.Header, Bodies: make(map[string]string)}
+	typ, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		return Parsed{}, err
+	}
+	if !strings.HasPrefix(typ, "multipart/") {
+		body, err := decode(msg.Body, msg.Header.Get("Content-Transfer-Encoding"))
+		p.Bodies[typ] = body
+		return p, err
+	}
+
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextRawPart()
+		if errors.Is(err, io.EOF) {
+			return p, nil
+		}
+		if err != nil {
+			return Parsed{}, err
+		}
+		typ, _, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		if err != nil {
+			return Parsed{}, err
+		}
+		if p.Bodies[typ], err = decode(part, part.Header.Get("Content-Transfer-Encoding")); err != nil {
+			return Parsed{}, err
+		}
+	}
+}
+
+func decode(r io.Reader, encoding string) (string, error) {
+	if strings.EqualFold(encoding, "quoted-printable") {
+		r = quotedprintable.NewReader(r)
+	}
+	b, err := io.ReadAll(bufio.NewReader(r))
+	return string(b), err
+}
@@ -43,6 +43,169 @@ func Run(ctx context.Context, pool *pgxpool.Pool) error {
 		`CREATE INDEX IF NOT EXISTS idx_users_team_id ON users(team_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_prs_author_id ON prs(author_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user_id ON pr_reviewers(user_id)`,
+
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		 key TEXT PRIMARY KEY,
+		 fingerprint TEXT NOT NULL,
+		 status_code INT,
+		 response_body BYTEA,
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		 completed_at TIMESTAMP WITH TIME ZONE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at)`,
+
+		`ALTER TABLE teams ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE`,
+
+		`CREATE TABLE IF NOT EXISTS webhooks (
+		 id SERIAL PRIMARY KEY,
+		 url TEXT NOT NULL,
+		 secret TEXT NOT NULL,
+		 events TEXT NOT NULL DEFAULT '',
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		 id SERIAL PRIMARY KEY,
+		 webhook_id INT NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		 event_id TEXT NOT NULL,
+		 event_type TEXT NOT NULL,
+		 payload BYTEA NOT NULL,
+		 status TEXT NOT NULL,
+		 attempts INT NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 response_code INT,
+		 next_attempt_at TIMESTAMP WITH TIME ZONE,
+		 last_attempt_at TIMESTAMP WITH TIME ZONE,
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id)`,
+
+		`CREATE TABLE IF NOT EXISTS outbox_events (
+		 id SERIAL PRIMARY KEY,
+		 event_id TEXT NOT NULL,
+		 event_type TEXT NOT NULL,
+		 ordering_key TEXT NOT NULL DEFAULT '',
+		 payload BYTEA NOT NULL,
+		 attempts INT NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		 locked_until TIMESTAMP WITH TIME ZONE,
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		 delivered_at TIMESTAMP WITH TIME ZONE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(ordering_key, id) WHERE delivered_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_events_delivered_at ON outbox_events(delivered_at) WHERE delivered_at IS NOT NULL`,
+
+		`CREATE TABLE IF NOT EXISTS identities (
+		 provider TEXT NOT NULL,
+		 login TEXT NOT NULL,
+		 user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		 PRIMARY KEY(provider, login),
+		 UNIQUE(user_id, provider)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS pr_external_refs (
+		 pr_id INT PRIMARY KEY REFERENCES prs(id) ON DELETE CASCADE,
+		 provider TEXT NOT NULL,
+		 repo TEXT NOT NULL,
+		 number INT NOT NULL,
+		 url TEXT NOT NULL DEFAULT '',
+		 UNIQUE(provider, repo, number)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS reviewer_syncs (
+		 pr_id INT PRIMARY KEY REFERENCES prs(id) ON DELETE CASCADE,
+		 status TEXT NOT NULL,
+		 generation INT NOT NULL DEFAULT 1,
+		 attempts INT NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 pushed TEXT NOT NULL DEFAULT '',
+		 next_attempt_at TIMESTAMP WITH TIME ZONE,
+		 locked_until TIMESTAMP WITH TIME ZONE,
+		 last_attempt_at TIMESTAMP WITH TIME ZONE,
+		 synced_at TIMESTAMP WITH TIME ZONE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reviewer_syncs_due ON reviewer_syncs(status, next_attempt_at)`,
+
+		`CREATE TABLE IF NOT EXISTS team_channels (
+		 team_id INT PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+		 webhook_url TEXT NOT NULL,
+		 channel TEXT NOT NULL DEFAULT '',
+		 updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS chat_messages (
+		 id SERIAL PRIMARY KEY,
+		 event_id TEXT NOT NULL,
+		 event_type TEXT NOT NULL,
+		 team_id INT NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		 text TEXT NOT NULL,
+		 status TEXT NOT NULL,
+		 attempts INT NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 next_attempt_at TIMESTAMP WITH TIME ZONE,
+		 last_attempt_at TIMESTAMP WITH TIME ZONE,
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		 UNIQUE(event_id, team_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_chat_messages_due ON chat_messages(status, next_attempt_at)`,
+
+		`CREATE TABLE IF NOT EXISTS digest_prefs (
+		 user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		 opt_out BOOLEAN NOT NULL DEFAULT false,
+		 send_at TEXT NOT NULL DEFAULT '',
+		 timezone TEXT NOT NULL DEFAULT '',
+		 last_sent_at TIMESTAMP WITH TIME ZONE,
+		 updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+		 id SERIAL PRIMARY KEY,
+		 name TEXT NOT NULL,
+		 role TEXT NOT NULL,
+		 user_id INT REFERENCES users(id) ON DELETE CASCADE,
+		 token_hash TEXT NOT NULL UNIQUE,
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now(),
+		 revoked_at TIMESTAMP WITH TIME ZONE
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS organizations (
+		 id SERIAL PRIMARY KEY,
+		 name TEXT UNIQUE NOT NULL,
+		 created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		)`,
+		// Everything created before organizations existed belongs to the
+		// default one.
+		`INSERT INTO organizations(id, name) VALUES(1, 'default') ON CONFLICT DO NOTHING`,
+		`SELECT setval(pg_get_serial_sequence('organizations', 'id'), (SELECT MAX(id) FROM organizations))`,
+		`ALTER TABLE teams ADD COLUMN IF NOT EXISTS org_id INT NOT NULL DEFAULT 1 REFERENCES organizations(id)`,
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id INT NOT NULL DEFAULT 1 REFERENCES organizations(id)`,
+		`ALTER TABLE prs ADD COLUMN IF NOT EXISTS org_id INT NOT NULL DEFAULT 1 REFERENCES organizations(id)`,
+		`ALTER TABLE api_tokens ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id)`,
+		`ALTER TABLE teams DROP CONSTRAINT IF EXISTS teams_name_key`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_teams_org_id_name ON teams(org_id, name)`,
+		`CREATE INDEX IF NOT EXISTS idx_users_org_id ON users(org_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_prs_org_id ON prs(org_id)`,
+
+		`CREATE TABLE IF NOT EXISTS team_slas (
+		 team_id INT PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+		 remind_after_hours INT NOT NULL DEFAULT 0,
+		 reassign_after_hours INT NOT NULL DEFAULT 0,
+		 escalate_after_hours INT NOT NULL DEFAULT 0,
+		 workday_start TEXT NOT NULL,
+		 workday_end TEXT NOT NULL,
+		 timezone TEXT NOT NULL,
+		 lead_id INT REFERENCES users(id) ON DELETE SET NULL,
+		 updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT now()
+		)`,
+		`ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS reminded_at TIMESTAMP WITH TIME ZONE`,
+		`ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP WITH TIME ZONE`,
+		`ALTER TABLE pr_reviewers ADD COLUMN IF NOT EXISTS reassign_failed_at TIMESTAMP WITH TIME ZONE`,
+
+		`ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS dead_at TIMESTAMP WITH TIME ZONE`,
 	}
 
 	for i, s := range stmts {
@@ -0,0 +1,290 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func RunSQLite(ctx context.Context, db *sql.DB) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS teams (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 name TEXT UNIQUE NOT NULL,
+		 created_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS users (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 team_id INTEGER REFERENCES teams(id) ON DELETE SET NULL,
+		 name TEXT NOT NULL,
+		 is_active BOOLEAN NOT NULL DEFAULT 1,
+		 created_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS prs (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 title TEXT NOT NULL,
+		 author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+		 status TEXT NOT NULL DEFAULT 'OPEN',
+		 created_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS pr_reviewers (
+		 pr_id INTEGER NOT NULL REFERENCES prs(id) ON DELETE CASCADE,
+		 user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+		 assigned_at DATETIME NOT NULL,
+		 PRIMARY KEY(pr_id, user_id)
+		)`,
+
+		`CREATE INDEX IF NOT EXISTS idx_users_team_id ON users(team_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_prs_author_id ON prs(author_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_pr_reviewers_user_id ON pr_reviewers(user_id)`,
+
+		`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		 key TEXT PRIMARY KEY,
+		 fingerprint TEXT NOT NULL,
+		 status_code INTEGER,
+		 response_body BLOB,
+		 created_at DATETIME NOT NULL,
+		 completed_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at)`,
+
+		`CREATE TABLE IF NOT EXISTS webhooks (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 url TEXT NOT NULL,
+		 secret TEXT NOT NULL,
+		 events TEXT NOT NULL DEFAULT '',
+		 created_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 webhook_id INTEGER NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+		 event_id TEXT NOT NULL,
+		 event_type TEXT NOT NULL,
+		 payload BLOB NOT NULL,
+		 status TEXT NOT NULL,
+		 attempts INTEGER NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 response_code INTEGER,
+		 next_attempt_at DATETIME,
+		 last_attempt_at DATETIME,
+		 created_at DATETIME NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, id)`,
+
+		`CREATE TABLE IF NOT EXISTS outbox_events (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 event_id TEXT NOT NULL,
+		 event_type TEXT NOT NULL,
+		 ordering_key TEXT NOT NULL DEFAULT '',
+		 payload BLOB NOT NULL,
+		 attempts INTEGER NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 next_attempt_at DATETIME NOT NULL,
+		 locked_until DATETIME,
+		 created_at DATETIME NOT NULL,
+		 delivered_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(ordering_key, id) WHERE delivered_at IS NULL`,
+		`CREATE INDEX IF NOT EXISTS idx_outbox_events_delivered_at ON outbox_events(delivered_at) WHERE delivered_at IS NOT NULL`,
+
+		`CREATE TABLE IF NOT EXISTS identities (
+		 provider TEXT NOT NULL,
+		 login TEXT NOT NULL,
+		 user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		 created_at DATETIME NOT NULL,
+		 PRIMARY KEY(provider, login),
+		 UNIQUE(user_id, provider)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS pr_external_refs (
+		 pr_id INTEGER PRIMARY KEY REFERENCES prs(id) ON DELETE CASCADE,
+		 provider TEXT NOT NULL,
+		 repo TEXT NOT NULL,
+		 number INTEGER NOT NULL,
+		 url TEXT NOT NULL DEFAULT '',
+		 UNIQUE(provider, repo, number)
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS reviewer_syncs (
+		 pr_id INTEGER PRIMARY KEY REFERENCES prs(id) ON DELETE CASCADE,
+		 status TEXT NOT NULL,
+		 generation INTEGER NOT NULL DEFAULT 1,
+		 attempts INTEGER NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 pushed TEXT NOT NULL DEFAULT '',
+		 next_attempt_at DATETIME,
+		 locked_until DATETIME,
+		 last_attempt_at DATETIME,
+		 synced_at DATETIME
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_reviewer_syncs_due ON reviewer_syncs(status, next_attempt_at)`,
+
+		`CREATE TABLE IF NOT EXISTS team_channels (
+		 team_id INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+		 webhook_url TEXT NOT NULL,
+		 channel TEXT NOT NULL DEFAULT '',
+		 updated_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS chat_messages (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 event_id TEXT NOT NULL,
+		 event_type TEXT NOT NULL,
+		 team_id INTEGER NOT NULL REFERENCES teams(id) ON DELETE CASCADE,
+		 text TEXT NOT NULL,
+		 status TEXT NOT NULL,
+		 attempts INTEGER NOT NULL DEFAULT 0,
+		 last_error TEXT NOT NULL DEFAULT '',
+		 next_attempt_at DATETIME,
+		 last_attempt_at DATETIME,
+		 created_at DATETIME NOT NULL,
+		 UNIQUE(event_id, team_id)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_chat_messages_due ON chat_messages(status, next_attempt_at)`,
+
+		`CREATE TABLE IF NOT EXISTS digest_prefs (
+		 user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+		 opt_out BOOLEAN NOT NULL DEFAULT 0,
+		 send_at TEXT NOT NULL DEFAULT '',
+		 timezone TEXT NOT NULL DEFAULT '',
+		 last_sent_at DATETIME,
+		 updated_at DATETIME NOT NULL
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS api_tokens (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 name TEXT NOT NULL,
+		 role TEXT NOT NULL,
+		 user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,
+		 token_hash TEXT NOT NULL UNIQUE,
+		 created_at DATETIME NOT NULL,
+		 revoked_at DATETIME
+		)`,
+
+		`CREATE TABLE IF NOT EXISTS organizations (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 name TEXT UNIQUE NOT NULL,
+		 created_at DATETIME NOT NULL
+		)`,
+		`INSERT OR IGNORE INTO organizations(id, name, created_at) VALUES(1, 'default', datetime('now'))`,
+
+		`CREATE TABLE IF NOT EXISTS team_slas (
+		 team_id INTEGER PRIMARY KEY REFERENCES teams(id) ON DELETE CASCADE,
+		 remind_after_hours INTEGER NOT NULL DEFAULT 0,
+		 reassign_after_hours INTEGER NOT NULL DEFAULT 0,
+		 escalate_after_hours INTEGER NOT NULL DEFAULT 0,
+		 workday_start TEXT NOT NULL,
+		 workday_end TEXT NOT NULL,
+		 timezone TEXT NOT NULL,
+		 lead_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+		 updated_at DATETIME NOT NULL
+		)`,
+	}
+
+	for i, s := range stmts {
+		if _, err := db.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("sqlite migrations stmt %d failed: %w", i, err)
+		}
+	}
+
+	columns := []struct{ table, name, def string }{
+		{"teams", "deleted_at", "DATETIME"},
+		{"users", "deleted_at", "DATETIME"},
+		// SQLite cannot add a column with both a foreign key and a non-NULL
+		// default, so org_id is not a foreign key here.
+		{"teams", "org_id", "INTEGER NOT NULL DEFAULT 1"},
+		{"users", "org_id", "INTEGER NOT NULL DEFAULT 1"},
+		{"prs", "org_id", "INTEGER NOT NULL DEFAULT 1"},
+		{"api_tokens", "org_id", "INTEGER REFERENCES organizations(id)"},
+		{"pr_reviewers", "reminded_at", "DATETIME"},
+		{"pr_reviewers", "escalated_at", "DATETIME"},
+		{"pr_reviewers", "reassign_failed_at", "DATETIME"},
+		{"outbox_events", "dead_at", "DATETIME"},
+	}
+	for _, c := range columns {
+		if err := addColumnSQLite(ctx, db, c.table, c.name, c.def); err != nil {
+			return fmt.Errorf("sqlite migrations add %s.%s failed: %w", c.table, c.name, err)
+		}
+	}
+
+	if err := dropTeamNameUniqueSQLite(ctx, db); err != nil {
+		return fmt.Errorf("sqlite migrations drop unique team names failed: %w", err)
+	}
+	indexes := []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_teams_org_id_name ON teams(org_id, name)`,
+		`CREATE INDEX IF NOT EXISTS idx_users_org_id ON users(org_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_prs_org_id ON prs(org_id)`,
+	}
+	for i, s := range indexes {
+		if _, err := db.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("sqlite migrations index %d failed: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// dropTeamNameUniqueSQLite rebuilds a teams table created when team names
+// were unique across the instance; SQLite cannot drop a column constraint in
+// place. Foreign keys are switched off on the connection doing the rebuild,
+// or dropping the old table would detach every user from their team.
+func dropTeamNameUniqueSQLite(ctx context.Context, db *sql.DB) error {
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'teams' AND name LIKE 'sqlite_autoindex_%'`).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+		return err
+	}
+	defer func() { _, _ = conn.ExecContext(context.WithoutCancel(ctx), `PRAGMA foreign_keys = ON`) }()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, s := range []string{
+		`CREATE TABLE teams_new (
+		 id INTEGER PRIMARY KEY AUTOINCREMENT,
+		 name TEXT NOT NULL,
+		 created_at DATETIME NOT NULL,
+		 deleted_at DATETIME,
+		 org_id INTEGER NOT NULL DEFAULT 1
+		)`,
+		`INSERT INTO teams_new(id, name, created_at, deleted_at, org_id) SELECT id, name, created_at, deleted_at, org_id FROM teams`,
+		`DROP TABLE teams`,
+		`ALTER TABLE teams_new RENAME TO teams`,
+	} {
+		if _, err := tx.ExecContext(ctx, s); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addColumnSQLite adds a column unless it already exists; SQLite has no
+// ADD COLUMN IF NOT EXISTS.
+func addColumnSQLite(ctx context.Context, db *sql.DB, table, name, def string) error {
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, name).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, table, name, def))
+	return err
+}
@@ -1,19 +1,59 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
-type Team struct {
+// DefaultOrgID is the organization that data from before organizations
+// existed belongs to, and that requests naming no organization act in.
+const DefaultOrgID = 1
+
+// Organization is a tenant: teams, users and PRs belong to exactly one, and
+// team names are unique only within it.
+type Organization struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+type Team struct {
+	ID        int        `json:"id"`
+	OrgID     int        `json:"org_id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type TeamWithMembers struct {
+	Team
+	Members []User `json:"members"`
+}
+
 type User struct {
-	ID        int       `json:"id"`
-	TeamID    *int      `json:"team_id"`
-	Name      string    `json:"name"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        int        `json:"id"`
+	OrgID     int        `json:"org_id"`
+	TeamID    *int       `json:"team_id"`
+	Name      string     `json:"name"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt time.Time  `json:"created_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+type UserWithTeam struct {
+	User
+	Team *Team `json:"team"`
+}
+
+type Reassignment struct {
+	PRID      int `json:"pr_id"`
+	OldUserID int `json:"old_user_id"`
+	NewUserID int `json:"new_user_id"`
+}
+
+type UserUpdateResult struct {
+	User          User           `json:"user"`
+	Reassignments []Reassignment `json:"reassignments"`
 }
 
 type PRStatus string
@@ -21,10 +61,12 @@ type PRStatus string
 const (
 	PRStatusOpen   PRStatus = "OPEN"
 	PRStatusMerged PRStatus = "MERGED"
+	PRStatusClosed PRStatus = "CLOSED"
 )
 
 type PR struct {
 	ID        int       `json:"id"`
+	OrgID     int       `json:"org_id"`
 	Title     string    `json:"title"`
 	AuthorID  int       `json:"author_id"`
 	Status    PRStatus  `json:"status"`
@@ -35,3 +77,260 @@ type PRWithReviewers struct {
 	PR
 	Reviewers []User `json:"reviewers"`
 }
+
+type AssignedPR struct {
+	PRWithReviewers
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+// ReviewerAssignment is a reviewer's assignment to a PR and the SLA stages
+// it went through, by the time it reached them.
+type ReviewerAssignment struct {
+	PRID             int        `json:"pr_id"`
+	UserID           int        `json:"user_id"`
+	AssignedAt       time.Time  `json:"assigned_at"`
+	RemindedAt       *time.Time `json:"reminded_at,omitempty"`
+	ReassignFailedAt *time.Time `json:"reassign_failed_at,omitempty"`
+	EscalatedAt      *time.Time `json:"escalated_at,omitempty"`
+}
+
+type IdempotencyRecord struct {
+	Key          string
+	Fingerprint  string
+	StatusCode   int
+	ResponseBody []byte
+	CreatedAt    time.Time
+	CompletedAt  *time.Time
+}
+
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+type WebhookDelivery struct {
+	ID            int             `json:"id"`
+	WebhookID     int             `json:"webhook_id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        DeliveryStatus  `json:"status"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+	ResponseCode  *int            `json:"response_code,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time      `json:"last_attempt_at,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// OutboxEvent is a domain event stored in the same transaction as the change
+// it describes, until the outbox relay has handed it to the publisher.
+// Events with the same OrderingKey are relayed strictly in ID order.
+type OutboxEvent struct {
+	ID            int             `json:"id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	OrderingKey   string          `json:"ordering_key"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	LockedUntil   *time.Time      `json:"locked_until,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	DeadAt        *time.Time      `json:"dead_at,omitempty"`
+}
+
+// ChatIdentityProvider is the identity provider under which users link their
+// chat handle: a Slack member ID such as U024BE7LH or a Mattermost username.
+const ChatIdentityProvider = "chat"
+
+// EmailIdentityProvider is the identity provider under which users link the
+// address their review digest is mailed to.
+const EmailIdentityProvider = "email"
+
+// OIDCIdentityProvider is the identity provider under which users link the
+// claim that identifies them in tokens of the single sign-on provider,
+// usually their subject.
+const OIDCIdentityProvider = "oidc"
+
+// Identity maps a user to their login on an external system such as GitHub,
+// or to their chat handle. Code host logins are stored lower-cased, since
+// the hosts compare them that way.
+type Identity struct {
+	UserID    int       `json:"user_id"`
+	Provider  string    `json:"provider"`
+	Login     string    `json:"login"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExternalRef links a PR to the pull or merge request it mirrors. Repo is the
+// repository's full name or project path, Number its PR number or MR IID.
+type ExternalRef struct {
+	PRID     int    `json:"pr_id"`
+	Provider string `json:"provider"`
+	Repo     string `json:"repo"`
+	Number   int    `json:"number"`
+	URL      string `json:"url,omitempty"`
+}
+
+type ExternalSyncStatus string
+
+const (
+	ExternalSyncCreated   ExternalSyncStatus = "created"
+	ExternalSyncUpdated   ExternalSyncStatus = "updated"
+	ExternalSyncUnchanged ExternalSyncStatus = "unchanged"
+	ExternalSyncIgnored   ExternalSyncStatus = "ignored"
+)
+
+// ExternalSyncResult reports what an incoming pull request event did. Reason
+// explains ignored events; PR is the tracked PR, when there is one.
+type ExternalSyncResult struct {
+	Status ExternalSyncStatus `json:"status"`
+	Reason string             `json:"reason,omitempty"`
+	PR     *PRWithReviewers   `json:"pr,omitempty"`
+}
+
+type ReviewerSyncStatus string
+
+const (
+	ReviewerSyncPending ReviewerSyncStatus = "pending"
+	ReviewerSyncSynced  ReviewerSyncStatus = "synced"
+	ReviewerSyncFailed  ReviewerSyncStatus = "failed"
+)
+
+// ReviewerSync tracks pushing a PR's reviewers to the code host it mirrors.
+// Pushed holds the logins currently requested there, so replaced reviewers
+// can be removed. Generation grows every time the reviewers change; a sync
+// attempt only settles the status if no change arrived while it ran.
+type ReviewerSync struct {
+	PRID          int                `json:"pr_id"`
+	Status        ReviewerSyncStatus `json:"status"`
+	Generation    int                `json:"-"`
+	Attempts      int                `json:"attempts"`
+	LastError     string             `json:"last_error,omitempty"`
+	Pushed        []string           `json:"pushed_reviewers"`
+	NextAttemptAt *time.Time         `json:"next_attempt_at,omitempty"`
+	LockedUntil   *time.Time         `json:"-"`
+	LastAttemptAt *time.Time         `json:"last_attempt_at,omitempty"`
+	SyncedAt      *time.Time         `json:"synced_at,omitempty"`
+}
+
+// TeamChannel is where chat notifications about a team's PRs are posted: a
+// Slack or Mattermost incoming webhook URL and an optional channel override.
+type TeamChannel struct {
+	TeamID     int       `json:"team_id"`
+	WebhookURL string    `json:"webhook_url"`
+	Channel    string    `json:"channel,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TeamSLA sets how long reviews by a team's members may wait. Thresholds are
+// working hours since the reviewer was assigned, counted Monday to Friday
+// between WorkdayStart and WorkdayEnd (DigestTimeLayout) in Timezone; zero
+// turns a stage off. Past RemindAfterHours the reviewer is reminded, past
+// ReassignAfterHours the review goes to someone else in the team, and a
+// review still waiting past EscalateAfterHours, because nobody could take it
+// over, is escalated to LeadID. A PR is escalated once, however many of its
+// reviews are overdue.
+type TeamSLA struct {
+	TeamID             int       `json:"team_id"`
+	RemindAfterHours   int       `json:"remind_after_hours"`
+	ReassignAfterHours int       `json:"reassign_after_hours"`
+	EscalateAfterHours int       `json:"escalate_after_hours"`
+	WorkdayStart       string    `json:"workday_start"`
+	WorkdayEnd         string    `json:"workday_end"`
+	Timezone           string    `json:"timezone"`
+	LeadID             *int      `json:"lead_id"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// SLAStage is a step an overdue review goes through at most once per
+// assignment.
+type SLAStage string
+
+const (
+	SLAReminded       SLAStage = "reminded"
+	SLAReassignFailed SLAStage = "reassign_failed"
+	SLAEscalated      SLAStage = "escalated"
+)
+
+// PendingReview is a reviewer's assignment to an open PR and the SLA stages
+// it has been through.
+type PendingReview struct {
+	PRID             int
+	UserID           int
+	AssignedAt       time.Time
+	RemindedAt       *time.Time
+	ReassignFailedAt *time.Time
+	EscalatedAt      *time.Time
+}
+
+// ChatMessage is a rendered notification waiting to be posted to the channel
+// of TeamID. EventID and TeamID are unique, so replayed events post once.
+type ChatMessage struct {
+	ID            int            `json:"id"`
+	EventID       string         `json:"event_id"`
+	EventType     string         `json:"event_type"`
+	TeamID        int            `json:"team_id"`
+	Text          string         `json:"text"`
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time     `json:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time     `json:"last_attempt_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// DigestTimeLayout is the format of DigestPrefs.SendAt.
+const DigestTimeLayout = "15:04"
+
+// DigestPrefs are a user's review digest settings. Users without stored
+// preferences get the digest at the server's default time. SendAt is a local
+// time of day such as "08:30" in Timezone, an IANA zone name; either may be
+// empty to use the server default.
+type DigestPrefs struct {
+	UserID     int        `json:"user_id"`
+	OptOut     bool       `json:"opt_out"`
+	SendAt     string     `json:"send_at,omitempty"`
+	Timezone   string     `json:"timezone,omitempty"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// Role decides what an API token may do: admins manage teams, users and
+// integrations, team leads act for the members of their team, and users act
+// for themselves.
+type Role string
+
+const (
+	RoleAdmin    Role = "admin"
+	RoleTeamLead Role = "team-lead"
+	RoleUser     Role = "user"
+)
+
+// APIToken is an issued API token. Only the SHA-256 hash of the token is
+// stored; the token itself is shown once, when it is issued. Team lead and
+// user tokens act as UserID, in UserID's organization. An admin token without
+// OrgID administers the whole instance.
+type APIToken struct {
+	ID        int        `json:"id"`
+	Name      string     `json:"name"`
+	Role      Role       `json:"role"`
+	UserID    *int       `json:"user_id,omitempty"`
+	OrgID     *int       `json:"org_id,omitempty"`
+	Hash      string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
@@ -0,0 +1,316 @@
+// Package notify posts chat messages about review assignments to the Slack or
+// Mattermost channel of the PR author's team.
+//
+// Publish renders a message from the event type's template and queues it;
+// Run posts queued messages to the team's incoming webhook in the
+// background, retrying failed attempts with exponential backoff until
+// MaxAttempts is reached. Messages are keyed by event and team, so an event
+// the outbox relays twice is posted once.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/retry"
+)
+
+type Config struct {
+	retry.Policy
+	PollInterval time.Duration
+	Timeout      time.Duration
+	BatchSize    int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Policy:       retry.Policy{MaxAttempts: 6, BaseBackoff: 10 * time.Second, MaxBackoff: 10 * time.Minute},
+		PollInterval: 5 * time.Second,
+		Timeout:      10 * time.Second,
+		BatchSize:    50,
+	}
+}
+
+type Notifier struct {
+	repo      repository.Repository
+	templates Templates
+	client    *http.Client
+	logger    *slog.Logger
+	cfg       Config
+	now       func() time.Time
+	wake      chan struct{}
+}
+
+func NewNotifier(r repository.Repository, templates Templates, logger *slog.Logger, cfg Config) *Notifier {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Notifier{
+		repo:      r,
+		templates: templates,
+		client:    &http.Client{Timeout: cfg.Timeout},
+		logger:    logger,
+		cfg:       cfg,
+		now:       time.Now,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Publish queues the message for e, if its type has a template and the PR
+// author's team has a chat channel. It is idempotent.
+func (n *Notifier) Publish(ctx context.Context, e events.Event) error {
+	tmpl, ok := n.templates[e.Type]
+	if !ok {
+		return nil
+	}
+	msg, teamID, err := n.message(ctx, e)
+	if err != nil || teamID == 0 {
+		return err
+	}
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, msg); err != nil {
+		return fmt.Errorf("render %s message: %w", e.Type, err)
+	}
+
+	next := n.now()
+	_, err = n.repo.CreateChatMessage(ctx, models.ChatMessage{
+		EventID:       e.ID,
+		EventType:     string(e.Type),
+		TeamID:        teamID,
+		Text:          text.String(),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &next,
+	})
+	if errors.Is(err, repository.ErrAlreadyExists) || errors.Is(err, repository.ErrInvalidReference) {
+		return nil // queued by an earlier relay, or the team is gone
+	}
+	if err != nil {
+		return fmt.Errorf("queue chat message for team %d: %w", teamID, err)
+	}
+
+	select {
+	case n.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// message gathers the template data for e and the team to post it to. A
+// zero team means there is nothing to post.
+func (n *Notifier) message(ctx context.Context, e events.Event) (Message, int, error) {
+	var prID, reviewerID, previousID, leadID int
+	var err error
+	switch e.Type {
+	case events.ReviewerAssigned:
+		var d events.ReviewerAssignedData
+		err = json.Unmarshal(e.Data, &d)
+		prID, reviewerID = d.PRID, d.ReviewerID
+	case events.ReviewerReassigned:
+		var d events.ReviewerReassignedData
+		err = json.Unmarshal(e.Data, &d)
+		prID, reviewerID, previousID = d.PRID, d.NewReviewerID, d.OldReviewerID
+	case events.ReviewReminder, events.ReviewEscalated:
+		var d events.ReviewOverdueData
+		err = json.Unmarshal(e.Data, &d)
+		prID, reviewerID = d.PRID, d.ReviewerID
+		if d.LeadID != nil {
+			leadID = *d.LeadID
+		}
+	default:
+		var d events.PRData
+		err = json.Unmarshal(e.Data, &d)
+		prID = d.PR.ID
+	}
+	if err != nil {
+		return Message{}, 0, fmt.Errorf("decode %s event: %w", e.Type, err)
+	}
+
+	pr, err := n.repo.GetPRByID(ctx, prID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return Message{}, 0, nil
+	}
+	if err != nil {
+		return Message{}, 0, fmt.Errorf("get PR %d: %w", prID, err)
+	}
+	author, err := n.repo.GetUserByID(ctx, pr.AuthorID)
+	if err != nil {
+		return Message{}, 0, fmt.Errorf("get author of PR %d: %w", prID, err)
+	}
+	if author.TeamID == nil {
+		return Message{}, 0, nil
+	}
+	if _, err := n.repo.GetTeamChannel(ctx, *author.TeamID); errors.Is(err, repository.ErrNotFound) {
+		return Message{}, 0, nil
+	} else if err != nil {
+		return Message{}, 0, fmt.Errorf("get chat channel of team %d: %w", *author.TeamID, err)
+	}
+
+	msg := Message{Event: e.Type, PR: pr}
+	if msg.Author, err = n.person(ctx, author); err != nil {
+		return Message{}, 0, err
+	}
+	if msg.Reviewer, err = n.personByID(ctx, reviewerID); err != nil {
+		return Message{}, 0, err
+	}
+	if msg.Previous, err = n.personByID(ctx, previousID); err != nil {
+		return Message{}, 0, err
+	}
+	if msg.Lead, err = n.personByID(ctx, leadID); err != nil {
+		return Message{}, 0, err
+	}
+	reviewers, err := n.repo.GetReviewersByPR(ctx, prID)
+	if err != nil {
+		return Message{}, 0, fmt.Errorf("get reviewers of PR %d: %w", prID, err)
+	}
+	for _, u := range reviewers {
+		p, err := n.person(ctx, u)
+		if err != nil {
+			return Message{}, 0, err
+		}
+		msg.Reviewers = append(msg.Reviewers, p)
+	}
+
+	ref, err := n.repo.GetExternalRef(ctx, prID)
+	switch {
+	case err == nil:
+		msg.URL = ref.URL
+	case !errors.Is(err, repository.ErrNotFound):
+		return Message{}, 0, fmt.Errorf("get external ref of PR %d: %w", prID, err)
+	}
+	return msg, *author.TeamID, nil
+}
+
+func (n *Notifier) personByID(ctx context.Context, userID int) (Person, error) {
+	if userID == 0 {
+		return Person{}, nil
+	}
+	u, err := n.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return Person{}, fmt.Errorf("get user %d: %w", userID, err)
+	}
+	return n.person(ctx, u)
+}
+
+func (n *Notifier) person(ctx context.Context, u models.User) (Person, error) {
+	ids, err := n.repo.ListIdentities(ctx, u.ID)
+	if err != nil {
+		return Person{}, fmt.Errorf("list identities of user %d: %w", u.ID, err)
+	}
+	p := Person{Name: u.Name}
+	if i := slices.IndexFunc(ids, func(id models.Identity) bool { return id.Provider == models.ChatIdentityProvider }); i >= 0 {
+		p.Handle = ids[i].Login
+	}
+	return p, nil
+}
+
+// Run posts due messages until ctx is cancelled.
+func (n *Notifier) Run(ctx context.Context) {
+	retry.Poll(ctx, n.cfg.PollInterval, n.wake, func(ctx context.Context) {
+		if _, err := n.SendDue(ctx); err != nil && ctx.Err() == nil {
+			n.logger.Error("failed to post chat messages", "error", err)
+		}
+	})
+}
+
+// SendDue makes one attempt for every message whose next attempt is due and
+// returns how many attempts were made.
+func (n *Notifier) SendDue(ctx context.Context) (int, error) {
+	due, err := n.repo.ListDueChatMessages(ctx, n.now(), n.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list due chat messages: %w", err)
+	}
+	for i, m := range due {
+		if err := n.attempt(ctx, m); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+func (n *Notifier) attempt(ctx context.Context, m models.ChatMessage) error {
+	var retryAfter time.Duration
+	ch, sendErr := n.repo.GetTeamChannel(ctx, m.TeamID)
+	switch {
+	case errors.Is(sendErr, repository.ErrNotFound):
+		sendErr = errors.New("team has no chat channel")
+	case sendErr != nil:
+		return fmt.Errorf("get chat channel of team %d: %w", m.TeamID, sendErr)
+	default:
+		retryAfter, sendErr = n.send(ctx, ch, m)
+	}
+	at := n.now()
+	m.Attempts++
+	m.LastAttemptAt = &at
+
+	switch next, ok := n.cfg.Next(m.Attempts, at, retryAfter); {
+	case sendErr == nil:
+		m.Status = models.DeliverySucceeded
+		m.LastError = ""
+		m.NextAttemptAt = nil
+	case !ok:
+		m.Status = models.DeliveryFailed
+		m.LastError = sendErr.Error()
+		m.NextAttemptAt = nil
+		n.logger.Warn("chat message failed permanently",
+			"message_id", m.ID, "team_id", m.TeamID, "attempts", m.Attempts, "error", sendErr)
+	default:
+		m.LastError = sendErr.Error()
+		m.NextAttemptAt = &next
+		n.logger.Info("chat message failed, will retry",
+			"message_id", m.ID, "team_id", m.TeamID, "attempts", m.Attempts, "next_attempt_at", next, "error", sendErr)
+	}
+
+	if err := n.repo.UpdateChatMessage(ctx, m); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("update chat message %d: %w", m.ID, err)
+	}
+	return nil
+}
+
+// payload is the body both Slack and Mattermost incoming webhooks accept.
+// Slack ignores Channel for app webhooks, which are bound to one channel.
+type payload struct {
+	Text    string `json:"text"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// send posts m to ch. When the receiver rate-limits the call it also returns
+// how long it asked to wait.
+func (n *Notifier) send(ctx context.Context, ch models.TeamChannel, m models.ChatMessage) (time.Duration, error) {
+	body, err := json.Marshal(payload{Text: m.Text, Channel: ch.Channel})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ch.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		secs, _ := strconv.Atoi(resp.Header.Get("Retry-After"))
+		return time.Duration(secs) * time.Second, errors.New("chat rate limit exceeded")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("chat responded %d: %s", resp.StatusCode, bytes.TrimSpace(reply))
+	}
+	return 0, nil
+}
@@ -0,0 +1,302 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/outbox"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChat stands in for a Slack or Mattermost incoming webhook. It records
+// every accepted payload and answers the next fail posts with status.
+type fakeChat struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	posts      []payload
+	fail       int
+	status     int
+	retryAfter string
+}
+
+func newFakeChat(t *testing.T) *fakeChat {
+	f := &fakeChat{status: http.StatusInternalServerError}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeChat) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.fail > 0 {
+		f.fail--
+		if f.retryAfter != "" {
+			w.Header().Set("Retry-After", f.retryAfter)
+		}
+		http.Error(w, "no_service", f.status)
+		return
+	}
+	var p payload
+	if err := json.NewDecoder(r.Body).Decode(&p); err != nil || r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "invalid_payload", http.StatusBadRequest)
+		return
+	}
+	f.posts = append(f.posts, p)
+	w.Write([]byte("ok"))
+}
+
+func (f *fakeChat) texts() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := make([]string, 0, len(f.posts))
+	for _, p := range f.posts {
+		res = append(res, p.Text)
+	}
+	return res
+}
+
+type fixture struct {
+	repo     repository.Repository
+	svc      *service.Service
+	relay    *outbox.Relay
+	notifier *Notifier
+	chat     *fakeChat
+	clock    time.Time
+	team     models.Team
+	users    map[string]models.User
+}
+
+var handles = map[string]string{"author": "author", "alice": "U0ALICE42", "bob": "bob"}
+
+func newFixture(t *testing.T, cfg Config, templates Templates) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewRepo()
+
+	f := &fixture{repo: repo, chat: newFakeChat(t), clock: time.Now(), users: make(map[string]models.User)}
+	f.svc = service.NewService(repo, logger)
+	f.notifier = NewNotifier(repo, templates, logger, cfg)
+	f.notifier.now = func() time.Time { return f.clock }
+	f.relay = outbox.NewRelay(repo, events.Fanout(f.notifier), logger, outbox.DefaultConfig())
+
+	var err error
+	f.team, err = f.svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	for _, name := range []string{"author", "alice", "bob", "carol"} {
+		u, err := f.svc.CreateUser(ctx, &f.team.ID, name, true)
+		require.NoError(t, err)
+		if h, ok := handles[name]; ok {
+			_, err = f.svc.SetIdentity(ctx, u.ID, models.ChatIdentityProvider, "@"+h)
+			require.NoError(t, err)
+		}
+		f.users[name] = u
+	}
+	_, err = f.svc.SetTeamChannel(ctx, "backend", f.chat.URL, "backend-reviews")
+	require.NoError(t, err)
+	return f
+}
+
+// run relays every recorded event and makes one round of posts.
+func (f *fixture) run(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	for {
+		n, err := f.relay.RelayDue(ctx)
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := f.notifier.SendDue(ctx)
+	require.NoError(t, err)
+}
+
+func mention(u models.User) string {
+	switch h := handles[u.Name]; {
+	case h == "":
+		return u.Name
+	case h == "U0ALICE42":
+		return "<@U0ALICE42>"
+	default:
+		return "@" + h
+	}
+}
+
+func TestAssignmentsReassignmentsAndMergesArePosted(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, DefaultConfig(), DefaultTemplates())
+
+	pr, err := f.svc.CreatePR(ctx, "Fix <script> & styles", f.users["author"].ID)
+	require.NoError(t, err)
+	require.Len(t, pr.Reviewers, 2)
+	f.run(t)
+
+	var want []string
+	for _, r := range pr.Reviewers {
+		want = append(want, mention(r)+", you have been asked to review *Fix &lt;script&gt; &amp; styles* by author.")
+	}
+	assert.ElementsMatch(t, want, f.chat.texts())
+	assert.Equal(t, "backend-reviews", f.chat.posts[0].Channel)
+
+	old := pr.Reviewers[0]
+	reassigned, err := f.svc.ReassignReviewer(ctx, pr.ID, old.ID)
+	require.NoError(t, err)
+	f.run(t)
+	var replacement models.User
+	for _, r := range reassigned.Reviewers {
+		if r.ID != pr.Reviewers[1].ID {
+			replacement = r
+		}
+	}
+	texts := f.chat.texts()
+	require.Len(t, texts, 3)
+	assert.Equal(t, mention(replacement)+", you have been asked to review *Fix &lt;script&gt; &amp; styles* by author instead of "+old.Name+".", texts[2])
+
+	_, err = f.svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+	f.run(t)
+	texts = f.chat.texts()
+	require.Len(t, texts, 4)
+	assert.Contains(t, texts[3], "*Fix &lt;script&gt; &amp; styles* by @author was merged. Thanks for reviewing,")
+	for _, r := range reassigned.Reviewers {
+		assert.Contains(t, texts[3], mention(r))
+	}
+}
+
+func TestOverdueReviewsArePosted(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, DefaultConfig(), DefaultTemplates())
+	pr, err := f.svc.CreatePR(ctx, "Add search", f.users["author"].ID)
+	require.NoError(t, err)
+	f.run(t)
+	reviewer := pr.Reviewers[0]
+	lead := f.users["bob"]
+
+	for _, e := range []struct {
+		typ  events.Type
+		lead *int
+	}{{events.ReviewReminder, nil}, {events.ReviewEscalated, &lead.ID}, {events.ReviewEscalated, nil}} {
+		ev, err := events.New(e.typ, events.ReviewOverdueData{PRID: pr.ID, ReviewerID: reviewer.ID, TeamID: f.team.ID, LeadID: e.lead})
+		require.NoError(t, err)
+		require.NoError(t, f.notifier.Publish(ctx, ev))
+	}
+	_, err = f.notifier.SendDue(ctx)
+	require.NoError(t, err)
+
+	texts := f.chat.texts()
+	require.Len(t, texts, 5)
+	assert.Equal(t, mention(reviewer)+", *Add search* by author is still waiting for your review.", texts[2])
+	assert.Equal(t, "@bob, *Add search* by author has waited too long for a review by "+reviewer.Name+".", texts[3])
+	assert.Equal(t, "*Add search* by author has waited too long for a review by "+reviewer.Name+".", texts[4], "teams without a lead get no mention")
+}
+
+func TestReplayedEventsArePostedOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, DefaultConfig(), DefaultTemplates())
+	pr, err := f.svc.CreatePR(ctx, "pr", f.users["author"].ID)
+	require.NoError(t, err)
+
+	e, err := events.New(events.PRMerged, events.PRData{PR: pr})
+	require.NoError(t, err)
+	require.NoError(t, f.notifier.Publish(ctx, e))
+	require.NoError(t, f.notifier.Publish(ctx, e))
+	_, err = f.notifier.SendDue(ctx)
+	require.NoError(t, err)
+	assert.Len(t, f.chat.texts(), 1)
+}
+
+func TestTeamsWithoutChannelAreSkipped(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, DefaultConfig(), DefaultTemplates())
+	require.NoError(t, f.svc.DeleteTeamChannel(ctx, "backend"))
+
+	_, err := f.svc.CreatePR(ctx, "pr", f.users["author"].ID)
+	require.NoError(t, err)
+	f.run(t)
+
+	due, err := f.repo.ListDueChatMessages(ctx, f.clock.Add(time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	assert.Empty(t, f.chat.texts())
+}
+
+func TestFailedPostsAreRetried(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.MaxAttempts = 2
+	f := newFixture(t, cfg, DefaultTemplates())
+	pr, err := f.svc.CreatePR(ctx, "pr", f.users["author"].ID)
+	require.NoError(t, err)
+
+	f.chat.fail, f.chat.status, f.chat.retryAfter = 2, http.StatusTooManyRequests, "3600"
+	f.run(t)
+	assert.Empty(t, f.chat.texts())
+
+	f.clock = f.clock.Add(cfg.BaseBackoff)
+	f.run(t)
+	assert.Empty(t, f.chat.texts(), "Retry-After outlasts the backoff")
+
+	f.clock = f.clock.Add(time.Hour)
+	f.run(t)
+	assert.Len(t, f.chat.texts(), len(pr.Reviewers))
+
+	f.chat.fail, f.chat.status, f.chat.retryAfter = 100, http.StatusNotFound, ""
+	_, err = f.svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+	for range cfg.MaxAttempts {
+		f.run(t)
+		f.clock = f.clock.Add(cfg.MaxBackoff)
+	}
+	due, err := f.repo.ListDueChatMessages(ctx, f.clock, 10)
+	require.NoError(t, err)
+	assert.Empty(t, due, "messages are given up after MaxAttempts")
+	assert.Len(t, f.chat.texts(), len(pr.Reviewers))
+}
+
+func TestCustomTemplates(t *testing.T) {
+	ctx := context.Background()
+	templates, err := ParseTemplates(map[string]string{
+		string(events.ReviewerAssigned): "",
+		string(events.PRMerged):         "merged: {{.PR.Title}} ({{len .Reviewers}} reviewers)",
+	})
+	require.NoError(t, err)
+	f := newFixture(t, DefaultConfig(), templates)
+
+	pr, err := f.svc.CreatePR(ctx, "Add search", f.users["author"].ID)
+	require.NoError(t, err)
+	_, err = f.svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+	f.run(t)
+	assert.Equal(t, []string{"merged: Add search (2 reviewers)"}, f.chat.texts(), "an empty template turns the event off")
+}
+
+func TestParseTemplatesRejectsMistakes(t *testing.T) {
+	for name, overrides := range map[string]map[string]string{
+		"unknown event": {"pr.created": "hi"},
+		"syntax":        {string(events.PRMerged): "{{.PR.Title"},
+		"unknown field": {string(events.PRMerged): "{{.PR.Name}}"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseTemplates(overrides)
+			assert.Error(t, err)
+		})
+	}
+}
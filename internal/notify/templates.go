@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+)
+
+// Templates holds the message template of every event type the notifier
+// posts about. Types without a template are not posted.
+type Templates map[events.Type]*template.Template
+
+var defaultTemplates = map[events.Type]string{
+	events.ReviewerAssigned:   `{{.Reviewer.Mention}}, you have been asked to review {{.PRLink}} by {{.Author}}.`,
+	events.ReviewerReassigned: `{{.Reviewer.Mention}}, you have been asked to review {{.PRLink}} by {{.Author}} instead of {{.Previous}}.`,
+	events.PRMerged:           `{{.PRLink}} by {{.Author.Mention}} was merged.{{if .Reviewers}} Thanks for reviewing,{{range $i, $r := .Reviewers}}{{if $i}},{{end}} {{$r.Mention}}{{end}}!{{end}}`,
+	events.ReviewReminder:     `{{.Reviewer.Mention}}, {{.PRLink}} by {{.Author}} is still waiting for your review.`,
+	events.ReviewEscalated:    `{{if .Lead.Name}}{{.Lead.Mention}}, {{end}}{{.PRLink}} by {{.Author}} has waited too long for a review by {{.Reviewer}}.`,
+}
+
+// DefaultTemplates returns the built-in templates.
+func DefaultTemplates() Templates {
+	t, err := ParseTemplates(nil)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// ParseTemplates parses overrides, keyed by event type, on top of the
+// built-in templates. An empty override turns the event type off. Every
+// template is executed against sample data, so a reference to a field that
+// does not exist fails here rather than when the first message is posted.
+func ParseTemplates(overrides map[string]string) (Templates, error) {
+	src := make(map[events.Type]string, len(defaultTemplates))
+	for typ, text := range defaultTemplates {
+		src[typ] = text
+	}
+	for typ, text := range overrides {
+		if _, ok := defaultTemplates[events.Type(typ)]; !ok {
+			return nil, fmt.Errorf("no chat message for event type %q", typ)
+		}
+		src[events.Type(typ)] = text
+	}
+
+	res := make(Templates, len(src))
+	for typ, text := range src {
+		if text == "" {
+			continue
+		}
+		t, err := template.New(string(typ)).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("parse %s template: %w", typ, err)
+		}
+		if err := t.Execute(io.Discard, sampleMessage(typ)); err != nil {
+			return nil, fmt.Errorf("check %s template: %w", typ, err)
+		}
+		res[typ] = t
+	}
+	return res, nil
+}
+
+// LoadTemplates reads overrides from a JSON object in path, e.g.
+// {"pr.merged": "{{.PRLink}} is in!"}. An empty path means the defaults.
+func LoadTemplates(path string) (Templates, error) {
+	if path == "" {
+		return DefaultTemplates(), nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read chat templates: %w", err)
+	}
+	var overrides map[string]string
+	if err := json.Unmarshal(raw, &overrides); err != nil {
+		return nil, fmt.Errorf("decode chat templates %s: %w", path, err)
+	}
+	return ParseTemplates(overrides)
+}
+
+// Message is the data a template is executed with.
+type Message struct {
+	Event  events.Type
+	PR     models.PR
+	URL    string // of the linked pull request, if any
+	Author Person
+	// Reviewer is the assigned reviewer and Previous the one they replaced.
+	Reviewer  Person
+	Previous  Person
+	Reviewers []Person
+	// Lead is the team lead an overdue review is escalated to.
+	Lead Person
+}
+
+// PRLink is the PR title, linked to the pull request when there is one.
+func (m Message) PRLink() string {
+	if m.URL == "" {
+		return "*" + Escape(m.PR.Title) + "*"
+	}
+	return "<" + m.URL + "|" + Escape(m.PR.Title) + ">"
+}
+
+// Person is a user as messages refer to them. Handle is their chat handle
+// from their chat identity, if they linked one.
+type Person struct {
+	Name   string
+	Handle string
+}
+
+// slackUserID matches Slack member IDs, which mentions must use instead of
+// display names.
+var slackUserID = regexp.MustCompile(`^[UW][A-Z0-9]{2,}$`)
+
+// Mention notifies the person in chat: <@U123> for Slack member IDs, @name
+// for Mattermost usernames. People without a handle are named instead.
+func (p Person) Mention() string {
+	switch {
+	case p.Handle == "":
+		return Escape(p.Name)
+	case slackUserID.MatchString(p.Handle):
+		return "<@" + p.Handle + ">"
+	default:
+		return "@" + p.Handle
+	}
+}
+
+// String is the person's name, escaped, as {{.Author}} prints it.
+func (p Person) String() string {
+	return Escape(p.Name)
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escape protects the control characters of Slack's message format, which
+// Mattermost webhooks understand as well.
+func Escape(s string) string {
+	return escaper.Replace(s)
+}
+
+func sampleMessage(typ events.Type) Message {
+	author := Person{Name: "Alice", Handle: "alice"}
+	bob := Person{Name: "Bob", Handle: "U024BE7LH"}
+	return Message{
+		Event:     typ,
+		PR:        models.PR{ID: 1, Title: "Add login page", AuthorID: 1, Status: models.PRStatusOpen},
+		URL:       "https://github.com/acme/web/pull/1",
+		Author:    author,
+		Reviewer:  bob,
+		Previous:  Person{Name: "Carol"},
+		Reviewers: []Person{bob},
+		Lead:      Person{Name: "Dave", Handle: "dave"},
+	}
+}
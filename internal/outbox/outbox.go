@@ -0,0 +1,172 @@
+// Package outbox relays domain events from the outbox table to a publisher.
+//
+// The service writes events in the same transaction as the state change they
+// describe, so an event exists if and only if the change was committed. The
+// Relay then hands them to the publisher at least once: an event is marked
+// delivered only after Publish returns, and a crash in between means it is
+// published again. Events sharing an ordering key (one key per PR) are
+// published strictly in the order they were written, and a failing event
+// holds back the later events of its key until it succeeds or, after
+// MaxAttempts, is dead-lettered: it stays in the table with its last error
+// and the rest of its key moves on.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/retry"
+)
+
+// PRKey is the ordering key of events about a pull request.
+func PRKey(prID int) string {
+	return "pr:" + strconv.Itoa(prID)
+}
+
+// Record builds the outbox row for e. It has to be written with the
+// repository that made the change e describes (see repository.WithTx).
+func Record(ctx context.Context, r repository.Repository, e events.Event, orderingKey string) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal %s event: %w", e.Type, err)
+	}
+	return r.CreateOutboxEvent(ctx, models.OutboxEvent{
+		EventID:     e.ID,
+		EventType:   string(e.Type),
+		OrderingKey: orderingKey,
+		Payload:     payload,
+	})
+}
+
+type Config struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed event is reserved for this relay. It must
+	// comfortably exceed a Publish call, or another replica may publish the
+	// same event concurrently.
+	Lease time.Duration
+	// Policy.MaxAttempts is how often an event is tried before it is
+	// dead-lettered; zero retries forever.
+	retry.Policy
+	// Retention is how long delivered events are kept before cleanup.
+	Retention time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		PollInterval: time.Second,
+		BatchSize:    100,
+		Lease:        time.Minute,
+		Policy:       retry.Policy{MaxAttempts: 20, BaseBackoff: time.Second, MaxBackoff: 5 * time.Minute},
+		Retention:    24 * time.Hour,
+	}
+}
+
+type Relay struct {
+	repo   repository.Repository
+	pub    events.Publisher
+	logger *slog.Logger
+	cfg    Config
+	now    func() time.Time
+}
+
+func NewRelay(r repository.Repository, pub events.Publisher, logger *slog.Logger, cfg Config) *Relay {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Relay{repo: r, pub: pub, logger: logger, cfg: cfg, now: time.Now}
+}
+
+// Run relays events until ctx is cancelled, and once an hour deletes
+// delivered events older than Retention.
+func (r *Relay) Run(ctx context.Context) {
+	poll := time.NewTicker(r.cfg.PollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		for {
+			n, err := r.RelayDue(ctx)
+			if err != nil && ctx.Err() == nil {
+				r.logger.Error("failed to relay outbox events", "error", err)
+			}
+			if err != nil || n == 0 {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-cleanup.C:
+			if n, err := r.Cleanup(ctx); err != nil {
+				r.logger.Error("failed to clean up outbox", "error", err)
+			} else if n > 0 {
+				r.logger.Info("cleaned up delivered outbox events", "count", n)
+			}
+		}
+	}
+}
+
+// RelayDue claims one batch of due events and publishes them. It returns the
+// number of events claimed.
+func (r *Relay) RelayDue(ctx context.Context) (int, error) {
+	claimed, err := r.repo.ClaimOutboxEvents(ctx, r.now(), r.cfg.Lease, r.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claim outbox events: %w", err)
+	}
+	for _, e := range claimed {
+		if err := r.relay(ctx, e); err != nil {
+			return len(claimed), err
+		}
+	}
+	return len(claimed), nil
+}
+
+func (r *Relay) relay(ctx context.Context, row models.OutboxEvent) error {
+	var e events.Event
+	err := json.Unmarshal(row.Payload, &e)
+	if err == nil {
+		err = r.pub.Publish(ctx, e)
+	}
+	if err == nil {
+		if err := r.repo.MarkOutboxEventDelivered(ctx, row.ID, r.now()); err != nil {
+			return fmt.Errorf("mark outbox event %d delivered: %w", row.ID, err)
+		}
+		return nil
+	}
+
+	row.Attempts++
+	row.LastError = err.Error()
+	next, ok := r.cfg.Next(row.Attempts, r.now(), 0)
+	if !ok {
+		r.logger.Error("failed to publish outbox event, giving up",
+			"outbox_id", row.ID, "event_id", row.EventID, "type", row.EventType,
+			"ordering_key", row.OrderingKey, "attempts", row.Attempts, "error", err)
+		if err := r.repo.DeadLetterOutboxEvent(ctx, row, r.now()); err != nil {
+			return fmt.Errorf("dead-letter outbox event %d: %w", row.ID, err)
+		}
+		return nil
+	}
+	row.NextAttemptAt = next
+	r.logger.Warn("failed to publish outbox event, will retry",
+		"outbox_id", row.ID, "event_id", row.EventID, "type", row.EventType,
+		"attempts", row.Attempts, "next_attempt_at", row.NextAttemptAt, "error", err)
+	if err := r.repo.RescheduleOutboxEvent(ctx, row); err != nil {
+		return fmt.Errorf("reschedule outbox event %d: %w", row.ID, err)
+	}
+	return nil
+}
+
+func (r *Relay) Cleanup(ctx context.Context) (int, error) {
+	return r.repo.DeleteDeliveredOutboxEvents(ctx, r.now().Add(-r.cfg.Retention))
+}
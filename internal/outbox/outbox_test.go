@@ -0,0 +1,152 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyPublisher fails every event whose ID is in failing.
+type flakyPublisher struct {
+	failing   map[string]bool
+	published []string
+}
+
+func (p *flakyPublisher) Publish(_ context.Context, e events.Event) error {
+	if p.failing[e.ID] {
+		return errors.New("broker unavailable")
+	}
+	p.published = append(p.published, e.ID)
+	return nil
+}
+
+func newRelay(t *testing.T) (*Relay, *flakyPublisher, repository.Repository, *time.Time) {
+	t.Helper()
+	repo := memory.NewRepo()
+	pub := &flakyPublisher{failing: map[string]bool{}}
+	// ahead of the wall clock, so events recorded by the test are already due
+	clock := time.Now().UTC().Add(time.Second)
+	cfg := DefaultConfig()
+	cfg.BaseBackoff = time.Second
+	r := NewRelay(repo, pub, slog.New(slog.NewTextHandler(io.Discard, nil)), cfg)
+	r.now = func() time.Time { return clock }
+	return r, pub, repo, &clock
+}
+
+func record(t *testing.T, repo repository.Repository, id string, prID int) {
+	t.Helper()
+	e := events.Event{ID: id, Type: events.PRCreated, OccurredAt: time.Now(), Data: []byte(`{}`)}
+	require.NoError(t, Record(context.Background(), repo, e, PRKey(prID)))
+}
+
+func drain(t *testing.T, r *Relay) {
+	t.Helper()
+	for {
+		n, err := r.RelayDue(context.Background())
+		require.NoError(t, err)
+		if n == 0 {
+			return
+		}
+	}
+}
+
+func TestRelayPublishesInOrderPerPR(t *testing.T) {
+	r, pub, repo, _ := newRelay(t)
+	record(t, repo, "a1", 1)
+	record(t, repo, "b1", 2)
+	record(t, repo, "a2", 1)
+	record(t, repo, "a3", 1)
+
+	drain(t, r)
+	assert.Equal(t, []string{"a1", "b1", "a2", "a3"}, pub.published)
+
+	drain(t, r)
+	assert.Len(t, pub.published, 4, "delivered events are not published again")
+}
+
+func TestFailedEventHoldsBackItsPR(t *testing.T) {
+	r, pub, repo, clock := newRelay(t)
+	pub.failing["a1"] = true
+	record(t, repo, "a1", 1)
+	record(t, repo, "a2", 1)
+	record(t, repo, "b1", 2)
+
+	drain(t, r)
+	assert.Equal(t, []string{"b1"}, pub.published, "other PRs are not blocked")
+
+	pub.failing["a1"] = false
+	drain(t, r)
+	assert.Equal(t, []string{"b1"}, pub.published, "retried only after the backoff")
+
+	*clock = clock.Add(time.Second)
+	drain(t, r)
+	assert.Equal(t, []string{"b1", "a1", "a2"}, pub.published)
+}
+
+func TestEventIsDeadLetteredAfterMaxAttempts(t *testing.T) {
+	r, pub, repo, clock := newRelay(t)
+	r.cfg.MaxAttempts = 3
+	pub.failing["a1"] = true
+	record(t, repo, "a1", 1)
+	record(t, repo, "a2", 1)
+
+	for range r.cfg.MaxAttempts - 1 {
+		drain(t, r)
+		assert.Empty(t, pub.published)
+		*clock = clock.Add(time.Minute)
+	}
+	drain(t, r)
+	assert.Equal(t, []string{"a2"}, pub.published, "after the last attempt the dead event no longer holds back its PR")
+
+	pub.failing["a1"] = false
+	*clock = clock.Add(time.Hour)
+	drain(t, r)
+	assert.Equal(t, []string{"a2"}, pub.published, "dead events are not retried")
+}
+
+func TestBackoffGrows(t *testing.T) {
+	r, pub, repo, clock := newRelay(t)
+	pub.failing["a1"] = true
+	record(t, repo, "a1", 1)
+
+	for _, wait := range []time.Duration{0, time.Second, 2 * time.Second, 4 * time.Second} {
+		*clock = clock.Add(wait)
+		n, err := r.RelayDue(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, 1, n, "retried after %s", wait)
+		n, err = r.RelayDue(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, n)
+	}
+}
+
+func TestCleanupDeletesOnlyOldDeliveredEvents(t *testing.T) {
+	r, pub, repo, clock := newRelay(t)
+	pub.failing["stuck"] = true
+	record(t, repo, "done", 1)
+	record(t, repo, "stuck", 2)
+	drain(t, r)
+
+	n, err := r.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n, "within retention")
+
+	*clock = clock.Add(r.cfg.Retention + time.Minute)
+	n, err = r.Cleanup(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	pub.failing["stuck"] = false
+	drain(t, r)
+	assert.Equal(t, []string{"done", "stuck"}, pub.published, "undelivered events survive cleanup")
+}
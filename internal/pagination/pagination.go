@@ -0,0 +1,103 @@
+// Package pagination holds the conventions shared by list endpoints: a
+// limit query parameter, opaque keyset cursors and the {items, next_cursor}
+// response envelope.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+
+	// SortByID is the Sort of cursors for lists that are only ordered by id.
+	SortByID = "id"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+)
+
+// Cursor points just past the last item of a page. Sort and Desc record the
+// ordering the cursor was issued for, so it cannot be replayed against a
+// different one. Time is the sort key for time-ordered lists and ID breaks
+// ties (or is the only key for lists ordered by id).
+type Cursor struct {
+	Sort string     `json:"s,omitempty"`
+	Desc bool       `json:"d,omitempty"`
+	Time *time.Time `json:"t,omitempty"`
+	ID   int        `json:"i"`
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func Decode(s string) (Cursor, error) {
+	var c Cursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// DecodeFor decodes s and checks that it was issued for the given ordering.
+func DecodeFor(s, sort string, desc bool) (*Cursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	c, err := Decode(s)
+	if err != nil {
+		return nil, err
+	}
+	if c.Sort != sort || c.Desc != desc {
+		return nil, fmt.Errorf("%w: cursor was issued for a different sort order", ErrInvalidCursor)
+	}
+	return &c, nil
+}
+
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%w: must be a positive integer", ErrInvalidLimit)
+	}
+	if n > MaxLimit {
+		return 0, fmt.Errorf("%w: must not exceed %d", ErrInvalidLimit, MaxLimit)
+	}
+	return n, nil
+}
+
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// NewPage builds a page from items fetched with limit+1: the extra item only
+// signals that another page exists and is dropped. cursorOf builds the cursor
+// for the last item that is kept.
+func NewPage[T any](items []T, limit int, cursorOf func(T) Cursor) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	if limit <= 0 || len(items) <= limit {
+		return Page[T]{Items: items}
+	}
+	items = items[:limit]
+	next := cursorOf(items[len(items)-1]).Encode()
+	return Page[T]{Items: items, NextCursor: &next}
+}
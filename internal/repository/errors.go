@@ -0,0 +1,9 @@
+package repository
+
+import "errors"
+
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrAlreadyExists    = errors.New("already exists")
+	ErrInvalidReference = errors.New("invalid reference")
+)
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+)
+
+type ReviewQueueSort string
+
+const (
+	SortByCreatedAt  ReviewQueueSort = "created_at"
+	SortByAssignedAt ReviewQueueSort = "assigned_at"
+)
+
+// ReviewQueueFilter narrows and orders ListPRsAssignedToUser. Results are
+// ordered by SortBy and then by PR id, in the same direction; After is a
+// keyset position in that order. A zero Limit means no limit.
+type ReviewQueueFilter struct {
+	Statuses      []models.PRStatus
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	SortBy        ReviewQueueSort
+	Desc          bool
+	After         *pagination.Cursor
+	Limit         int
+}
+
+// SortKey returns the value of the sort column for pr under this filter.
+func (f ReviewQueueFilter) SortKey(pr models.AssignedPR) time.Time {
+	if f.SortBy == SortByAssignedAt {
+		return pr.AssignedAt
+	}
+	return pr.CreatedAt
+}
+
+// TeamFilter pages through ListTeams, which orders teams by id.
+type TeamFilter struct {
+	After *pagination.Cursor
+	Limit int
+}
+
+// UserFilter narrows ListUsers, which orders users by id.
+type UserFilter struct {
+	TeamID *int
+	Active *bool
+	After  *pagination.Cursor
+	Limit  int
+}
+
+// DeliveryFilter pages through ListWebhookDeliveries, which returns the newest
+// deliveries first.
+type DeliveryFilter struct {
+	After *pagination.Cursor
+	Limit int
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func copyChatMessage(m models.ChatMessage) models.ChatMessage {
+	m.NextAttemptAt = truncOrNil(m.NextAttemptAt)
+	m.LastAttemptAt = truncOrNil(m.LastAttemptAt)
+	return m
+}
+
+func (r *repo) SetTeamChannel(_ context.Context, c models.TeamChannel) (models.TeamChannel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[c.TeamID]; !ok {
+		return models.TeamChannel{}, fmt.Errorf("set team channel: %w: team %d", repository.ErrInvalidReference, c.TeamID)
+	}
+	c.UpdatedAt = now()
+	r.teamChannels[c.TeamID] = c
+	return c, nil
+}
+
+func (r *repo) GetTeamChannel(_ context.Context, teamID int) (models.TeamChannel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.teamChannels[teamID]
+	if !ok {
+		return models.TeamChannel{}, fmt.Errorf("get team channel: %w", repository.ErrNotFound)
+	}
+	return c, nil
+}
+
+func (r *repo) DeleteTeamChannel(_ context.Context, teamID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teamChannels[teamID]; !ok {
+		return fmt.Errorf("delete team channel: %w", repository.ErrNotFound)
+	}
+	delete(r.teamChannels, teamID)
+	return nil
+}
+
+func (r *repo) CreateChatMessage(_ context.Context, m models.ChatMessage) (models.ChatMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[m.TeamID]; !ok {
+		return models.ChatMessage{}, fmt.Errorf("create chat message: %w: team %d", repository.ErrInvalidReference, m.TeamID)
+	}
+	for _, existing := range r.chatMessages {
+		if existing.EventID == m.EventID && existing.TeamID == m.TeamID {
+			return models.ChatMessage{}, fmt.Errorf("create chat message: %w", repository.ErrAlreadyExists)
+		}
+	}
+	r.lastChatMsgID++
+	m = copyChatMessage(m)
+	m.ID = r.lastChatMsgID
+	m.CreatedAt = now()
+	r.chatMessages[m.ID] = m
+	return m, nil
+}
+
+func (r *repo) UpdateChatMessage(_ context.Context, m models.ChatMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur, ok := r.chatMessages[m.ID]
+	if !ok {
+		return fmt.Errorf("update chat message: %w", repository.ErrNotFound)
+	}
+	cur.Status = m.Status
+	cur.Attempts = m.Attempts
+	cur.LastError = m.LastError
+	cur.NextAttemptAt = m.NextAttemptAt
+	cur.LastAttemptAt = m.LastAttemptAt
+	r.chatMessages[m.ID] = copyChatMessage(cur)
+	return nil
+}
+
+func (r *repo) ListDueChatMessages(_ context.Context, now time.Time, limit int) ([]models.ChatMessage, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.ChatMessage, 0)
+	for _, m := range r.chatMessages {
+		if m.Status == models.DeliveryPending && m.NextAttemptAt != nil && !m.NextAttemptAt.After(now) {
+			res = append(res, m)
+		}
+	}
+	slices.SortFunc(res, func(a, b models.ChatMessage) int {
+		if c := a.NextAttemptAt.Compare(*b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
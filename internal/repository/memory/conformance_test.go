@@ -0,0 +1,16 @@
+package memory
+
+import (
+	"testing"
+
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(*testing.T) repository.Repository { return NewRepo() })
+}
+
+func BenchmarkListPRsAssignedToUser(b *testing.B) {
+	repotest.BenchmarkListPRsAssignedToUser(b, NewRepo())
+}
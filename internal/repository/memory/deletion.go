@@ -0,0 +1,91 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"prmanager/internal/repository"
+)
+
+func (r *repo) DeleteTeam(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.teams[id]
+	if !ok || t.DeletedAt != nil || !visible(ctx, t.OrgID) {
+		return fmt.Errorf("delete team: %w", repository.ErrNotFound)
+	}
+	at := now()
+	t.DeletedAt = &at
+	r.teams[id] = t
+	return nil
+}
+
+func (r *repo) DeleteUser(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt != nil || !visible(ctx, u.OrgID) {
+		return fmt.Errorf("delete user: %w", repository.ErrNotFound)
+	}
+	at := now()
+	u.DeletedAt = &at
+	u.IsActive = false
+	r.users[id] = u
+	return nil
+}
+
+func (r *repo) PurgeTeam(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t, ok := r.teams[id]; !ok || !visible(ctx, t.OrgID) {
+		return fmt.Errorf("purge team: %w", repository.ErrNotFound)
+	}
+	delete(r.teams, id)
+	delete(r.teamChannels, id)
+	delete(r.teamSLAs, id)
+	for mid, m := range r.chatMessages {
+		if m.TeamID == id {
+			delete(r.chatMessages, mid)
+		}
+	}
+	for uid, u := range r.users {
+		if u.TeamID != nil && *u.TeamID == id {
+			u.TeamID = nil
+			r.users[uid] = u
+		}
+	}
+	return nil
+}
+
+func (r *repo) PurgeUser(ctx context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok || !visible(ctx, u.OrgID) {
+		return fmt.Errorf("purge user: %w", repository.ErrNotFound)
+	}
+	if u.DeletedAt == nil {
+		at := now()
+		u.DeletedAt = &at
+	}
+	u.Name = repository.AnonymizedUserName
+	u.TeamID = nil
+	u.IsActive = false
+	r.users[id] = u
+	delete(r.digestPrefs, id)
+	for tid, t := range r.apiTokens {
+		if t.UserID != nil && *t.UserID == id {
+			delete(r.apiTokens, tid)
+		}
+	}
+	for k, ident := range r.identities {
+		if ident.UserID == id {
+			delete(r.identities, k)
+		}
+	}
+	return nil
+}
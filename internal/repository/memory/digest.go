@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func (r *repo) SetDigestPrefs(_ context.Context, p models.DigestPrefs) (models.DigestPrefs, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[p.UserID]; !ok {
+		return models.DigestPrefs{}, fmt.Errorf("set digest prefs: %w: user %d", repository.ErrInvalidReference, p.UserID)
+	}
+	p.LastSentAt = r.digestPrefs[p.UserID].LastSentAt
+	p.UpdatedAt = now()
+	r.digestPrefs[p.UserID] = p
+	return p, nil
+}
+
+func (r *repo) GetDigestPrefs(_ context.Context, userID int) (models.DigestPrefs, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.digestPrefs[userID]
+	if !ok {
+		return models.DigestPrefs{}, fmt.Errorf("get digest prefs: %w", repository.ErrNotFound)
+	}
+	return p, nil
+}
+
+func (r *repo) ListDigestPrefs(_ context.Context) ([]models.DigestPrefs, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.DigestPrefs, 0, len(r.digestPrefs))
+	for _, id := range sortedKeys(r.digestPrefs) {
+		res = append(res, r.digestPrefs[id])
+	}
+	return res, nil
+}
+
+func (r *repo) ClaimDigest(_ context.Context, userID int, due, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[userID]; !ok {
+		return false, fmt.Errorf("claim digest: %w: user %d", repository.ErrInvalidReference, userID)
+	}
+	p, ok := r.digestPrefs[userID]
+	if !ok {
+		p = models.DigestPrefs{UserID: userID, UpdatedAt: now()}
+	}
+	if p.LastSentAt != nil && !p.LastSentAt.Before(due) {
+		return false, nil
+	}
+	p.LastSentAt = truncOrNil(&at)
+	r.digestPrefs[userID] = p
+	return true, nil
+}
@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func copyIdempotencyRecord(rec models.IdempotencyRecord) models.IdempotencyRecord {
+	rec.ResponseBody = slices.Clone(rec.ResponseBody)
+	if rec.CompletedAt != nil {
+		at := *rec.CompletedAt
+		rec.CompletedAt = &at
+	}
+	return rec
+}
+
+func (r *repo) CreateIdempotencyKey(_ context.Context, rec models.IdempotencyRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.idempotencyKeys[rec.Key]; ok {
+		return fmt.Errorf("create idempotency key: %w", repository.ErrAlreadyExists)
+	}
+	r.idempotencyKeys[rec.Key] = models.IdempotencyRecord{
+		Key:         rec.Key,
+		Fingerprint: rec.Fingerprint,
+		CreatedAt:   rec.CreatedAt.UTC().Truncate(time.Microsecond),
+	}
+	return nil
+}
+
+func (r *repo) GetIdempotencyKey(_ context.Context, key string) (models.IdempotencyRecord, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rec, ok := r.idempotencyKeys[key]
+	if !ok {
+		return models.IdempotencyRecord{}, fmt.Errorf("get idempotency key: %w", repository.ErrNotFound)
+	}
+	return copyIdempotencyRecord(rec), nil
+}
+
+func (r *repo) CompleteIdempotencyKey(_ context.Context, key string, statusCode int, body []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rec, ok := r.idempotencyKeys[key]
+	if !ok {
+		return nil
+	}
+	at := now()
+	rec.StatusCode = statusCode
+	rec.ResponseBody = slices.Clone(body)
+	rec.CompletedAt = &at
+	r.idempotencyKeys[key] = rec
+	return nil
+}
+
+func (r *repo) DeleteIdempotencyKey(_ context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.idempotencyKeys, key)
+	return nil
+}
+
+func (r *repo) DeleteIdempotencyKeysBefore(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for k, rec := range r.idempotencyKeys {
+		if rec.CreatedAt.Before(before) {
+			delete(r.idempotencyKeys, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+type identityKey struct {
+	provider string
+	login    string
+}
+
+func (r *repo) SetIdentity(_ context.Context, id models.Identity) (models.Identity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id.UserID]; !ok {
+		return models.Identity{}, fmt.Errorf("set identity: %w: user %d", repository.ErrInvalidReference, id.UserID)
+	}
+	key := identityKey{id.Provider, id.Login}
+	if existing, ok := r.identities[key]; ok {
+		if existing.UserID != id.UserID {
+			return models.Identity{}, fmt.Errorf("set identity: %w", repository.ErrAlreadyExists)
+		}
+		return existing, nil
+	}
+	for k, existing := range r.identities {
+		if existing.UserID == id.UserID && existing.Provider == id.Provider {
+			delete(r.identities, k)
+		}
+	}
+	id.CreatedAt = now()
+	r.identities[key] = id
+	return id, nil
+}
+
+func (r *repo) GetIdentityByLogin(_ context.Context, provider, login string) (models.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.identities[identityKey{provider, login}]
+	if !ok {
+		return models.Identity{}, fmt.Errorf("get identity: %w", repository.ErrNotFound)
+	}
+	return id, nil
+}
+
+func (r *repo) ListIdentities(_ context.Context, userID int) ([]models.Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := []models.Identity{}
+	for _, id := range r.identities {
+		if id.UserID == userID {
+			res = append(res, id)
+		}
+	}
+	slices.SortFunc(res, func(a, b models.Identity) int {
+		return strings.Compare(a.Provider, b.Provider)
+	})
+	return res, nil
+}
+
+func (r *repo) DeleteIdentity(_ context.Context, userID int, provider string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for k, id := range r.identities {
+		if id.UserID == userID && id.Provider == provider {
+			delete(r.identities, k)
+			return nil
+		}
+	}
+	return fmt.Errorf("delete identity: %w", repository.ErrNotFound)
+}
+
+func (r *repo) LinkExternalPR(_ context.Context, ref models.ExternalRef) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.prs[ref.PRID]; !ok {
+		return fmt.Errorf("link external PR: %w: PR %d", repository.ErrInvalidReference, ref.PRID)
+	}
+	if _, ok := r.externalRefs[ref.PRID]; ok {
+		return fmt.Errorf("link external PR: %w", repository.ErrAlreadyExists)
+	}
+	for _, existing := range r.externalRefs {
+		if existing.Provider == ref.Provider && existing.Repo == ref.Repo && existing.Number == ref.Number {
+			return fmt.Errorf("link external PR: %w", repository.ErrAlreadyExists)
+		}
+	}
+	r.externalRefs[ref.PRID] = ref
+	return nil
+}
+
+func (r *repo) GetExternalRef(_ context.Context, prID int) (models.ExternalRef, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ref, ok := r.externalRefs[prID]
+	if !ok {
+		return models.ExternalRef{}, fmt.Errorf("get external ref: %w", repository.ErrNotFound)
+	}
+	return ref, nil
+}
+
+func (r *repo) GetPRByExternalRef(ctx context.Context, provider, repoName string, number int) (models.PR, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, ref := range r.externalRefs {
+		if ref.Provider == provider && ref.Repo == repoName && ref.Number == number && visible(ctx, r.prs[ref.PRID].OrgID) {
+			return r.prs[ref.PRID], nil
+		}
+	}
+	return models.PR{}, fmt.Errorf("get PR by external ref: %w", repository.ErrNotFound)
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+// visible reports whether a row of orgID is visible in ctx.
+func visible(ctx context.Context, orgID int) bool {
+	id, ok := repository.OrgFrom(ctx)
+	return !ok || id == orgID
+}
+
+func (r *repo) CreateOrganization(_ context.Context, name string) (models.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, o := range r.orgs {
+		if o.Name == name {
+			return models.Organization{}, fmt.Errorf("create organization: %w: organization name %q", repository.ErrAlreadyExists, name)
+		}
+	}
+	r.lastOrgID++
+	o := models.Organization{ID: r.lastOrgID, Name: name, CreatedAt: now()}
+	r.orgs[o.ID] = o
+	return o, nil
+}
+
+func (r *repo) GetOrganization(_ context.Context, id int) (models.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	o, ok := r.orgs[id]
+	if !ok {
+		return models.Organization{}, fmt.Errorf("get organization: %w", repository.ErrNotFound)
+	}
+	return o, nil
+}
+
+func (r *repo) GetOrganizationByName(_ context.Context, name string) (models.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, o := range r.orgs {
+		if o.Name == name {
+			return o, nil
+		}
+	}
+	return models.Organization{}, fmt.Errorf("get organization by name: %w", repository.ErrNotFound)
+}
+
+func (r *repo) ListOrganizations(_ context.Context) ([]models.Organization, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.Organization, 0, len(r.orgs))
+	for _, id := range sortedKeys(r.orgs) {
+		res = append(res, r.orgs[id])
+	}
+	return res, nil
+}
+
+func (r *repo) RestoreOrganization(_ context.Context, o models.Organization) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.orgs {
+		if existing.ID != o.ID && existing.Name == o.Name {
+			return fmt.Errorf("restore organization %d: %w: organization name %q", o.ID, repository.ErrAlreadyExists, o.Name)
+		}
+	}
+	r.orgs[o.ID] = o
+	r.lastOrgID = max(r.lastOrgID, o.ID)
+	return nil
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func copyOutboxEvent(e models.OutboxEvent) models.OutboxEvent {
+	e.Payload = slices.Clone(e.Payload)
+	e.LockedUntil = truncOrNil(e.LockedUntil)
+	e.DeliveredAt = truncOrNil(e.DeliveredAt)
+	e.DeadAt = truncOrNil(e.DeadAt)
+	return e
+}
+
+func (r *repo) CreateOutboxEvent(_ context.Context, e models.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastOutboxID++
+	e = copyOutboxEvent(e)
+	e.ID = r.lastOutboxID
+	e.CreatedAt = now()
+	if e.NextAttemptAt.IsZero() {
+		e.NextAttemptAt = e.CreatedAt
+	}
+	e.NextAttemptAt = e.NextAttemptAt.UTC().Truncate(time.Microsecond)
+	r.outbox[e.ID] = e
+	return nil
+}
+
+func (r *repo) ClaimOutboxEvents(_ context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// the oldest undelivered, live event of every ordering key
+	heads := make(map[string]int)
+	for _, id := range sortedKeys(r.outbox) {
+		e := r.outbox[id]
+		if e.DeliveredAt != nil || e.DeadAt != nil || e.OrderingKey == "" {
+			continue
+		}
+		if _, ok := heads[e.OrderingKey]; !ok {
+			heads[e.OrderingKey] = id
+		}
+	}
+
+	until := now.Add(lease).UTC().Truncate(time.Microsecond)
+	res := make([]models.OutboxEvent, 0)
+	for _, id := range sortedKeys(r.outbox) {
+		if limit > 0 && len(res) >= limit {
+			break
+		}
+		e := r.outbox[id]
+		if e.DeliveredAt != nil || e.DeadAt != nil || e.NextAttemptAt.After(now) {
+			continue
+		}
+		if e.LockedUntil != nil && e.LockedUntil.After(now) {
+			continue
+		}
+		if e.OrderingKey != "" && heads[e.OrderingKey] != id {
+			continue
+		}
+		e.LockedUntil = &until
+		r.outbox[id] = e
+		res = append(res, copyOutboxEvent(e))
+	}
+	return res, nil
+}
+
+func (r *repo) MarkOutboxEventDelivered(_ context.Context, id int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.outbox[id]
+	if !ok {
+		return fmt.Errorf("mark outbox event delivered: %w", repository.ErrNotFound)
+	}
+	at = at.UTC().Truncate(time.Microsecond)
+	e.DeliveredAt = &at
+	e.LockedUntil = nil
+	r.outbox[id] = e
+	return nil
+}
+
+func (r *repo) RescheduleOutboxEvent(_ context.Context, upd models.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.outbox[upd.ID]
+	if !ok {
+		return fmt.Errorf("reschedule outbox event: %w", repository.ErrNotFound)
+	}
+	e.Attempts = upd.Attempts
+	e.LastError = upd.LastError
+	e.NextAttemptAt = upd.NextAttemptAt.UTC().Truncate(time.Microsecond)
+	e.LockedUntil = nil
+	r.outbox[e.ID] = e
+	return nil
+}
+
+func (r *repo) DeadLetterOutboxEvent(_ context.Context, upd models.OutboxEvent, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e, ok := r.outbox[upd.ID]
+	if !ok {
+		return fmt.Errorf("dead-letter outbox event: %w", repository.ErrNotFound)
+	}
+	e.Attempts = upd.Attempts
+	e.LastError = upd.LastError
+	e.DeadAt = truncOrNil(&at)
+	e.LockedUntil = nil
+	r.outbox[e.ID] = e
+	return nil
+}
+
+func (r *repo) DeleteDeliveredOutboxEvents(_ context.Context, before time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for id, e := range r.outbox {
+		if e.DeliveredAt != nil && e.DeliveredAt.Before(before) {
+			delete(r.outbox, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,569 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+type reviewerKey struct {
+	prID   int
+	userID int
+}
+
+// locker is the part of sync.RWMutex the repository uses. A repo bound to a
+// transaction runs while WithTx holds the real lock, so it gets a no-op one.
+type locker interface {
+	Lock()
+	Unlock()
+	RLock()
+	RUnlock()
+}
+
+type nopLocker struct{}
+
+func (nopLocker) Lock()    {}
+func (nopLocker) Unlock()  {}
+func (nopLocker) RLock()   {}
+func (nopLocker) RUnlock() {}
+
+type state struct {
+	orgs      map[int]models.Organization
+	teams     map[int]models.Team
+	users     map[int]models.User
+	prs       map[int]models.PR
+	reviewers map[reviewerKey]time.Time
+
+	idempotencyKeys map[string]models.IdempotencyRecord
+
+	webhooks   map[int]models.Webhook
+	deliveries map[int]models.WebhookDelivery
+	outbox     map[int]models.OutboxEvent
+
+	identities   map[identityKey]models.Identity
+	externalRefs map[int]models.ExternalRef
+	reviewerSync map[int]models.ReviewerSync
+
+	teamChannels map[int]models.TeamChannel
+	teamSLAs     map[int]models.TeamSLA
+	reviewStages map[reviewerKey]reviewStages
+	chatMessages map[int]models.ChatMessage
+	digestPrefs  map[int]models.DigestPrefs
+	apiTokens    map[int]models.APIToken
+
+	lastOrgID      int
+	lastTeamID     int
+	lastUserID     int
+	lastPRID       int
+	lastWebhookID  int
+	lastDeliveryID int
+	lastOutboxID   int
+	lastChatMsgID  int
+	lastTokenID    int
+}
+
+// clone copies the maps so a rollback can restore them. Stored values are
+// always replaced, never mutated in place, so a shallow copy is enough.
+func (s *state) clone() *state {
+	c := *s
+	c.orgs = maps.Clone(s.orgs)
+	c.teams = maps.Clone(s.teams)
+	c.users = maps.Clone(s.users)
+	c.prs = maps.Clone(s.prs)
+	c.reviewers = maps.Clone(s.reviewers)
+	c.idempotencyKeys = maps.Clone(s.idempotencyKeys)
+	c.webhooks = maps.Clone(s.webhooks)
+	c.deliveries = maps.Clone(s.deliveries)
+	c.outbox = maps.Clone(s.outbox)
+	c.identities = maps.Clone(s.identities)
+	c.externalRefs = maps.Clone(s.externalRefs)
+	c.reviewerSync = maps.Clone(s.reviewerSync)
+	c.teamChannels = maps.Clone(s.teamChannels)
+	c.teamSLAs = maps.Clone(s.teamSLAs)
+	c.reviewStages = maps.Clone(s.reviewStages)
+	c.chatMessages = maps.Clone(s.chatMessages)
+	c.digestPrefs = maps.Clone(s.digestPrefs)
+	c.apiTokens = maps.Clone(s.apiTokens)
+	return &c
+}
+
+type repo struct {
+	mu locker
+	*state
+}
+
+func NewRepo() repository.Repository {
+	return &repo{
+		mu: &sync.RWMutex{},
+		state: &state{
+			orgs: map[int]models.Organization{
+				models.DefaultOrgID: {ID: models.DefaultOrgID, Name: "default", CreatedAt: now()},
+			},
+			lastOrgID: models.DefaultOrgID,
+
+			teams:     make(map[int]models.Team),
+			users:     make(map[int]models.User),
+			prs:       make(map[int]models.PR),
+			reviewers: make(map[reviewerKey]time.Time),
+
+			idempotencyKeys: make(map[string]models.IdempotencyRecord),
+
+			webhooks:   make(map[int]models.Webhook),
+			deliveries: make(map[int]models.WebhookDelivery),
+			outbox:     make(map[int]models.OutboxEvent),
+
+			identities:   make(map[identityKey]models.Identity),
+			externalRefs: make(map[int]models.ExternalRef),
+			reviewerSync: make(map[int]models.ReviewerSync),
+
+			teamChannels: make(map[int]models.TeamChannel),
+			teamSLAs:     make(map[int]models.TeamSLA),
+			reviewStages: make(map[reviewerKey]reviewStages),
+			chatMessages: make(map[int]models.ChatMessage),
+			digestPrefs:  make(map[int]models.DigestPrefs),
+			apiTokens:    make(map[int]models.APIToken),
+		},
+	}
+}
+
+// WithTx holds the write lock for the whole of fn, so transactions are
+// serialized with each other and with every other call, and restores the
+// previous state if fn fails.
+func (r *repo) WithTx(_ context.Context, fn func(tx repository.Repository) error) error {
+	if _, ok := r.mu.(nopLocker); ok {
+		return fn(r)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	backup := r.state.clone()
+	if err := fn(&repo{mu: nopLocker{}, state: r.state}); err != nil {
+		*r.state = *backup
+		return err
+	}
+	return nil
+}
+
+// ReadSnapshot runs fn against a copy of the state taken under the read lock,
+// so fn holds no lock and writes through it are discarded.
+func (r *repo) ReadSnapshot(_ context.Context, fn func(r repository.Repository) error) error {
+	if _, ok := r.mu.(nopLocker); ok {
+		return fn(r)
+	}
+
+	r.mu.RLock()
+	snap := r.state.clone()
+	r.mu.RUnlock()
+	return fn(&repo{mu: nopLocker{}, state: snap})
+}
+
+// now mirrors the precision of timestamptz so values round-trip the same way
+// they do through the postgres backend.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func copyUser(u models.User) models.User {
+	if u.TeamID != nil {
+		id := *u.TeamID
+		u.TeamID = &id
+	}
+	if u.DeletedAt != nil {
+		at := *u.DeletedAt
+		u.DeletedAt = &at
+	}
+	return u
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func (r *repo) CreateTeam(ctx context.Context, name string) (models.Team, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	orgID := repository.OrgOrDefault(ctx)
+	if _, ok := r.orgs[orgID]; !ok {
+		return models.Team{}, fmt.Errorf("create team: %w: organization %d", repository.ErrInvalidReference, orgID)
+	}
+	for _, t := range r.teams {
+		if t.OrgID == orgID && t.Name == name {
+			return models.Team{}, fmt.Errorf("create team: %w: team name %q", repository.ErrAlreadyExists, name)
+		}
+	}
+
+	r.lastTeamID++
+	t := models.Team{ID: r.lastTeamID, OrgID: orgID, Name: name, CreatedAt: now()}
+	r.teams[t.ID] = t
+	return t, nil
+}
+
+func (r *repo) GetTeamByID(ctx context.Context, id int) (models.Team, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.teams[id]
+	if !ok || !visible(ctx, t.OrgID) {
+		return models.Team{}, fmt.Errorf("get team: %w", repository.ErrNotFound)
+	}
+	return t, nil
+}
+
+func (r *repo) GetTeamByName(ctx context.Context, name string) (models.Team, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	orgID := repository.OrgOrDefault(ctx)
+	for _, t := range r.teams {
+		if t.OrgID == orgID && t.Name == name {
+			return t, nil
+		}
+	}
+	return models.Team{}, fmt.Errorf("get team by name: %w", repository.ErrNotFound)
+}
+
+func (r *repo) ListTeams(ctx context.Context, f repository.TeamFilter) ([]models.Team, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.Team, 0)
+	for _, id := range sortedKeys(r.teams) {
+		t := r.teams[id]
+		if f.After != nil && id <= f.After.ID || t.DeletedAt != nil || !visible(ctx, t.OrgID) {
+			continue
+		}
+		if f.Limit > 0 && len(res) == f.Limit {
+			break
+		}
+		res = append(res, t)
+	}
+	return res, nil
+}
+
+func (r *repo) CreateUser(ctx context.Context, u models.User) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	orgID := repository.OrgOrDefault(ctx)
+	if u.TeamID != nil {
+		t, ok := r.teams[*u.TeamID]
+		if !ok || !visible(ctx, t.OrgID) {
+			return models.User{}, fmt.Errorf("create user: %w: team %d", repository.ErrInvalidReference, *u.TeamID)
+		}
+		orgID = t.OrgID
+	}
+
+	r.lastUserID++
+	res := copyUser(models.User{ID: r.lastUserID, OrgID: orgID, TeamID: u.TeamID, Name: u.Name, IsActive: u.IsActive, CreatedAt: now()})
+	r.users[res.ID] = res
+	return copyUser(res), nil
+}
+
+func (r *repo) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok || !visible(ctx, u.OrgID) {
+		return models.User{}, fmt.Errorf("get user: %w", repository.ErrNotFound)
+	}
+	return copyUser(u), nil
+}
+
+func (r *repo) UpdateUser(ctx context.Context, u models.User) (models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[u.ID]
+	if !ok || !visible(ctx, existing.OrgID) {
+		return models.User{}, fmt.Errorf("update user: %w", repository.ErrNotFound)
+	}
+	if u.TeamID != nil {
+		if t, ok := r.teams[*u.TeamID]; !ok || t.OrgID != existing.OrgID {
+			return models.User{}, fmt.Errorf("update user: %w: team %d", repository.ErrInvalidReference, *u.TeamID)
+		}
+	}
+
+	existing.TeamID = u.TeamID
+	existing.Name = u.Name
+	existing.IsActive = u.IsActive
+	existing = copyUser(existing)
+	r.users[u.ID] = existing
+	return copyUser(existing), nil
+}
+
+func (r *repo) ListActiveUsersInTeam(ctx context.Context, teamID int) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.User, 0)
+	for _, id := range sortedKeys(r.users) {
+		u := r.users[id]
+		if u.TeamID != nil && *u.TeamID == teamID && u.IsActive && u.DeletedAt == nil && visible(ctx, u.OrgID) {
+			res = append(res, copyUser(u))
+		}
+	}
+	return res, nil
+}
+
+func (r *repo) ListTeamMembers(ctx context.Context, teamID int) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.User, 0)
+	for _, id := range sortedKeys(r.users) {
+		u := r.users[id]
+		if u.TeamID != nil && *u.TeamID == teamID && u.DeletedAt == nil && visible(ctx, u.OrgID) {
+			res = append(res, copyUser(u))
+		}
+	}
+	return res, nil
+}
+
+func (r *repo) ListUsers(ctx context.Context, f repository.UserFilter) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.User, 0)
+	for _, id := range sortedKeys(r.users) {
+		u := r.users[id]
+		switch {
+		case u.DeletedAt != nil || !visible(ctx, u.OrgID):
+			continue
+		case f.After != nil && id <= f.After.ID:
+			continue
+		case f.TeamID != nil && (u.TeamID == nil || *u.TeamID != *f.TeamID):
+			continue
+		case f.Active != nil && u.IsActive != *f.Active:
+			continue
+		}
+		if f.Limit > 0 && len(res) == f.Limit {
+			break
+		}
+		res = append(res, copyUser(u))
+	}
+	return res, nil
+}
+
+func (r *repo) DeactivateUsersInTeam(ctx context.Context, teamID int, userIDs []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, id := range userIDs {
+		u, ok := r.users[id]
+		if !ok || u.TeamID == nil || *u.TeamID != teamID || !visible(ctx, u.OrgID) {
+			continue
+		}
+		u.IsActive = false
+		r.users[id] = u
+	}
+	return nil
+}
+
+func (r *repo) CreatePR(ctx context.Context, pr models.PR) (models.PR, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	author, ok := r.users[pr.AuthorID]
+	if !ok || !visible(ctx, author.OrgID) {
+		return models.PR{}, fmt.Errorf("create PR: %w: author %d", repository.ErrInvalidReference, pr.AuthorID)
+	}
+
+	r.lastPRID++
+	res := models.PR{ID: r.lastPRID, OrgID: author.OrgID, Title: pr.Title, AuthorID: pr.AuthorID, Status: pr.Status, CreatedAt: now()}
+	r.prs[res.ID] = res
+	return res, nil
+}
+
+func (r *repo) GetPRByID(ctx context.Context, id int) (models.PR, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.prs[id]
+	if !ok || !visible(ctx, p.OrgID) {
+		return models.PR{}, fmt.Errorf("get PR: %w", repository.ErrNotFound)
+	}
+	return p, nil
+}
+
+// LockPR is GetPRByID: WithTx already serializes transactions.
+func (r *repo) LockPR(ctx context.Context, id int) (models.PR, error) {
+	return r.GetPRByID(ctx, id)
+}
+
+func (r *repo) SetPRStatus(ctx context.Context, id int, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p, ok := r.prs[id]
+	if !ok || !visible(ctx, p.OrgID) {
+		return nil
+	}
+	p.Status = models.PRStatus(status)
+	r.prs[id] = p
+	return nil
+}
+
+func (r *repo) AssignReviewers(ctx context.Context, prID int, userIDs []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, uid := range userIDs {
+		if err := r.insertReviewer(ctx, prID, uid, now()); err != nil {
+			return fmt.Errorf("assign reviewer %d: %w", uid, err)
+		}
+	}
+	return nil
+}
+
+// insertReviewer behaves like INSERT ... ON CONFLICT DO NOTHING: an existing
+// assignment is kept with its original timestamp. The reviewer must belong
+// to the PR's organization. Callers must hold mu.
+func (r *repo) insertReviewer(ctx context.Context, prID, userID int, at time.Time) error {
+	p, ok := r.prs[prID]
+	if !ok || !visible(ctx, p.OrgID) {
+		return fmt.Errorf("%w: PR %d", repository.ErrInvalidReference, prID)
+	}
+	if u, ok := r.users[userID]; !ok || u.OrgID != p.OrgID {
+		return fmt.Errorf("%w: user %d", repository.ErrInvalidReference, userID)
+	}
+	key := reviewerKey{prID: prID, userID: userID}
+	if _, ok := r.reviewers[key]; !ok {
+		r.reviewers[key] = at
+	}
+	return nil
+}
+
+func (r *repo) GetReviewersByPR(ctx context.Context, prID int) ([]models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if p, ok := r.prs[prID]; ok && !visible(ctx, p.OrgID) {
+		return make([]models.User, 0), nil
+	}
+	return r.reviewersOf(prID), nil
+}
+
+// Callers must hold mu.
+func (r *repo) reviewersOf(prID int) []models.User {
+	res := make([]models.User, 0)
+	for _, id := range sortedKeys(r.users) {
+		if _, ok := r.reviewers[reviewerKey{prID: prID, userID: id}]; ok {
+			res = append(res, copyUser(r.users[id]))
+		}
+	}
+	return res
+}
+
+func (r *repo) ReplaceReviewer(ctx context.Context, prID int, oldUserID int, newUserID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	oldKey := reviewerKey{prID: prID, userID: oldUserID}
+	oldAt, hadOld := r.reviewers[oldKey]
+	delete(r.reviewers, oldKey)
+
+	if err := r.insertReviewer(ctx, prID, newUserID, now()); err != nil {
+		if hadOld {
+			r.reviewers[oldKey] = oldAt
+		}
+		return fmt.Errorf("insert new reviewer: %w", err)
+	}
+	delete(r.reviewStages, oldKey)
+	return nil
+}
+
+func (r *repo) ListPRsAssignedToUser(ctx context.Context, userID int, f repository.ReviewQueueFilter) ([]models.AssignedPR, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]models.AssignedPR, 0)
+	for _, id := range sortedKeys(r.prs) {
+		at, ok := r.reviewers[reviewerKey{prID: id, userID: userID}]
+		if !ok {
+			continue
+		}
+		p := r.prs[id]
+		if !visible(ctx, p.OrgID) {
+			continue
+		}
+		if len(f.Statuses) > 0 && !slices.Contains(f.Statuses, p.Status) {
+			continue
+		}
+		if f.CreatedAfter != nil && !p.CreatedAt.After(*f.CreatedAfter) {
+			continue
+		}
+		if f.CreatedBefore != nil && !p.CreatedAt.Before(*f.CreatedBefore) {
+			continue
+		}
+		out = append(out, models.AssignedPR{PRWithReviewers: models.PRWithReviewers{PR: p}, AssignedAt: at})
+	}
+
+	cmp := func(a, b models.AssignedPR) int {
+		c := f.SortKey(a).Compare(f.SortKey(b))
+		if c == 0 {
+			c = a.ID - b.ID
+		}
+		if f.Desc {
+			c = -c
+		}
+		return c
+	}
+	slices.SortFunc(out, cmp)
+
+	if f.After != nil && f.After.Time != nil {
+		pos := models.AssignedPR{AssignedAt: *f.After.Time}
+		pos.CreatedAt = *f.After.Time
+		pos.ID = f.After.ID
+		out = slices.DeleteFunc(out, func(p models.AssignedPR) bool { return cmp(p, pos) <= 0 })
+	}
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	for i := range out {
+		out[i].Reviewers = r.reviewersOf(out[i].ID)
+	}
+	return out, nil
+}
+
+func (r *repo) ListPRsByAuthor(ctx context.Context, authorID int, statuses []models.PRStatus) ([]models.PRWithReviewers, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]models.PRWithReviewers, 0)
+	for _, id := range sortedKeys(r.prs) {
+		p := r.prs[id]
+		if p.AuthorID != authorID || !visible(ctx, p.OrgID) || len(statuses) > 0 && !slices.Contains(statuses, p.Status) {
+			continue
+		}
+		out = append(out, models.PRWithReviewers{PR: p, Reviewers: r.reviewersOf(id)})
+	}
+	slices.SortStableFunc(out, func(a, b models.PRWithReviewers) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return out, nil
+}
+
+func (r *repo) CountAssignments(ctx context.Context) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	n := 0
+	for k := range r.reviewers {
+		if visible(ctx, r.prs[k.prID].OrgID) {
+			n++
+		}
+	}
+	return n, nil
+}
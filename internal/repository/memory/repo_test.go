@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeamNamesAreUnique(t *testing.T) {
+	r := NewRepo()
+	ctx := context.Background()
+
+	_, err := r.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+
+	_, err = r.CreateTeam(ctx, "backend")
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+}
+
+func TestMissingRowsReturnNotFound(t *testing.T) {
+	r := NewRepo()
+	ctx := context.Background()
+
+	_, err := r.GetTeamByID(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.GetUserByID(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.GetPRByID(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestForeignKeyChecks(t *testing.T) {
+	r := NewRepo()
+	ctx := context.Background()
+
+	missingTeam := 42
+	_, err := r.CreateUser(ctx, models.User{TeamID: &missingTeam, Name: "ghost"})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	_, err = r.CreatePR(ctx, models.PR{Title: "orphan", AuthorID: 42, Status: models.PRStatusOpen})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	author, err := r.CreateUser(ctx, models.User{Name: "author", IsActive: true})
+	require.NoError(t, err)
+	pr, err := r.CreatePR(ctx, models.PR{Title: "pr", AuthorID: author.ID, Status: models.PRStatusOpen})
+	require.NoError(t, err)
+
+	err = r.AssignReviewers(ctx, pr.ID, []int{999})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+}
+
+func TestAssignReviewersIgnoresDuplicates(t *testing.T) {
+	r := NewRepo()
+	ctx := context.Background()
+
+	author, _ := r.CreateUser(ctx, models.User{Name: "author", IsActive: true})
+	reviewer, _ := r.CreateUser(ctx, models.User{Name: "reviewer", IsActive: true})
+	pr, _ := r.CreatePR(ctx, models.PR{Title: "pr", AuthorID: author.ID, Status: models.PRStatusOpen})
+
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{reviewer.ID}))
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{reviewer.ID, reviewer.ID}))
+
+	count, err := r.CountAssignments(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestReplaceReviewerKeepsOldOnFailure(t *testing.T) {
+	r := NewRepo()
+	ctx := context.Background()
+
+	author, _ := r.CreateUser(ctx, models.User{Name: "author", IsActive: true})
+	reviewer, _ := r.CreateUser(ctx, models.User{Name: "reviewer", IsActive: true})
+	pr, _ := r.CreatePR(ctx, models.PR{Title: "pr", AuthorID: author.ID, Status: models.PRStatusOpen})
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{reviewer.ID}))
+
+	err := r.ReplaceReviewer(ctx, pr.ID, reviewer.ID, 999)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, reviewer.ID, revs[0].ID)
+}
+
+func TestReturnedUsersDoNotAliasStoredRows(t *testing.T) {
+	r := NewRepo()
+	ctx := context.Background()
+
+	team, _ := r.CreateTeam(ctx, "backend")
+	u, err := r.CreateUser(ctx, models.User{TeamID: &team.ID, Name: "alice", IsActive: true})
+	require.NoError(t, err)
+
+	*u.TeamID = 999
+	stored, err := r.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, team.ID, *stored.TeamID)
+}
+
+func TestConcurrentCreateTeam(t *testing.T) {
+	r := NewRepo()
+	ctx := context.Background()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		successes int
+	)
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := r.CreateTeam(ctx, fmt.Sprintf("team-%d", i%5))
+			if err == nil {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 5, successes)
+}
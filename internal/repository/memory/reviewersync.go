@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func copyReviewerSync(s models.ReviewerSync) models.ReviewerSync {
+	s.Pushed = slices.Clone(s.Pushed)
+	if s.Pushed == nil {
+		s.Pushed = []string{}
+	}
+	s.NextAttemptAt = truncOrNil(s.NextAttemptAt)
+	s.LockedUntil = truncOrNil(s.LockedUntil)
+	s.LastAttemptAt = truncOrNil(s.LastAttemptAt)
+	s.SyncedAt = truncOrNil(s.SyncedAt)
+	return s
+}
+
+func (r *repo) MarkReviewerSyncPending(_ context.Context, prID int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.prs[prID]; !ok {
+		return fmt.Errorf("mark reviewer sync pending: %w: PR %d", repository.ErrInvalidReference, prID)
+	}
+	s, ok := r.reviewerSync[prID]
+	if !ok {
+		s = models.ReviewerSync{PRID: prID}
+	}
+	s.Status = models.ReviewerSyncPending
+	s.Generation++
+	s.Attempts = 0
+	s.NextAttemptAt = &at
+	r.reviewerSync[prID] = copyReviewerSync(s)
+	return nil
+}
+
+func (r *repo) GetReviewerSync(_ context.Context, prID int) (models.ReviewerSync, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.reviewerSync[prID]
+	if !ok {
+		return models.ReviewerSync{}, fmt.Errorf("get reviewer sync: %w", repository.ErrNotFound)
+	}
+	return copyReviewerSync(s), nil
+}
+
+func (r *repo) ClaimReviewerSyncs(_ context.Context, now time.Time, lease time.Duration, limit int) ([]models.ReviewerSync, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	due := make([]models.ReviewerSync, 0)
+	for _, s := range r.reviewerSync {
+		if s.Status != models.ReviewerSyncPending || s.NextAttemptAt == nil || s.NextAttemptAt.After(now) {
+			continue
+		}
+		if s.LockedUntil != nil && s.LockedUntil.After(now) {
+			continue
+		}
+		due = append(due, s)
+	}
+	slices.SortFunc(due, func(a, b models.ReviewerSync) int {
+		if c := a.NextAttemptAt.Compare(*b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return a.PRID - b.PRID
+	})
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	until := now.Add(lease)
+	for i, s := range due {
+		s.LockedUntil = &until
+		s = copyReviewerSync(s)
+		r.reviewerSync[s.PRID] = s
+		due[i] = copyReviewerSync(s)
+	}
+	return due, nil
+}
+
+func (r *repo) CompleteReviewerSync(_ context.Context, upd models.ReviewerSync) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.reviewerSync[upd.PRID]
+	if !ok {
+		return fmt.Errorf("complete reviewer sync: %w", repository.ErrNotFound)
+	}
+	s.Pushed = upd.Pushed
+	s.Attempts = upd.Attempts
+	s.LastError = upd.LastError
+	s.LastAttemptAt = upd.LastAttemptAt
+	s.LockedUntil = nil
+	if s.Generation == upd.Generation {
+		s.Status = upd.Status
+		s.NextAttemptAt = upd.NextAttemptAt
+		s.SyncedAt = upd.SyncedAt
+	}
+	r.reviewerSync[s.PRID] = copyReviewerSync(s)
+	return nil
+}
@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+// reviewStages are the SLA stages an assignment went through, by the time
+// it reached them.
+type reviewStages struct {
+	reminded       *time.Time
+	reassignFailed *time.Time
+	escalated      *time.Time
+}
+
+func copyTeamSLA(s models.TeamSLA) models.TeamSLA {
+	if s.LeadID != nil {
+		id := *s.LeadID
+		s.LeadID = &id
+	}
+	return s
+}
+
+func (r *repo) SetTeamSLA(_ context.Context, s models.TeamSLA) (models.TeamSLA, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[s.TeamID]; !ok {
+		return models.TeamSLA{}, fmt.Errorf("set team SLA: %w: team %d", repository.ErrInvalidReference, s.TeamID)
+	}
+	if s.LeadID != nil {
+		if _, ok := r.users[*s.LeadID]; !ok {
+			return models.TeamSLA{}, fmt.Errorf("set team SLA: %w: user %d", repository.ErrInvalidReference, *s.LeadID)
+		}
+	}
+	s = copyTeamSLA(s)
+	s.UpdatedAt = now()
+	r.teamSLAs[s.TeamID] = s
+	return copyTeamSLA(s), nil
+}
+
+func (r *repo) GetTeamSLA(_ context.Context, teamID int) (models.TeamSLA, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.teamSLAs[teamID]
+	if !ok {
+		return models.TeamSLA{}, fmt.Errorf("get team SLA: %w", repository.ErrNotFound)
+	}
+	return copyTeamSLA(s), nil
+}
+
+func (r *repo) DeleteTeamSLA(_ context.Context, teamID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teamSLAs[teamID]; !ok {
+		return fmt.Errorf("delete team SLA: %w", repository.ErrNotFound)
+	}
+	delete(r.teamSLAs, teamID)
+	return nil
+}
+
+func (r *repo) ListTeamSLAs(_ context.Context) ([]models.TeamSLA, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.TeamSLA, 0, len(r.teamSLAs))
+	for _, id := range sortedKeys(r.teamSLAs) {
+		res = append(res, copyTeamSLA(r.teamSLAs[id]))
+	}
+	return res, nil
+}
+
+func (r *repo) ListPendingReviews(ctx context.Context, teamID int) ([]models.PendingReview, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.PendingReview, 0)
+	for k, at := range r.reviewers {
+		p := r.prs[k.prID]
+		if p.Status != models.PRStatusOpen || !visible(ctx, p.OrgID) {
+			continue
+		}
+		if u := r.users[k.userID]; u.TeamID == nil || *u.TeamID != teamID {
+			continue
+		}
+		st := r.reviewStages[k]
+		res = append(res, models.PendingReview{
+			PRID:             k.prID,
+			UserID:           k.userID,
+			AssignedAt:       at,
+			RemindedAt:       truncOrNil(st.reminded),
+			ReassignFailedAt: truncOrNil(st.reassignFailed),
+			EscalatedAt:      truncOrNil(st.escalated),
+		})
+	}
+	slices.SortFunc(res, func(a, b models.PendingReview) int {
+		return cmp.Or(a.AssignedAt.Compare(b.AssignedAt), cmp.Compare(a.PRID, b.PRID), cmp.Compare(a.UserID, b.UserID))
+	})
+	return res, nil
+}
+
+func (r *repo) MarkReviewSLAStage(_ context.Context, prID, userID int, stage models.SLAStage, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := reviewerKey{prID: prID, userID: userID}
+	if _, ok := r.reviewers[key]; !ok {
+		return false, fmt.Errorf("mark review %s: %w", stage, repository.ErrNotFound)
+	}
+	st := r.reviewStages[key]
+	var mark **time.Time
+	switch stage {
+	case models.SLAReminded:
+		mark = &st.reminded
+	case models.SLAReassignFailed:
+		mark = &st.reassignFailed
+	case models.SLAEscalated:
+		mark = &st.escalated
+	default:
+		return false, fmt.Errorf("unknown SLA stage %q", stage)
+	}
+	if *mark != nil {
+		return false, nil
+	}
+	*mark = truncOrNil(&at)
+	r.reviewStages[key] = st
+	return true, nil
+}
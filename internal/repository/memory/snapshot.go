@@ -0,0 +1,340 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+// The ForEach methods copy the rows under the lock and release it before
+// invoking fn, so callbacks are free to call back into the repository.
+
+func (r *repo) ForEachTeam(ctx context.Context, fn func(models.Team) error) error {
+	r.mu.RLock()
+	teams := make([]models.Team, 0, len(r.teams))
+	for _, id := range sortedKeys(r.teams) {
+		if visible(ctx, r.teams[id].OrgID) {
+			teams = append(teams, r.teams[id])
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, t := range teams {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) ForEachUser(ctx context.Context, fn func(models.User) error) error {
+	r.mu.RLock()
+	users := make([]models.User, 0, len(r.users))
+	for _, id := range sortedKeys(r.users) {
+		if visible(ctx, r.users[id].OrgID) {
+			users = append(users, copyUser(r.users[id]))
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, u := range users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) ForEachPR(ctx context.Context, fn func(models.PR) error) error {
+	r.mu.RLock()
+	prs := make([]models.PR, 0, len(r.prs))
+	for _, id := range sortedKeys(r.prs) {
+		if visible(ctx, r.prs[id].OrgID) {
+			prs = append(prs, r.prs[id])
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, p := range prs {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) ForEachReviewerAssignment(ctx context.Context, fn func(models.ReviewerAssignment) error) error {
+	r.mu.RLock()
+	assignments := make([]models.ReviewerAssignment, 0, len(r.reviewers))
+	for k, at := range r.reviewers {
+		if !visible(ctx, r.prs[k.prID].OrgID) {
+			continue
+		}
+		st := r.reviewStages[k]
+		assignments = append(assignments, models.ReviewerAssignment{
+			PRID:             k.prID,
+			UserID:           k.userID,
+			AssignedAt:       at,
+			RemindedAt:       truncOrNil(st.reminded),
+			ReassignFailedAt: truncOrNil(st.reassignFailed),
+			EscalatedAt:      truncOrNil(st.escalated),
+		})
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(assignments, func(a, b models.ReviewerAssignment) int {
+		return cmp.Or(cmp.Compare(a.PRID, b.PRID), cmp.Compare(a.UserID, b.UserID))
+	})
+	for _, a := range assignments {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) RestoreTeam(_ context.Context, t models.Team) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t.OrgID = cmp.Or(t.OrgID, models.DefaultOrgID)
+	if _, ok := r.teams[t.ID]; ok {
+		return fmt.Errorf("restore team %d: %w", t.ID, repository.ErrAlreadyExists)
+	}
+	if _, ok := r.orgs[t.OrgID]; !ok {
+		return fmt.Errorf("restore team %d: %w: organization %d", t.ID, repository.ErrInvalidReference, t.OrgID)
+	}
+	for _, existing := range r.teams {
+		if existing.OrgID == t.OrgID && existing.Name == t.Name {
+			return fmt.Errorf("restore team %d: %w: team name %q", t.ID, repository.ErrAlreadyExists, t.Name)
+		}
+	}
+
+	r.teams[t.ID] = t
+	r.lastTeamID = max(r.lastTeamID, t.ID)
+	return nil
+}
+
+func (r *repo) RestoreUser(_ context.Context, u models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u.OrgID = cmp.Or(u.OrgID, models.DefaultOrgID)
+	if _, ok := r.users[u.ID]; ok {
+		return fmt.Errorf("restore user %d: %w", u.ID, repository.ErrAlreadyExists)
+	}
+	if _, ok := r.orgs[u.OrgID]; !ok {
+		return fmt.Errorf("restore user %d: %w: organization %d", u.ID, repository.ErrInvalidReference, u.OrgID)
+	}
+	if u.TeamID != nil {
+		if t, ok := r.teams[*u.TeamID]; !ok || t.OrgID != u.OrgID {
+			return fmt.Errorf("restore user %d: %w: team %d", u.ID, repository.ErrInvalidReference, *u.TeamID)
+		}
+	}
+
+	r.users[u.ID] = copyUser(u)
+	r.lastUserID = max(r.lastUserID, u.ID)
+	return nil
+}
+
+func (r *repo) RestorePR(_ context.Context, pr models.PR) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pr.OrgID = cmp.Or(pr.OrgID, models.DefaultOrgID)
+	if _, ok := r.prs[pr.ID]; ok {
+		return fmt.Errorf("restore PR %d: %w", pr.ID, repository.ErrAlreadyExists)
+	}
+	if u, ok := r.users[pr.AuthorID]; !ok || u.OrgID != pr.OrgID {
+		return fmt.Errorf("restore PR %d: %w: author %d", pr.ID, repository.ErrInvalidReference, pr.AuthorID)
+	}
+
+	r.prs[pr.ID] = pr
+	r.lastPRID = max(r.lastPRID, pr.ID)
+	return nil
+}
+
+func (r *repo) RestoreReviewerAssignment(ctx context.Context, a models.ReviewerAssignment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.reviewers[reviewerKey{prID: a.PRID, userID: a.UserID}]; ok {
+		return fmt.Errorf("restore reviewer assignment %d/%d: %w", a.PRID, a.UserID, repository.ErrAlreadyExists)
+	}
+	if err := r.insertReviewer(ctx, a.PRID, a.UserID, a.AssignedAt); err != nil {
+		return fmt.Errorf("restore reviewer assignment %d/%d: %w", a.PRID, a.UserID, err)
+	}
+	st := reviewStages{reminded: truncOrNil(a.RemindedAt), reassignFailed: truncOrNil(a.ReassignFailedAt), escalated: truncOrNil(a.EscalatedAt)}
+	if st != (reviewStages{}) {
+		r.reviewStages[reviewerKey{prID: a.PRID, userID: a.UserID}] = st
+	}
+	return nil
+}
+
+func (r *repo) ForEachIdentity(ctx context.Context, fn func(models.Identity) error) error {
+	r.mu.RLock()
+	ids := make([]models.Identity, 0, len(r.identities))
+	for _, id := range r.identities {
+		if visible(ctx, r.users[id.UserID].OrgID) {
+			ids = append(ids, id)
+		}
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(ids, func(a, b models.Identity) int {
+		return cmp.Or(cmp.Compare(a.UserID, b.UserID), cmp.Compare(a.Provider, b.Provider))
+	})
+	for _, id := range ids {
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) ForEachExternalRef(ctx context.Context, fn func(models.ExternalRef) error) error {
+	r.mu.RLock()
+	refs := make([]models.ExternalRef, 0, len(r.externalRefs))
+	for _, id := range sortedKeys(r.externalRefs) {
+		if visible(ctx, r.prs[id].OrgID) {
+			refs = append(refs, r.externalRefs[id])
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, ref := range refs {
+		if err := fn(ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) ForEachReviewerSync(ctx context.Context, fn func(models.ReviewerSync) error) error {
+	r.mu.RLock()
+	syncs := make([]models.ReviewerSync, 0, len(r.reviewerSync))
+	for _, id := range sortedKeys(r.reviewerSync) {
+		if visible(ctx, r.prs[id].OrgID) {
+			syncs = append(syncs, copyReviewerSync(r.reviewerSync[id]))
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, s := range syncs {
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) ForEachTeamChannel(ctx context.Context, fn func(models.TeamChannel) error) error {
+	r.mu.RLock()
+	channels := make([]models.TeamChannel, 0, len(r.teamChannels))
+	for _, id := range sortedKeys(r.teamChannels) {
+		if visible(ctx, r.teams[id].OrgID) {
+			channels = append(channels, r.teamChannels[id])
+		}
+	}
+	r.mu.RUnlock()
+
+	for _, c := range channels {
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *repo) RestoreIdentity(_ context.Context, id models.Identity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id.UserID]; !ok {
+		return fmt.Errorf("restore identity: %w: user %d", repository.ErrInvalidReference, id.UserID)
+	}
+	if _, ok := r.identities[identityKey{id.Provider, id.Login}]; ok {
+		return fmt.Errorf("restore identity: %w", repository.ErrAlreadyExists)
+	}
+	for _, existing := range r.identities {
+		if existing.UserID == id.UserID && existing.Provider == id.Provider {
+			return fmt.Errorf("restore identity: %w", repository.ErrAlreadyExists)
+		}
+	}
+	r.identities[identityKey{id.Provider, id.Login}] = id
+	return nil
+}
+
+func (r *repo) RestoreReviewerSync(_ context.Context, s models.ReviewerSync) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.prs[s.PRID]; !ok {
+		return fmt.Errorf("restore reviewer sync: %w: PR %d", repository.ErrInvalidReference, s.PRID)
+	}
+	if _, ok := r.reviewerSync[s.PRID]; ok {
+		return fmt.Errorf("restore reviewer sync: %w", repository.ErrAlreadyExists)
+	}
+	s.Generation = max(s.Generation, 1)
+	s.LockedUntil = nil
+	r.reviewerSync[s.PRID] = copyReviewerSync(s)
+	return nil
+}
+
+func (r *repo) RestoreTeamChannel(_ context.Context, c models.TeamChannel) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[c.TeamID]; !ok {
+		return fmt.Errorf("restore team channel: %w: team %d", repository.ErrInvalidReference, c.TeamID)
+	}
+	if _, ok := r.teamChannels[c.TeamID]; ok {
+		return fmt.Errorf("restore team channel: %w", repository.ErrAlreadyExists)
+	}
+	r.teamChannels[c.TeamID] = c
+	return nil
+}
+
+func (r *repo) RestoreTeamSLA(_ context.Context, s models.TeamSLA) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.teams[s.TeamID]; !ok {
+		return fmt.Errorf("restore team SLA: %w: team %d", repository.ErrInvalidReference, s.TeamID)
+	}
+	if s.LeadID != nil {
+		if _, ok := r.users[*s.LeadID]; !ok {
+			return fmt.Errorf("restore team SLA: %w: user %d", repository.ErrInvalidReference, *s.LeadID)
+		}
+	}
+	if _, ok := r.teamSLAs[s.TeamID]; ok {
+		return fmt.Errorf("restore team SLA: %w", repository.ErrAlreadyExists)
+	}
+	r.teamSLAs[s.TeamID] = copyTeamSLA(s)
+	return nil
+}
+
+func (r *repo) RestoreDigestPrefs(_ context.Context, p models.DigestPrefs) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[p.UserID]; !ok {
+		return fmt.Errorf("restore digest prefs: %w: user %d", repository.ErrInvalidReference, p.UserID)
+	}
+	if _, ok := r.digestPrefs[p.UserID]; ok {
+		return fmt.Errorf("restore digest prefs: %w", repository.ErrAlreadyExists)
+	}
+	p.LastSentAt = truncOrNil(p.LastSentAt)
+	r.digestPrefs[p.UserID] = p
+	return nil
+}
+
+// SyncSequences has nothing to do: the Restore methods advance the last ids
+// as they insert.
+func (r *repo) SyncSequences(context.Context) error {
+	return nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func copyAPIToken(t models.APIToken) models.APIToken {
+	if t.UserID != nil {
+		id := *t.UserID
+		t.UserID = &id
+	}
+	if t.OrgID != nil {
+		id := *t.OrgID
+		t.OrgID = &id
+	}
+	t.RevokedAt = truncOrNil(t.RevokedAt)
+	return t
+}
+
+func (r *repo) CreateAPIToken(_ context.Context, t models.APIToken) (models.APIToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if t.UserID != nil {
+		if _, ok := r.users[*t.UserID]; !ok {
+			return models.APIToken{}, fmt.Errorf("create API token: %w: user %d", repository.ErrInvalidReference, *t.UserID)
+		}
+	}
+	if t.OrgID != nil {
+		if _, ok := r.orgs[*t.OrgID]; !ok {
+			return models.APIToken{}, fmt.Errorf("create API token: %w: organization %d", repository.ErrInvalidReference, *t.OrgID)
+		}
+	}
+	for _, existing := range r.apiTokens {
+		if existing.Hash == t.Hash {
+			return models.APIToken{}, fmt.Errorf("create API token: %w", repository.ErrAlreadyExists)
+		}
+	}
+	r.lastTokenID++
+	t = copyAPIToken(t)
+	t.ID = r.lastTokenID
+	t.CreatedAt = now()
+	t.RevokedAt = nil
+	r.apiTokens[t.ID] = t
+	return copyAPIToken(t), nil
+}
+
+func (r *repo) GetAPITokenByHash(_ context.Context, hash string) (models.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, t := range r.apiTokens {
+		if t.Hash == hash {
+			return copyAPIToken(t), nil
+		}
+	}
+	return models.APIToken{}, fmt.Errorf("get API token: %w", repository.ErrNotFound)
+}
+
+func (r *repo) ListAPITokens(_ context.Context) ([]models.APIToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.APIToken, 0, len(r.apiTokens))
+	for _, id := range sortedKeys(r.apiTokens) {
+		res = append(res, copyAPIToken(r.apiTokens[id]))
+	}
+	return res, nil
+}
+
+func (r *repo) RevokeAPIToken(_ context.Context, id int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.apiTokens[id]
+	if !ok || t.RevokedAt != nil {
+		return fmt.Errorf("revoke API token: %w", repository.ErrNotFound)
+	}
+	t.RevokedAt = truncOrNil(&at)
+	r.apiTokens[id] = t
+	return nil
+}
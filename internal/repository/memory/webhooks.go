@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func copyWebhook(w models.Webhook) models.Webhook {
+	w.Events = slices.Clone(w.Events)
+	return w
+}
+
+func copyDelivery(d models.WebhookDelivery) models.WebhookDelivery {
+	d.Payload = slices.Clone(d.Payload)
+	if d.ResponseCode != nil {
+		code := *d.ResponseCode
+		d.ResponseCode = &code
+	}
+	if d.NextAttemptAt != nil {
+		at := *d.NextAttemptAt
+		d.NextAttemptAt = &at
+	}
+	if d.LastAttemptAt != nil {
+		at := *d.LastAttemptAt
+		d.LastAttemptAt = &at
+	}
+	return d
+}
+
+func truncOrNil(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	at := t.UTC().Truncate(time.Microsecond)
+	return &at
+}
+
+func (r *repo) CreateWebhook(_ context.Context, w models.Webhook) (models.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastWebhookID++
+	w.ID = r.lastWebhookID
+	w.Events = slices.Clone(w.Events)
+	if w.Events == nil {
+		w.Events = []string{}
+	}
+	w.CreatedAt = now()
+	r.webhooks[w.ID] = w
+	return copyWebhook(w), nil
+}
+
+func (r *repo) GetWebhook(_ context.Context, id int) (models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	w, ok := r.webhooks[id]
+	if !ok {
+		return models.Webhook{}, fmt.Errorf("get webhook: %w", repository.ErrNotFound)
+	}
+	return copyWebhook(w), nil
+}
+
+func (r *repo) ListWebhooks(_ context.Context) ([]models.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.Webhook, 0, len(r.webhooks))
+	for _, id := range sortedKeys(r.webhooks) {
+		res = append(res, copyWebhook(r.webhooks[id]))
+	}
+	return res, nil
+}
+
+func (r *repo) DeleteWebhook(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return fmt.Errorf("delete webhook: %w", repository.ErrNotFound)
+	}
+	delete(r.webhooks, id)
+	for did, d := range r.deliveries {
+		if d.WebhookID == id {
+			delete(r.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (r *repo) CreateWebhookDelivery(_ context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[d.WebhookID]; !ok {
+		return models.WebhookDelivery{}, fmt.Errorf("create webhook delivery: %w: webhook %d", repository.ErrInvalidReference, d.WebhookID)
+	}
+	r.lastDeliveryID++
+	d = copyDelivery(d)
+	d.ID = r.lastDeliveryID
+	d.NextAttemptAt = truncOrNil(d.NextAttemptAt)
+	d.LastAttemptAt = truncOrNil(d.LastAttemptAt)
+	d.CreatedAt = now()
+	r.deliveries[d.ID] = d
+	return copyDelivery(d), nil
+}
+
+func (r *repo) UpdateWebhookDelivery(_ context.Context, d models.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur, ok := r.deliveries[d.ID]
+	if !ok {
+		return fmt.Errorf("update webhook delivery: %w", repository.ErrNotFound)
+	}
+	d = copyDelivery(d)
+	cur.Status = d.Status
+	cur.Attempts = d.Attempts
+	cur.LastError = d.LastError
+	cur.ResponseCode = d.ResponseCode
+	cur.NextAttemptAt = truncOrNil(d.NextAttemptAt)
+	cur.LastAttemptAt = truncOrNil(d.LastAttemptAt)
+	r.deliveries[d.ID] = cur
+	return nil
+}
+
+func (r *repo) ListDueWebhookDeliveries(_ context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if d.Status == models.DeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			res = append(res, copyDelivery(d))
+		}
+	}
+	slices.SortFunc(res, func(a, b models.WebhookDelivery) int {
+		if c := a.NextAttemptAt.Compare(*b.NextAttemptAt); c != 0 {
+			return c
+		}
+		return a.ID - b.ID
+	})
+	if limit > 0 && len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (r *repo) ListWebhookDeliveries(_ context.Context, webhookID int, f repository.DeliveryFilter) ([]models.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]models.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if d.WebhookID != webhookID {
+			continue
+		}
+		if f.After != nil && d.ID >= f.After.ID {
+			continue
+		}
+		res = append(res, copyDelivery(d))
+	}
+	slices.SortFunc(res, func(a, b models.WebhookDelivery) int { return b.ID - a.ID })
+	if f.Limit > 0 && len(res) > f.Limit {
+		res = res[:f.Limit]
+	}
+	return res, nil
+}
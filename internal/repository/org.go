@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+
+	"prmanager/internal/models"
+)
+
+type orgKey struct{}
+
+// WithOrg scopes the repository calls made with the returned context to the
+// organization orgID.
+func WithOrg(ctx context.Context, orgID int) context.Context {
+	return context.WithValue(ctx, orgKey{}, orgID)
+}
+
+// OrgFrom returns the organization ctx is scoped to.
+func OrgFrom(ctx context.Context) (int, bool) {
+	id, ok := ctx.Value(orgKey{}).(int)
+	return id, ok
+}
+
+// OrgFilter returns the organization of ctx, or nil if ctx is unscoped. SQL
+// backends pass it as a parameter and match org_id = COALESCE(param, org_id).
+func OrgFilter(ctx context.Context) *int {
+	if id, ok := OrgFrom(ctx); ok {
+		return &id
+	}
+	return nil
+}
+
+// OrgOrDefault returns the organization of ctx, or models.DefaultOrgID if
+// ctx is unscoped.
+func OrgOrDefault(ctx context.Context) int {
+	if id, ok := OrgFrom(ctx); ok {
+		return id
+	}
+	return models.DefaultOrgID
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const chatMessageColumns = `id, event_id, event_type, team_id, text, status, attempts, last_error, next_attempt_at, last_attempt_at, created_at`
+
+func scanChatMessage(row pgx.CollectableRow) (models.ChatMessage, error) {
+	var m models.ChatMessage
+	err := row.Scan(&m.ID, &m.EventID, &m.EventType, &m.TeamID, &m.Text, &m.Status, &m.Attempts, &m.LastError, &m.NextAttemptAt, &m.LastAttemptAt, &m.CreatedAt)
+	return m, err
+}
+
+func (r *repo) SetTeamChannel(ctx context.Context, c models.TeamChannel) (models.TeamChannel, error) {
+	var res models.TeamChannel
+	row := r.db.QueryRow(ctx, `INSERT INTO team_channels(team_id, webhook_url, channel, updated_at) VALUES($1,$2,$3,now())
+		ON CONFLICT(team_id) DO UPDATE SET
+		 webhook_url=excluded.webhook_url, channel=excluded.channel, updated_at=excluded.updated_at
+		RETURNING team_id, webhook_url, channel, updated_at`, c.TeamID, c.WebhookURL, c.Channel)
+	if err := row.Scan(&res.TeamID, &res.WebhookURL, &res.Channel, &res.UpdatedAt); err != nil {
+		return res, fmt.Errorf("set team channel: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetTeamChannel(ctx context.Context, teamID int) (models.TeamChannel, error) {
+	var c models.TeamChannel
+	row := r.db.QueryRow(ctx, `SELECT team_id, webhook_url, channel, updated_at FROM team_channels WHERE team_id=$1`, teamID)
+	if err := row.Scan(&c.TeamID, &c.WebhookURL, &c.Channel, &c.UpdatedAt); err != nil {
+		return c, fmt.Errorf("get team channel: %w", translateErr(err))
+	}
+	return c, nil
+}
+
+func (r *repo) DeleteTeamChannel(ctx context.Context, teamID int) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM team_channels WHERE team_id=$1`, teamID)
+	if err != nil {
+		return fmt.Errorf("delete team channel: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete team channel: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) CreateChatMessage(ctx context.Context, m models.ChatMessage) (models.ChatMessage, error) {
+	rows, err := r.db.Query(ctx, `INSERT INTO chat_messages(event_id, event_type, team_id, text, status, attempts, last_error, next_attempt_at, last_attempt_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING `+chatMessageColumns,
+		m.EventID, m.EventType, m.TeamID, m.Text, m.Status, m.Attempts, m.LastError, m.NextAttemptAt, m.LastAttemptAt)
+	if err != nil {
+		return models.ChatMessage{}, fmt.Errorf("create chat message: %w", translateErr(err))
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, scanChatMessage)
+	if err != nil {
+		return models.ChatMessage{}, fmt.Errorf("create chat message: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) UpdateChatMessage(ctx context.Context, m models.ChatMessage) error {
+	tag, err := r.db.Exec(ctx, `UPDATE chat_messages SET status=$2, attempts=$3, last_error=$4, next_attempt_at=$5, last_attempt_at=$6 WHERE id=$1`,
+		m.ID, m.Status, m.Attempts, m.LastError, m.NextAttemptAt, m.LastAttemptAt)
+	if err != nil {
+		return fmt.Errorf("update chat message: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update chat message: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) ListDueChatMessages(ctx context.Context, now time.Time, limit int) ([]models.ChatMessage, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+chatMessageColumns+` FROM chat_messages
+		WHERE status=$1 AND next_attempt_at <= $2 ORDER BY next_attempt_at, id LIMIT $3`, models.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list due chat messages: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanChatMessage)
+	if err != nil {
+		return nil, fmt.Errorf("list due chat messages: %w", translateErr(err))
+	}
+	return res, nil
+}
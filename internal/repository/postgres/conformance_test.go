@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"prmanager/internal/migration"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/repotest"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/require"
+)
+
+// newThrowawayDB creates a fresh database next to the one PG_TEST_DSN points
+// at and drops it when the test finishes. The test is skipped when
+// PG_TEST_DSN is not set.
+func newThrowawayDB(t testing.TB) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("PG_TEST_DSN")
+	if dsn == "" {
+		t.Skip("PG_TEST_DSN is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := pgx.Connect(ctx, dsn)
+	require.NoError(t, err)
+	defer admin.Close(ctx)
+
+	name := fmt.Sprintf("prmanager_test_%d", time.Now().UnixNano())
+	_, err = admin.Exec(ctx, "CREATE DATABASE "+name)
+	require.NoError(t, err)
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	require.NoError(t, err)
+	cfg.ConnConfig.Database = name
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		pool.Close()
+		conn, err := pgx.Connect(context.Background(), dsn)
+		if err != nil {
+			t.Logf("drop %s: %v", name, err)
+			return
+		}
+		defer conn.Close(context.Background())
+		if _, err := conn.Exec(context.Background(), "DROP DATABASE "+name+" WITH (FORCE)"); err != nil {
+			t.Logf("drop %s: %v", name, err)
+		}
+	})
+
+	require.NoError(t, migration.Run(ctx, pool))
+	return pool
+}
+
+func TestConformance(t *testing.T) {
+	pool := newThrowawayDB(t)
+
+	repotest.Run(t, func(t *testing.T) repository.Repository {
+		_, err := pool.Exec(context.Background(), `TRUNCATE pr_reviewers, prs, users, teams, idempotency_keys, webhook_deliveries, webhooks, outbox_events, identities, pr_external_refs, reviewer_syncs, team_channels, chat_messages, digest_prefs, api_tokens RESTART IDENTITY CASCADE`)
+		require.NoError(t, err)
+		_, err = pool.Exec(context.Background(), `DELETE FROM organizations WHERE id <> 1; UPDATE organizations SET name = 'default' WHERE id = 1`)
+		require.NoError(t, err)
+		return NewRepo(pool)
+	})
+}
+
+func BenchmarkListPRsAssignedToUser(b *testing.B) {
+	repotest.BenchmarkListPRsAssignedToUser(b, NewRepo(newThrowawayDB(b)))
+}
@@ -0,0 +1,56 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"prmanager/internal/repository"
+)
+
+func (r *repo) DeleteTeam(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, `UPDATE teams SET deleted_at=now() WHERE id=$1 AND deleted_at IS NULL AND org_id=COALESCE($2, org_id)`, id, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("delete team: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete team: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) DeleteUser(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, `UPDATE users SET deleted_at=now(), is_active=false WHERE id=$1 AND deleted_at IS NULL AND org_id=COALESCE($2, org_id)`, id, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("delete user: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete user: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) PurgeTeam(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM teams WHERE id=$1 AND org_id=COALESCE($2, org_id)`, id, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("purge team: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("purge team: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) PurgeUser(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, `WITH target AS (SELECT id FROM users WHERE id=$2 AND org_id=COALESCE($3, org_id)),
+		 forgotten AS (DELETE FROM identities WHERE user_id IN (SELECT id FROM target)),
+		 unsubscribed AS (DELETE FROM digest_prefs WHERE user_id IN (SELECT id FROM target)),
+		 revoked AS (DELETE FROM api_tokens WHERE user_id IN (SELECT id FROM target))
+		UPDATE users SET name=$1, team_id=NULL, is_active=false, deleted_at=COALESCE(deleted_at, now()) WHERE id IN (SELECT id FROM target)`, repository.AnonymizedUserName, id, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("purge user: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("purge user: %w", repository.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+)
+
+const digestPrefsColumns = `user_id, opt_out, send_at, timezone, last_sent_at, updated_at`
+
+func scanDigestPrefs(row pgx.CollectableRow) (models.DigestPrefs, error) {
+	var p models.DigestPrefs
+	err := row.Scan(&p.UserID, &p.OptOut, &p.SendAt, &p.Timezone, &p.LastSentAt, &p.UpdatedAt)
+	return p, err
+}
+
+func (r *repo) SetDigestPrefs(ctx context.Context, p models.DigestPrefs) (models.DigestPrefs, error) {
+	rows, err := r.db.Query(ctx, `INSERT INTO digest_prefs(user_id, opt_out, send_at, timezone, updated_at) VALUES($1,$2,$3,$4,now())
+		ON CONFLICT(user_id) DO UPDATE SET
+		 opt_out=excluded.opt_out, send_at=excluded.send_at, timezone=excluded.timezone, updated_at=excluded.updated_at
+		RETURNING `+digestPrefsColumns, p.UserID, p.OptOut, p.SendAt, p.Timezone)
+	if err != nil {
+		return models.DigestPrefs{}, fmt.Errorf("set digest prefs: %w", translateErr(err))
+	}
+	res, err := pgx.CollectExactlyOneRow(rows, scanDigestPrefs)
+	if err != nil {
+		return models.DigestPrefs{}, fmt.Errorf("set digest prefs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetDigestPrefs(ctx context.Context, userID int) (models.DigestPrefs, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+digestPrefsColumns+` FROM digest_prefs WHERE user_id=$1`, userID)
+	if err != nil {
+		return models.DigestPrefs{}, fmt.Errorf("get digest prefs: %w", translateErr(err))
+	}
+	p, err := pgx.CollectExactlyOneRow(rows, scanDigestPrefs)
+	if err != nil {
+		return models.DigestPrefs{}, fmt.Errorf("get digest prefs: %w", translateErr(err))
+	}
+	return p, nil
+}
+
+func (r *repo) ListDigestPrefs(ctx context.Context) ([]models.DigestPrefs, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+digestPrefsColumns+` FROM digest_prefs ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list digest prefs: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanDigestPrefs)
+	if err != nil {
+		return nil, fmt.Errorf("scan digest prefs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) ClaimDigest(ctx context.Context, userID int, due, at time.Time) (bool, error) {
+	tag, err := r.db.Exec(ctx, `INSERT INTO digest_prefs(user_id, last_sent_at) VALUES($1,$2)
+		ON CONFLICT(user_id) DO UPDATE SET last_sent_at=excluded.last_sent_at
+		WHERE digest_prefs.last_sent_at IS NULL OR digest_prefs.last_sent_at < $3`, userID, at, due)
+	if err != nil {
+		return false, fmt.Errorf("claim digest: %w", translateErr(err))
+	}
+	return tag.RowsAffected() > 0, nil
+}
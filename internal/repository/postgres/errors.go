@@ -0,0 +1,35 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+
+	"prmanager/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+)
+
+// translateErr tags driver errors with the backend-neutral sentinels from the
+// repository package while keeping the original error in the chain.
+func translateErr(err error) error {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("%w: %w", repository.ErrNotFound, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return fmt.Errorf("%w: %w", repository.ErrAlreadyExists, err)
+		case pgForeignKeyViolation:
+			return fmt.Errorf("%w: %w", repository.ErrInvalidReference, err)
+		}
+	}
+	return err
+}
@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+// SetIdentity upserts on (user_id, provider); a login held by another user
+// trips the (provider, login) key instead and surfaces as ErrAlreadyExists.
+func (r *repo) SetIdentity(ctx context.Context, id models.Identity) (models.Identity, error) {
+	var res models.Identity
+	row := r.db.QueryRow(ctx, `INSERT INTO identities(provider, login, user_id, created_at) VALUES($1,$2,$3,now())
+		ON CONFLICT(user_id, provider) DO UPDATE SET
+		 login=excluded.login,
+		 created_at=CASE WHEN identities.login = excluded.login THEN identities.created_at ELSE excluded.created_at END
+		RETURNING user_id, provider, login, created_at`, id.Provider, id.Login, id.UserID)
+	if err := row.Scan(&res.UserID, &res.Provider, &res.Login, &res.CreatedAt); err != nil {
+		return res, fmt.Errorf("set identity: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetIdentityByLogin(ctx context.Context, provider, login string) (models.Identity, error) {
+	var id models.Identity
+	row := r.db.QueryRow(ctx, `SELECT user_id, provider, login, created_at FROM identities WHERE provider=$1 AND login=$2`, provider, login)
+	if err := row.Scan(&id.UserID, &id.Provider, &id.Login, &id.CreatedAt); err != nil {
+		return id, fmt.Errorf("get identity: %w", translateErr(err))
+	}
+	return id, nil
+}
+
+func (r *repo) ListIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	rows, err := r.db.Query(ctx, `SELECT user_id, provider, login, created_at FROM identities WHERE user_id=$1 ORDER BY provider`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list identities: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := []models.Identity{}
+	for rows.Next() {
+		var id models.Identity
+		if err := rows.Scan(&id.UserID, &id.Provider, &id.Login, &id.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list identities: %w", translateErr(err))
+		}
+		res = append(res, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list identities: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) DeleteIdentity(ctx context.Context, userID int, provider string) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM identities WHERE user_id=$1 AND provider=$2`, userID, provider)
+	if err != nil {
+		return fmt.Errorf("delete identity: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete identity: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) LinkExternalPR(ctx context.Context, ref models.ExternalRef) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO pr_external_refs(pr_id, provider, repo, number, url) VALUES($1,$2,$3,$4,$5)`, ref.PRID, ref.Provider, ref.Repo, ref.Number, ref.URL)
+	if err != nil {
+		return fmt.Errorf("link external PR: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) GetExternalRef(ctx context.Context, prID int) (models.ExternalRef, error) {
+	var ref models.ExternalRef
+	row := r.db.QueryRow(ctx, `SELECT pr_id, provider, repo, number, url FROM pr_external_refs WHERE pr_id=$1`, prID)
+	if err := row.Scan(&ref.PRID, &ref.Provider, &ref.Repo, &ref.Number, &ref.URL); err != nil {
+		return ref, fmt.Errorf("get external ref: %w", translateErr(err))
+	}
+	return ref, nil
+}
+
+func (r *repo) GetPRByExternalRef(ctx context.Context, provider, repoName string, number int) (models.PR, error) {
+	var p models.PR
+	row := r.db.QueryRow(ctx, `SELECT p.id, p.org_id, p.title, p.author_id, p.status, p.created_at
+		FROM prs p JOIN pr_external_refs x ON x.pr_id = p.id
+		WHERE x.provider=$1 AND x.repo=$2 AND x.number=$3 AND p.org_id=COALESCE($4, p.org_id)`, provider, repoName, number, repository.OrgFilter(ctx))
+	if err := row.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
+		return p, fmt.Errorf("get PR by external ref: %w", translateErr(err))
+	}
+	return p, nil
+}
@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+)
+
+const orgColumns = `id, name, created_at`
+
+func scanOrganization(row pgx.CollectableRow) (models.Organization, error) {
+	var o models.Organization
+	err := row.Scan(&o.ID, &o.Name, &o.CreatedAt)
+	return o, err
+}
+
+func (r *repo) CreateOrganization(ctx context.Context, name string) (models.Organization, error) {
+	rows, err := r.db.Query(ctx, `INSERT INTO organizations(name) VALUES($1) RETURNING `+orgColumns, name)
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("create organization: %w", translateErr(err))
+	}
+	o, err := pgx.CollectExactlyOneRow(rows, scanOrganization)
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("create organization: %w", translateErr(err))
+	}
+	return o, nil
+}
+
+func (r *repo) GetOrganization(ctx context.Context, id int) (models.Organization, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+orgColumns+` FROM organizations WHERE id=$1`, id)
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("get organization: %w", translateErr(err))
+	}
+	o, err := pgx.CollectExactlyOneRow(rows, scanOrganization)
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("get organization: %w", translateErr(err))
+	}
+	return o, nil
+}
+
+func (r *repo) GetOrganizationByName(ctx context.Context, name string) (models.Organization, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+orgColumns+` FROM organizations WHERE name=$1`, name)
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("get organization by name: %w", translateErr(err))
+	}
+	o, err := pgx.CollectExactlyOneRow(rows, scanOrganization)
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("get organization by name: %w", translateErr(err))
+	}
+	return o, nil
+}
+
+func (r *repo) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+orgColumns+` FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanOrganization)
+	if err != nil {
+		return nil, fmt.Errorf("scan organization: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) RestoreOrganization(ctx context.Context, o models.Organization) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO organizations(id, name, created_at) VALUES($1,$2,$3)
+		ON CONFLICT(id) DO UPDATE SET name=excluded.name, created_at=excluded.created_at`, o.ID, o.Name, o.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("restore organization %d: %w", o.ID, translateErr(err))
+	}
+	return nil
+}
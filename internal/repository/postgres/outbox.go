@@ -0,0 +1,106 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const outboxColumns = `id, event_id, event_type, ordering_key, payload, attempts, last_error, next_attempt_at, locked_until, created_at, delivered_at, dead_at`
+
+func scanOutboxEvent(row pgx.CollectableRow) (models.OutboxEvent, error) {
+	var (
+		e       models.OutboxEvent
+		payload []byte
+	)
+	err := row.Scan(&e.ID, &e.EventID, &e.EventType, &e.OrderingKey, &payload, &e.Attempts, &e.LastError, &e.NextAttemptAt, &e.LockedUntil, &e.CreatedAt, &e.DeliveredAt, &e.DeadAt)
+	e.Payload = payload
+	return e, err
+}
+
+func (r *repo) CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) error {
+	var next *time.Time
+	if !e.NextAttemptAt.IsZero() {
+		next = &e.NextAttemptAt
+	}
+	_, err := r.db.Exec(ctx, `INSERT INTO outbox_events(event_id, event_type, ordering_key, payload, next_attempt_at)
+		VALUES($1,$2,$3,$4,COALESCE($5::timestamptz, now()))`, e.EventID, e.EventType, e.OrderingKey, []byte(e.Payload), next)
+	if err != nil {
+		return fmt.Errorf("create outbox event: %w", translateErr(err))
+	}
+	return nil
+}
+
+// ClaimOutboxEvents skips rows locked by a concurrent claim, and the NOT
+// EXISTS keeps every event behind the undelivered head of its ordering key.
+// Dead events are neither claimed nor heads.
+func (r *repo) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	rows, err := r.db.Query(ctx, `UPDATE outbox_events SET locked_until=$2 WHERE id IN (
+			SELECT e.id FROM outbox_events e
+			WHERE e.delivered_at IS NULL AND e.dead_at IS NULL AND e.next_attempt_at <= $1
+			  AND (e.locked_until IS NULL OR e.locked_until <= $1)
+			  AND (e.ordering_key = '' OR NOT EXISTS (
+				SELECT 1 FROM outbox_events o
+				WHERE o.ordering_key = e.ordering_key AND o.delivered_at IS NULL AND o.dead_at IS NULL AND o.id < e.id))
+			ORDER BY e.id LIMIT $3
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+outboxColumns, now, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanOutboxEvent)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", translateErr(err))
+	}
+	slices.SortFunc(res, func(a, b models.OutboxEvent) int { return a.ID - b.ID })
+	return res, nil
+}
+
+func (r *repo) MarkOutboxEventDelivered(ctx context.Context, id int, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `UPDATE outbox_events SET delivered_at=$2, locked_until=NULL WHERE id=$1`, id, at)
+	if err != nil {
+		return fmt.Errorf("mark outbox event delivered: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("mark outbox event delivered: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) RescheduleOutboxEvent(ctx context.Context, e models.OutboxEvent) error {
+	tag, err := r.db.Exec(ctx, `UPDATE outbox_events SET attempts=$2, last_error=$3, next_attempt_at=$4, locked_until=NULL WHERE id=$1`,
+		e.ID, e.Attempts, e.LastError, e.NextAttemptAt)
+	if err != nil {
+		return fmt.Errorf("reschedule outbox event: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("reschedule outbox event: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) DeadLetterOutboxEvent(ctx context.Context, e models.OutboxEvent, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `UPDATE outbox_events SET attempts=$2, last_error=$3, dead_at=$4, locked_until=NULL WHERE id=$1`,
+		e.ID, e.Attempts, e.LastError, at)
+	if err != nil {
+		return fmt.Errorf("dead-letter outbox event: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("dead-letter outbox event: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) DeleteDeliveredOutboxEvents(ctx context.Context, before time.Time) (int, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM outbox_events WHERE delivered_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("delete delivered outbox events: %w", translateErr(err))
+	}
+	return int(tag.RowsAffected()), nil
+}
@@ -2,131 +2,352 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"prmanager/internal/models"
 	"prmanager/internal/repository"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// querier is what the repository needs from a connection. Both *pgxpool.Pool
+// and pgx.Tx implement it; Begin on a pgx.Tx opens a savepoint, so methods that
+// use their own transaction still nest inside WithTx.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
 type repo struct {
-	pool *pgxpool.Pool
+	db querier
 }
 
 func NewRepo(pool *pgxpool.Pool) repository.Repository {
-	return &repo{pool: pool}
+	return &repo{db: pool}
+}
+
+func (r *repo) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", translateErr(err))
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&repo{db: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", translateErr(err))
+	}
+	return nil
+}
+
+// ReadSnapshot runs fn in a REPEATABLE READ, read-only transaction, or in the
+// current one when the repo is already bound to a transaction by WithTx.
+func (r *repo) ReadSnapshot(ctx context.Context, fn func(r repository.Repository) error) error {
+	pool, ok := r.db.(*pgxpool.Pool)
+	if !ok {
+		return fn(r)
+	}
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", translateErr(err))
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&repo{db: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", translateErr(err))
+	}
+	return nil
 }
 
 func (r *repo) CreateTeam(ctx context.Context, name string) (models.Team, error) {
 	var t models.Team
-	row := r.pool.QueryRow(ctx, `INSERT INTO teams(name) VALUES($1) RETURNING id, name, created_at`, name)
-	if err := row.Scan(&t.ID, &t.Name, &t.CreatedAt); err != nil {
-		return t, fmt.Errorf("create team: %w", err)
+	row := r.db.QueryRow(ctx, `INSERT INTO teams(org_id, name) VALUES($1,$2) RETURNING id, org_id, name, created_at, deleted_at`, repository.OrgOrDefault(ctx), name)
+	if err := row.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+		return t, fmt.Errorf("create team: %w", translateErr(err))
 	}
 	return t, nil
 }
 
 func (r *repo) GetTeamByID(ctx context.Context, id int) (models.Team, error) {
 	var t models.Team
-	row := r.pool.QueryRow(ctx, `SELECT id, name, created_at FROM teams WHERE id=$1`, id)
-	if err := row.Scan(&t.ID, &t.Name, &t.CreatedAt); err != nil {
-		return t, fmt.Errorf("get team: %w", err)
+	row := r.db.QueryRow(ctx, `SELECT id, org_id, name, created_at, deleted_at FROM teams WHERE id=$1 AND org_id=COALESCE($2, org_id)`, id, repository.OrgFilter(ctx))
+	if err := row.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+		return t, fmt.Errorf("get team: %w", translateErr(err))
+	}
+	return t, nil
+}
+
+func (r *repo) GetTeamByName(ctx context.Context, name string) (models.Team, error) {
+	var t models.Team
+	row := r.db.QueryRow(ctx, `SELECT id, org_id, name, created_at, deleted_at FROM teams WHERE org_id=$1 AND name=$2`, repository.OrgOrDefault(ctx), name)
+	if err := row.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+		return t, fmt.Errorf("get team by name: %w", translateErr(err))
 	}
 	return t, nil
 }
 
+func (r *repo) ListTeams(ctx context.Context, f repository.TeamFilter) ([]models.Team, error) {
+	after := 0
+	if f.After != nil {
+		after = f.After.ID
+	}
+	q := `SELECT id, org_id, name, created_at, deleted_at FROM teams WHERE id > $1 AND deleted_at IS NULL AND org_id = COALESCE($2, org_id) ORDER BY id`
+	args := []any{after, repository.OrgFilter(ctx)}
+	if f.Limit > 0 {
+		q += ` LIMIT $3`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Team, error) {
+		var t models.Team
+		err := row.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt)
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan team: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+// teamOrg returns the organization of a team that is visible in ctx; any
+// other team is ErrInvalidReference.
+func teamOrg(ctx context.Context, q querier, teamID int) (int, error) {
+	var orgID int
+	err := q.QueryRow(ctx, `SELECT org_id FROM teams WHERE id=$1 AND org_id=COALESCE($2, org_id)`, teamID, repository.OrgFilter(ctx)).Scan(&orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%w: team %d", repository.ErrInvalidReference, teamID)
+	}
+	return orgID, translateErr(err)
+}
+
 func (r *repo) CreateUser(ctx context.Context, u models.User) (models.User, error) {
+	orgID := repository.OrgOrDefault(ctx)
+	if u.TeamID != nil {
+		var err error
+		if orgID, err = teamOrg(ctx, r.db, *u.TeamID); err != nil {
+			return models.User{}, fmt.Errorf("create user: %w", err)
+		}
+	}
+
 	var res models.User
-	row := r.pool.QueryRow(ctx, `INSERT INTO users(team_id, name, is_active) VALUES($1,$2,$3) RETURNING id, team_id, name, is_active, created_at`, u.TeamID, u.Name, u.IsActive)
-	if err := row.Scan(&res.ID, &res.TeamID, &res.Name, &res.IsActive, &res.CreatedAt); err != nil {
-		return res, fmt.Errorf("create user: %w", err)
+	row := r.db.QueryRow(ctx, `INSERT INTO users(org_id, team_id, name, is_active) VALUES($1,$2,$3,$4) RETURNING id, org_id, team_id, name, is_active, created_at, deleted_at`, orgID, u.TeamID, u.Name, u.IsActive)
+	if err := row.Scan(&res.ID, &res.OrgID, &res.TeamID, &res.Name, &res.IsActive, &res.CreatedAt, &res.DeletedAt); err != nil {
+		return res, fmt.Errorf("create user: %w", translateErr(err))
 	}
 	return res, nil
 }
 
 func (r *repo) GetUserByID(ctx context.Context, id int) (models.User, error) {
 	var u models.User
-	row := r.pool.QueryRow(ctx, `SELECT id, team_id, name, is_active, created_at FROM users WHERE id=$1`, id)
-	if err := row.Scan(&u.ID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt); err != nil {
-		return u, fmt.Errorf("get user: %w", err)
+	row := r.db.QueryRow(ctx, `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE id=$1 AND org_id=COALESCE($2, org_id)`, id, repository.OrgFilter(ctx))
+	if err := row.Scan(&u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+		return u, fmt.Errorf("get user: %w", translateErr(err))
 	}
 	return u, nil
 }
 
+func (r *repo) UpdateUser(ctx context.Context, u models.User) (models.User, error) {
+	var orgID int
+	if err := r.db.QueryRow(ctx, `SELECT org_id FROM users WHERE id=$1 AND org_id=COALESCE($2, org_id)`, u.ID, repository.OrgFilter(ctx)).Scan(&orgID); err != nil {
+		return models.User{}, fmt.Errorf("update user: %w", translateErr(err))
+	}
+	if u.TeamID != nil {
+		// The team must be in the user's organization, scoped or not.
+		if _, err := teamOrg(repository.WithOrg(ctx, orgID), r.db, *u.TeamID); err != nil {
+			return models.User{}, fmt.Errorf("update user: %w", err)
+		}
+	}
+
+	var res models.User
+	row := r.db.QueryRow(ctx, `UPDATE users SET team_id=$1, name=$2, is_active=$3 WHERE id=$4 RETURNING id, org_id, team_id, name, is_active, created_at, deleted_at`, u.TeamID, u.Name, u.IsActive, u.ID)
+	if err := row.Scan(&res.ID, &res.OrgID, &res.TeamID, &res.Name, &res.IsActive, &res.CreatedAt, &res.DeletedAt); err != nil {
+		return res, fmt.Errorf("update user: %w", translateErr(err))
+	}
+	return res, nil
+}
+
 func (r *repo) ListActiveUsersInTeam(ctx context.Context, teamID int) ([]models.User, error) {
-	rows, err := r.pool.Query(ctx, `SELECT id, team_id, name, is_active, created_at FROM users WHERE team_id=$1 AND is_active=true`, teamID)
+	rows, err := r.db.Query(ctx, `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE team_id=$1 AND is_active=true AND deleted_at IS NULL AND org_id=COALESCE($2, org_id) ORDER BY id`, teamID, repository.OrgFilter(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("list active users: %w", err)
+		return nil, fmt.Errorf("list active users: %w", translateErr(err))
 	}
 	defer rows.Close()
 
 	res := make([]models.User, 0)
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan user: %w", err)
+		if err := rows.Scan(&u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan user: %w", translateErr(err))
 		}
 		res = append(res, u)
 	}
 	return res, nil
 }
 
+func (r *repo) ListTeamMembers(ctx context.Context, teamID int) ([]models.User, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE team_id=$1 AND deleted_at IS NULL AND org_id=COALESCE($2, org_id) ORDER BY id`, teamID, repository.OrgFilter(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list team members: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanUser)
+	if err != nil {
+		return nil, fmt.Errorf("scan user: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) ListUsers(ctx context.Context, f repository.UserFilter) ([]models.User, error) {
+	args := []any{}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds := []string{"deleted_at IS NULL"}
+	conds = append(conds, "org_id = COALESCE("+arg(repository.OrgFilter(ctx))+", org_id)")
+	if f.TeamID != nil {
+		conds = append(conds, "team_id = "+arg(*f.TeamID))
+	}
+	if f.Active != nil {
+		conds = append(conds, "is_active = "+arg(*f.Active))
+	}
+	if f.After != nil {
+		conds = append(conds, "id > "+arg(f.After.ID))
+	}
+	q := `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY id`
+	if f.Limit > 0 {
+		q += " LIMIT " + arg(f.Limit)
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanUser)
+	if err != nil {
+		return nil, fmt.Errorf("scan user: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func scanUser(row pgx.CollectableRow) (models.User, error) {
+	var u models.User
+	err := row.Scan(&u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt)
+	return u, err
+}
+
 func (r *repo) DeactivateUsersInTeam(ctx context.Context, teamID int, userIDs []int) error {
-	_, err := r.pool.Exec(ctx, `UPDATE users SET is_active = false WHERE team_id = $1 AND id = ANY($2)`, teamID, userIDs)
+	_, err := r.db.Exec(ctx, `UPDATE users SET is_active = false WHERE team_id = $1 AND id = ANY($2) AND org_id = COALESCE($3, org_id)`, teamID, userIDs, repository.OrgFilter(ctx))
 	if err != nil {
-		return fmt.Errorf("deactivate users: %w", err)
+		return fmt.Errorf("deactivate users: %w", translateErr(err))
 	}
 	return nil
 }
 
 func (r *repo) CreatePR(ctx context.Context, pr models.PR) (models.PR, error) {
+	var orgID int
+	err := r.db.QueryRow(ctx, `SELECT org_id FROM users WHERE id=$1 AND org_id=COALESCE($2, org_id)`, pr.AuthorID, repository.OrgFilter(ctx)).Scan(&orgID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.PR{}, fmt.Errorf("create PR: %w: author %d", repository.ErrInvalidReference, pr.AuthorID)
+	}
+	if err != nil {
+		return models.PR{}, fmt.Errorf("create PR: %w", translateErr(err))
+	}
+
 	var res models.PR
-	row := r.pool.QueryRow(ctx, `INSERT INTO prs(title, author_id, status) VALUES($1,$2,$3) RETURNING id, title, author_id, status, created_at`, pr.Title, pr.AuthorID, pr.Status)
-	if err := row.Scan(&res.ID, &res.Title, &res.AuthorID, &res.Status, &res.CreatedAt); err != nil {
-		return res, fmt.Errorf("create PR: %w", err)
+	row := r.db.QueryRow(ctx, `INSERT INTO prs(org_id, title, author_id, status) VALUES($1,$2,$3,$4) RETURNING id, org_id, title, author_id, status, created_at`, orgID, pr.Title, pr.AuthorID, pr.Status)
+	if err := row.Scan(&res.ID, &res.OrgID, &res.Title, &res.AuthorID, &res.Status, &res.CreatedAt); err != nil {
+		return res, fmt.Errorf("create PR: %w", translateErr(err))
 	}
 	return res, nil
 }
 
 func (r *repo) GetPRByID(ctx context.Context, id int) (models.PR, error) {
 	var p models.PR
-	row := r.pool.QueryRow(ctx, `SELECT id, title, author_id, status, created_at FROM prs WHERE id=$1`, id)
-	if err := row.Scan(&p.ID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
-		return p, fmt.Errorf("get PR: %w", err)
+	row := r.db.QueryRow(ctx, `SELECT id, org_id, title, author_id, status, created_at FROM prs WHERE id=$1 AND org_id=COALESCE($2, org_id)`, id, repository.OrgFilter(ctx))
+	if err := row.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
+		return p, fmt.Errorf("get PR: %w", translateErr(err))
+	}
+	return p, nil
+}
+
+func (r *repo) LockPR(ctx context.Context, id int) (models.PR, error) {
+	var p models.PR
+	row := r.db.QueryRow(ctx, `SELECT id, org_id, title, author_id, status, created_at FROM prs WHERE id=$1 AND org_id=COALESCE($2, org_id) FOR UPDATE`, id, repository.OrgFilter(ctx))
+	if err := row.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
+		return p, fmt.Errorf("lock PR: %w", translateErr(err))
 	}
 	return p, nil
 }
 
 func (r *repo) SetPRStatus(ctx context.Context, id int, status string) error {
-	_, err := r.pool.Exec(ctx, `UPDATE prs SET status=$1 WHERE id=$2`, status, id)
+	_, err := r.db.Exec(ctx, `UPDATE prs SET status=$1 WHERE id=$2 AND org_id=COALESCE($3, org_id)`, status, id, repository.OrgFilter(ctx))
 	if err != nil {
-		return fmt.Errorf("set PR status: %w", err)
+		return fmt.Errorf("set PR status: %w", translateErr(err))
+	}
+	return nil
+}
+
+// checkReviewer fails with ErrInvalidReference unless the PR is visible in
+// ctx and the user belongs to the PR's organization.
+func checkReviewer(ctx context.Context, q querier, prID, userID int) error {
+	var pr, user bool
+	err := q.QueryRow(ctx, `SELECT
+		 EXISTS(SELECT 1 FROM prs WHERE id=$1 AND org_id=COALESCE($2, org_id)),
+		 EXISTS(SELECT 1 FROM prs p JOIN users u ON u.org_id = p.org_id WHERE p.id=$1 AND u.id=$3)`,
+		prID, repository.OrgFilter(ctx), userID).Scan(&pr, &user)
+	switch {
+	case err != nil:
+		return translateErr(err)
+	case !pr:
+		return fmt.Errorf("%w: PR %d", repository.ErrInvalidReference, prID)
+	case !user:
+		return fmt.Errorf("%w: user %d", repository.ErrInvalidReference, userID)
 	}
 	return nil
 }
 
 func (r *repo) AssignReviewers(ctx context.Context, prID int, userIDs []int) error {
 	for _, uid := range userIDs {
-		if _, err := r.pool.Exec(ctx, `INSERT INTO pr_reviewers(pr_id, user_id) VALUES($1,$2) ON CONFLICT DO NOTHING`, prID, uid); err != nil {
+		if err := checkReviewer(ctx, r.db, prID, uid); err != nil {
 			return fmt.Errorf("assign reviewer %d: %w", uid, err)
 		}
+		if _, err := r.db.Exec(ctx, `INSERT INTO pr_reviewers(pr_id, user_id) VALUES($1,$2) ON CONFLICT DO NOTHING`, prID, uid); err != nil {
+			return fmt.Errorf("assign reviewer %d: %w", uid, translateErr(err))
+		}
 	}
 	return nil
 }
 
 func (r *repo) GetReviewersByPR(ctx context.Context, prID int) ([]models.User, error) {
-	rows, err := r.pool.Query(ctx, `SELECT u.id, u.team_id, u.name, u.is_active, u.created_at FROM users u JOIN pr_reviewers r ON r.user_id = u.id WHERE r.pr_id=$1`, prID)
+	rows, err := r.db.Query(ctx, `SELECT u.id, u.org_id, u.team_id, u.name, u.is_active, u.created_at, u.deleted_at
+		FROM users u JOIN pr_reviewers r ON r.user_id = u.id JOIN prs p ON p.id = r.pr_id
+		WHERE r.pr_id=$1 AND p.org_id=COALESCE($2, p.org_id) ORDER BY u.id`, prID, repository.OrgFilter(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("get reviewers by PR: %w", err)
+		return nil, fmt.Errorf("get reviewers by PR: %w", translateErr(err))
 	}
 	defer rows.Close()
 
 	res := make([]models.User, 0)
 	for rows.Next() {
 		var u models.User
-		if err := rows.Scan(&u.ID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan reviewer: %w", err)
+		if err := rows.Scan(&u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan reviewer: %w", translateErr(err))
 		}
 		res = append(res, u)
 	}
@@ -134,53 +355,169 @@ func (r *repo) GetReviewersByPR(ctx context.Context, prID int) ([]models.User, e
 }
 
 func (r *repo) ReplaceReviewer(ctx context.Context, prID int, oldUserID int, newUserID int) error {
-	tx, err := r.pool.Begin(ctx)
+	tx, err := r.db.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("begin transaction: %w", err)
+		return fmt.Errorf("begin transaction: %w", translateErr(err))
 	}
 	defer tx.Rollback(ctx)
 
+	if err := checkReviewer(ctx, tx, prID, newUserID); err != nil {
+		return fmt.Errorf("insert new reviewer: %w", err)
+	}
 	if _, err := tx.Exec(ctx, `DELETE FROM pr_reviewers WHERE pr_id=$1 AND user_id=$2`, prID, oldUserID); err != nil {
-		return fmt.Errorf("delete old reviewer: %w", err)
+		return fmt.Errorf("delete old reviewer: %w", translateErr(err))
 	}
 
 	if _, err := tx.Exec(ctx, `INSERT INTO pr_reviewers(pr_id, user_id) VALUES($1,$2) ON CONFLICT DO NOTHING`, prID, newUserID); err != nil {
-		return fmt.Errorf("insert new reviewer: %w", err)
+		return fmt.Errorf("insert new reviewer: %w", translateErr(err))
 	}
 
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("commit transaction: %w", err)
+		return fmt.Errorf("commit transaction: %w", translateErr(err))
 	}
 	return nil
 }
 
-func (r *repo) ListPRsAssignedToUser(ctx context.Context, userID int) ([]models.PRWithReviewers, error) {
-	rows, err := r.pool.Query(ctx, `SELECT p.id, p.title, p.author_id, p.status, p.created_at FROM prs p JOIN pr_reviewers r ON r.pr_id = p.id WHERE r.user_id=$1`, userID)
+func (r *repo) ListPRsAssignedToUser(ctx context.Context, userID int, f repository.ReviewQueueFilter) ([]models.AssignedPR, error) {
+	args := []any{userID, repository.OrgFilter(ctx)}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds := []string{"r.user_id = $1", "p.org_id = COALESCE($2, p.org_id)"}
+	if len(f.Statuses) > 0 {
+		statuses := make([]string, len(f.Statuses))
+		for i, st := range f.Statuses {
+			statuses[i] = string(st)
+		}
+		conds = append(conds, "p.status = ANY("+arg(statuses)+")")
+	}
+	if f.CreatedAfter != nil {
+		conds = append(conds, "p.created_at > "+arg(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		conds = append(conds, "p.created_at < "+arg(*f.CreatedBefore))
+	}
+
+	col, dir, cmp := "p.created_at", "ASC", ">"
+	if f.SortBy == repository.SortByAssignedAt {
+		col = "r.assigned_at"
+	}
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if f.After != nil && f.After.Time != nil {
+		conds = append(conds, fmt.Sprintf("(%s, p.id) %s (%s, %s)", col, cmp, arg(*f.After.Time), arg(f.After.ID)))
+	}
+
+	q := `SELECT p.id, p.org_id, p.title, p.author_id, p.status, p.created_at, r.assigned_at FROM prs p JOIN pr_reviewers r ON r.pr_id = p.id WHERE ` +
+		strings.Join(conds, " AND ") + fmt.Sprintf(" ORDER BY %s %s, p.id %s", col, dir, dir)
+	if f.Limit > 0 {
+		q += " LIMIT " + arg(f.Limit)
+	}
+
+	rows, err := r.db.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list PRs assigned to user: %w", translateErr(err))
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.AssignedPR, error) {
+		var p models.AssignedPR
+		err := row.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt, &p.AssignedAt)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan PR: %w", translateErr(err))
+	}
+
+	prIDs := make([]int, len(out))
+	for i, p := range out {
+		prIDs[i] = p.ID
+	}
+	revs, err := r.reviewersByPRs(ctx, prIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Reviewers = revs[out[i].ID]
+	}
+	return out, nil
+}
+
+func (r *repo) ListPRsByAuthor(ctx context.Context, authorID int, statuses []models.PRStatus) ([]models.PRWithReviewers, error) {
+	args := []any{authorID, repository.OrgFilter(ctx)}
+	q := `SELECT id, org_id, title, author_id, status, created_at FROM prs WHERE author_id = $1 AND org_id = COALESCE($2, org_id)`
+	if len(statuses) > 0 {
+		sts := make([]string, len(statuses))
+		for i, st := range statuses {
+			sts[i] = string(st)
+		}
+		args = append(args, sts)
+		q += " AND status = ANY($3)"
+	}
+	rows, err := r.db.Query(ctx, q+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("list PRs by author: %w", translateErr(err))
+	}
+	out, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.PRWithReviewers, error) {
+		var p models.PRWithReviewers
+		err := row.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt)
+		return p, err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("list PRs assigned to user: %w", err)
+		return nil, fmt.Errorf("scan PR: %w", translateErr(err))
+	}
+
+	prIDs := make([]int, len(out))
+	for i, p := range out {
+		prIDs[i] = p.ID
+	}
+	revs, err := r.reviewersByPRs(ctx, prIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Reviewers = revs[out[i].ID]
+	}
+	return out, nil
+}
+
+// reviewersByPRs loads the reviewers of all given PRs in one round trip. Every
+// requested PR gets a non-nil slice, ordered by user id.
+func (r *repo) reviewersByPRs(ctx context.Context, prIDs []int) (map[int][]models.User, error) {
+	res := make(map[int][]models.User, len(prIDs))
+	if len(prIDs) == 0 {
+		return res, nil
+	}
+	for _, id := range prIDs {
+		res[id] = make([]models.User, 0)
+	}
+
+	rows, err := r.db.Query(ctx, `SELECT r.pr_id, u.id, u.org_id, u.team_id, u.name, u.is_active, u.created_at, u.deleted_at FROM pr_reviewers r JOIN users u ON u.id = r.user_id WHERE r.pr_id = ANY($1) ORDER BY r.pr_id, u.id`, prIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get reviewers by PRs: %w", translateErr(err))
 	}
 	defer rows.Close()
 
-	out := make([]models.PRWithReviewers, 0)
 	for rows.Next() {
-		var p models.PR
-		if err := rows.Scan(&p.ID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan PR: %w", err)
-		}
-		revs, err := r.GetReviewersByPR(ctx, p.ID)
-		if err != nil {
-			return nil, fmt.Errorf("get reviewers for PR %d: %w", p.ID, err)
+		var prID int
+		var u models.User
+		if err := rows.Scan(&prID, &u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan reviewer: %w", translateErr(err))
 		}
-		out = append(out, models.PRWithReviewers{PR: p, Reviewers: revs})
+		res[prID] = append(res[prID], u)
 	}
-	return out, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get reviewers by PRs: %w", translateErr(err))
+	}
+	return res, nil
 }
 
 func (r *repo) CountAssignments(ctx context.Context) (int, error) {
-	row := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM pr_reviewers`)
+	row := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM pr_reviewers r JOIN prs p ON p.id = r.pr_id WHERE p.org_id = COALESCE($1, p.org_id)`, repository.OrgFilter(ctx))
 	var c int
 	if err := row.Scan(&c); err != nil {
-		return 0, fmt.Errorf("count assignments: %w", err)
+		return 0, fmt.Errorf("count assignments: %w", translateErr(err))
 	}
 	return c, nil
 }
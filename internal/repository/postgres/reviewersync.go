@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const reviewerSyncColumns = `pr_id, status, generation, attempts, last_error, pushed, next_attempt_at, locked_until, last_attempt_at, synced_at`
+
+func splitLogins(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+func scanReviewerSync(row pgx.CollectableRow) (models.ReviewerSync, error) {
+	var (
+		s      models.ReviewerSync
+		pushed string
+	)
+	err := row.Scan(&s.PRID, &s.Status, &s.Generation, &s.Attempts, &s.LastError, &pushed, &s.NextAttemptAt, &s.LockedUntil, &s.LastAttemptAt, &s.SyncedAt)
+	s.Pushed = splitLogins(pushed)
+	return s, err
+}
+
+func (r *repo) MarkReviewerSyncPending(ctx context.Context, prID int, at time.Time) error {
+	_, err := r.db.Exec(ctx, `INSERT INTO reviewer_syncs(pr_id, status, next_attempt_at) VALUES($1,$2,$3)
+		ON CONFLICT(pr_id) DO UPDATE SET
+		 status=excluded.status, generation=reviewer_syncs.generation+1, attempts=0, next_attempt_at=excluded.next_attempt_at`,
+		prID, models.ReviewerSyncPending, at)
+	if err != nil {
+		return fmt.Errorf("mark reviewer sync pending: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) GetReviewerSync(ctx context.Context, prID int) (models.ReviewerSync, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+reviewerSyncColumns+` FROM reviewer_syncs WHERE pr_id=$1`, prID)
+	if err != nil {
+		return models.ReviewerSync{}, fmt.Errorf("get reviewer sync: %w", translateErr(err))
+	}
+	s, err := pgx.CollectExactlyOneRow(rows, scanReviewerSync)
+	if err != nil {
+		return models.ReviewerSync{}, fmt.Errorf("get reviewer sync: %w", translateErr(err))
+	}
+	return s, nil
+}
+
+// ClaimReviewerSyncs skips rows another replica is claiming, so concurrent
+// workers never push the same PR at once.
+func (r *repo) ClaimReviewerSyncs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.ReviewerSync, error) {
+	rows, err := r.db.Query(ctx, `UPDATE reviewer_syncs SET locked_until=$1 WHERE pr_id IN (
+			SELECT pr_id FROM reviewer_syncs
+			WHERE status=$2 AND next_attempt_at <= $3 AND (locked_until IS NULL OR locked_until <= $3)
+			ORDER BY next_attempt_at, pr_id LIMIT $4
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+reviewerSyncColumns, now.Add(lease), models.ReviewerSyncPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim reviewer syncs: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanReviewerSync)
+	if err != nil {
+		return nil, fmt.Errorf("claim reviewer syncs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) CompleteReviewerSync(ctx context.Context, s models.ReviewerSync) error {
+	tag, err := r.db.Exec(ctx, `UPDATE reviewer_syncs SET
+		 pushed=$1, attempts=$2, last_error=$3, last_attempt_at=$4, locked_until=NULL,
+		 status=CASE WHEN generation=$5 THEN $6 ELSE status END,
+		 next_attempt_at=CASE WHEN generation=$5 THEN $7 ELSE next_attempt_at END,
+		 synced_at=CASE WHEN generation=$5 THEN $8 ELSE synced_at END
+		WHERE pr_id=$9`,
+		strings.Join(s.Pushed, ","), s.Attempts, s.LastError, s.LastAttemptAt,
+		s.Generation, s.Status, s.NextAttemptAt, s.SyncedAt, s.PRID)
+	if err != nil {
+		return fmt.Errorf("complete reviewer sync: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("complete reviewer sync: %w", repository.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const teamSLAColumns = `team_id, remind_after_hours, reassign_after_hours, escalate_after_hours, workday_start, workday_end, timezone, lead_id, updated_at`
+
+func scanTeamSLA(row pgx.CollectableRow) (models.TeamSLA, error) {
+	var s models.TeamSLA
+	err := row.Scan(&s.TeamID, &s.RemindAfterHours, &s.ReassignAfterHours, &s.EscalateAfterHours, &s.WorkdayStart, &s.WorkdayEnd, &s.Timezone, &s.LeadID, &s.UpdatedAt)
+	return s, err
+}
+
+func (r *repo) SetTeamSLA(ctx context.Context, s models.TeamSLA) (models.TeamSLA, error) {
+	rows, err := r.db.Query(ctx, `INSERT INTO team_slas(team_id, remind_after_hours, reassign_after_hours, escalate_after_hours, workday_start, workday_end, timezone, lead_id, updated_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,now())
+		ON CONFLICT(team_id) DO UPDATE SET
+		 remind_after_hours=excluded.remind_after_hours, reassign_after_hours=excluded.reassign_after_hours,
+		 escalate_after_hours=excluded.escalate_after_hours, workday_start=excluded.workday_start, workday_end=excluded.workday_end,
+		 timezone=excluded.timezone, lead_id=excluded.lead_id, updated_at=excluded.updated_at
+		RETURNING `+teamSLAColumns,
+		s.TeamID, s.RemindAfterHours, s.ReassignAfterHours, s.EscalateAfterHours, s.WorkdayStart, s.WorkdayEnd, s.Timezone, s.LeadID)
+	if err != nil {
+		return models.TeamSLA{}, fmt.Errorf("set team SLA: %w", translateErr(err))
+	}
+	res, err := pgx.CollectExactlyOneRow(rows, scanTeamSLA)
+	if err != nil {
+		return models.TeamSLA{}, fmt.Errorf("set team SLA: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetTeamSLA(ctx context.Context, teamID int) (models.TeamSLA, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+teamSLAColumns+` FROM team_slas WHERE team_id=$1`, teamID)
+	if err != nil {
+		return models.TeamSLA{}, fmt.Errorf("get team SLA: %w", translateErr(err))
+	}
+	s, err := pgx.CollectExactlyOneRow(rows, scanTeamSLA)
+	if err != nil {
+		return models.TeamSLA{}, fmt.Errorf("get team SLA: %w", translateErr(err))
+	}
+	return s, nil
+}
+
+func (r *repo) DeleteTeamSLA(ctx context.Context, teamID int) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM team_slas WHERE team_id=$1`, teamID)
+	if err != nil {
+		return fmt.Errorf("delete team SLA: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete team SLA: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) ListTeamSLAs(ctx context.Context) ([]models.TeamSLA, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+teamSLAColumns+` FROM team_slas ORDER BY team_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list team SLAs: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanTeamSLA)
+	if err != nil {
+		return nil, fmt.Errorf("scan team SLAs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) ListPendingReviews(ctx context.Context, teamID int) ([]models.PendingReview, error) {
+	rows, err := r.db.Query(ctx, `SELECT r.pr_id, r.user_id, r.assigned_at, r.reminded_at, r.reassign_failed_at, r.escalated_at
+		FROM pr_reviewers r JOIN prs p ON p.id = r.pr_id JOIN users u ON u.id = r.user_id
+		WHERE u.team_id=$1 AND p.status=$2 AND p.org_id=COALESCE($3, p.org_id)
+		ORDER BY r.assigned_at, r.pr_id, r.user_id`, teamID, string(models.PRStatusOpen), repository.OrgFilter(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list pending reviews: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.PendingReview, error) {
+		var p models.PendingReview
+		err := row.Scan(&p.PRID, &p.UserID, &p.AssignedAt, &p.RemindedAt, &p.ReassignFailedAt, &p.EscalatedAt)
+		return p, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan pending reviews: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+// slaStageColumn is the pr_reviewers column recording stage.
+func slaStageColumn(stage models.SLAStage) (string, error) {
+	switch stage {
+	case models.SLAReminded:
+		return "reminded_at", nil
+	case models.SLAReassignFailed:
+		return "reassign_failed_at", nil
+	case models.SLAEscalated:
+		return "escalated_at", nil
+	}
+	return "", fmt.Errorf("unknown SLA stage %q", stage)
+}
+
+func (r *repo) MarkReviewSLAStage(ctx context.Context, prID, userID int, stage models.SLAStage, at time.Time) (bool, error) {
+	col, err := slaStageColumn(stage)
+	if err != nil {
+		return false, err
+	}
+	tag, err := r.db.Exec(ctx, `UPDATE pr_reviewers SET `+col+`=$3 WHERE pr_id=$1 AND user_id=$2 AND `+col+` IS NULL`, prID, userID, at)
+	if err != nil {
+		return false, fmt.Errorf("mark review %s: %w", stage, translateErr(err))
+	}
+	if tag.RowsAffected() > 0 {
+		return true, nil
+	}
+	var exists bool
+	if err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM pr_reviewers WHERE pr_id=$1 AND user_id=$2)`, prID, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("mark review %s: %w", stage, translateErr(err))
+	}
+	if !exists {
+		return false, fmt.Errorf("mark review %s: %w", stage, repository.ErrNotFound)
+	}
+	return false, nil
+}
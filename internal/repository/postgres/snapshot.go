@@ -0,0 +1,267 @@
+package postgres
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"strings"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+func (r *repo) ForEachTeam(ctx context.Context, fn func(models.Team) error) error {
+	rows, err := r.db.Query(ctx, `SELECT id, org_id, name, created_at, deleted_at FROM teams WHERE org_id=COALESCE($1, org_id) ORDER BY id`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate teams: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t models.Team
+		if err := rows.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+			return fmt.Errorf("scan team: %w", translateErr(err))
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate teams: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) ForEachUser(ctx context.Context, fn func(models.User) error) error {
+	rows, err := r.db.Query(ctx, `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE org_id=COALESCE($1, org_id) ORDER BY id`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate users: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+			return fmt.Errorf("scan user: %w", translateErr(err))
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate users: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) ForEachPR(ctx context.Context, fn func(models.PR) error) error {
+	rows, err := r.db.Query(ctx, `SELECT id, org_id, title, author_id, status, created_at FROM prs WHERE org_id=COALESCE($1, org_id) ORDER BY id`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate PRs: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.PR
+		if err := rows.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
+			return fmt.Errorf("scan PR: %w", translateErr(err))
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate PRs: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) ForEachReviewerAssignment(ctx context.Context, fn func(models.ReviewerAssignment) error) error {
+	rows, err := r.db.Query(ctx, `SELECT r.pr_id, r.user_id, r.assigned_at, r.reminded_at, r.reassign_failed_at, r.escalated_at FROM pr_reviewers r JOIN prs p ON p.id = r.pr_id WHERE p.org_id=COALESCE($1, p.org_id) ORDER BY r.pr_id, r.user_id`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate reviewer assignments: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var a models.ReviewerAssignment
+		if err := rows.Scan(&a.PRID, &a.UserID, &a.AssignedAt, &a.RemindedAt, &a.ReassignFailedAt, &a.EscalatedAt); err != nil {
+			return fmt.Errorf("scan reviewer assignment: %w", translateErr(err))
+		}
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate reviewer assignments: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreTeam(ctx context.Context, t models.Team) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO teams(id, org_id, name, created_at, deleted_at) VALUES($1,$2,$3,$4,$5)`, t.ID, cmp.Or(t.OrgID, models.DefaultOrgID), t.Name, t.CreatedAt, t.DeletedAt); err != nil {
+		return fmt.Errorf("restore team %d: %w", t.ID, translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreUser(ctx context.Context, u models.User) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO users(id, org_id, team_id, name, is_active, created_at, deleted_at) VALUES($1,$2,$3,$4,$5,$6,$7)`, u.ID, cmp.Or(u.OrgID, models.DefaultOrgID), u.TeamID, u.Name, u.IsActive, u.CreatedAt, u.DeletedAt); err != nil {
+		return fmt.Errorf("restore user %d: %w", u.ID, translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestorePR(ctx context.Context, pr models.PR) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO prs(id, org_id, title, author_id, status, created_at) VALUES($1,$2,$3,$4,$5,$6)`, pr.ID, cmp.Or(pr.OrgID, models.DefaultOrgID), pr.Title, pr.AuthorID, pr.Status, pr.CreatedAt); err != nil {
+		return fmt.Errorf("restore PR %d: %w", pr.ID, translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreReviewerAssignment(ctx context.Context, a models.ReviewerAssignment) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO pr_reviewers(pr_id, user_id, assigned_at, reminded_at, reassign_failed_at, escalated_at) VALUES($1,$2,$3,$4,$5,$6)`,
+		a.PRID, a.UserID, a.AssignedAt, a.RemindedAt, a.ReassignFailedAt, a.EscalatedAt); err != nil {
+		return fmt.Errorf("restore reviewer assignment %d/%d: %w", a.PRID, a.UserID, translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) ForEachIdentity(ctx context.Context, fn func(models.Identity) error) error {
+	rows, err := r.db.Query(ctx, `SELECT i.user_id, i.provider, i.login, i.created_at FROM identities i JOIN users u ON u.id = i.user_id WHERE u.org_id=COALESCE($1, u.org_id) ORDER BY i.user_id, i.provider`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate identities: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id models.Identity
+		if err := rows.Scan(&id.UserID, &id.Provider, &id.Login, &id.CreatedAt); err != nil {
+			return fmt.Errorf("scan identity: %w", translateErr(err))
+		}
+		if err := fn(id); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate identities: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) ForEachExternalRef(ctx context.Context, fn func(models.ExternalRef) error) error {
+	rows, err := r.db.Query(ctx, `SELECT x.pr_id, x.provider, x.repo, x.number, x.url FROM pr_external_refs x JOIN prs p ON p.id = x.pr_id WHERE p.org_id=COALESCE($1, p.org_id) ORDER BY x.pr_id`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate external refs: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var ref models.ExternalRef
+		if err := rows.Scan(&ref.PRID, &ref.Provider, &ref.Repo, &ref.Number, &ref.URL); err != nil {
+			return fmt.Errorf("scan external ref: %w", translateErr(err))
+		}
+		if err := fn(ref); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate external refs: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) ForEachReviewerSync(ctx context.Context, fn func(models.ReviewerSync) error) error {
+	rows, err := r.db.Query(ctx, `SELECT s.pr_id, s.status, s.generation, s.attempts, s.last_error, s.pushed, s.next_attempt_at, s.locked_until, s.last_attempt_at, s.synced_at
+		FROM reviewer_syncs s JOIN prs p ON p.id = s.pr_id WHERE p.org_id=COALESCE($1, p.org_id) ORDER BY s.pr_id`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate reviewer syncs: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanReviewerSync(rows)
+		if err != nil {
+			return fmt.Errorf("scan reviewer sync: %w", translateErr(err))
+		}
+		if err := fn(s); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate reviewer syncs: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) ForEachTeamChannel(ctx context.Context, fn func(models.TeamChannel) error) error {
+	rows, err := r.db.Query(ctx, `SELECT c.team_id, c.webhook_url, c.channel, c.updated_at FROM team_channels c JOIN teams t ON t.id = c.team_id WHERE t.org_id=COALESCE($1, t.org_id) ORDER BY c.team_id`, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("iterate team channels: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c models.TeamChannel
+		if err := rows.Scan(&c.TeamID, &c.WebhookURL, &c.Channel, &c.UpdatedAt); err != nil {
+			return fmt.Errorf("scan team channel: %w", translateErr(err))
+		}
+		if err := fn(c); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate team channels: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreIdentity(ctx context.Context, id models.Identity) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO identities(provider, login, user_id, created_at) VALUES($1,$2,$3,$4)`, id.Provider, id.Login, id.UserID, id.CreatedAt); err != nil {
+		return fmt.Errorf("restore identity: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreReviewerSync(ctx context.Context, s models.ReviewerSync) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO reviewer_syncs(pr_id, status, generation, attempts, last_error, pushed, next_attempt_at, last_attempt_at, synced_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		s.PRID, s.Status, max(s.Generation, 1), s.Attempts, s.LastError, strings.Join(s.Pushed, ","), s.NextAttemptAt, s.LastAttemptAt, s.SyncedAt); err != nil {
+		return fmt.Errorf("restore reviewer sync: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreTeamChannel(ctx context.Context, c models.TeamChannel) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO team_channels(team_id, webhook_url, channel, updated_at) VALUES($1,$2,$3,$4)`, c.TeamID, c.WebhookURL, c.Channel, c.UpdatedAt); err != nil {
+		return fmt.Errorf("restore team channel: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreTeamSLA(ctx context.Context, s models.TeamSLA) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO team_slas(`+teamSLAColumns+`) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9)`,
+		s.TeamID, s.RemindAfterHours, s.ReassignAfterHours, s.EscalateAfterHours, s.WorkdayStart, s.WorkdayEnd, s.Timezone, s.LeadID, s.UpdatedAt); err != nil {
+		return fmt.Errorf("restore team SLA: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) RestoreDigestPrefs(ctx context.Context, p models.DigestPrefs) error {
+	if _, err := r.db.Exec(ctx, `INSERT INTO digest_prefs(`+digestPrefsColumns+`) VALUES($1,$2,$3,$4,$5,$6)`,
+		p.UserID, p.OptOut, p.SendAt, p.Timezone, p.LastSentAt, p.UpdatedAt); err != nil {
+		return fmt.Errorf("restore digest prefs: %w", translateErr(err))
+	}
+	return nil
+}
+
+// Rows restored with explicit IDs bypass the SERIAL sequences, so they have
+// to be moved past them or the next regular insert collides.
+func (r *repo) SyncSequences(ctx context.Context) error {
+	for _, table := range []string{"organizations", "teams", "users", "prs"} {
+		q := fmt.Sprintf(`SELECT setval(pg_get_serial_sequence('%[1]s', 'id'), COALESCE(MAX(id), 1)) FROM %[1]s`, table)
+		if _, err := r.db.Exec(ctx, q); err != nil {
+			return fmt.Errorf("sync %s sequence: %w", table, translateErr(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const apiTokenColumns = `id, name, role, user_id, org_id, token_hash, created_at, revoked_at`
+
+func scanAPIToken(row pgx.CollectableRow) (models.APIToken, error) {
+	var t models.APIToken
+	err := row.Scan(&t.ID, &t.Name, &t.Role, &t.UserID, &t.OrgID, &t.Hash, &t.CreatedAt, &t.RevokedAt)
+	return t, err
+}
+
+func (r *repo) CreateAPIToken(ctx context.Context, t models.APIToken) (models.APIToken, error) {
+	rows, err := r.db.Query(ctx, `INSERT INTO api_tokens(name, role, user_id, org_id, token_hash) VALUES($1,$2,$3,$4,$5) RETURNING `+apiTokenColumns,
+		t.Name, t.Role, t.UserID, t.OrgID, t.Hash)
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("create API token: %w", translateErr(err))
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, scanAPIToken)
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("create API token: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) GetAPITokenByHash(ctx context.Context, hash string) (models.APIToken, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash=$1`, hash)
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("get API token: %w", translateErr(err))
+	}
+	t, err := pgx.CollectExactlyOneRow(rows, scanAPIToken)
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("get API token: %w", translateErr(err))
+	}
+	return t, nil
+}
+
+func (r *repo) ListAPITokens(ctx context.Context) ([]models.APIToken, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list API tokens: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanAPIToken)
+	if err != nil {
+		return nil, fmt.Errorf("scan API token: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) RevokeAPIToken(ctx context.Context, id int, at time.Time) error {
+	tag, err := r.db.Exec(ctx, `UPDATE api_tokens SET revoked_at=$2 WHERE id=$1 AND revoked_at IS NULL`, id, at)
+	if err != nil {
+		return fmt.Errorf("revoke API token: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("revoke API token: %w", repository.ErrNotFound)
+	}
+	return nil
+}
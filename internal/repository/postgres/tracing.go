@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueryTracer traces every query run on a connection as a client span that
+// carries the SQL text. Query arguments are left out, as they may hold
+// tokens and other secrets. Set it as the Tracer of a pgx.ConnConfig.
+type QueryTracer struct {
+	tracer trace.Tracer
+}
+
+func NewQueryTracer(tp trace.TracerProvider) *QueryTracer {
+	return &QueryTracer{tracer: tp.Tracer("prmanager/internal/repository/postgres")}
+}
+
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	op := queryOperation(data.SQL)
+	ctx, _ = t.tracer.Start(ctx, op, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBOperationName(op),
+		semconv.DBQueryText(data.SQL),
+	))
+	return ctx
+}
+
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+	}
+	span.End()
+}
+
+// queryOperation returns the SQL command of query, e.g. "SELECT".
+func queryOperation(query string) string {
+	if f := strings.Fields(query); len(f) > 0 {
+		return strings.ToUpper(f[0])
+	}
+	return "QUERY"
+}
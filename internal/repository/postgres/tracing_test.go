@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestQueryTracer(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	tracer := NewQueryTracer(tp)
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	const query = "  select id from users where id = $1"
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: query, Args: []any{"secret"}})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+	parent.End()
+
+	spans := exp.GetSpans()
+	require.Len(t, spans, 2)
+	q := spans[0]
+	assert.Equal(t, "SELECT", q.Name)
+	assert.Equal(t, trace.SpanKindClient, q.SpanKind)
+	assert.Equal(t, parent.SpanContext().SpanID(), q.Parent.SpanID())
+	assert.Equal(t, codes.Error, q.Status.Code)
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("db.system.name", "postgresql"),
+		attribute.String("db.operation.name", "SELECT"),
+		attribute.String("db.query.text", query),
+	}, q.Attributes, "arguments are not recorded")
+}
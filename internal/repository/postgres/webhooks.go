@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, last_error, response_code, next_attempt_at, last_attempt_at, created_at`
+
+func splitEvents(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+func scanWebhook(row pgx.CollectableRow) (models.Webhook, error) {
+	var (
+		w      models.Webhook
+		events string
+	)
+	err := row.Scan(&w.ID, &w.URL, &w.Secret, &events, &w.CreatedAt)
+	w.Events = splitEvents(events)
+	return w, err
+}
+
+func scanDelivery(row pgx.CollectableRow) (models.WebhookDelivery, error) {
+	var (
+		d       models.WebhookDelivery
+		payload []byte
+	)
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.LastError, &d.ResponseCode, &d.NextAttemptAt, &d.LastAttemptAt, &d.CreatedAt)
+	d.Payload = payload
+	return d, err
+}
+
+func (r *repo) CreateWebhook(ctx context.Context, w models.Webhook) (models.Webhook, error) {
+	rows, err := r.db.Query(ctx, `INSERT INTO webhooks(url, secret, events) VALUES($1,$2,$3) RETURNING id, url, secret, events, created_at`, w.URL, w.Secret, strings.Join(w.Events, ","))
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("create webhook: %w", translateErr(err))
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, scanWebhook)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("create webhook: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) GetWebhook(ctx context.Context, id int) (models.Webhook, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, url, secret, events, created_at FROM webhooks WHERE id=$1`, id)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("get webhook: %w", translateErr(err))
+	}
+	w, err := pgx.CollectExactlyOneRow(rows, scanWebhook)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("get webhook: %w", translateErr(err))
+	}
+	return w, nil
+}
+
+func (r *repo) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := r.db.Query(ctx, `SELECT id, url, secret, events, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanWebhook)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) DeleteWebhook(ctx context.Context, id int) error {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id=$1`, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("delete webhook: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) CreateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `INSERT INTO webhook_deliveries(webhook_id, event_id, event_type, payload, status, attempts, last_error, response_code, next_attempt_at, last_attempt_at)
+		VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING `+deliveryColumns,
+		d.WebhookID, d.EventID, d.EventType, []byte(d.Payload), d.Status, d.Attempts, d.LastError, d.ResponseCode, d.NextAttemptAt, d.LastAttemptAt)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("create webhook delivery: %w", translateErr(err))
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, scanDelivery)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("create webhook delivery: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	tag, err := r.db.Exec(ctx, `UPDATE webhook_deliveries SET status=$2, attempts=$3, last_error=$4, response_code=$5, next_attempt_at=$6, last_attempt_at=$7 WHERE id=$1`,
+		d.ID, d.Status, d.Attempts, d.LastError, d.ResponseCode, d.NextAttemptAt, d.LastAttemptAt)
+	if err != nil {
+		return fmt.Errorf("update webhook delivery: %w", translateErr(err))
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("update webhook delivery: %w", repository.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status=$1 AND next_attempt_at <= $2 ORDER BY next_attempt_at, id LIMIT $3`, models.DeliveryPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list due webhook deliveries: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanDelivery)
+	if err != nil {
+		return nil, fmt.Errorf("list due webhook deliveries: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) ListWebhookDeliveries(ctx context.Context, webhookID int, f repository.DeliveryFilter) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE webhook_id=$1`
+	args := []any{webhookID}
+	if f.After != nil {
+		args = append(args, f.After.ID)
+		query += fmt.Sprintf(` AND id < $%d`, len(args))
+	}
+	query += ` ORDER BY id DESC`
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", translateErr(err))
+	}
+	res, err := pgx.CollectRows(rows, scanDelivery)
+	if err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", translateErr(err))
+	}
+	return res, nil
+}
@@ -2,27 +2,204 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"prmanager/internal/models"
 )
 
+// AnonymizedUserName replaces the name of a purged user.
+const AnonymizedUserName = "Deleted user"
+
+// Repository is the storage contract. Teams and users are soft-deleted:
+// DeleteTeam and DeleteUser set deleted_at, which hides them from candidate
+// pools and listings while Get* and reviewer lists still return them so
+// historical PRs render. PurgeTeam removes a team row; PurgeUser anonymizes
+// the user in place so PR foreign keys stay intact.
+//
+// Teams, users and PRs belong to an organization. In a context scoped with
+// WithOrg every method on them only sees that organization: rows of other
+// organizations are ErrNotFound, and referencing them is
+// ErrInvalidReference. Unscoped calls, made by background workers and the
+// CLI, see all organizations; what they create goes to the organization of
+// the rows it references, else to models.DefaultOrgID. Rows hanging off a
+// user, team or PR, such as identities or chat channels, are addressed by
+// its id, which callers get from a scoped lookup first.
 type Repository interface {
+	CreateOrganization(ctx context.Context, name string) (models.Organization, error)
+	GetOrganization(ctx context.Context, id int) (models.Organization, error)
+	GetOrganizationByName(ctx context.Context, name string) (models.Organization, error)
+	ListOrganizations(ctx context.Context) ([]models.Organization, error)
+	// RestoreOrganization inserts o with its id, or overwrites the
+	// organization with that id, which the default organization always has.
+	RestoreOrganization(ctx context.Context, o models.Organization) error
+
 	CreateTeam(ctx context.Context, name string) (models.Team, error)
 	GetTeamByID(ctx context.Context, id int) (models.Team, error)
+	GetTeamByName(ctx context.Context, name string) (models.Team, error)
+	ListTeams(ctx context.Context, f TeamFilter) ([]models.Team, error)
 
 	CreateUser(ctx context.Context, u models.User) (models.User, error)
 	GetUserByID(ctx context.Context, id int) (models.User, error)
+	UpdateUser(ctx context.Context, u models.User) (models.User, error)
 	ListActiveUsersInTeam(ctx context.Context, teamID int) ([]models.User, error)
+	ListTeamMembers(ctx context.Context, teamID int) ([]models.User, error)
+	ListUsers(ctx context.Context, f UserFilter) ([]models.User, error)
 	DeactivateUsersInTeam(ctx context.Context, teamID int, userIDs []int) error
 
+	DeleteTeam(ctx context.Context, id int) error
+	DeleteUser(ctx context.Context, id int) error
+	PurgeTeam(ctx context.Context, id int) error
+	PurgeUser(ctx context.Context, id int) error
+
 	CreatePR(ctx context.Context, pr models.PR) (models.PR, error)
 	GetPRByID(ctx context.Context, id int) (models.PR, error)
+	// LockPR is GetPRByID that also holds the PR's row lock until the
+	// transaction ends. Every transaction that changes a PR or records an
+	// event for it takes the lock first, so they commit, and their outbox
+	// events get ids, in the order they were applied.
+	LockPR(ctx context.Context, id int) (models.PR, error)
 	SetPRStatus(ctx context.Context, id int, status string) error
 
 	AssignReviewers(ctx context.Context, prID int, userIDs []int) error
 	GetReviewersByPR(ctx context.Context, prID int) ([]models.User, error)
 	ReplaceReviewer(ctx context.Context, prID int, oldUserID int, newUserID int) error
 
-	ListPRsAssignedToUser(ctx context.Context, userID int) ([]models.PRWithReviewers, error)
+	ListPRsAssignedToUser(ctx context.Context, userID int, f ReviewQueueFilter) ([]models.AssignedPR, error)
+	// ListPRsByAuthor returns the author's PRs in any of statuses, oldest
+	// first. No statuses means all of them.
+	ListPRsByAuthor(ctx context.Context, authorID int, statuses []models.PRStatus) ([]models.PRWithReviewers, error)
 	CountAssignments(ctx context.Context) (int, error)
+
+	ForEachTeam(ctx context.Context, fn func(models.Team) error) error
+	ForEachUser(ctx context.Context, fn func(models.User) error) error
+	ForEachPR(ctx context.Context, fn func(models.PR) error) error
+	ForEachReviewerAssignment(ctx context.Context, fn func(models.ReviewerAssignment) error) error
+	ForEachIdentity(ctx context.Context, fn func(models.Identity) error) error
+	ForEachExternalRef(ctx context.Context, fn func(models.ExternalRef) error) error
+	ForEachReviewerSync(ctx context.Context, fn func(models.ReviewerSync) error) error
+	ForEachTeamChannel(ctx context.Context, fn func(models.TeamChannel) error) error
+	// ReadSnapshot runs fn against a read-only repository that sees the data
+	// as of a single point in time, so all reads made by fn agree with each
+	// other even while other writers commit.
+	ReadSnapshot(ctx context.Context, fn func(r Repository) error) error
+
+	RestoreTeam(ctx context.Context, t models.Team) error
+	RestoreUser(ctx context.Context, u models.User) error
+	RestorePR(ctx context.Context, pr models.PR) error
+	RestoreReviewerAssignment(ctx context.Context, a models.ReviewerAssignment) error
+	RestoreIdentity(ctx context.Context, id models.Identity) error
+	// RestoreReviewerSync inserts s without its lease, so a sync that was in
+	// flight is claimed again.
+	RestoreReviewerSync(ctx context.Context, s models.ReviewerSync) error
+	RestoreTeamChannel(ctx context.Context, c models.TeamChannel) error
+	RestoreTeamSLA(ctx context.Context, s models.TeamSLA) error
+	RestoreDigestPrefs(ctx context.Context, p models.DigestPrefs) error
+	// SyncSequences moves id generation past the ids inserted by the Restore
+	// methods. Call it once after restoring, before regular inserts.
+	SyncSequences(ctx context.Context) error
+
+	CreateIdempotencyKey(ctx context.Context, rec models.IdempotencyRecord) error
+	GetIdempotencyKey(ctx context.Context, key string) (models.IdempotencyRecord, error)
+	CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, body []byte) error
+	DeleteIdempotencyKey(ctx context.Context, key string) error
+	DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (int, error)
+
+	CreateWebhook(ctx context.Context, w models.Webhook) (models.Webhook, error)
+	GetWebhook(ctx context.Context, id int) (models.Webhook, error)
+	ListWebhooks(ctx context.Context) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, id int) error
+
+	CreateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error
+	ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error)
+	ListWebhookDeliveries(ctx context.Context, webhookID int, f DeliveryFilter) ([]models.WebhookDelivery, error)
+
+	// WithTx runs fn against a repository bound to one transaction: all its
+	// writes are committed if fn returns nil and rolled back otherwise. The
+	// bound repository must not be used concurrently or after fn returns.
+	WithTx(ctx context.Context, fn func(tx Repository) error) error
+
+	CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) error
+	// ClaimOutboxEvents leases up to limit undelivered events that are due at
+	// now and are the oldest undelivered event of their ordering key, so at
+	// most one event per key is in flight. The lease expires at now+lease.
+	ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error)
+	MarkOutboxEventDelivered(ctx context.Context, id int, at time.Time) error
+	// RescheduleOutboxEvent stores Attempts, LastError and NextAttemptAt of a
+	// failed event and releases its lease.
+	RescheduleOutboxEvent(ctx context.Context, e models.OutboxEvent) error
+	// DeadLetterOutboxEvent stores Attempts and LastError of an event that
+	// will not be retried, sets its DeadAt to at and releases its lease. A
+	// dead event is never claimed again and no longer holds back its
+	// ordering key; it is kept for inspection.
+	DeadLetterOutboxEvent(ctx context.Context, e models.OutboxEvent, at time.Time) error
+	DeleteDeliveredOutboxEvents(ctx context.Context, before time.Time) (int, error)
+
+	// SetIdentity creates or replaces the user's login for id.Provider. A
+	// login that already belongs to another user is ErrAlreadyExists.
+	SetIdentity(ctx context.Context, id models.Identity) (models.Identity, error)
+	GetIdentityByLogin(ctx context.Context, provider, login string) (models.Identity, error)
+	ListIdentities(ctx context.Context, userID int) ([]models.Identity, error)
+	DeleteIdentity(ctx context.Context, userID int, provider string) error
+
+	// LinkExternalPR records ref for ref.PRID. A ref that is already linked
+	// to a PR, or a PR that already has one, is ErrAlreadyExists.
+	LinkExternalPR(ctx context.Context, ref models.ExternalRef) error
+	GetExternalRef(ctx context.Context, prID int) (models.ExternalRef, error)
+	GetPRByExternalRef(ctx context.Context, provider, repo string, number int) (models.PR, error)
+
+	// MarkReviewerSyncPending schedules a reviewer sync for prID at at,
+	// creating its row or bumping the generation of the existing one.
+	MarkReviewerSyncPending(ctx context.Context, prID int, at time.Time) error
+	GetReviewerSync(ctx context.Context, prID int) (models.ReviewerSync, error)
+	// ClaimReviewerSyncs leases up to limit pending syncs that are due.
+	ClaimReviewerSyncs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.ReviewerSync, error)
+	// CompleteReviewerSync records an attempt and releases the lease. Pushed,
+	// Attempts, LastError and LastAttemptAt are always stored; Status,
+	// NextAttemptAt and SyncedAt only if s.Generation is still current.
+	CompleteReviewerSync(ctx context.Context, s models.ReviewerSync) error
+
+	// SetTeamChannel creates or replaces the chat channel of c.TeamID.
+	SetTeamChannel(ctx context.Context, c models.TeamChannel) (models.TeamChannel, error)
+	GetTeamChannel(ctx context.Context, teamID int) (models.TeamChannel, error)
+	DeleteTeamChannel(ctx context.Context, teamID int) error
+
+	// SetTeamSLA creates or replaces the review SLA of s.TeamID.
+	SetTeamSLA(ctx context.Context, s models.TeamSLA) (models.TeamSLA, error)
+	GetTeamSLA(ctx context.Context, teamID int) (models.TeamSLA, error)
+	DeleteTeamSLA(ctx context.Context, teamID int) error
+	ListTeamSLAs(ctx context.Context) ([]models.TeamSLA, error)
+	// ListPendingReviews returns the assignments to open PRs of reviewers
+	// who are members of teamID, oldest first.
+	ListPendingReviews(ctx context.Context, teamID int) ([]models.PendingReview, error)
+	// MarkReviewSLAStage records that the assignment of userID to prID
+	// reached stage at at. It reports false and changes nothing if the stage
+	// was recorded before; a missing assignment is ErrNotFound. Replacing a
+	// reviewer starts the new assignment with no stages.
+	MarkReviewSLAStage(ctx context.Context, prID, userID int, stage models.SLAStage, at time.Time) (bool, error)
+
+	// CreateChatMessage queues m. A message for the same event and team is
+	// ErrAlreadyExists.
+	CreateChatMessage(ctx context.Context, m models.ChatMessage) (models.ChatMessage, error)
+	UpdateChatMessage(ctx context.Context, m models.ChatMessage) error
+	ListDueChatMessages(ctx context.Context, now time.Time, limit int) ([]models.ChatMessage, error)
+
+	// SetDigestPrefs creates or replaces OptOut, SendAt and Timezone of
+	// p.UserID, keeping LastSentAt.
+	SetDigestPrefs(ctx context.Context, p models.DigestPrefs) (models.DigestPrefs, error)
+	GetDigestPrefs(ctx context.Context, userID int) (models.DigestPrefs, error)
+	ListDigestPrefs(ctx context.Context) ([]models.DigestPrefs, error)
+	// ClaimDigest sets LastSentAt of userID to at, creating default
+	// preferences if needed, unless a digest was already sent at or after
+	// due. It reports whether the caller should send the digest due at due.
+	ClaimDigest(ctx context.Context, userID int, due, at time.Time) (bool, error)
+
+	// CreateAPIToken stores t. A hash that is already stored is
+	// ErrAlreadyExists.
+	CreateAPIToken(ctx context.Context, t models.APIToken) (models.APIToken, error)
+	GetAPITokenByHash(ctx context.Context, hash string) (models.APIToken, error)
+	ListAPITokens(ctx context.Context) ([]models.APIToken, error)
+	// RevokeAPIToken sets RevokedAt of a token that is not revoked yet;
+	// anything else is ErrNotFound.
+	RevokeAPIToken(ctx context.Context, id int, at time.Time) error
 }
@@ -0,0 +1,2079 @@
+// Package repotest is a conformance suite for repository.Repository
+// implementations. It pins down the behaviour the service layer relies on:
+// sentinel errors for missing rows and broken constraints, result ordering,
+// ON CONFLICT semantics and atomicity of ReplaceReviewer.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory returns an empty repository. It is called once per test case.
+type Factory func(t *testing.T) repository.Repository
+
+func Run(t *testing.T, newRepo Factory) {
+	cases := []struct {
+		name string
+		fn   func(t *testing.T, r repository.Repository)
+	}{
+		{"Teams", testTeams},
+		{"ListTeams", testListTeams},
+		{"Users", testUsers},
+		{"UpdateUser", testUpdateUser},
+		{"ListTeamMembers", testListTeamMembers},
+		{"SoftDelete", testSoftDelete},
+		{"Purge", testPurge},
+		{"ListUsers", testListUsers},
+		{"ListActiveUsersInTeam", testListActiveUsersInTeam},
+		{"DeactivateUsersInTeam", testDeactivateUsersInTeam},
+		{"PRs", testPRs},
+		{"AssignReviewers", testAssignReviewers},
+		{"GetReviewersByPR", testGetReviewersByPR},
+		{"ReplaceReviewer", testReplaceReviewer},
+		{"ReplaceReviewerFailureIsAtomic", testReplaceReviewerFailureIsAtomic},
+		{"ListPRsAssignedToUser", testListPRsAssignedToUser},
+		{"ListPRsByAuthor", testListPRsByAuthor},
+		{"ReviewQueueFilter", testReviewQueueFilter},
+		{"ReviewQueuePagination", testReviewQueuePagination},
+		{"CountAssignments", testCountAssignments},
+		{"ForEach", testForEach},
+		{"ForEachLinked", testForEachLinked},
+		{"ReadSnapshot", testReadSnapshot},
+		{"Restore", testRestore},
+		{"IdempotencyKeys", testIdempotencyKeys},
+		{"Webhooks", testWebhooks},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"WithTxCommits", testWithTxCommits},
+		{"WithTxRollsBack", testWithTxRollsBack},
+		{"Outbox", testOutbox},
+		{"OutboxOrdering", testOutboxOrdering},
+		{"Identities", testIdentities},
+		{"ExternalRefs", testExternalRefs},
+		{"ReviewerSync", testReviewerSync},
+		{"TeamChannels", testTeamChannels},
+		{"TeamSLAs", testTeamSLAs},
+		{"PendingReviews", testPendingReviews},
+		{"ChatMessages", testChatMessages},
+		{"DigestPrefs", testDigestPrefs},
+		{"ClaimDigest", testClaimDigest},
+		{"APITokens", testAPITokens},
+		{"Organizations", testOrganizations},
+		{"OrgIsolation", testOrgIsolation},
+		{"ConcurrentCreateTeam", testConcurrentCreateTeam},
+		{"ConcurrentAssignReviewers", testConcurrentAssignReviewers},
+		{"ConcurrentReplaceReviewer", testConcurrentReplaceReviewer},
+		{"ConcurrentPRTransactions", testConcurrentPRTransactions},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.fn(t, newRepo(t))
+		})
+	}
+}
+
+type fixture struct {
+	team   models.Team
+	author models.User
+	users  []models.User
+}
+
+func seed(t *testing.T, r repository.Repository, teamName string, members int) fixture {
+	t.Helper()
+	return seedIn(t, context.Background(), r, teamName, members)
+}
+
+// seedIn is seed in the organization of ctx.
+func seedIn(t *testing.T, ctx context.Context, r repository.Repository, teamName string, members int) fixture {
+	t.Helper()
+	team, err := r.CreateTeam(ctx, teamName)
+	require.NoError(t, err)
+	author, err := r.CreateUser(ctx, models.User{TeamID: &team.ID, Name: teamName + "-author", IsActive: true})
+	require.NoError(t, err)
+
+	f := fixture{team: team, author: author}
+	for i := range members {
+		u, err := r.CreateUser(ctx, models.User{TeamID: &team.ID, Name: fmt.Sprintf("%s-member-%d", teamName, i), IsActive: true})
+		require.NoError(t, err)
+		f.users = append(f.users, u)
+	}
+	return f
+}
+
+func createPR(t *testing.T, r repository.Repository, authorID int, title string) models.PR {
+	t.Helper()
+	pr, err := r.CreatePR(context.Background(), models.PR{Title: title, AuthorID: authorID, Status: models.PRStatusOpen})
+	require.NoError(t, err)
+	return pr
+}
+
+func ids(users []models.User) []int {
+	res := make([]int, 0, len(users))
+	for _, u := range users {
+		res = append(res, u.ID)
+	}
+	return res
+}
+
+func testTeams(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+
+	team, err := r.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	assert.Positive(t, team.ID)
+	assert.Equal(t, "backend", team.Name)
+	assert.False(t, team.CreatedAt.IsZero())
+
+	got, err := r.GetTeamByID(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, team.ID, got.ID)
+	assert.Equal(t, team.Name, got.Name)
+	assert.True(t, team.CreatedAt.Equal(got.CreatedAt))
+
+	_, err = r.CreateTeam(ctx, "backend")
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+	other, err := r.CreateTeam(ctx, "frontend")
+	require.NoError(t, err)
+	assert.Greater(t, other.ID, team.ID)
+
+	_, err = r.GetTeamByID(ctx, other.ID+1000)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	byName, err := r.GetTeamByName(ctx, "frontend")
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, byName.ID)
+
+	_, err = r.GetTeamByName(ctx, "mobile")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testListTeams(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+
+	empty, err := r.ListTeams(ctx, repository.TeamFilter{})
+	require.NoError(t, err)
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+
+	var want []int
+	for _, name := range []string{"c", "a", "b"} {
+		team, err := r.CreateTeam(ctx, name)
+		require.NoError(t, err)
+		want = append(want, team.ID)
+	}
+
+	all, err := r.ListTeams(ctx, repository.TeamFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, want, teamIDs(all), "teams ordered by id")
+
+	first, err := r.ListTeams(ctx, repository.TeamFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, want[:2], teamIDs(first))
+
+	rest, err := r.ListTeams(ctx, repository.TeamFilter{After: &pagination.Cursor{ID: first[1].ID}, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, want[2:], teamIDs(rest))
+}
+
+func teamIDs(teams []models.Team) []int {
+	res := make([]int, 0, len(teams))
+	for _, t := range teams {
+		res = append(res, t.ID)
+	}
+	return res
+}
+
+func testUsers(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+
+	team, err := r.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+
+	u, err := r.CreateUser(ctx, models.User{TeamID: &team.ID, Name: "alice", IsActive: true})
+	require.NoError(t, err)
+	assert.Positive(t, u.ID)
+	require.NotNil(t, u.TeamID)
+	assert.Equal(t, team.ID, *u.TeamID)
+	assert.True(t, u.IsActive)
+	assert.False(t, u.CreatedAt.IsZero())
+
+	got, err := r.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u.Name, got.Name)
+	require.NotNil(t, got.TeamID)
+	assert.Equal(t, team.ID, *got.TeamID)
+
+	loner, err := r.CreateUser(ctx, models.User{Name: "bob", IsActive: false})
+	require.NoError(t, err)
+	assert.Nil(t, loner.TeamID)
+	assert.False(t, loner.IsActive)
+
+	missing := team.ID + 1000
+	_, err = r.CreateUser(ctx, models.User{TeamID: &missing, Name: "ghost", IsActive: true})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	_, err = r.GetUserByID(ctx, loner.ID+1000)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testListActiveUsersInTeam(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 3)
+	other := seed(t, r, "frontend", 1)
+
+	inactive, err := r.CreateUser(ctx, models.User{TeamID: &f.team.ID, Name: "inactive", IsActive: false})
+	require.NoError(t, err)
+
+	got, err := r.ListActiveUsersInTeam(ctx, f.team.ID)
+	require.NoError(t, err)
+	want := append([]int{f.author.ID}, ids(f.users)...)
+	assert.Equal(t, want, ids(got), "active members ordered by id")
+	assert.NotContains(t, ids(got), inactive.ID)
+	assert.NotContains(t, ids(got), other.author.ID)
+
+	empty, err := r.ListActiveUsersInTeam(ctx, f.team.ID+1000)
+	require.NoError(t, err)
+	assert.NotNil(t, empty)
+	assert.Empty(t, empty)
+}
+
+func testUpdateUser(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	be := seed(t, r, "backend", 1)
+	fe := seed(t, r, "frontend", 0)
+
+	u := be.users[0]
+	u.Name = "renamed"
+	u.TeamID = &fe.team.ID
+	u.IsActive = false
+	got, err := r.UpdateUser(ctx, u)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", got.Name)
+	require.NotNil(t, got.TeamID)
+	assert.Equal(t, fe.team.ID, *got.TeamID)
+	assert.False(t, got.IsActive)
+	assert.True(t, be.users[0].CreatedAt.Equal(got.CreatedAt))
+
+	stored, err := r.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", stored.Name)
+	assert.Equal(t, fe.team.ID, *stored.TeamID)
+
+	u.TeamID = nil
+	got, err = r.UpdateUser(ctx, u)
+	require.NoError(t, err)
+	assert.Nil(t, got.TeamID)
+
+	missing := fe.team.ID + 1000
+	u.TeamID = &missing
+	_, err = r.UpdateUser(ctx, u)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	u.ID += 1000
+	u.TeamID = nil
+	_, err = r.UpdateUser(ctx, u)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testSoftDelete(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 2)
+	other := seed(t, r, "frontend", 0)
+	gone := f.users[0]
+	pr := createPR(t, r, f.author.ID, "pr")
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{gone.ID}))
+
+	require.NoError(t, r.DeleteUser(ctx, gone.ID))
+	assert.ErrorIs(t, r.DeleteUser(ctx, gone.ID), repository.ErrNotFound, "already deleted")
+	assert.ErrorIs(t, r.DeleteUser(ctx, gone.ID+1000), repository.ErrNotFound)
+
+	got, err := r.GetUserByID(ctx, gone.ID)
+	require.NoError(t, err, "deleted users can still be fetched")
+	require.NotNil(t, got.DeletedAt)
+	assert.False(t, got.IsActive)
+	assert.Equal(t, gone.Name, got.Name)
+
+	active, err := r.ListActiveUsersInTeam(ctx, f.team.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, ids(active), gone.ID, "deleted users are not candidates")
+	members, err := r.ListTeamMembers(ctx, f.team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.author.ID, f.users[1].ID}, ids(members))
+	users, err := r.ListUsers(ctx, repository.UserFilter{})
+	require.NoError(t, err)
+	assert.NotContains(t, ids(users), gone.ID)
+
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	require.Equal(t, []int{gone.ID}, ids(revs), "history keeps deleted reviewers")
+	assert.Equal(t, gone.Name, revs[0].Name)
+
+	require.NoError(t, r.DeleteTeam(ctx, other.team.ID))
+	assert.ErrorIs(t, r.DeleteTeam(ctx, other.team.ID), repository.ErrNotFound)
+	team, err := r.GetTeamByID(ctx, other.team.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, team.DeletedAt)
+	teams, err := r.ListTeams(ctx, repository.TeamFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.team.ID}, teamIDs(teams))
+}
+
+func testPurge(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+	other := seed(t, r, "frontend", 0)
+	pr := createPR(t, r, f.author.ID, "pr")
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID}))
+	_, err := r.SetIdentity(ctx, models.Identity{UserID: f.author.ID, Provider: "github", Login: "alice"})
+	require.NoError(t, err)
+
+	require.NoError(t, r.PurgeUser(ctx, f.author.ID))
+	author, err := r.GetUserByID(ctx, f.author.ID)
+	require.NoError(t, err)
+	assert.Equal(t, repository.AnonymizedUserName, author.Name)
+	assert.Nil(t, author.TeamID)
+	assert.False(t, author.IsActive)
+	assert.NotNil(t, author.DeletedAt)
+	_, err = r.GetIdentityByLogin(ctx, "github", "alice")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "purging forgets external logins")
+
+	gotPR, err := r.GetPRByID(ctx, pr.ID)
+	require.NoError(t, err, "PRs of purged authors survive")
+	assert.Equal(t, f.author.ID, gotPR.AuthorID)
+	assert.ErrorIs(t, r.PurgeUser(ctx, f.author.ID+1000), repository.ErrNotFound)
+
+	require.NoError(t, r.PurgeTeam(ctx, other.team.ID))
+	_, err = r.GetTeamByID(ctx, other.team.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.GetTeamByName(ctx, "frontend")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, r.PurgeTeam(ctx, other.team.ID), repository.ErrNotFound)
+
+	require.NoError(t, r.PurgeTeam(ctx, f.team.ID))
+	member, err := r.GetUserByID(ctx, f.users[0].ID)
+	require.NoError(t, err)
+	assert.Nil(t, member.TeamID, "members of a purged team are detached")
+
+	_, err = r.CreateTeam(ctx, "frontend")
+	assert.NoError(t, err, "purging frees the team name")
+}
+
+func testListTeamMembers(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 2)
+	seed(t, r, "frontend", 1)
+	require.NoError(t, r.DeactivateUsersInTeam(ctx, f.team.ID, []int{f.users[0].ID}))
+
+	got, err := r.ListTeamMembers(ctx, f.team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.author.ID, f.users[0].ID, f.users[1].ID}, ids(got), "inactive members are included")
+	assert.False(t, got[1].IsActive)
+
+	none, err := r.ListTeamMembers(ctx, f.team.ID+1000)
+	require.NoError(t, err)
+	assert.NotNil(t, none)
+	assert.Empty(t, none)
+}
+
+func testListUsers(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	be := seed(t, r, "backend", 2)
+	fe := seed(t, r, "frontend", 1)
+	loner, err := r.CreateUser(ctx, models.User{Name: "loner", IsActive: true})
+	require.NoError(t, err)
+	require.NoError(t, r.DeactivateUsersInTeam(ctx, be.team.ID, []int{be.users[1].ID}))
+
+	list := func(f repository.UserFilter) []int {
+		t.Helper()
+		got, err := r.ListUsers(ctx, f)
+		require.NoError(t, err)
+		assert.NotNil(t, got)
+		return ids(got)
+	}
+	active, inactive := true, false
+
+	assert.Equal(t, []int{be.author.ID, be.users[0].ID, be.users[1].ID, fe.author.ID, fe.users[0].ID, loner.ID}, list(repository.UserFilter{}))
+	assert.Equal(t, []int{be.author.ID, be.users[0].ID, be.users[1].ID}, list(repository.UserFilter{TeamID: &be.team.ID}))
+	assert.Equal(t, []int{be.author.ID, be.users[0].ID}, list(repository.UserFilter{TeamID: &be.team.ID, Active: &active}))
+	assert.Equal(t, []int{be.users[1].ID}, list(repository.UserFilter{Active: &inactive}))
+	assert.Equal(t, []int{be.users[0].ID, be.users[1].ID}, list(repository.UserFilter{TeamID: &be.team.ID, After: &pagination.Cursor{ID: be.author.ID}, Limit: 5}))
+	assert.Equal(t, []int{fe.author.ID, fe.users[0].ID}, list(repository.UserFilter{After: &pagination.Cursor{ID: be.users[1].ID}, Limit: 2}))
+
+	missing := fe.team.ID + 1000
+	assert.Empty(t, list(repository.UserFilter{TeamID: &missing}))
+}
+
+func testDeactivateUsersInTeam(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 2)
+	other := seed(t, r, "frontend", 1)
+
+	require.NoError(t, r.DeactivateUsersInTeam(ctx, f.team.ID, []int{f.users[0].ID, other.users[0].ID}))
+
+	u, err := r.GetUserByID(ctx, f.users[0].ID)
+	require.NoError(t, err)
+	assert.False(t, u.IsActive)
+
+	u, err = r.GetUserByID(ctx, f.users[1].ID)
+	require.NoError(t, err)
+	assert.True(t, u.IsActive)
+
+	u, err = r.GetUserByID(ctx, other.users[0].ID)
+	require.NoError(t, err)
+	assert.True(t, u.IsActive, "users of other teams are untouched")
+
+	require.NoError(t, r.DeactivateUsersInTeam(ctx, f.team.ID, []int{}))
+}
+
+func testPRs(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 0)
+
+	pr := createPR(t, r, f.author.ID, "Add search")
+	assert.Positive(t, pr.ID)
+	assert.Equal(t, "Add search", pr.Title)
+	assert.Equal(t, f.author.ID, pr.AuthorID)
+	assert.Equal(t, models.PRStatusOpen, pr.Status)
+	assert.False(t, pr.CreatedAt.IsZero())
+
+	got, err := r.GetPRByID(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pr.Title, got.Title)
+	assert.True(t, pr.CreatedAt.Equal(got.CreatedAt))
+
+	require.NoError(t, r.SetPRStatus(ctx, pr.ID, string(models.PRStatusMerged)))
+	got, err = r.GetPRByID(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PRStatusMerged, got.Status)
+
+	_, err = r.CreatePR(ctx, models.PR{Title: "orphan", AuthorID: f.author.ID + 1000, Status: models.PRStatusOpen})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	_, err = r.GetPRByID(ctx, pr.ID+1000)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testAssignReviewers(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 3)
+	pr := createPR(t, r, f.author.ID, "pr")
+
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID}))
+
+	var firstAt time.Time
+	require.NoError(t, r.ForEachReviewerAssignment(ctx, func(a models.ReviewerAssignment) error {
+		firstAt = a.AssignedAt
+		return nil
+	}))
+
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID, f.users[1].ID}), "duplicates are ignored")
+
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.users[0].ID, f.users[1].ID}, ids(revs))
+
+	require.NoError(t, r.ForEachReviewerAssignment(ctx, func(a models.ReviewerAssignment) error {
+		if a.UserID == f.users[0].ID {
+			assert.True(t, firstAt.Equal(a.AssignedAt), "existing assignment keeps its timestamp")
+		}
+		return nil
+	}))
+
+	err = r.AssignReviewers(ctx, pr.ID, []int{f.users[2].ID + 1000})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+	err = r.AssignReviewers(ctx, pr.ID+1000, []int{f.users[2].ID})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, nil))
+}
+
+func testGetReviewersByPR(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 3)
+	pr := createPR(t, r, f.author.ID, "pr")
+
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, revs)
+	assert.Empty(t, revs)
+
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{f.users[2].ID, f.users[0].ID}))
+	revs, err = r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.users[0].ID, f.users[2].ID}, ids(revs), "reviewers ordered by user id")
+	assert.Equal(t, f.users[0].Name, revs[0].Name)
+
+	revs, err = r.GetReviewersByPR(ctx, pr.ID+1000)
+	require.NoError(t, err)
+	assert.Empty(t, revs)
+}
+
+func testReplaceReviewer(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 3)
+	pr := createPR(t, r, f.author.ID, "pr")
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID, f.users[1].ID}))
+
+	require.NoError(t, r.ReplaceReviewer(ctx, pr.ID, f.users[0].ID, f.users[2].ID))
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.users[1].ID, f.users[2].ID}, ids(revs))
+
+	// replacing with someone already assigned just drops the old reviewer
+	require.NoError(t, r.ReplaceReviewer(ctx, pr.ID, f.users[1].ID, f.users[2].ID))
+	revs, err = r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.users[2].ID}, ids(revs))
+}
+
+func testReplaceReviewerFailureIsAtomic(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+	pr := createPR(t, r, f.author.ID, "pr")
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID}))
+
+	err := r.ReplaceReviewer(ctx, pr.ID, f.users[0].ID, f.users[0].ID+1000)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.users[0].ID}, ids(revs), "old reviewer survives a failed replacement")
+}
+
+func testListPRsAssignedToUser(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 3)
+	reviewer := f.users[0]
+
+	pr1 := createPR(t, r, f.author.ID, "first")
+	pr2 := createPR(t, r, f.author.ID, "second")
+	pr3 := createPR(t, r, f.author.ID, "not assigned")
+	require.NoError(t, r.AssignReviewers(ctx, pr2.ID, []int{reviewer.ID, f.users[1].ID}))
+	require.NoError(t, r.AssignReviewers(ctx, pr1.ID, []int{reviewer.ID}))
+	require.NoError(t, r.AssignReviewers(ctx, pr3.ID, []int{f.users[2].ID}))
+
+	got, err := r.ListPRsAssignedToUser(ctx, reviewer.ID, repository.ReviewQueueFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, pr1.ID, got[0].ID, "PRs ordered by created_at, then id")
+	assert.Equal(t, pr2.ID, got[1].ID)
+	assert.Equal(t, []int{reviewer.ID}, ids(got[0].Reviewers))
+	assert.Equal(t, []int{reviewer.ID, f.users[1].ID}, ids(got[1].Reviewers), "full reviewer list is loaded")
+
+	assert.False(t, got[0].AssignedAt.IsZero())
+
+	none, err := r.ListPRsAssignedToUser(ctx, f.author.ID, repository.ReviewQueueFilter{})
+	require.NoError(t, err)
+	assert.NotNil(t, none)
+	assert.Empty(t, none)
+}
+
+func testListPRsByAuthor(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 2)
+
+	pr1 := createPR(t, r, f.author.ID, "first")
+	pr2 := createPR(t, r, f.author.ID, "second")
+	createPR(t, r, f.users[0].ID, "someone else's")
+	merged := createPR(t, r, f.author.ID, "merged")
+	require.NoError(t, r.SetPRStatus(ctx, merged.ID, string(models.PRStatusMerged)))
+	require.NoError(t, r.AssignReviewers(ctx, pr2.ID, []int{f.users[1].ID, f.users[0].ID}))
+
+	got, err := r.ListPRsByAuthor(ctx, f.author.ID, []models.PRStatus{models.PRStatusOpen})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, pr1.ID, got[0].ID, "oldest first")
+	assert.Equal(t, pr2.ID, got[1].ID)
+	assert.NotNil(t, got[0].Reviewers)
+	assert.Empty(t, got[0].Reviewers)
+	assert.Equal(t, []int{f.users[0].ID, f.users[1].ID}, ids(got[1].Reviewers))
+
+	all, err := r.ListPRsByAuthor(ctx, f.author.ID, nil)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	none, err := r.ListPRsByAuthor(ctx, f.author.ID+1000, nil)
+	require.NoError(t, err)
+	assert.NotNil(t, none)
+	assert.Empty(t, none)
+}
+
+func prIDs(prs []models.AssignedPR) []int {
+	out := make([]int, len(prs))
+	for i, p := range prs {
+		out[i] = p.ID
+	}
+	return out
+}
+
+// reviewQueue creates three PRs assigned to one reviewer in the order
+// pr3, pr1, pr2, so assigned_at and created_at orders differ. pr2 is merged.
+func reviewQueue(t *testing.T, r repository.Repository) (reviewer models.User, prs []models.PR) {
+	t.Helper()
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+
+	for i := range 3 {
+		prs = append(prs, createPR(t, r, f.author.ID, fmt.Sprintf("pr%d", i+1)))
+		time.Sleep(2 * time.Millisecond)
+	}
+	for _, i := range []int{2, 0, 1} {
+		require.NoError(t, r.AssignReviewers(ctx, prs[i].ID, []int{f.users[0].ID}))
+		time.Sleep(2 * time.Millisecond)
+	}
+	require.NoError(t, r.SetPRStatus(ctx, prs[1].ID, string(models.PRStatusMerged)))
+	return f.users[0], prs
+}
+
+func testReviewQueueFilter(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	reviewer, prs := reviewQueue(t, r)
+	pr1, pr2, pr3 := prs[0].ID, prs[1].ID, prs[2].ID
+
+	list := func(f repository.ReviewQueueFilter) []int {
+		t.Helper()
+		got, err := r.ListPRsAssignedToUser(ctx, reviewer.ID, f)
+		require.NoError(t, err)
+		return prIDs(got)
+	}
+
+	assert.Equal(t, []int{pr1, pr2, pr3}, list(repository.ReviewQueueFilter{SortBy: repository.SortByCreatedAt}))
+	assert.Equal(t, []int{pr3, pr2, pr1}, list(repository.ReviewQueueFilter{SortBy: repository.SortByCreatedAt, Desc: true}))
+	assert.Equal(t, []int{pr3, pr1, pr2}, list(repository.ReviewQueueFilter{SortBy: repository.SortByAssignedAt}))
+	assert.Equal(t, []int{pr2, pr1, pr3}, list(repository.ReviewQueueFilter{SortBy: repository.SortByAssignedAt, Desc: true}))
+
+	assert.Equal(t, []int{pr1, pr3}, list(repository.ReviewQueueFilter{Statuses: []models.PRStatus{models.PRStatusOpen}}))
+	assert.Equal(t, []int{pr2}, list(repository.ReviewQueueFilter{Statuses: []models.PRStatus{models.PRStatusMerged}}))
+	assert.Equal(t, []int{pr1, pr2, pr3}, list(repository.ReviewQueueFilter{Statuses: []models.PRStatus{models.PRStatusOpen, models.PRStatusMerged}}))
+
+	after, before := prs[0].CreatedAt, prs[2].CreatedAt
+	assert.Equal(t, []int{pr2, pr3}, list(repository.ReviewQueueFilter{CreatedAfter: &after}), "created_after is exclusive")
+	assert.Equal(t, []int{pr1, pr2}, list(repository.ReviewQueueFilter{CreatedBefore: &before}), "created_before is exclusive")
+	assert.Equal(t, []int{pr2}, list(repository.ReviewQueueFilter{CreatedAfter: &after, CreatedBefore: &before}))
+
+	assert.Equal(t, []int{pr1, pr2}, list(repository.ReviewQueueFilter{Limit: 2}))
+
+	future := time.Now().Add(time.Hour)
+	got, err := r.ListPRsAssignedToUser(ctx, reviewer.ID, repository.ReviewQueueFilter{CreatedAfter: &future})
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.Empty(t, got)
+}
+
+func testReviewQueuePagination(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	reviewer, prs := reviewQueue(t, r)
+
+	for _, sortBy := range []repository.ReviewQueueSort{repository.SortByCreatedAt, repository.SortByAssignedAt} {
+		for _, desc := range []bool{false, true} {
+			f := repository.ReviewQueueFilter{SortBy: sortBy, Desc: desc}
+			all, err := r.ListPRsAssignedToUser(ctx, reviewer.ID, f)
+			require.NoError(t, err)
+			require.Len(t, all, len(prs))
+
+			var walked []int
+			f.Limit = 1
+			for {
+				page, err := r.ListPRsAssignedToUser(ctx, reviewer.ID, f)
+				require.NoError(t, err)
+				if len(page) == 0 {
+					break
+				}
+				require.Len(t, page, 1)
+				walked = append(walked, page[0].ID)
+				key := f.SortKey(page[0])
+				f.After = &pagination.Cursor{Time: &key, ID: page[0].ID}
+			}
+			assert.Equal(t, prIDs(all), walked, "sort=%s desc=%v", sortBy, desc)
+		}
+	}
+}
+
+func testCountAssignments(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+
+	c, err := r.CountAssignments(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, c)
+
+	f := seed(t, r, "backend", 2)
+	pr := createPR(t, r, f.author.ID, "pr")
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, ids(f.users)))
+
+	c, err = r.CountAssignments(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c)
+}
+
+func testForEach(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 2)
+	g := seed(t, r, "frontend", 0)
+	pr1 := createPR(t, r, f.author.ID, "first")
+	pr2 := createPR(t, r, g.author.ID, "second")
+	require.NoError(t, r.AssignReviewers(ctx, pr2.ID, []int{f.users[1].ID}))
+	require.NoError(t, r.AssignReviewers(ctx, pr1.ID, []int{f.users[1].ID, f.users[0].ID}))
+
+	var teams []int
+	require.NoError(t, r.ForEachTeam(ctx, func(t models.Team) error {
+		teams = append(teams, t.ID)
+		return nil
+	}))
+	assert.Equal(t, []int{f.team.ID, g.team.ID}, teams)
+
+	var users []int
+	require.NoError(t, r.ForEachUser(ctx, func(u models.User) error {
+		users = append(users, u.ID)
+		return nil
+	}))
+	assert.Equal(t, []int{f.author.ID, f.users[0].ID, f.users[1].ID, g.author.ID}, users)
+
+	var prs []int
+	require.NoError(t, r.ForEachPR(ctx, func(p models.PR) error {
+		prs = append(prs, p.ID)
+		return nil
+	}))
+	assert.Equal(t, []int{pr1.ID, pr2.ID}, prs)
+
+	var assignments [][2]int
+	require.NoError(t, r.ForEachReviewerAssignment(ctx, func(a models.ReviewerAssignment) error {
+		assert.False(t, a.AssignedAt.IsZero())
+		assignments = append(assignments, [2]int{a.PRID, a.UserID})
+		return nil
+	}))
+	assert.Equal(t, [][2]int{{pr1.ID, f.users[0].ID}, {pr1.ID, f.users[1].ID}, {pr2.ID, f.users[1].ID}}, assignments,
+		"assignments ordered by PR then user")
+
+	stop := errors.New("stop")
+	calls := 0
+	err := r.ForEachUser(ctx, func(models.User) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop, "callback errors are returned as is")
+	assert.Equal(t, 1, calls)
+}
+
+func testForEachLinked(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	acme, err := r.CreateOrganization(ctx, "acme")
+	require.NoError(t, err)
+	acmeCtx := repository.WithOrg(ctx, acme.ID)
+
+	var prs []models.PR
+	var fixtures []fixture
+	for _, orgCtx := range []context.Context{ctx, acmeCtx} {
+		f := seedIn(t, orgCtx, r, "backend", 1)
+		pr := createPR(t, r, f.author.ID, "pr")
+		require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID}))
+		marked, err := r.MarkReviewSLAStage(ctx, pr.ID, f.users[0].ID, models.SLAReminded, now)
+		require.NoError(t, err)
+		require.True(t, marked)
+		require.NoError(t, r.LinkExternalPR(ctx, models.ExternalRef{PRID: pr.ID, Provider: "github", Repo: "acme/api", Number: pr.ID}))
+		_, err = r.SetIdentity(ctx, models.Identity{UserID: f.author.ID, Provider: "github", Login: fmt.Sprintf("author-%d", f.author.ID)})
+		require.NoError(t, err)
+		require.NoError(t, r.MarkReviewerSyncPending(ctx, pr.ID, now))
+		_, err = r.SetTeamChannel(ctx, models.TeamChannel{TeamID: f.team.ID, WebhookURL: "https://hooks.example.com/x"})
+		require.NoError(t, err)
+		prs, fixtures = append(prs, pr), append(fixtures, f)
+	}
+
+	var stages []*time.Time
+	require.NoError(t, r.ForEachReviewerAssignment(ctx, func(a models.ReviewerAssignment) error {
+		stages = append(stages, a.RemindedAt)
+		assert.Nil(t, a.EscalatedAt)
+		return nil
+	}))
+	require.Len(t, stages, 2)
+	require.NotNil(t, stages[0])
+	assert.True(t, now.Equal(*stages[0]), "assignments carry their SLA stages")
+
+	var refs []int
+	require.NoError(t, r.ForEachExternalRef(ctx, func(ref models.ExternalRef) error {
+		refs = append(refs, ref.PRID)
+		return nil
+	}))
+	assert.Equal(t, []int{prs[0].ID, prs[1].ID}, refs)
+
+	var identities []int
+	require.NoError(t, r.ForEachIdentity(ctx, func(id models.Identity) error {
+		assert.False(t, id.CreatedAt.IsZero())
+		identities = append(identities, id.UserID)
+		return nil
+	}))
+	assert.Equal(t, []int{fixtures[0].author.ID, fixtures[1].author.ID}, identities)
+
+	var syncs []int
+	require.NoError(t, r.ForEachReviewerSync(ctx, func(s models.ReviewerSync) error {
+		assert.Equal(t, models.ReviewerSyncPending, s.Status)
+		syncs = append(syncs, s.PRID)
+		return nil
+	}))
+	assert.Equal(t, []int{prs[0].ID, prs[1].ID}, syncs)
+
+	var channels []int
+	require.NoError(t, r.ForEachTeamChannel(ctx, func(c models.TeamChannel) error {
+		channels = append(channels, c.TeamID)
+		return nil
+	}))
+	assert.Equal(t, []int{fixtures[0].team.ID, fixtures[1].team.ID}, channels)
+
+	n := 0
+	count := func() error { n++; return nil }
+	require.NoError(t, r.ForEachExternalRef(acmeCtx, func(models.ExternalRef) error { return count() }))
+	require.NoError(t, r.ForEachIdentity(acmeCtx, func(models.Identity) error { return count() }))
+	require.NoError(t, r.ForEachReviewerSync(acmeCtx, func(models.ReviewerSync) error { return count() }))
+	require.NoError(t, r.ForEachTeamChannel(acmeCtx, func(models.TeamChannel) error { return count() }))
+	assert.Equal(t, 4, n, "a scoped context sees the rows of its organization only")
+}
+
+func testReadSnapshot(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+
+	err := r.ReadSnapshot(ctx, func(snap repository.Repository) error {
+		var before []int
+		require.NoError(t, snap.ForEachUser(ctx, func(u models.User) error {
+			before = append(before, u.ID)
+			return nil
+		}))
+
+		_, err := r.CreateUser(ctx, models.User{Name: "late", IsActive: true})
+		require.NoError(t, err)
+		createPR(t, r, f.author.ID, "late")
+
+		var after []int
+		require.NoError(t, snap.ForEachUser(ctx, func(u models.User) error {
+			after = append(after, u.ID)
+			return nil
+		}))
+		assert.Equal(t, before, after, "writes committed during the snapshot are not seen")
+		return snap.ForEachPR(ctx, func(models.PR) error {
+			t.Error("PR created during the snapshot is seen")
+			return nil
+		})
+	})
+	require.NoError(t, err)
+
+	n := 0
+	require.NoError(t, r.ForEachUser(ctx, func(models.User) error {
+		n++
+		return nil
+	}))
+	assert.Equal(t, 3, n)
+}
+
+func testRestore(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456000, time.UTC)
+
+	team := models.Team{ID: 40, Name: "restored", CreatedAt: ts}
+	require.NoError(t, r.RestoreTeam(ctx, team))
+	user := models.User{ID: 50, TeamID: &team.ID, Name: "restored user", IsActive: true, CreatedAt: ts.Add(time.Minute)}
+	require.NoError(t, r.RestoreUser(ctx, user))
+	deletedAt := ts.Add(5 * time.Minute)
+	reviewer := models.User{ID: 51, Name: "restored reviewer", IsActive: false, CreatedAt: ts.Add(2 * time.Minute), DeletedAt: &deletedAt}
+	require.NoError(t, r.RestoreUser(ctx, reviewer))
+	pr := models.PR{ID: 60, Title: "restored pr", AuthorID: user.ID, Status: models.PRStatusMerged, CreatedAt: ts.Add(3 * time.Minute)}
+	require.NoError(t, r.RestorePR(ctx, pr))
+	escalated := ts.Add(6 * time.Minute)
+	assignment := models.ReviewerAssignment{PRID: pr.ID, UserID: reviewer.ID, AssignedAt: ts.Add(4 * time.Minute), EscalatedAt: &escalated}
+	require.NoError(t, r.RestoreReviewerAssignment(ctx, assignment))
+
+	identity := models.Identity{UserID: user.ID, Provider: "github", Login: "restored", CreatedAt: ts}
+	require.NoError(t, r.RestoreIdentity(ctx, identity))
+	ref := models.ExternalRef{PRID: pr.ID, Provider: "github", Repo: "acme/api", Number: 3, URL: "https://github.com/acme/api/pull/3"}
+	require.NoError(t, r.LinkExternalPR(ctx, ref))
+	sync := models.ReviewerSync{PRID: pr.ID, Status: models.ReviewerSyncFailed, Attempts: 3, LastError: "502", Pushed: []string{"bob"}, LastAttemptAt: &deletedAt}
+	require.NoError(t, r.RestoreReviewerSync(ctx, sync))
+	channel := models.TeamChannel{TeamID: team.ID, WebhookURL: "https://hooks.example.com/x", Channel: "#restored", UpdatedAt: ts}
+	require.NoError(t, r.RestoreTeamChannel(ctx, channel))
+	sla := models.TeamSLA{TeamID: team.ID, RemindAfterHours: 4, EscalateAfterHours: 16, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC", LeadID: &user.ID, UpdatedAt: ts}
+	require.NoError(t, r.RestoreTeamSLA(ctx, sla))
+	prefs := models.DigestPrefs{UserID: user.ID, SendAt: "08:30", Timezone: "UTC", LastSentAt: &deletedAt, UpdatedAt: ts}
+	require.NoError(t, r.RestoreDigestPrefs(ctx, prefs))
+	require.NoError(t, r.SyncSequences(ctx))
+
+	gotIdentity, err := r.GetIdentityByLogin(ctx, "github", "restored")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, gotIdentity.UserID)
+	assert.True(t, ts.Equal(gotIdentity.CreatedAt))
+	gotRef, err := r.GetExternalRef(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ref, gotRef)
+	gotSync, err := r.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewerSyncFailed, gotSync.Status)
+	assert.Equal(t, []string{"bob"}, gotSync.Pushed)
+	require.NotNil(t, gotSync.LastAttemptAt)
+	assert.True(t, deletedAt.Equal(*gotSync.LastAttemptAt))
+	assert.Nil(t, gotSync.LockedUntil)
+	gotChannel, err := r.GetTeamChannel(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "#restored", gotChannel.Channel)
+	assert.True(t, ts.Equal(gotChannel.UpdatedAt))
+	gotSLA, err := r.GetTeamSLA(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 16, gotSLA.EscalateAfterHours)
+	assert.Equal(t, &user.ID, gotSLA.LeadID)
+	assert.True(t, ts.Equal(gotSLA.UpdatedAt))
+	gotPrefs, err := r.GetDigestPrefs(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "08:30", gotPrefs.SendAt)
+	require.NotNil(t, gotPrefs.LastSentAt)
+	assert.True(t, deletedAt.Equal(*gotPrefs.LastSentAt), "restored users do not get a second digest")
+	assert.ErrorIs(t, r.RestoreIdentity(ctx, identity), repository.ErrAlreadyExists)
+	assert.ErrorIs(t, r.RestoreTeamChannel(ctx, models.TeamChannel{TeamID: team.ID + 1000, WebhookURL: "x", UpdatedAt: ts}), repository.ErrInvalidReference)
+
+	gotTeam, err := r.GetTeamByID(ctx, team.ID)
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(gotTeam.CreatedAt), "timestamps are preserved")
+
+	gotUser, err := r.GetUserByID(ctx, reviewer.ID)
+	require.NoError(t, err)
+	assert.False(t, gotUser.IsActive)
+	assert.Nil(t, gotUser.TeamID)
+	require.NotNil(t, gotUser.DeletedAt)
+	assert.True(t, deletedAt.Equal(*gotUser.DeletedAt))
+	assert.Nil(t, gotTeam.DeletedAt)
+
+	gotPR, err := r.GetPRByID(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PRStatusMerged, gotPR.Status)
+	assert.True(t, pr.CreatedAt.Equal(gotPR.CreatedAt))
+
+	require.NoError(t, r.ForEachReviewerAssignment(ctx, func(a models.ReviewerAssignment) error {
+		assert.Equal(t, assignment.PRID, a.PRID)
+		assert.Equal(t, assignment.UserID, a.UserID)
+		assert.True(t, assignment.AssignedAt.Equal(a.AssignedAt))
+		assert.Nil(t, a.RemindedAt)
+		require.NotNil(t, a.EscalatedAt)
+		assert.True(t, escalated.Equal(*a.EscalatedAt), "SLA stages are preserved")
+		return nil
+	}))
+
+	// regular inserts continue after the restored IDs
+	newTeam, err := r.CreateTeam(ctx, "fresh")
+	require.NoError(t, err)
+	assert.Greater(t, newTeam.ID, team.ID)
+	newUser, err := r.CreateUser(ctx, models.User{Name: "fresh", IsActive: true})
+	require.NoError(t, err)
+	assert.Greater(t, newUser.ID, reviewer.ID)
+	newPR := createPR(t, r, newUser.ID, "fresh")
+	assert.Greater(t, newPR.ID, pr.ID)
+
+	assert.ErrorIs(t, r.RestoreTeam(ctx, team), repository.ErrAlreadyExists)
+	assert.ErrorIs(t, r.RestorePR(ctx, models.PR{ID: 99, Title: "x", AuthorID: 9999, Status: models.PRStatusOpen, CreatedAt: ts}),
+		repository.ErrInvalidReference)
+}
+
+func testIdempotencyKeys(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	created := time.Now().Add(-time.Minute)
+
+	_, err := r.GetIdempotencyKey(ctx, "k1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	require.NoError(t, r.CreateIdempotencyKey(ctx, models.IdempotencyRecord{Key: "k1", Fingerprint: "fp1", CreatedAt: created}))
+	err = r.CreateIdempotencyKey(ctx, models.IdempotencyRecord{Key: "k1", Fingerprint: "other", CreatedAt: created})
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+	rec, err := r.GetIdempotencyKey(ctx, "k1")
+	require.NoError(t, err)
+	assert.Equal(t, "fp1", rec.Fingerprint)
+	assert.Nil(t, rec.CompletedAt, "new keys are pending")
+	assert.WithinDuration(t, created, rec.CreatedAt, time.Millisecond)
+
+	body := []byte(`{"id":1}`)
+	require.NoError(t, r.CompleteIdempotencyKey(ctx, "k1", 201, body))
+	rec, err = r.GetIdempotencyKey(ctx, "k1")
+	require.NoError(t, err)
+	assert.Equal(t, 201, rec.StatusCode)
+	assert.Equal(t, body, rec.ResponseBody)
+	assert.NotNil(t, rec.CompletedAt)
+
+	require.NoError(t, r.CreateIdempotencyKey(ctx, models.IdempotencyRecord{Key: "old", Fingerprint: "fp", CreatedAt: created.Add(-time.Hour)}))
+	n, err := r.DeleteIdempotencyKeysBefore(ctx, created.Add(-time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = r.GetIdempotencyKey(ctx, "old")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	require.NoError(t, r.DeleteIdempotencyKey(ctx, "k1"))
+	_, err = r.GetIdempotencyKey(ctx, "k1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	require.NoError(t, r.DeleteIdempotencyKey(ctx, "k1"), "deleting a missing key is not an error")
+}
+
+func testWebhooks(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+
+	_, err := r.GetWebhook(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	w, err := r.CreateWebhook(ctx, models.Webhook{URL: "http://a", Secret: "s", Events: []string{"pr.created", "pr.merged"}})
+	require.NoError(t, err)
+	assert.NotZero(t, w.ID)
+	assert.False(t, w.CreatedAt.IsZero())
+	all, err := r.CreateWebhook(ctx, models.Webhook{URL: "http://b", Secret: "s"})
+	require.NoError(t, err)
+	assert.Empty(t, all.Events)
+
+	got, err := r.GetWebhook(ctx, w.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "http://a", got.URL)
+	assert.Equal(t, "s", got.Secret)
+	assert.Equal(t, []string{"pr.created", "pr.merged"}, got.Events)
+
+	list, err := r.ListWebhooks(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, w.ID, list[0].ID)
+	assert.Equal(t, all.ID, list[1].ID)
+
+	require.NoError(t, r.DeleteWebhook(ctx, w.ID))
+	assert.ErrorIs(t, r.DeleteWebhook(ctx, w.ID), repository.ErrNotFound)
+	_, err = r.GetWebhook(ctx, w.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func testWebhookDeliveries(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	w, err := r.CreateWebhook(ctx, models.Webhook{URL: "http://a", Secret: "s"})
+	require.NoError(t, err)
+
+	_, err = r.CreateWebhookDelivery(ctx, models.WebhookDelivery{WebhookID: w.ID + 1000, EventID: "e", EventType: "pr.created", Payload: []byte(`{}`), Status: models.DeliveryPending})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	base := time.Now().UTC().Truncate(time.Second)
+	var created []models.WebhookDelivery
+	for i, at := range []time.Time{base.Add(2 * time.Second), base, base.Add(time.Hour)} {
+		next := at
+		d, err := r.CreateWebhookDelivery(ctx, models.WebhookDelivery{
+			WebhookID:     w.ID,
+			EventID:       fmt.Sprintf("e%d", i),
+			EventType:     "pr.created",
+			Payload:       []byte(`{"pr_id":1}`),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &next,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, `{"pr_id":1}`, string(d.Payload))
+		created = append(created, d)
+	}
+
+	due, err := r.ListDueWebhookDeliveries(ctx, base.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, created[1].ID, due[0].ID, "ordered by next attempt")
+	assert.Equal(t, created[0].ID, due[1].ID)
+
+	due, err = r.ListDueWebhookDeliveries(ctx, base.Add(time.Minute), 1)
+	require.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	d := created[1]
+	at := base.Add(time.Second)
+	code := 200
+	d.Status = models.DeliverySucceeded
+	d.Attempts = 1
+	d.ResponseCode = &code
+	d.LastAttemptAt = &at
+	d.NextAttemptAt = nil
+	require.NoError(t, r.UpdateWebhookDelivery(ctx, d))
+	missing := d
+	missing.ID += 1000
+	assert.ErrorIs(t, r.UpdateWebhookDelivery(ctx, missing), repository.ErrNotFound)
+
+	due, err = r.ListDueWebhookDeliveries(ctx, base.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, created[0].ID, due[0].ID, "delivered rows are no longer due")
+
+	log, err := r.ListWebhookDeliveries(ctx, w.ID, repository.DeliveryFilter{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, log, 2)
+	assert.Equal(t, created[2].ID, log[0].ID, "newest first")
+	assert.Equal(t, created[1].ID, log[1].ID)
+	assert.Equal(t, models.DeliverySucceeded, log[1].Status)
+	assert.Equal(t, 1, log[1].Attempts)
+	require.NotNil(t, log[1].ResponseCode)
+	assert.Equal(t, 200, *log[1].ResponseCode)
+	require.NotNil(t, log[1].LastAttemptAt)
+	assert.True(t, at.Equal(*log[1].LastAttemptAt))
+	assert.Nil(t, log[1].NextAttemptAt)
+
+	log, err = r.ListWebhookDeliveries(ctx, w.ID, repository.DeliveryFilter{After: &pagination.Cursor{ID: created[1].ID}, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, log, 1)
+	assert.Equal(t, created[0].ID, log[0].ID)
+
+	require.NoError(t, r.DeleteWebhook(ctx, w.ID))
+	log, err = r.ListWebhookDeliveries(ctx, w.ID, repository.DeliveryFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, log, "deliveries are removed with their webhook")
+}
+
+func testWithTxCommits(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 2)
+
+	var pr models.PR
+	err := r.WithTx(ctx, func(tx repository.Repository) error {
+		var err error
+		if pr, err = tx.CreatePR(ctx, models.PR{Title: "tx", AuthorID: f.author.ID, Status: models.PRStatusOpen}); err != nil {
+			return err
+		}
+		if err := tx.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID}); err != nil {
+			return err
+		}
+		if err := tx.ReplaceReviewer(ctx, pr.ID, f.users[0].ID, f.users[1].ID); err != nil {
+			return err
+		}
+		got, err := tx.GetReviewersByPR(ctx, pr.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []int{f.users[1].ID}, ids(got), "writes are visible inside the transaction")
+		return tx.CreateOutboxEvent(ctx, models.OutboxEvent{EventID: "e1", EventType: "pr.created", Payload: []byte(`{}`)})
+	})
+	require.NoError(t, err)
+
+	got, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{f.users[1].ID}, ids(got))
+	claimed, err := r.ClaimOutboxEvents(ctx, time.Now().Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 1)
+}
+
+func testWithTxRollsBack(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+	boom := errors.New("boom")
+
+	err := r.WithTx(ctx, func(tx repository.Repository) error {
+		pr, err := tx.CreatePR(ctx, models.PR{Title: "tx", AuthorID: f.author.ID, Status: models.PRStatusOpen})
+		require.NoError(t, err)
+		require.NoError(t, tx.AssignReviewers(ctx, pr.ID, []int{f.users[0].ID}))
+		require.NoError(t, tx.CreateOutboxEvent(ctx, models.OutboxEvent{EventID: "e1", EventType: "pr.created", Payload: []byte(`{}`)}))
+		_, err = tx.CreateTeam(ctx, "frontend")
+		require.NoError(t, err)
+		return boom
+	})
+	assert.ErrorIs(t, err, boom)
+
+	n, err := r.CountAssignments(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	_, err = r.GetTeamByName(ctx, "frontend")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	claimed, err := r.ClaimOutboxEvents(ctx, time.Now().Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "outbox writes roll back with the state change")
+
+	_, err = r.CreateTeam(ctx, "frontend")
+	assert.NoError(t, err, "the repository is usable after a rollback")
+}
+
+func testOutbox(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	require.NoError(t, r.CreateOutboxEvent(ctx, models.OutboxEvent{EventID: "e1", EventType: "pr.created", Payload: []byte(`{"a":1}`)}))
+	require.NoError(t, r.CreateOutboxEvent(ctx, models.OutboxEvent{EventID: "e2", EventType: "pr.merged", Payload: []byte(`{}`), NextAttemptAt: now.Add(time.Hour)}))
+
+	claimed, err := r.ClaimOutboxEvents(ctx, now.Add(time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "events scheduled later are not due")
+	e := claimed[0]
+	assert.Equal(t, "e1", e.EventID)
+	assert.Equal(t, "pr.created", e.EventType)
+	assert.Equal(t, `{"a":1}`, string(e.Payload))
+	require.NotNil(t, e.LockedUntil)
+	assert.True(t, now.Add(2*time.Minute).Equal(*e.LockedUntil))
+
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "leased events are not claimed twice")
+
+	e.Attempts = 1
+	e.LastError = "receiver down"
+	e.NextAttemptAt = now.Add(3 * time.Minute)
+	require.NoError(t, r.RescheduleOutboxEvent(ctx, e))
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "rescheduled events wait for their next attempt")
+
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(3*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, 1, claimed[0].Attempts)
+	assert.Equal(t, "receiver down", claimed[0].LastError)
+
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(10*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "an expired lease can be claimed again")
+
+	require.NoError(t, r.MarkOutboxEventDelivered(ctx, e.ID, now.Add(10*time.Minute)))
+	assert.ErrorIs(t, r.MarkOutboxEventDelivered(ctx, e.ID+1000, now), repository.ErrNotFound)
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(20*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "delivered events are never claimed")
+
+	n, err := r.DeleteDeliveredOutboxEvents(ctx, now.Add(10*time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	n, err = r.DeleteDeliveredOutboxEvents(ctx, now.Add(11*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(2*time.Hour), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "undelivered events survive cleanup")
+	assert.Equal(t, "e2", claimed[0].EventID)
+}
+
+func testOutboxOrdering(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+	for _, e := range []struct{ id, key string }{
+		{"a1", "pr:1"}, {"b1", "pr:2"}, {"a2", "pr:1"}, {"u1", ""}, {"u2", ""}, {"a3", "pr:1"},
+	} {
+		require.NoError(t, r.CreateOutboxEvent(ctx, models.OutboxEvent{EventID: e.id, EventType: "t", OrderingKey: e.key, Payload: []byte(`{}`)}))
+	}
+	eventIDs := func(es []models.OutboxEvent) []string {
+		out := make([]string, len(es))
+		for i, e := range es {
+			out[i] = e.EventID
+		}
+		return out
+	}
+
+	claimed, err := r.ClaimOutboxEvents(ctx, now.Add(time.Minute), time.Hour, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a1", "b1", "u1", "u2"}, eventIDs(claimed), "only the head of each key, events without a key are unordered")
+
+	byID := map[string]models.OutboxEvent{}
+	for _, e := range claimed {
+		byID[e.EventID] = e
+	}
+	failed := byID["a1"]
+	failed.Attempts = 1
+	failed.NextAttemptAt = now.Add(time.Hour)
+	require.NoError(t, r.RescheduleOutboxEvent(ctx, failed))
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(time.Minute), time.Hour, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "a failed head blocks the rest of its key")
+
+	require.NoError(t, r.MarkOutboxEventDelivered(ctx, failed.ID, now))
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(time.Minute), time.Hour, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a2"}, eventIDs(claimed))
+
+	dead := claimed[0]
+	dead.Attempts = 5
+	dead.LastError = "gone"
+	require.NoError(t, r.DeadLetterOutboxEvent(ctx, dead, now))
+	claimed, err = r.ClaimOutboxEvents(ctx, now.Add(time.Minute), time.Hour, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a3"}, eventIDs(claimed), "a dead event is skipped and unblocks its key")
+	assert.ErrorIs(t, r.DeadLetterOutboxEvent(ctx, models.OutboxEvent{ID: 9999}, now), repository.ErrNotFound)
+}
+
+func testIdentities(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+	alice, bob := f.author, f.users[0]
+
+	created, err := r.SetIdentity(ctx, models.Identity{UserID: alice.ID, Provider: "github", Login: "alice"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", created.Login)
+	assert.False(t, created.CreatedAt.IsZero())
+	_, err = r.SetIdentity(ctx, models.Identity{UserID: alice.ID, Provider: "gitlab", Login: "alice"})
+	require.NoError(t, err, "logins are scoped by provider")
+
+	got, err := r.GetIdentityByLogin(ctx, "github", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, got.UserID)
+
+	_, err = r.SetIdentity(ctx, models.Identity{UserID: bob.ID, Provider: "github", Login: "alice"})
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+	_, err = r.SetIdentity(ctx, models.Identity{UserID: alice.ID + 1000, Provider: "github", Login: "ghost"})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	_, err = r.SetIdentity(ctx, models.Identity{UserID: alice.ID, Provider: "github", Login: "alice-renamed"})
+	require.NoError(t, err)
+	_, err = r.GetIdentityByLogin(ctx, "github", "alice")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "setting a new login replaces the old one")
+	_, err = r.SetIdentity(ctx, models.Identity{UserID: bob.ID, Provider: "github", Login: "alice"})
+	require.NoError(t, err, "the old login is free again")
+
+	list, err := r.ListIdentities(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "github", list[0].Provider)
+	assert.Equal(t, "alice-renamed", list[0].Login)
+	assert.Equal(t, "gitlab", list[1].Provider)
+
+	require.NoError(t, r.DeleteIdentity(ctx, alice.ID, "gitlab"))
+	assert.ErrorIs(t, r.DeleteIdentity(ctx, alice.ID, "gitlab"), repository.ErrNotFound)
+	list, err = r.ListIdentities(ctx, alice.ID)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func testExternalRefs(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 0)
+	pr := createPR(t, r, f.author.ID, "pr")
+	other := createPR(t, r, f.author.ID, "other")
+
+	ref := models.ExternalRef{PRID: pr.ID, Provider: "github", Repo: "acme/api", Number: 7, URL: "https://github.com/acme/api/pull/7"}
+	require.NoError(t, r.LinkExternalPR(ctx, ref))
+
+	got, err := r.GetPRByExternalRef(ctx, "github", "acme/api", 7)
+	require.NoError(t, err)
+	assert.Equal(t, pr.ID, got.ID)
+	gotRef, err := r.GetExternalRef(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ref, gotRef)
+
+	dup := ref
+	dup.PRID = other.ID
+	assert.ErrorIs(t, r.LinkExternalPR(ctx, dup), repository.ErrAlreadyExists, "a pull request mirrors one PR")
+	second := ref
+	second.Number = 8
+	assert.ErrorIs(t, r.LinkExternalPR(ctx, second), repository.ErrAlreadyExists, "a PR mirrors one pull request")
+
+	_, err = r.GetPRByExternalRef(ctx, "github", "acme/web", 7)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.GetExternalRef(ctx, other.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, r.LinkExternalPR(ctx, models.ExternalRef{PRID: other.ID + 1000, Provider: "github", Repo: "acme/api", Number: 9}), repository.ErrInvalidReference)
+}
+
+func testReviewerSync(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 0)
+	pr := createPR(t, r, f.author.ID, "pr")
+	other := createPR(t, r, f.author.ID, "other")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	_, err := r.GetReviewerSync(ctx, pr.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, r.MarkReviewerSyncPending(ctx, pr.ID+1000, now), repository.ErrInvalidReference)
+
+	require.NoError(t, r.MarkReviewerSyncPending(ctx, pr.ID, now))
+	require.NoError(t, r.MarkReviewerSyncPending(ctx, other.ID, now.Add(time.Hour)))
+
+	claimed, err := r.ClaimReviewerSyncs(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1, "only due syncs are claimed")
+	s := claimed[0]
+	assert.Equal(t, pr.ID, s.PRID)
+	assert.Equal(t, models.ReviewerSyncPending, s.Status)
+	assert.Empty(t, s.Pushed)
+
+	claimed, err = r.ClaimReviewerSyncs(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed, "claimed syncs are leased")
+
+	// the reviewers change while the attempt runs
+	require.NoError(t, r.MarkReviewerSyncPending(ctx, pr.ID, now))
+	at := now.Add(time.Second)
+	s.Status = models.ReviewerSyncSynced
+	s.Attempts = 1
+	s.Pushed = []string{"alice", "bob"}
+	s.LastAttemptAt = &at
+	s.SyncedAt = &at
+	s.NextAttemptAt = nil
+	require.NoError(t, r.CompleteReviewerSync(ctx, s))
+
+	got, err := r.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewerSyncPending, got.Status, "a stale attempt does not settle the status")
+	assert.Equal(t, []string{"alice", "bob"}, got.Pushed, "but records what it pushed")
+	assert.Nil(t, got.SyncedAt)
+	assert.Nil(t, got.LockedUntil)
+
+	claimed, err = r.ClaimReviewerSyncs(ctx, now.Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	s = claimed[0]
+	s.Status = models.ReviewerSyncSynced
+	s.Pushed = []string{"alice"}
+	s.LastError = ""
+	s.SyncedAt = &at
+	s.NextAttemptAt = nil
+	require.NoError(t, r.CompleteReviewerSync(ctx, s))
+
+	got, err = r.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewerSyncSynced, got.Status)
+	assert.Equal(t, []string{"alice"}, got.Pushed)
+	require.NotNil(t, got.SyncedAt)
+	assert.True(t, at.Equal(*got.SyncedAt))
+	assert.Nil(t, got.NextAttemptAt)
+
+	s.PRID = pr.ID + 1000
+	assert.ErrorIs(t, r.CompleteReviewerSync(ctx, s), repository.ErrNotFound)
+}
+
+func testTeamChannels(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 0)
+
+	_, err := r.GetTeamChannel(ctx, f.team.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.SetTeamChannel(ctx, models.TeamChannel{TeamID: f.team.ID + 1000, WebhookURL: "https://chat.example.com/hooks/x"})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	set, err := r.SetTeamChannel(ctx, models.TeamChannel{TeamID: f.team.ID, WebhookURL: "https://chat.example.com/hooks/a"})
+	require.NoError(t, err)
+	assert.Equal(t, f.team.ID, set.TeamID)
+	assert.False(t, set.UpdatedAt.IsZero())
+
+	_, err = r.SetTeamChannel(ctx, models.TeamChannel{TeamID: f.team.ID, WebhookURL: "https://chat.example.com/hooks/b", Channel: "backend-reviews"})
+	require.NoError(t, err)
+	got, err := r.GetTeamChannel(ctx, f.team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://chat.example.com/hooks/b", got.WebhookURL, "setting replaces")
+	assert.Equal(t, "backend-reviews", got.Channel)
+
+	require.NoError(t, r.DeleteTeamChannel(ctx, f.team.ID))
+	assert.ErrorIs(t, r.DeleteTeamChannel(ctx, f.team.ID), repository.ErrNotFound)
+
+	_, err = r.SetTeamChannel(ctx, models.TeamChannel{TeamID: f.team.ID, WebhookURL: "https://chat.example.com/hooks/a"})
+	require.NoError(t, err)
+	require.NoError(t, r.PurgeTeam(ctx, f.team.ID))
+	_, err = r.GetTeamChannel(ctx, f.team.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "purging a team drops its channel")
+}
+
+func testTeamSLAs(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+	other := seed(t, r, "frontend", 0)
+	sla := models.TeamSLA{TeamID: f.team.ID, RemindAfterHours: 4, ReassignAfterHours: 8, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC"}
+
+	_, err := r.GetTeamSLA(ctx, f.team.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.SetTeamSLA(ctx, models.TeamSLA{TeamID: f.team.ID + 1000, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC"})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+	bad := sla
+	bad.LeadID = new(int)
+	*bad.LeadID = f.users[0].ID + 1000
+	_, err = r.SetTeamSLA(ctx, bad)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	set, err := r.SetTeamSLA(ctx, sla)
+	require.NoError(t, err)
+	assert.Equal(t, 8, set.ReassignAfterHours)
+	assert.Nil(t, set.LeadID)
+	assert.False(t, set.UpdatedAt.IsZero())
+
+	sla.EscalateAfterHours = 16
+	sla.Timezone = "Europe/Berlin"
+	sla.LeadID = &f.author.ID
+	_, err = r.SetTeamSLA(ctx, sla)
+	require.NoError(t, err)
+	got, err := r.GetTeamSLA(ctx, f.team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 16, got.EscalateAfterHours, "setting replaces")
+	assert.Equal(t, "Europe/Berlin", got.Timezone)
+	require.NotNil(t, got.LeadID)
+	assert.Equal(t, f.author.ID, *got.LeadID)
+
+	_, err = r.SetTeamSLA(ctx, models.TeamSLA{TeamID: other.team.ID, RemindAfterHours: 2, WorkdayStart: "08:00", WorkdayEnd: "16:00", Timezone: "UTC"})
+	require.NoError(t, err)
+	all, err := r.ListTeamSLAs(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, f.team.ID, all[0].TeamID)
+	assert.Equal(t, other.team.ID, all[1].TeamID)
+
+	require.NoError(t, r.DeleteTeamSLA(ctx, other.team.ID))
+	assert.ErrorIs(t, r.DeleteTeamSLA(ctx, other.team.ID), repository.ErrNotFound)
+
+	require.NoError(t, r.PurgeTeam(ctx, f.team.ID))
+	_, err = r.GetTeamSLA(ctx, f.team.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "purging a team drops its SLA")
+}
+
+func testPendingReviews(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 3)
+	other := seed(t, r, "frontend", 1)
+	first := createPR(t, r, f.author.ID, "first")
+	second := createPR(t, r, f.author.ID, "second")
+	merged := createPR(t, r, f.author.ID, "merged")
+	require.NoError(t, r.AssignReviewers(ctx, first.ID, []int{f.users[0].ID, other.users[0].ID}))
+	time.Sleep(time.Millisecond)
+	require.NoError(t, r.AssignReviewers(ctx, second.ID, []int{f.users[1].ID}))
+	require.NoError(t, r.AssignReviewers(ctx, merged.ID, []int{f.users[0].ID}))
+	require.NoError(t, r.SetPRStatus(ctx, merged.ID, string(models.PRStatusMerged)))
+
+	pending, err := r.ListPendingReviews(ctx, f.team.ID)
+	require.NoError(t, err)
+	require.Len(t, pending, 2, "only open PRs and reviewers in the team")
+	assert.Equal(t, first.ID, pending[0].PRID, "oldest assignment first")
+	assert.Equal(t, f.users[0].ID, pending[0].UserID)
+	assert.Equal(t, second.ID, pending[1].PRID)
+	assert.False(t, pending[0].AssignedAt.IsZero())
+	assert.Nil(t, pending[0].RemindedAt)
+	assert.Nil(t, pending[0].EscalatedAt)
+
+	at := time.Now().UTC().Truncate(time.Second)
+	ok, err := r.MarkReviewSLAStage(ctx, first.ID, f.users[0].ID, models.SLAReminded, at)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = r.MarkReviewSLAStage(ctx, first.ID, f.users[0].ID, models.SLAReminded, at.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, ok, "a stage is reached once")
+	_, err = r.MarkReviewSLAStage(ctx, first.ID, f.users[2].ID, models.SLAReminded, at)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	pending, err = r.ListPendingReviews(ctx, f.team.ID)
+	require.NoError(t, err)
+	require.NotNil(t, pending[0].RemindedAt)
+	assert.True(t, at.Equal(*pending[0].RemindedAt))
+	assert.Nil(t, pending[0].EscalatedAt)
+
+	ok, err = r.MarkReviewSLAStage(ctx, first.ID, f.users[0].ID, models.SLAReassignFailed, at)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	pending, err = r.ListPendingReviews(ctx, f.team.ID)
+	require.NoError(t, err)
+	require.NotNil(t, pending[0].ReassignFailedAt)
+	assert.True(t, at.Equal(*pending[0].ReassignFailedAt))
+
+	require.NoError(t, r.ReplaceReviewer(ctx, first.ID, f.users[0].ID, f.users[2].ID))
+	require.NoError(t, r.ReplaceReviewer(ctx, first.ID, f.users[2].ID, f.users[0].ID))
+	pending, err = r.ListPendingReviews(ctx, f.team.ID)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, first.ID, pending[1].PRID, "a replacement is a new assignment")
+	assert.Nil(t, pending[1].RemindedAt, "and starts with no stages")
+	assert.Nil(t, pending[1].ReassignFailedAt)
+}
+
+func testChatMessages(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 0)
+	now := time.Now().UTC().Truncate(time.Second)
+	later := now.Add(time.Hour)
+
+	msg := models.ChatMessage{EventID: "evt-1", EventType: "pr.merged", TeamID: f.team.ID, Text: "merged", Status: models.DeliveryPending, NextAttemptAt: &now}
+	created, err := r.CreateChatMessage(ctx, msg)
+	require.NoError(t, err)
+	assert.NotZero(t, created.ID)
+	assert.Equal(t, "merged", created.Text)
+	_, err = r.CreateChatMessage(ctx, msg)
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists, "one message per event and team")
+
+	_, err = r.CreateChatMessage(ctx, models.ChatMessage{EventID: "evt-2", EventType: "pr.merged", TeamID: f.team.ID, Text: "later", Status: models.DeliveryPending, NextAttemptAt: &later})
+	require.NoError(t, err)
+	msg.TeamID = f.team.ID + 1000
+	_, err = r.CreateChatMessage(ctx, msg)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	due, err := r.ListDueChatMessages(ctx, now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, created.ID, due[0].ID)
+
+	created.Status = models.DeliverySucceeded
+	created.Attempts = 1
+	created.LastAttemptAt = &now
+	created.NextAttemptAt = nil
+	require.NoError(t, r.UpdateChatMessage(ctx, created))
+	due, err = r.ListDueChatMessages(ctx, later, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1, "sent messages are no longer due")
+	assert.Equal(t, "later", due[0].Text)
+
+	created.ID += 1000
+	assert.ErrorIs(t, r.UpdateChatMessage(ctx, created), repository.ErrNotFound)
+}
+
+func testDigestPrefs(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+
+	_, err := r.GetDigestPrefs(ctx, f.author.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.SetDigestPrefs(ctx, models.DigestPrefs{UserID: f.author.ID + 1000})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	set, err := r.SetDigestPrefs(ctx, models.DigestPrefs{UserID: f.author.ID, SendAt: "08:30", Timezone: "Europe/Berlin"})
+	require.NoError(t, err)
+	assert.Equal(t, "08:30", set.SendAt)
+	assert.False(t, set.UpdatedAt.IsZero())
+	assert.Nil(t, set.LastSentAt)
+
+	at := time.Now().UTC().Truncate(time.Second)
+	claimed, err := r.ClaimDigest(ctx, f.author.ID, at, at)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	_, err = r.SetDigestPrefs(ctx, models.DigestPrefs{UserID: f.author.ID, OptOut: true})
+	require.NoError(t, err)
+	got, err := r.GetDigestPrefs(ctx, f.author.ID)
+	require.NoError(t, err)
+	assert.True(t, got.OptOut)
+	assert.Empty(t, got.SendAt, "setting replaces")
+	assert.Empty(t, got.Timezone)
+	require.NotNil(t, got.LastSentAt, "setting keeps the last send")
+	assert.True(t, at.Equal(*got.LastSentAt))
+
+	_, err = r.SetDigestPrefs(ctx, models.DigestPrefs{UserID: f.users[0].ID})
+	require.NoError(t, err)
+	all, err := r.ListDigestPrefs(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, f.author.ID, all[0].UserID)
+	assert.Equal(t, f.users[0].ID, all[1].UserID)
+
+	require.NoError(t, r.PurgeUser(ctx, f.author.ID))
+	_, err = r.GetDigestPrefs(ctx, f.author.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "purging a user drops their preferences")
+}
+
+func testClaimDigest(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 0)
+	due := time.Now().UTC().Truncate(time.Second)
+	sent := due.Add(time.Minute)
+
+	_, err := r.ClaimDigest(ctx, f.author.ID+1000, due, sent)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	claimed, err := r.ClaimDigest(ctx, f.author.ID, due, sent)
+	require.NoError(t, err)
+	assert.True(t, claimed, "a user without preferences can be claimed")
+	got, err := r.GetDigestPrefs(ctx, f.author.ID)
+	require.NoError(t, err)
+	assert.False(t, got.OptOut)
+	require.NotNil(t, got.LastSentAt)
+	assert.True(t, sent.Equal(*got.LastSentAt))
+
+	claimed, err = r.ClaimDigest(ctx, f.author.ID, due, sent.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed, "the same digest is claimed once")
+
+	tomorrow := due.Add(24 * time.Hour)
+	claimed, err = r.ClaimDigest(ctx, f.author.ID, tomorrow, tomorrow)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+}
+
+func testAPITokens(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 0)
+
+	admin, err := r.CreateAPIToken(ctx, models.APIToken{Name: "ops", Role: models.RoleAdmin, Hash: "h1"})
+	require.NoError(t, err)
+	assert.NotZero(t, admin.ID)
+	assert.Nil(t, admin.UserID)
+	assert.False(t, admin.CreatedAt.IsZero())
+	assert.Nil(t, admin.RevokedAt)
+
+	own, err := r.CreateAPIToken(ctx, models.APIToken{Name: "laptop", Role: models.RoleUser, UserID: &f.author.ID, Hash: "h2"})
+	require.NoError(t, err)
+	require.NotNil(t, own.UserID)
+	assert.Equal(t, f.author.ID, *own.UserID)
+
+	_, err = r.CreateAPIToken(ctx, models.APIToken{Name: "dup", Role: models.RoleUser, Hash: "h1"})
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+	missing := f.author.ID + 1000
+	_, err = r.CreateAPIToken(ctx, models.APIToken{Name: "ghost", Role: models.RoleUser, UserID: &missing, Hash: "h3"})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	got, err := r.GetAPITokenByHash(ctx, "h2")
+	require.NoError(t, err)
+	assert.Equal(t, own.ID, got.ID)
+	assert.Equal(t, models.RoleUser, got.Role)
+	assert.Equal(t, "laptop", got.Name)
+	_, err = r.GetAPITokenByHash(ctx, "nope")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	at := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, r.RevokeAPIToken(ctx, admin.ID, at))
+	assert.ErrorIs(t, r.RevokeAPIToken(ctx, admin.ID, at), repository.ErrNotFound, "a token is revoked once")
+	assert.ErrorIs(t, r.RevokeAPIToken(ctx, own.ID+1000, at), repository.ErrNotFound)
+	got, err = r.GetAPITokenByHash(ctx, "h1")
+	require.NoError(t, err)
+	require.NotNil(t, got.RevokedAt)
+	assert.True(t, at.Equal(*got.RevokedAt))
+
+	all, err := r.ListAPITokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, admin.ID, all[0].ID)
+	assert.Equal(t, own.ID, all[1].ID)
+
+	require.NoError(t, r.PurgeUser(ctx, f.author.ID))
+	_, err = r.GetAPITokenByHash(ctx, "h2")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "purging a user drops their tokens")
+}
+
+func testOrganizations(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+
+	def, err := r.GetOrganization(ctx, models.DefaultOrgID)
+	require.NoError(t, err, "the default organization always exists")
+	assert.Equal(t, "default", def.Name)
+
+	acme, err := r.CreateOrganization(ctx, "acme")
+	require.NoError(t, err)
+	assert.NotEqual(t, models.DefaultOrgID, acme.ID)
+	assert.False(t, acme.CreatedAt.IsZero())
+	_, err = r.CreateOrganization(ctx, "acme")
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+	got, err := r.GetOrganizationByName(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, got.ID)
+	_, err = r.GetOrganizationByName(ctx, "nope")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.GetOrganization(ctx, acme.ID+1000)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	all, err := r.ListOrganizations(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+	assert.Equal(t, models.DefaultOrgID, all[0].ID)
+	assert.Equal(t, acme.ID, all[1].ID)
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, r.RestoreOrganization(ctx, models.Organization{ID: models.DefaultOrgID, Name: "main", CreatedAt: at}))
+	require.NoError(t, r.RestoreOrganization(ctx, models.Organization{ID: acme.ID + 10, Name: "globex", CreatedAt: at}))
+	require.NoError(t, r.SyncSequences(ctx))
+	got, err = r.GetOrganization(ctx, models.DefaultOrgID)
+	require.NoError(t, err)
+	assert.Equal(t, "main", got.Name)
+	assert.True(t, at.Equal(got.CreatedAt))
+	next, err := r.CreateOrganization(ctx, "initech")
+	require.NoError(t, err)
+	assert.Greater(t, next.ID, acme.ID+10, "restored ids are not handed out again")
+}
+
+// testOrgIsolation checks that a context scoped to one organization can
+// neither see nor reference the rows of another, while an unscoped context
+// sees both.
+func testOrgIsolation(t *testing.T, r repository.Repository) {
+	bg := context.Background()
+	acme, err := r.CreateOrganization(bg, "acme")
+	require.NoError(t, err)
+	globex, err := r.CreateOrganization(bg, "globex")
+	require.NoError(t, err)
+	inAcme := repository.WithOrg(bg, acme.ID)
+	inGlobex := repository.WithOrg(bg, globex.ID)
+
+	// Both organizations have a team called backend.
+	a := seedIn(t, inAcme, r, "backend", 2)
+	g := seedIn(t, inGlobex, r, "backend", 2)
+	assert.Equal(t, acme.ID, a.team.OrgID)
+	assert.Equal(t, globex.ID, g.author.OrgID)
+	_, err = r.CreateTeam(inAcme, "backend")
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+	got, err := r.GetTeamByName(inGlobex, "backend")
+	require.NoError(t, err)
+	assert.Equal(t, g.team.ID, got.ID)
+	_, err = r.GetTeamByName(bg, "backend")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "unscoped name lookups use the default organization")
+
+	_, err = r.GetTeamByID(inAcme, g.team.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.GetUserByID(inAcme, g.author.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = r.GetUserByID(bg, g.author.ID)
+	assert.NoError(t, err, "unscoped calls see every organization")
+
+	teams, err := r.ListTeams(inAcme, repository.TeamFilter{})
+	require.NoError(t, err)
+	require.Len(t, teams, 1)
+	assert.Equal(t, a.team.ID, teams[0].ID)
+	users, err := r.ListUsers(inAcme, repository.UserFilter{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, append(ids(a.users), a.author.ID), ids(users))
+	members, err := r.ListActiveUsersInTeam(inAcme, g.team.ID)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+	members, err = r.ListTeamMembers(inAcme, g.team.ID)
+	require.NoError(t, err)
+	assert.Empty(t, members)
+
+	_, err = r.CreateUser(inAcme, models.User{TeamID: &g.team.ID, Name: "mole", IsActive: true})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+	_, err = r.UpdateUser(bg, models.User{ID: a.author.ID, TeamID: &g.team.ID, Name: a.author.Name, IsActive: true})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference, "users never change organization")
+	_, err = r.UpdateUser(inAcme, models.User{ID: g.author.ID, Name: "renamed", IsActive: true})
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	_, err = r.CreatePR(inAcme, models.PR{Title: "spoof", AuthorID: g.author.ID, Status: models.PRStatusOpen})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+	pr, err := r.CreatePR(inAcme, models.PR{Title: "fix", AuthorID: a.author.ID, Status: models.PRStatusOpen})
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, pr.OrgID)
+	gpr, err := r.CreatePR(bg, models.PR{Title: "theirs", AuthorID: g.author.ID, Status: models.PRStatusOpen})
+	require.NoError(t, err)
+	assert.Equal(t, globex.ID, gpr.OrgID, "a PR belongs to its author's organization")
+
+	assert.ErrorIs(t, r.AssignReviewers(inAcme, pr.ID, []int{g.users[0].ID}), repository.ErrInvalidReference)
+	assert.ErrorIs(t, r.AssignReviewers(bg, pr.ID, []int{g.users[0].ID}), repository.ErrInvalidReference, "reviewers come from the PR's organization even unscoped")
+	assert.ErrorIs(t, r.AssignReviewers(inAcme, gpr.ID, []int{g.users[0].ID}), repository.ErrInvalidReference)
+	require.NoError(t, r.AssignReviewers(inAcme, pr.ID, []int{a.users[0].ID}))
+	require.NoError(t, r.AssignReviewers(inGlobex, gpr.ID, []int{g.users[0].ID}))
+	assert.ErrorIs(t, r.ReplaceReviewer(inAcme, pr.ID, a.users[0].ID, g.users[1].ID), repository.ErrInvalidReference)
+	reviewers, err := r.GetReviewersByPR(inAcme, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{a.users[0].ID}, ids(reviewers), "a failed replacement keeps the old reviewer")
+
+	_, err = r.GetPRByID(inAcme, gpr.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	reviewers, err = r.GetReviewersByPR(inAcme, gpr.ID)
+	require.NoError(t, err)
+	assert.Empty(t, reviewers)
+	queue, err := r.ListPRsAssignedToUser(inAcme, g.users[0].ID, repository.ReviewQueueFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, queue)
+	own, err := r.ListPRsByAuthor(inAcme, g.author.ID, nil)
+	require.NoError(t, err)
+	assert.Empty(t, own)
+	n, err := r.CountAssignments(inAcme)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = r.CountAssignments(bg)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	require.NoError(t, r.SetPRStatus(inAcme, gpr.ID, string(models.PRStatusMerged)))
+	got2, err := r.GetPRByID(bg, gpr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PRStatusOpen, got2.Status, "status changes across organizations are ignored")
+
+	require.NoError(t, r.DeactivateUsersInTeam(inAcme, g.team.ID, ids(g.users)))
+	active, err := r.ListActiveUsersInTeam(inGlobex, g.team.ID)
+	require.NoError(t, err)
+	assert.Len(t, active, 3)
+	assert.ErrorIs(t, r.DeleteUser(inAcme, g.author.ID), repository.ErrNotFound)
+	assert.ErrorIs(t, r.DeleteTeam(inAcme, g.team.ID), repository.ErrNotFound)
+	assert.ErrorIs(t, r.PurgeUser(inAcme, g.author.ID), repository.ErrNotFound)
+	assert.ErrorIs(t, r.PurgeTeam(inAcme, g.team.ID), repository.ErrNotFound)
+
+	var exported []int
+	require.NoError(t, r.ForEachUser(inGlobex, func(u models.User) error {
+		exported = append(exported, u.ID)
+		return nil
+	}))
+	assert.ElementsMatch(t, append(ids(g.users), g.author.ID), exported)
+}
+
+func testConcurrentCreateTeam(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	const workers = 16
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = r.CreateTeam(ctx, "contended")
+		}()
+	}
+	wg.Wait()
+
+	succeeded := 0
+	for _, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func testConcurrentAssignReviewers(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 2)
+	pr := createPR(t, r, f.author.ID, "pr")
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, r.AssignReviewers(ctx, pr.ID, ids(f.users)))
+		}()
+	}
+	wg.Wait()
+
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ids(f.users), ids(revs))
+}
+
+func testConcurrentReplaceReviewer(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	const prs = 8
+	f := seed(t, r, "backend", 4)
+	a, b, c, d := f.users[0].ID, f.users[1].ID, f.users[2].ID, f.users[3].ID
+
+	prIDs := make([]int, 0, prs)
+	for i := range prs {
+		pr := createPR(t, r, f.author.ID, fmt.Sprintf("pr-%d", i))
+		require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{a, b}))
+		prIDs = append(prIDs, pr.ID)
+	}
+
+	// Two replacements of different reviewers race on every PR. Whatever the
+	// interleaving, each one must be applied as a whole.
+	var wg sync.WaitGroup
+	for _, id := range prIDs {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, r.ReplaceReviewer(ctx, id, a, c))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, r.ReplaceReviewer(ctx, id, b, d))
+		}()
+	}
+	wg.Wait()
+
+	for _, id := range prIDs {
+		revs, err := r.GetReviewersByPR(ctx, id)
+		require.NoError(t, err)
+		assert.Equal(t, []int{c, d}, ids(revs), "PR %d", id)
+	}
+
+	count, err := r.CountAssignments(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2*prs, count)
+}
+
+// testConcurrentPRTransactions races a merge against a reassignment of the
+// same PR. The second transaction must wait for the first one's lock, see its
+// status change and record its event after it.
+func testConcurrentPRTransactions(t *testing.T, r repository.Repository) {
+	ctx := context.Background()
+	f := seed(t, r, "backend", 1)
+	pr := createPR(t, r, f.author.ID, "pr")
+	key := fmt.Sprintf("pr:%d", pr.ID)
+	record := func(tx repository.Repository, id string) error {
+		return tx.CreateOutboxEvent(ctx, models.OutboxEvent{EventID: id, EventType: "t", OrderingKey: key, Payload: []byte(`{}`)})
+	}
+
+	locked, release := make(chan struct{}), make(chan struct{})
+	merged := make(chan error, 1)
+	go func() {
+		merged <- r.WithTx(ctx, func(tx repository.Repository) error {
+			if _, err := tx.LockPR(ctx, pr.ID); err != nil {
+				return err
+			}
+			if err := record(tx, "merged"); err != nil {
+				return err
+			}
+			close(locked)
+			<-release
+			return tx.SetPRStatus(ctx, pr.ID, string(models.PRStatusMerged))
+		})
+	}()
+	select {
+	case <-locked:
+	case err := <-merged:
+		t.Fatalf("first transaction failed: %v", err)
+	}
+
+	var seen models.PRStatus
+	reassigned := make(chan error, 1)
+	go func() {
+		reassigned <- r.WithTx(ctx, func(tx repository.Repository) error {
+			got, err := tx.LockPR(ctx, pr.ID)
+			if err != nil {
+				return err
+			}
+			seen = got.Status
+			return record(tx, "reassigned")
+		})
+	}()
+
+	select {
+	case err := <-reassigned:
+		t.Fatalf("second transaction did not wait for the PR lock: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	require.NoError(t, <-merged)
+	require.NoError(t, <-reassigned)
+	assert.Equal(t, models.PRStatusMerged, seen)
+
+	var delivered []string
+	for range 2 {
+		claimed, err := r.ClaimOutboxEvents(ctx, time.Now().Add(time.Minute), time.Hour, 10)
+		require.NoError(t, err)
+		require.Len(t, claimed, 1)
+		delivered = append(delivered, claimed[0].EventID)
+		require.NoError(t, r.MarkOutboxEventDelivered(ctx, claimed[0].ID, time.Now()))
+	}
+	assert.Equal(t, []string{"merged", "reassigned"}, delivered)
+}
+
+// BenchmarkListPRsAssignedToUser measures the review queue of a reviewer with
+// 1000 assigned PRs, each of which has two reviewers.
+func BenchmarkListPRsAssignedToUser(b *testing.B, r repository.Repository) {
+	ctx := context.Background()
+	team, err := r.CreateTeam(ctx, "bench")
+	require.NoError(b, err)
+	var users []models.User
+	for i := range 3 {
+		u, err := r.CreateUser(ctx, models.User{TeamID: &team.ID, Name: fmt.Sprintf("u%d", i), IsActive: true})
+		require.NoError(b, err)
+		users = append(users, u)
+	}
+	author, reviewer := users[0], users[1]
+	for i := range 1000 {
+		pr, err := r.CreatePR(ctx, models.PR{Title: fmt.Sprintf("pr%d", i), AuthorID: author.ID, Status: models.PRStatusOpen})
+		require.NoError(b, err)
+		require.NoError(b, r.AssignReviewers(ctx, pr.ID, []int{reviewer.ID, users[2].ID}))
+	}
+
+	for _, bc := range []struct {
+		name  string
+		limit int
+	}{
+		{"All", 0},
+		{"Page", 50},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			f := repository.ReviewQueueFilter{SortBy: repository.SortByAssignedAt, Limit: bc.limit}
+			for b.Loop() {
+				prs, err := r.ListPRsAssignedToUser(ctx, reviewer.ID, f)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(prs[0].Reviewers) != 2 {
+					b.Fatalf("got %d reviewers", len(prs[0].Reviewers))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+const chatMessageColumns = `id, event_id, event_type, team_id, text, status, attempts, last_error, next_attempt_at, last_attempt_at, created_at`
+
+func scanChatMessage(row scanner) (models.ChatMessage, error) {
+	var m models.ChatMessage
+	err := row.Scan(&m.ID, &m.EventID, &m.EventType, &m.TeamID, &m.Text, &m.Status, &m.Attempts, &m.LastError, &m.NextAttemptAt, &m.LastAttemptAt, &m.CreatedAt)
+	return m, err
+}
+
+func (r *repo) SetTeamChannel(ctx context.Context, c models.TeamChannel) (models.TeamChannel, error) {
+	var res models.TeamChannel
+	row := r.db.QueryRowContext(ctx, `INSERT INTO team_channels(team_id, webhook_url, channel, updated_at) VALUES(?,?,?,?)
+		ON CONFLICT(team_id) DO UPDATE SET
+		 webhook_url=excluded.webhook_url, channel=excluded.channel, updated_at=excluded.updated_at
+		RETURNING team_id, webhook_url, channel, updated_at`, c.TeamID, c.WebhookURL, c.Channel, now())
+	if err := row.Scan(&res.TeamID, &res.WebhookURL, &res.Channel, &res.UpdatedAt); err != nil {
+		return res, fmt.Errorf("set team channel: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetTeamChannel(ctx context.Context, teamID int) (models.TeamChannel, error) {
+	var c models.TeamChannel
+	row := r.db.QueryRowContext(ctx, `SELECT team_id, webhook_url, channel, updated_at FROM team_channels WHERE team_id=?`, teamID)
+	if err := row.Scan(&c.TeamID, &c.WebhookURL, &c.Channel, &c.UpdatedAt); err != nil {
+		return c, fmt.Errorf("get team channel: %w", translateErr(err))
+	}
+	return c, nil
+}
+
+func (r *repo) DeleteTeamChannel(ctx context.Context, teamID int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM team_channels WHERE team_id=?`, teamID)
+	return expectAffected(res, err, "delete team channel")
+}
+
+func (r *repo) CreateChatMessage(ctx context.Context, m models.ChatMessage) (models.ChatMessage, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO chat_messages(event_id, event_type, team_id, text, status, attempts, last_error, next_attempt_at, last_attempt_at, created_at)
+		VALUES(?,?,?,?,?,?,?,?,?,?) RETURNING `+chatMessageColumns,
+		m.EventID, m.EventType, m.TeamID, m.Text, m.Status, m.Attempts, m.LastError, utcOrNil(m.NextAttemptAt), utcOrNil(m.LastAttemptAt), now())
+	created, err := scanChatMessage(row)
+	if err != nil {
+		return models.ChatMessage{}, fmt.Errorf("create chat message: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) UpdateChatMessage(ctx context.Context, m models.ChatMessage) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE chat_messages SET status=?, attempts=?, last_error=?, next_attempt_at=?, last_attempt_at=? WHERE id=?`,
+		m.Status, m.Attempts, m.LastError, utcOrNil(m.NextAttemptAt), utcOrNil(m.LastAttemptAt), m.ID)
+	return expectAffected(res, err, "update chat message")
+}
+
+func (r *repo) ListDueChatMessages(ctx context.Context, now time.Time, limit int) ([]models.ChatMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+chatMessageColumns+` FROM chat_messages
+		WHERE status=? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`, models.DeliveryPending, now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list due chat messages: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.ChatMessage, 0)
+	for rows.Next() {
+		m, err := scanChatMessage(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan chat message: %w", translateErr(err))
+		}
+		res = append(res, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list due chat messages: %w", translateErr(err))
+	}
+	return res, nil
+}
@@ -0,0 +1,16 @@
+package sqlite
+
+import (
+	"testing"
+
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.Repository { return newTestRepo(t) })
+}
+
+func BenchmarkListPRsAssignedToUser(b *testing.B) {
+	repotest.BenchmarkListPRsAssignedToUser(b, newTestRepo(b))
+}
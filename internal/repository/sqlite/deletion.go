@@ -0,0 +1,62 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"prmanager/internal/repository"
+)
+
+func (r *repo) DeleteTeam(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE teams SET deleted_at=? WHERE id=? AND deleted_at IS NULL AND org_id=COALESCE(?, org_id)`, now(), id, repository.OrgFilter(ctx))
+	return expectAffected(res, err, "delete team")
+}
+
+func (r *repo) DeleteUser(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET deleted_at=?, is_active=0 WHERE id=? AND deleted_at IS NULL AND org_id=COALESCE(?, org_id)`, now(), id, repository.OrgFilter(ctx))
+	return expectAffected(res, err, "delete user")
+}
+
+func (r *repo) PurgeTeam(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM teams WHERE id=? AND org_id=COALESCE(?, org_id)`, id, repository.OrgFilter(ctx))
+	return expectAffected(res, err, "purge team")
+}
+
+func (r *repo) PurgeUser(ctx context.Context, id int) error {
+	return r.inTx(ctx, func(tx querier) error {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE id=? AND org_id=COALESCE(?, org_id)`, id, repository.OrgFilter(ctx)).Scan(&n); err != nil {
+			return fmt.Errorf("purge user: %w", translateErr(err))
+		}
+		if n == 0 {
+			return fmt.Errorf("purge user: %w", repository.ErrNotFound)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM identities WHERE user_id=?`, id); err != nil {
+			return fmt.Errorf("purge user identities: %w", translateErr(err))
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM digest_prefs WHERE user_id=?`, id); err != nil {
+			return fmt.Errorf("purge user digest prefs: %w", translateErr(err))
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM api_tokens WHERE user_id=?`, id); err != nil {
+			return fmt.Errorf("purge user API tokens: %w", translateErr(err))
+		}
+		res, err := tx.ExecContext(ctx, `UPDATE users SET name=?, team_id=NULL, is_active=0, deleted_at=COALESCE(deleted_at, ?) WHERE id=?`, repository.AnonymizedUserName, now(), id)
+		return expectAffected(res, err, "purge user")
+	})
+}
+
+// expectAffected turns an update that matched no rows into ErrNotFound.
+func expectAffected(res sql.Result, err error, op string) error {
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, translateErr(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, translateErr(err))
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, repository.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+const digestPrefsColumns = `user_id, opt_out, send_at, timezone, last_sent_at, updated_at`
+
+func scanDigestPrefs(row scanner) (models.DigestPrefs, error) {
+	var p models.DigestPrefs
+	err := row.Scan(&p.UserID, &p.OptOut, &p.SendAt, &p.Timezone, &p.LastSentAt, &p.UpdatedAt)
+	return p, err
+}
+
+func (r *repo) SetDigestPrefs(ctx context.Context, p models.DigestPrefs) (models.DigestPrefs, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO digest_prefs(user_id, opt_out, send_at, timezone, updated_at) VALUES(?,?,?,?,?)
+		ON CONFLICT(user_id) DO UPDATE SET
+		 opt_out=excluded.opt_out, send_at=excluded.send_at, timezone=excluded.timezone, updated_at=excluded.updated_at
+		RETURNING `+digestPrefsColumns, p.UserID, p.OptOut, p.SendAt, p.Timezone, now())
+	res, err := scanDigestPrefs(row)
+	if err != nil {
+		return models.DigestPrefs{}, fmt.Errorf("set digest prefs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetDigestPrefs(ctx context.Context, userID int) (models.DigestPrefs, error) {
+	p, err := scanDigestPrefs(r.db.QueryRowContext(ctx, `SELECT `+digestPrefsColumns+` FROM digest_prefs WHERE user_id=?`, userID))
+	if err != nil {
+		return models.DigestPrefs{}, fmt.Errorf("get digest prefs: %w", translateErr(err))
+	}
+	return p, nil
+}
+
+func (r *repo) ListDigestPrefs(ctx context.Context) ([]models.DigestPrefs, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+digestPrefsColumns+` FROM digest_prefs ORDER BY user_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list digest prefs: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.DigestPrefs, 0)
+	for rows.Next() {
+		p, err := scanDigestPrefs(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan digest prefs: %w", translateErr(err))
+		}
+		res = append(res, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list digest prefs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) ClaimDigest(ctx context.Context, userID int, due, at time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO digest_prefs(user_id, last_sent_at, updated_at) VALUES(?,?,?)
+		ON CONFLICT(user_id) DO UPDATE SET last_sent_at=excluded.last_sent_at
+		WHERE digest_prefs.last_sent_at IS NULL OR digest_prefs.last_sent_at < ?`, userID, at.UTC(), now(), due.UTC())
+	if err != nil {
+		return false, fmt.Errorf("claim digest: %w", translateErr(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("claim digest: %w", translateErr(err))
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,57 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+func (r *repo) CreateIdempotencyKey(ctx context.Context, rec models.IdempotencyRecord) error {
+	if _, err := r.db.ExecContext(ctx, `INSERT INTO idempotency_keys(key, fingerprint, created_at) VALUES(?,?,?)`, rec.Key, rec.Fingerprint, rec.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("create idempotency key: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) GetIdempotencyKey(ctx context.Context, key string) (models.IdempotencyRecord, error) {
+	var (
+		rec    models.IdempotencyRecord
+		status *int
+	)
+	row := r.db.QueryRowContext(ctx, `SELECT key, fingerprint, status_code, response_body, created_at, completed_at FROM idempotency_keys WHERE key=?`, key)
+	if err := row.Scan(&rec.Key, &rec.Fingerprint, &status, &rec.ResponseBody, &rec.CreatedAt, &rec.CompletedAt); err != nil {
+		return rec, fmt.Errorf("get idempotency key: %w", translateErr(err))
+	}
+	if status != nil {
+		rec.StatusCode = *status
+	}
+	return rec, nil
+}
+
+func (r *repo) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, body []byte) error {
+	if _, err := r.db.ExecContext(ctx, `UPDATE idempotency_keys SET status_code=?, response_body=?, completed_at=? WHERE key=?`, statusCode, body, now(), key); err != nil {
+		return fmt.Errorf("complete idempotency key: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE key=?`, key); err != nil {
+		return fmt.Errorf("delete idempotency key: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE created_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", translateErr(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete expired idempotency keys: %w", translateErr(err))
+	}
+	return int(n), nil
+}
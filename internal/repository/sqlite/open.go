@@ -0,0 +1,55 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+const Scheme = "sqlite"
+
+// PathFromConn extracts the database file path from a DB_CONN value of the
+// form sqlite:///abs/path.db or sqlite://relative.db.
+func PathFromConn(conn string) (string, error) {
+	u, err := url.Parse(conn)
+	if err != nil {
+		return "", fmt.Errorf("parse sqlite connection string: %w", err)
+	}
+	if u.Scheme != Scheme {
+		return "", fmt.Errorf("not a sqlite connection string: %q", conn)
+	}
+
+	path := u.Host + u.Path
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+	if path == "" {
+		return "", fmt.Errorf("sqlite connection string has no path: %q", conn)
+	}
+	return path, nil
+}
+
+// Open opens the database file with foreign keys enforced and write
+// transactions taking the lock up front, so that concurrent writers wait on
+// busy_timeout instead of failing halfway through a transaction.
+func Open(ctx context.Context, path string) (*sql.DB, error) {
+	q := url.Values{}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Add("_pragma", "busy_timeout(5000)")
+	q.Add("_pragma", "journal_mode(WAL)")
+	q.Set("_txlock", "immediate")
+	q.Set("_time_format", "sqlite")
+
+	db, err := sql.Open("sqlite", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open sqlite database: %w", err)
+	}
+	return db, nil
+}
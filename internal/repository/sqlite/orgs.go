@@ -0,0 +1,70 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+
+	"prmanager/internal/models"
+)
+
+const orgColumns = `id, name, created_at`
+
+func scanOrganization(row scanner) (models.Organization, error) {
+	var o models.Organization
+	err := row.Scan(&o.ID, &o.Name, &o.CreatedAt)
+	return o, err
+}
+
+func (r *repo) CreateOrganization(ctx context.Context, name string) (models.Organization, error) {
+	o, err := scanOrganization(r.db.QueryRowContext(ctx, `INSERT INTO organizations(name, created_at) VALUES(?,?) RETURNING `+orgColumns, name, now()))
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("create organization: %w", translateErr(err))
+	}
+	return o, nil
+}
+
+func (r *repo) GetOrganization(ctx context.Context, id int) (models.Organization, error) {
+	o, err := scanOrganization(r.db.QueryRowContext(ctx, `SELECT `+orgColumns+` FROM organizations WHERE id=?`, id))
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("get organization: %w", translateErr(err))
+	}
+	return o, nil
+}
+
+func (r *repo) GetOrganizationByName(ctx context.Context, name string) (models.Organization, error) {
+	o, err := scanOrganization(r.db.QueryRowContext(ctx, `SELECT `+orgColumns+` FROM organizations WHERE name=?`, name))
+	if err != nil {
+		return models.Organization{}, fmt.Errorf("get organization by name: %w", translateErr(err))
+	}
+	return o, nil
+}
+
+func (r *repo) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+orgColumns+` FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.Organization, 0)
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan organization: %w", translateErr(err))
+		}
+		res = append(res, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list organizations: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) RestoreOrganization(ctx context.Context, o models.Organization) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO organizations(id, name, created_at) VALUES(?,?,?)
+		ON CONFLICT(id) DO UPDATE SET name=excluded.name, created_at=excluded.created_at`, o.ID, o.Name, o.CreatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("restore organization %d: %w", o.ID, translateErr(err))
+	}
+	return nil
+}
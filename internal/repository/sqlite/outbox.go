@@ -0,0 +1,93 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+const outboxColumns = `id, event_id, event_type, ordering_key, payload, attempts, last_error, next_attempt_at, locked_until, created_at, delivered_at, dead_at`
+
+func (r *repo) CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) error {
+	created := now()
+	next := e.NextAttemptAt
+	if next.IsZero() {
+		next = created
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO outbox_events(event_id, event_type, ordering_key, payload, next_attempt_at, created_at) VALUES(?,?,?,?,?,?)`,
+		e.EventID, e.EventType, e.OrderingKey, []byte(e.Payload), next.UTC(), created)
+	if err != nil {
+		return fmt.Errorf("create outbox event: %w", translateErr(err))
+	}
+	return nil
+}
+
+// ClaimOutboxEvents is a single UPDATE, so SQLite's database-level write lock
+// makes the claim atomic; the NOT EXISTS keeps every event behind the
+// undelivered head of its ordering key. Dead events are neither claimed nor
+// heads.
+func (r *repo) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE outbox_events SET locked_until=? WHERE id IN (
+			SELECT e.id FROM outbox_events e
+			WHERE e.delivered_at IS NULL AND e.dead_at IS NULL AND e.next_attempt_at <= ?
+			  AND (e.locked_until IS NULL OR e.locked_until <= ?)
+			  AND (e.ordering_key = '' OR NOT EXISTS (
+				SELECT 1 FROM outbox_events o
+				WHERE o.ordering_key = e.ordering_key AND o.delivered_at IS NULL AND o.dead_at IS NULL AND o.id < e.id))
+			ORDER BY e.id LIMIT ?)
+		RETURNING `+outboxColumns, now.Add(lease).UTC(), now.UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.OutboxEvent, 0)
+	for rows.Next() {
+		var (
+			e       models.OutboxEvent
+			payload []byte
+		)
+		if err := rows.Scan(&e.ID, &e.EventID, &e.EventType, &e.OrderingKey, &payload, &e.Attempts, &e.LastError, &e.NextAttemptAt, &e.LockedUntil, &e.CreatedAt, &e.DeliveredAt, &e.DeadAt); err != nil {
+			return nil, fmt.Errorf("scan outbox event: %w", translateErr(err))
+		}
+		e.Payload = payload
+		res = append(res, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim outbox events: %w", translateErr(err))
+	}
+	slices.SortFunc(res, func(a, b models.OutboxEvent) int { return a.ID - b.ID })
+	return res, nil
+}
+
+func (r *repo) MarkOutboxEventDelivered(ctx context.Context, id int, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE outbox_events SET delivered_at=?, locked_until=NULL WHERE id=?`, at.UTC(), id)
+	return expectAffected(res, err, "mark outbox event delivered")
+}
+
+func (r *repo) RescheduleOutboxEvent(ctx context.Context, e models.OutboxEvent) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE outbox_events SET attempts=?, last_error=?, next_attempt_at=?, locked_until=NULL WHERE id=?`,
+		e.Attempts, e.LastError, e.NextAttemptAt.UTC(), e.ID)
+	return expectAffected(res, err, "reschedule outbox event")
+}
+
+func (r *repo) DeadLetterOutboxEvent(ctx context.Context, e models.OutboxEvent, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE outbox_events SET attempts=?, last_error=?, dead_at=?, locked_until=NULL WHERE id=?`,
+		e.Attempts, e.LastError, at.UTC(), e.ID)
+	return expectAffected(res, err, "dead-letter outbox event")
+}
+
+func (r *repo) DeleteDeliveredOutboxEvents(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox_events WHERE delivered_at < ?`, before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("delete delivered outbox events: %w", translateErr(err))
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("delete delivered outbox events: %w", translateErr(err))
+	}
+	return int(n), nil
+}
@@ -0,0 +1,557 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type repo struct {
+	db querier
+}
+
+func NewRepo(db *sql.DB) repository.Repository {
+	return &repo{db: db}
+}
+
+// inTx runs fn in a transaction, or in the current one when the repo is
+// already bound to a transaction by WithTx (SQLite has no nested BEGIN).
+func (r *repo) inTx(ctx context.Context, fn func(tx querier) error) error {
+	db, ok := r.db.(*sql.DB)
+	if !ok {
+		return fn(r.db)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", translateErr(err))
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return r.inTx(ctx, func(tx querier) error {
+		return fn(&repo{db: tx})
+	})
+}
+
+// ReadSnapshot runs fn in a read-only transaction, which in WAL mode reads
+// from the snapshot taken at its first read without blocking writers.
+func (r *repo) ReadSnapshot(ctx context.Context, fn func(r repository.Repository) error) error {
+	db, ok := r.db.(*sql.DB)
+	if !ok {
+		return fn(r)
+	}
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", translateErr(err))
+	}
+	defer tx.Rollback()
+
+	if err := fn(&repo{db: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", translateErr(err))
+	}
+	return nil
+}
+
+// SQLite has no server-side clock with timestamptz semantics, so timestamps
+// are produced here with the same microsecond precision as postgres.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+func utcOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (r *repo) CreateTeam(ctx context.Context, name string) (models.Team, error) {
+	var t models.Team
+	row := r.db.QueryRowContext(ctx, `INSERT INTO teams(org_id, name, created_at) VALUES(?,?,?) RETURNING id, org_id, name, created_at, deleted_at`, repository.OrgOrDefault(ctx), name, now())
+	if err := row.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+		return t, fmt.Errorf("create team: %w", translateErr(err))
+	}
+	return t, nil
+}
+
+func (r *repo) GetTeamByID(ctx context.Context, id int) (models.Team, error) {
+	var t models.Team
+	row := r.db.QueryRowContext(ctx, `SELECT id, org_id, name, created_at, deleted_at FROM teams WHERE id=? AND org_id=COALESCE(?, org_id)`, id, repository.OrgFilter(ctx))
+	if err := row.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+		return t, fmt.Errorf("get team: %w", translateErr(err))
+	}
+	return t, nil
+}
+
+func (r *repo) GetTeamByName(ctx context.Context, name string) (models.Team, error) {
+	var t models.Team
+	row := r.db.QueryRowContext(ctx, `SELECT id, org_id, name, created_at, deleted_at FROM teams WHERE org_id=? AND name=?`, repository.OrgOrDefault(ctx), name)
+	if err := row.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+		return t, fmt.Errorf("get team by name: %w", translateErr(err))
+	}
+	return t, nil
+}
+
+func (r *repo) ListTeams(ctx context.Context, f repository.TeamFilter) ([]models.Team, error) {
+	after := 0
+	if f.After != nil {
+		after = f.After.ID
+	}
+	q := `SELECT id, org_id, name, created_at, deleted_at FROM teams WHERE id > ? AND deleted_at IS NULL AND org_id=COALESCE(?, org_id) ORDER BY id`
+	args := []any{after, repository.OrgFilter(ctx)}
+	if f.Limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list teams: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.Team, 0)
+	for rows.Next() {
+		var t models.Team
+		if err := rows.Scan(&t.ID, &t.OrgID, &t.Name, &t.CreatedAt, &t.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan team: %w", translateErr(err))
+		}
+		res = append(res, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list teams: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+// teamOrg returns the organization of a team that is visible in ctx; any
+// other team is ErrInvalidReference.
+func teamOrg(ctx context.Context, q querier, teamID int) (int, error) {
+	var orgID int
+	err := q.QueryRowContext(ctx, `SELECT org_id FROM teams WHERE id=? AND org_id=COALESCE(?, org_id)`, teamID, repository.OrgFilter(ctx)).Scan(&orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%w: team %d", repository.ErrInvalidReference, teamID)
+	}
+	return orgID, translateErr(err)
+}
+
+func (r *repo) CreateUser(ctx context.Context, u models.User) (models.User, error) {
+	orgID := repository.OrgOrDefault(ctx)
+	if u.TeamID != nil {
+		var err error
+		if orgID, err = teamOrg(ctx, r.db, *u.TeamID); err != nil {
+			return models.User{}, fmt.Errorf("create user: %w", err)
+		}
+	}
+
+	var res models.User
+	row := r.db.QueryRowContext(ctx, `INSERT INTO users(org_id, team_id, name, is_active, created_at) VALUES(?,?,?,?,?) RETURNING id, org_id, team_id, name, is_active, created_at, deleted_at`, orgID, u.TeamID, u.Name, u.IsActive, now())
+	if err := row.Scan(&res.ID, &res.OrgID, &res.TeamID, &res.Name, &res.IsActive, &res.CreatedAt, &res.DeletedAt); err != nil {
+		return res, fmt.Errorf("create user: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	var u models.User
+	row := r.db.QueryRowContext(ctx, `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE id=? AND org_id=COALESCE(?, org_id)`, id, repository.OrgFilter(ctx))
+	if err := row.Scan(&u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+		return u, fmt.Errorf("get user: %w", translateErr(err))
+	}
+	return u, nil
+}
+
+func (r *repo) UpdateUser(ctx context.Context, u models.User) (models.User, error) {
+	var res models.User
+	err := r.inTx(ctx, func(tx querier) error {
+		var orgID int
+		if err := tx.QueryRowContext(ctx, `SELECT org_id FROM users WHERE id=? AND org_id=COALESCE(?, org_id)`, u.ID, repository.OrgFilter(ctx)).Scan(&orgID); err != nil {
+			return fmt.Errorf("update user: %w", translateErr(err))
+		}
+		if u.TeamID != nil {
+			// The team must be in the user's organization, scoped or not.
+			if _, err := teamOrg(repository.WithOrg(ctx, orgID), tx, *u.TeamID); err != nil {
+				return fmt.Errorf("update user: %w", err)
+			}
+		}
+
+		row := tx.QueryRowContext(ctx, `UPDATE users SET team_id=?, name=?, is_active=? WHERE id=? RETURNING id, org_id, team_id, name, is_active, created_at, deleted_at`, u.TeamID, u.Name, u.IsActive, u.ID)
+		if err := row.Scan(&res.ID, &res.OrgID, &res.TeamID, &res.Name, &res.IsActive, &res.CreatedAt, &res.DeletedAt); err != nil {
+			return fmt.Errorf("update user: %w", translateErr(err))
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (r *repo) ListActiveUsersInTeam(ctx context.Context, teamID int) ([]models.User, error) {
+	res, err := r.queryUsers(ctx, `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE team_id=? AND is_active=1 AND deleted_at IS NULL AND org_id=COALESCE(?, org_id) ORDER BY id`, teamID, repository.OrgFilter(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list active users: %w", err)
+	}
+	return res, nil
+}
+
+func (r *repo) ListTeamMembers(ctx context.Context, teamID int) ([]models.User, error) {
+	res, err := r.queryUsers(ctx, `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE team_id=? AND deleted_at IS NULL AND org_id=COALESCE(?, org_id) ORDER BY id`, teamID, repository.OrgFilter(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list team members: %w", err)
+	}
+	return res, nil
+}
+
+func (r *repo) ListUsers(ctx context.Context, f repository.UserFilter) ([]models.User, error) {
+	conds := []string{"deleted_at IS NULL", "org_id = COALESCE(?, org_id)"}
+	args := []any{repository.OrgFilter(ctx)}
+	if f.TeamID != nil {
+		conds = append(conds, "team_id = ?")
+		args = append(args, *f.TeamID)
+	}
+	if f.Active != nil {
+		conds = append(conds, "is_active = ?")
+		args = append(args, *f.Active)
+	}
+	if f.After != nil {
+		conds = append(conds, "id > ?")
+		args = append(args, f.After.ID)
+	}
+	q := `SELECT id, org_id, team_id, name, is_active, created_at, deleted_at FROM users WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY id`
+	if f.Limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	res, err := r.queryUsers(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	return res, nil
+}
+
+func (r *repo) queryUsers(ctx context.Context, q string, args ...any) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, translateErr(err)
+	}
+	defer rows.Close()
+
+	res := make([]models.User, 0)
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+			return nil, fmt.Errorf("scan user: %w", translateErr(err))
+		}
+		res = append(res, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, translateErr(err)
+	}
+	return res, nil
+}
+
+func (r *repo) DeactivateUsersInTeam(ctx context.Context, teamID int, userIDs []int) error {
+	return r.inTx(ctx, func(tx querier) error {
+		for _, uid := range userIDs {
+			if _, err := tx.ExecContext(ctx, `UPDATE users SET is_active = 0 WHERE team_id = ? AND id = ? AND org_id = COALESCE(?, org_id)`, teamID, uid, repository.OrgFilter(ctx)); err != nil {
+				return fmt.Errorf("deactivate users: %w", translateErr(err))
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *repo) CreatePR(ctx context.Context, pr models.PR) (models.PR, error) {
+	var orgID int
+	err := r.db.QueryRowContext(ctx, `SELECT org_id FROM users WHERE id=? AND org_id=COALESCE(?, org_id)`, pr.AuthorID, repository.OrgFilter(ctx)).Scan(&orgID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.PR{}, fmt.Errorf("create PR: %w: author %d", repository.ErrInvalidReference, pr.AuthorID)
+	}
+	if err != nil {
+		return models.PR{}, fmt.Errorf("create PR: %w", translateErr(err))
+	}
+
+	var res models.PR
+	row := r.db.QueryRowContext(ctx, `INSERT INTO prs(org_id, title, author_id, status, created_at) VALUES(?,?,?,?,?) RETURNING id, org_id, title, author_id, status, created_at`, orgID, pr.Title, pr.AuthorID, pr.Status, now())
+	if err := row.Scan(&res.ID, &res.OrgID, &res.Title, &res.AuthorID, &res.Status, &res.CreatedAt); err != nil {
+		return res, fmt.Errorf("create PR: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetPRByID(ctx context.Context, id int) (models.PR, error) {
+	var p models.PR
+	row := r.db.QueryRowContext(ctx, `SELECT id, org_id, title, author_id, status, created_at FROM prs WHERE id=? AND org_id=COALESCE(?, org_id)`, id, repository.OrgFilter(ctx))
+	if err := row.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
+		return p, fmt.Errorf("get PR: %w", translateErr(err))
+	}
+	return p, nil
+}
+
+// LockPR is GetPRByID: write transactions start with BEGIN IMMEDIATE, so
+// they already exclude each other.
+func (r *repo) LockPR(ctx context.Context, id int) (models.PR, error) {
+	return r.GetPRByID(ctx, id)
+}
+
+func (r *repo) SetPRStatus(ctx context.Context, id int, status string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE prs SET status=? WHERE id=? AND org_id=COALESCE(?, org_id)`, status, id, repository.OrgFilter(ctx))
+	if err != nil {
+		return fmt.Errorf("set PR status: %w", translateErr(err))
+	}
+	return nil
+}
+
+// checkReviewer fails with ErrInvalidReference unless the PR is visible in
+// ctx and the user belongs to the PR's organization.
+func checkReviewer(ctx context.Context, q querier, prID, userID int) error {
+	var pr, user int
+	err := q.QueryRowContext(ctx, `SELECT
+		 (SELECT COUNT(*) FROM prs WHERE id=? AND org_id=COALESCE(?, org_id)),
+		 (SELECT COUNT(*) FROM prs p JOIN users u ON u.org_id = p.org_id WHERE p.id=? AND u.id=?)`,
+		prID, repository.OrgFilter(ctx), prID, userID).Scan(&pr, &user)
+	switch {
+	case err != nil:
+		return translateErr(err)
+	case pr == 0:
+		return fmt.Errorf("%w: PR %d", repository.ErrInvalidReference, prID)
+	case user == 0:
+		return fmt.Errorf("%w: user %d", repository.ErrInvalidReference, userID)
+	}
+	return nil
+}
+
+func (r *repo) AssignReviewers(ctx context.Context, prID int, userIDs []int) error {
+	return r.inTx(ctx, func(tx querier) error {
+		at := now()
+		for _, uid := range userIDs {
+			if err := checkReviewer(ctx, tx, prID, uid); err != nil {
+				return fmt.Errorf("assign reviewer %d: %w", uid, err)
+			}
+			if _, err := tx.ExecContext(ctx, `INSERT INTO pr_reviewers(pr_id, user_id, assigned_at) VALUES(?,?,?) ON CONFLICT DO NOTHING`, prID, uid, at); err != nil {
+				return fmt.Errorf("assign reviewer %d: %w", uid, translateErr(err))
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *repo) GetReviewersByPR(ctx context.Context, prID int) ([]models.User, error) {
+	res, err := r.queryUsers(ctx, `SELECT u.id, u.org_id, u.team_id, u.name, u.is_active, u.created_at, u.deleted_at
+		FROM users u JOIN pr_reviewers r ON r.user_id = u.id JOIN prs p ON p.id = r.pr_id
+		WHERE r.pr_id=? AND p.org_id=COALESCE(?, p.org_id) ORDER BY u.id`, prID, repository.OrgFilter(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("get reviewers by PR: %w", err)
+	}
+	return res, nil
+}
+
+func (r *repo) ReplaceReviewer(ctx context.Context, prID int, oldUserID int, newUserID int) error {
+	return r.inTx(ctx, func(tx querier) error {
+		if err := checkReviewer(ctx, tx, prID, newUserID); err != nil {
+			return fmt.Errorf("insert new reviewer: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM pr_reviewers WHERE pr_id=? AND user_id=?`, prID, oldUserID); err != nil {
+			return fmt.Errorf("delete old reviewer: %w", translateErr(err))
+		}
+
+		if _, err := tx.ExecContext(ctx, `INSERT INTO pr_reviewers(pr_id, user_id, assigned_at) VALUES(?,?,?) ON CONFLICT DO NOTHING`, prID, newUserID, now()); err != nil {
+			return fmt.Errorf("insert new reviewer: %w", translateErr(err))
+		}
+
+		return nil
+	})
+}
+
+func (r *repo) ListPRsAssignedToUser(ctx context.Context, userID int, f repository.ReviewQueueFilter) ([]models.AssignedPR, error) {
+	args := []any{userID, repository.OrgFilter(ctx)}
+	conds := []string{"r.user_id = ?", "p.org_id = COALESCE(?, p.org_id)"}
+	if len(f.Statuses) > 0 {
+		marks := make([]string, len(f.Statuses))
+		for i, st := range f.Statuses {
+			marks[i] = "?"
+			args = append(args, string(st))
+		}
+		conds = append(conds, "p.status IN ("+strings.Join(marks, ",")+")")
+	}
+	if f.CreatedAfter != nil {
+		conds = append(conds, "p.created_at > ?")
+		args = append(args, f.CreatedAfter.UTC())
+	}
+	if f.CreatedBefore != nil {
+		conds = append(conds, "p.created_at < ?")
+		args = append(args, f.CreatedBefore.UTC())
+	}
+
+	col, dir, cmp := "p.created_at", "ASC", ">"
+	if f.SortBy == repository.SortByAssignedAt {
+		col = "r.assigned_at"
+	}
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	if f.After != nil && f.After.Time != nil {
+		conds = append(conds, fmt.Sprintf("(%s, p.id) %s (?, ?)", col, cmp))
+		args = append(args, f.After.Time.UTC(), f.After.ID)
+	}
+
+	q := `SELECT p.id, p.org_id, p.title, p.author_id, p.status, p.created_at, r.assigned_at FROM prs p JOIN pr_reviewers r ON r.pr_id = p.id WHERE ` +
+		strings.Join(conds, " AND ") + fmt.Sprintf(" ORDER BY %s %s, p.id %s", col, dir, dir)
+	if f.Limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list PRs assigned to user: %w", translateErr(err))
+	}
+
+	out := make([]models.AssignedPR, 0)
+	for rows.Next() {
+		var p models.AssignedPR
+		if err := rows.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt, &p.AssignedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan PR: %w", translateErr(err))
+		}
+		out = append(out, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list PRs assigned to user: %w", translateErr(err))
+	}
+
+	prIDs := make([]int, len(out))
+	for i, p := range out {
+		prIDs[i] = p.ID
+	}
+	revs, err := r.reviewersByPRs(ctx, prIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Reviewers = revs[out[i].ID]
+	}
+	return out, nil
+}
+
+func (r *repo) ListPRsByAuthor(ctx context.Context, authorID int, statuses []models.PRStatus) ([]models.PRWithReviewers, error) {
+	args := []any{authorID, repository.OrgFilter(ctx)}
+	q := `SELECT id, org_id, title, author_id, status, created_at FROM prs WHERE author_id = ? AND org_id = COALESCE(?, org_id)`
+	if len(statuses) > 0 {
+		marks := make([]string, len(statuses))
+		for i, st := range statuses {
+			marks[i] = "?"
+			args = append(args, string(st))
+		}
+		q += " AND status IN (" + strings.Join(marks, ",") + ")"
+	}
+	rows, err := r.db.QueryContext(ctx, q+" ORDER BY created_at, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("list PRs by author: %w", translateErr(err))
+	}
+
+	out := make([]models.PRWithReviewers, 0)
+	for rows.Next() {
+		var p models.PRWithReviewers
+		if err := rows.Scan(&p.ID, &p.OrgID, &p.Title, &p.AuthorID, &p.Status, &p.CreatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan PR: %w", translateErr(err))
+		}
+		out = append(out, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list PRs by author: %w", translateErr(err))
+	}
+
+	prIDs := make([]int, len(out))
+	for i, p := range out {
+		prIDs[i] = p.ID
+	}
+	revs, err := r.reviewersByPRs(ctx, prIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range out {
+		out[i].Reviewers = revs[out[i].ID]
+	}
+	return out, nil
+}
+
+// maxBatchParams keeps IN lists well below SQLITE_MAX_VARIABLE_NUMBER.
+const maxBatchParams = 500
+
+// reviewersByPRs loads the reviewers of all given PRs with one query per
+// maxBatchParams PRs. Every requested PR gets a non-nil slice, ordered by
+// user id.
+func (r *repo) reviewersByPRs(ctx context.Context, prIDs []int) (map[int][]models.User, error) {
+	res := make(map[int][]models.User, len(prIDs))
+	for _, id := range prIDs {
+		res[id] = make([]models.User, 0)
+	}
+
+	for batch := range slices.Chunk(prIDs, maxBatchParams) {
+		marks := make([]string, len(batch))
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			marks[i] = "?"
+			args[i] = id
+		}
+
+		rows, err := r.db.QueryContext(ctx, `SELECT r.pr_id, u.id, u.org_id, u.team_id, u.name, u.is_active, u.created_at, u.deleted_at FROM pr_reviewers r JOIN users u ON u.id = r.user_id WHERE r.pr_id IN (`+strings.Join(marks, ",")+`) ORDER BY r.pr_id, u.id`, args...)
+		if err != nil {
+			return nil, fmt.Errorf("get reviewers by PRs: %w", translateErr(err))
+		}
+		for rows.Next() {
+			var prID int
+			var u models.User
+			if err := rows.Scan(&prID, &u.ID, &u.OrgID, &u.TeamID, &u.Name, &u.IsActive, &u.CreatedAt, &u.DeletedAt); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan reviewer: %w", translateErr(err))
+			}
+			res[prID] = append(res[prID], u)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("get reviewers by PRs: %w", translateErr(err))
+		}
+	}
+	return res, nil
+}
+
+func (r *repo) CountAssignments(ctx context.Context) (int, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pr_reviewers r JOIN prs p ON p.id = r.pr_id WHERE p.org_id = COALESCE(?, p.org_id)`, repository.OrgFilter(ctx))
+	var c int
+	if err := row.Scan(&c); err != nil {
+		return 0, fmt.Errorf("count assignments: %w", translateErr(err))
+	}
+	return c, nil
+}
@@ -0,0 +1,154 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"prmanager/internal/migration"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRepo(t testing.TB) repository.Repository {
+	t.Helper()
+
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, migration.RunSQLite(context.Background(), db))
+	return NewRepo(db)
+}
+
+func TestPathFromConn(t *testing.T) {
+	cases := map[string]string{
+		"sqlite:///var/lib/pr-manager/data.db": "/var/lib/pr-manager/data.db",
+		"sqlite://data.db":                     "data.db",
+		"sqlite:data.db":                       "data.db",
+	}
+	for conn, want := range cases {
+		got, err := PathFromConn(conn)
+		require.NoError(t, err, conn)
+		assert.Equal(t, want, got, conn)
+	}
+
+	_, err := PathFromConn("postgres://localhost/db")
+	assert.Error(t, err)
+	_, err = PathFromConn("sqlite://")
+	assert.Error(t, err)
+}
+
+func TestCreateAndReadBack(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	team, err := r.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	assert.False(t, team.CreatedAt.IsZero())
+
+	got, err := r.GetTeamByID(ctx, team.ID)
+	require.NoError(t, err)
+	assert.Equal(t, team, got)
+
+	u, err := r.CreateUser(ctx, models.User{TeamID: &team.ID, Name: "alice", IsActive: true})
+	require.NoError(t, err)
+	gotUser, err := r.GetUserByID(ctx, u.ID)
+	require.NoError(t, err)
+	assert.Equal(t, u, gotUser)
+
+	noTeam, err := r.CreateUser(ctx, models.User{Name: "bob", IsActive: false})
+	require.NoError(t, err)
+	assert.Nil(t, noTeam.TeamID)
+	assert.False(t, noTeam.IsActive)
+}
+
+func TestConstraintErrors(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	_, err := r.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	_, err = r.CreateTeam(ctx, "backend")
+	assert.ErrorIs(t, err, repository.ErrAlreadyExists)
+
+	missing := 42
+	_, err = r.CreateUser(ctx, models.User{TeamID: &missing, Name: "ghost"})
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	_, err = r.GetPRByID(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestReplaceReviewerIsAtomic(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	author, _ := r.CreateUser(ctx, models.User{Name: "author", IsActive: true})
+	reviewer, _ := r.CreateUser(ctx, models.User{Name: "reviewer", IsActive: true})
+	pr, err := r.CreatePR(ctx, models.PR{Title: "pr", AuthorID: author.ID, Status: models.PRStatusOpen})
+	require.NoError(t, err)
+	require.NoError(t, r.AssignReviewers(ctx, pr.ID, []int{reviewer.ID}))
+
+	err = r.ReplaceReviewer(ctx, pr.ID, reviewer.ID, 999)
+	assert.ErrorIs(t, err, repository.ErrInvalidReference)
+
+	revs, err := r.GetReviewersByPR(ctx, pr.ID)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, reviewer.ID, revs[0].ID)
+}
+
+func TestConcurrentWrites(t *testing.T) {
+	r := newTestRepo(t)
+	ctx := context.Background()
+
+	author, _ := r.CreateUser(ctx, models.User{Name: "author", IsActive: true})
+	reviewers := make([]int, 0, 10)
+	for range 10 {
+		u, err := r.CreateUser(ctx, models.User{Name: "reviewer", IsActive: true})
+		require.NoError(t, err)
+		reviewers = append(reviewers, u.ID)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pr, err := r.CreatePR(ctx, models.PR{Title: "pr", AuthorID: author.ID, Status: models.PRStatusOpen})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if err := r.AssignReviewers(ctx, pr.ID, reviewers[:2]); err != nil {
+				errs <- err
+				return
+			}
+			errs <- r.ReplaceReviewer(ctx, pr.ID, reviewers[0], reviewers[5])
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	count, err := r.CountAssignments(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 40, count)
+}
+
+func TestMigrationsAreRepeatable(t *testing.T) {
+	db, err := Open(context.Background(), filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer db.Close()
+
+	require.NoError(t, migration.RunSQLite(context.Background(), db))
+	require.NoError(t, migration.RunSQLite(context.Background(), db))
+}
@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+const reviewerSyncColumns = `pr_id, status, generation, attempts, last_error, pushed, next_attempt_at, locked_until, last_attempt_at, synced_at`
+
+func splitLogins(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+func scanReviewerSync(row scanner) (models.ReviewerSync, error) {
+	var (
+		s      models.ReviewerSync
+		pushed string
+	)
+	err := row.Scan(&s.PRID, &s.Status, &s.Generation, &s.Attempts, &s.LastError, &pushed, &s.NextAttemptAt, &s.LockedUntil, &s.LastAttemptAt, &s.SyncedAt)
+	s.Pushed = splitLogins(pushed)
+	return s, err
+}
+
+func (r *repo) MarkReviewerSyncPending(ctx context.Context, prID int, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO reviewer_syncs(pr_id, status, next_attempt_at) VALUES(?,?,?)
+		ON CONFLICT(pr_id) DO UPDATE SET
+		 status=excluded.status, generation=reviewer_syncs.generation+1, attempts=0, next_attempt_at=excluded.next_attempt_at`,
+		prID, models.ReviewerSyncPending, at.UTC())
+	if err != nil {
+		return fmt.Errorf("mark reviewer sync pending: %w", translateErr(err))
+	}
+	return nil
+}
+
+func (r *repo) GetReviewerSync(ctx context.Context, prID int) (models.ReviewerSync, error) {
+	s, err := scanReviewerSync(r.db.QueryRowContext(ctx, `SELECT `+reviewerSyncColumns+` FROM reviewer_syncs WHERE pr_id=?`, prID))
+	if err != nil {
+		return models.ReviewerSync{}, fmt.Errorf("get reviewer sync: %w", translateErr(err))
+	}
+	return s, nil
+}
+
+func (r *repo) ClaimReviewerSyncs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.ReviewerSync, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE reviewer_syncs SET locked_until=? WHERE pr_id IN (
+			SELECT pr_id FROM reviewer_syncs
+			WHERE status=? AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until <= ?)
+			ORDER BY next_attempt_at, pr_id LIMIT ?)
+		RETURNING `+reviewerSyncColumns, now.Add(lease).UTC(), models.ReviewerSyncPending, now.UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("claim reviewer syncs: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.ReviewerSync, 0)
+	for rows.Next() {
+		s, err := scanReviewerSync(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan reviewer sync: %w", translateErr(err))
+		}
+		res = append(res, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim reviewer syncs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) CompleteReviewerSync(ctx context.Context, s models.ReviewerSync) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE reviewer_syncs SET
+		 pushed=?, attempts=?, last_error=?, last_attempt_at=?, locked_until=NULL,
+		 status=CASE WHEN generation=? THEN ? ELSE status END,
+		 next_attempt_at=CASE WHEN generation=? THEN ? ELSE next_attempt_at END,
+		 synced_at=CASE WHEN generation=? THEN ? ELSE synced_at END
+		WHERE pr_id=?`,
+		strings.Join(s.Pushed, ","), s.Attempts, s.LastError, utcOrNil(s.LastAttemptAt),
+		s.Generation, s.Status,
+		s.Generation, utcOrNil(s.NextAttemptAt),
+		s.Generation, utcOrNil(s.SyncedAt),
+		s.PRID)
+	return expectAffected(res, err, "complete reviewer sync")
+}
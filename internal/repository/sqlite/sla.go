@@ -0,0 +1,129 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const teamSLAColumns = `team_id, remind_after_hours, reassign_after_hours, escalate_after_hours, workday_start, workday_end, timezone, lead_id, updated_at`
+
+func scanTeamSLA(row scanner) (models.TeamSLA, error) {
+	var s models.TeamSLA
+	err := row.Scan(&s.TeamID, &s.RemindAfterHours, &s.ReassignAfterHours, &s.EscalateAfterHours, &s.WorkdayStart, &s.WorkdayEnd, &s.Timezone, &s.LeadID, &s.UpdatedAt)
+	return s, err
+}
+
+func (r *repo) SetTeamSLA(ctx context.Context, s models.TeamSLA) (models.TeamSLA, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO team_slas(team_id, remind_after_hours, reassign_after_hours, escalate_after_hours, workday_start, workday_end, timezone, lead_id, updated_at)
+		VALUES(?,?,?,?,?,?,?,?,?)
+		ON CONFLICT(team_id) DO UPDATE SET
+		 remind_after_hours=excluded.remind_after_hours, reassign_after_hours=excluded.reassign_after_hours,
+		 escalate_after_hours=excluded.escalate_after_hours, workday_start=excluded.workday_start, workday_end=excluded.workday_end,
+		 timezone=excluded.timezone, lead_id=excluded.lead_id, updated_at=excluded.updated_at
+		RETURNING `+teamSLAColumns,
+		s.TeamID, s.RemindAfterHours, s.ReassignAfterHours, s.EscalateAfterHours, s.WorkdayStart, s.WorkdayEnd, s.Timezone, s.LeadID, now())
+	res, err := scanTeamSLA(row)
+	if err != nil {
+		return models.TeamSLA{}, fmt.Errorf("set team SLA: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) GetTeamSLA(ctx context.Context, teamID int) (models.TeamSLA, error) {
+	s, err := scanTeamSLA(r.db.QueryRowContext(ctx, `SELECT `+teamSLAColumns+` FROM team_slas WHERE team_id=?`, teamID))
+	if err != nil {
+		return models.TeamSLA{}, fmt.Errorf("get team SLA: %w", translateErr(err))
+	}
+	return s, nil
+}
+
+func (r *repo) DeleteTeamSLA(ctx context.Context, teamID int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM team_slas WHERE team_id=?`, teamID)
+	return expectAffected(res, err, "delete team SLA")
+}
+
+func (r *repo) ListTeamSLAs(ctx context.Context) ([]models.TeamSLA, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+teamSLAColumns+` FROM team_slas ORDER BY team_id`)
+	if err != nil {
+		return nil, fmt.Errorf("list team SLAs: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.TeamSLA, 0)
+	for rows.Next() {
+		s, err := scanTeamSLA(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan team SLAs: %w", translateErr(err))
+		}
+		res = append(res, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list team SLAs: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) ListPendingReviews(ctx context.Context, teamID int) ([]models.PendingReview, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT r.pr_id, r.user_id, r.assigned_at, r.reminded_at, r.reassign_failed_at, r.escalated_at
+		FROM pr_reviewers r JOIN prs p ON p.id = r.pr_id JOIN users u ON u.id = r.user_id
+		WHERE u.team_id=? AND p.status=? AND p.org_id=COALESCE(?, p.org_id)
+		ORDER BY r.assigned_at, r.pr_id, r.user_id`, teamID, models.PRStatusOpen, repository.OrgFilter(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("list pending reviews: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.PendingReview, 0)
+	for rows.Next() {
+		var p models.PendingReview
+		if err := rows.Scan(&p.PRID, &p.UserID, &p.AssignedAt, &p.RemindedAt, &p.ReassignFailedAt, &p.EscalatedAt); err != nil {
+			return nil, fmt.Errorf("scan pending reviews: %w", translateErr(err))
+		}
+		res = append(res, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list pending reviews: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+// slaStageColumn is the pr_reviewers column recording stage.
+func slaStageColumn(stage models.SLAStage) (string, error) {
+	switch stage {
+	case models.SLAReminded:
+		return "reminded_at", nil
+	case models.SLAReassignFailed:
+		return "reassign_failed_at", nil
+	case models.SLAEscalated:
+		return "escalated_at", nil
+	}
+	return "", fmt.Errorf("unknown SLA stage %q", stage)
+}
+
+func (r *repo) MarkReviewSLAStage(ctx context.Context, prID, userID int, stage models.SLAStage, at time.Time) (bool, error) {
+	col, err := slaStageColumn(stage)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE pr_reviewers SET `+col+`=? WHERE pr_id=? AND user_id=? AND `+col+` IS NULL`, at.UTC(), prID, userID)
+	if err != nil {
+		return false, fmt.Errorf("mark review %s: %w", stage, translateErr(err))
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, fmt.Errorf("mark review %s: %w", stage, translateErr(err))
+	} else if n > 0 {
+		return true, nil
+	}
+	var n int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pr_reviewers WHERE pr_id=? AND user_id=?`, prID, userID).Scan(&n); err != nil {
+		return false, fmt.Errorf("mark review %s: %w", stage, translateErr(err))
+	}
+	if n == 0 {
+		return false, fmt.Errorf("mark review %s: %w", stage, repository.ErrNotFound)
+	}
+	return false, nil
+}
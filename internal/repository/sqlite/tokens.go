@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+const apiTokenColumns = `id, name, role, user_id, org_id, token_hash, created_at, revoked_at`
+
+func scanAPIToken(row scanner) (models.APIToken, error) {
+	var t models.APIToken
+	err := row.Scan(&t.ID, &t.Name, &t.Role, &t.UserID, &t.OrgID, &t.Hash, &t.CreatedAt, &t.RevokedAt)
+	return t, err
+}
+
+func (r *repo) CreateAPIToken(ctx context.Context, t models.APIToken) (models.APIToken, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO api_tokens(name, role, user_id, org_id, token_hash, created_at) VALUES(?,?,?,?,?,?) RETURNING `+apiTokenColumns,
+		t.Name, t.Role, t.UserID, t.OrgID, t.Hash, now())
+	created, err := scanAPIToken(row)
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("create API token: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) GetAPITokenByHash(ctx context.Context, hash string) (models.APIToken, error) {
+	t, err := scanAPIToken(r.db.QueryRowContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash=?`, hash))
+	if err != nil {
+		return models.APIToken{}, fmt.Errorf("get API token: %w", translateErr(err))
+	}
+	return t, nil
+}
+
+func (r *repo) ListAPITokens(ctx context.Context) ([]models.APIToken, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiTokenColumns+` FROM api_tokens ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list API tokens: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.APIToken, 0)
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan API token: %w", translateErr(err))
+		}
+		res = append(res, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list API tokens: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) RevokeAPIToken(ctx context.Context, id int, at time.Time) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE api_tokens SET revoked_at=? WHERE id=? AND revoked_at IS NULL`, at.UTC(), id)
+	return expectAffected(res, err, "revoke API token")
+}
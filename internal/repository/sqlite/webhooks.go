@@ -0,0 +1,148 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, last_error, response_code, next_attempt_at, last_attempt_at, created_at`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func splitEvents(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
+func scanWebhook(row scanner) (models.Webhook, error) {
+	var (
+		w      models.Webhook
+		events string
+	)
+	err := row.Scan(&w.ID, &w.URL, &w.Secret, &events, &w.CreatedAt)
+	w.Events = splitEvents(events)
+	return w, err
+}
+
+func scanDelivery(row scanner) (models.WebhookDelivery, error) {
+	var (
+		d       models.WebhookDelivery
+		payload []byte
+	)
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &payload, &d.Status, &d.Attempts, &d.LastError, &d.ResponseCode, &d.NextAttemptAt, &d.LastAttemptAt, &d.CreatedAt)
+	d.Payload = payload
+	return d, err
+}
+
+func (r *repo) CreateWebhook(ctx context.Context, w models.Webhook) (models.Webhook, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO webhooks(url, secret, events, created_at) VALUES(?,?,?,?) RETURNING id, url, secret, events, created_at`, w.URL, w.Secret, strings.Join(w.Events, ","), now())
+	created, err := scanWebhook(row)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("create webhook: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) GetWebhook(ctx context.Context, id int) (models.Webhook, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT id, url, secret, events, created_at FROM webhooks WHERE id=?`, id)
+	w, err := scanWebhook(row)
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("get webhook: %w", translateErr(err))
+	}
+	return w, nil
+}
+
+func (r *repo) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, url, secret, events, created_at FROM webhooks ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook: %w", translateErr(err))
+		}
+		res = append(res, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", translateErr(err))
+	}
+	return res, nil
+}
+
+func (r *repo) DeleteWebhook(ctx context.Context, id int) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id=?`, id)
+	return expectAffected(res, err, "delete webhook")
+}
+
+func (r *repo) CreateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	row := r.db.QueryRowContext(ctx, `INSERT INTO webhook_deliveries(webhook_id, event_id, event_type, payload, status, attempts, last_error, response_code, next_attempt_at, last_attempt_at, created_at)
+		VALUES(?,?,?,?,?,?,?,?,?,?,?) RETURNING `+deliveryColumns,
+		d.WebhookID, d.EventID, d.EventType, []byte(d.Payload), d.Status, d.Attempts, d.LastError, d.ResponseCode, utcOrNil(d.NextAttemptAt), utcOrNil(d.LastAttemptAt), now())
+	created, err := scanDelivery(row)
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("create webhook delivery: %w", translateErr(err))
+	}
+	return created, nil
+}
+
+func (r *repo) UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE webhook_deliveries SET status=?, attempts=?, last_error=?, response_code=?, next_attempt_at=?, last_attempt_at=? WHERE id=?`,
+		d.Status, d.Attempts, d.LastError, d.ResponseCode, utcOrNil(d.NextAttemptAt), utcOrNil(d.LastAttemptAt), d.ID)
+	return expectAffected(res, err, "update webhook delivery")
+}
+
+func (r *repo) ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status=? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`, models.DeliveryPending, now.UTC(), limit)
+	return collectDeliveries(rows, err, "list due webhook deliveries")
+}
+
+func (r *repo) ListWebhookDeliveries(ctx context.Context, webhookID int, f repository.DeliveryFilter) ([]models.WebhookDelivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE webhook_id=?`
+	args := []any{webhookID}
+	if f.After != nil {
+		query += ` AND id < ?`
+		args = append(args, f.After.ID)
+	}
+	query += ` ORDER BY id DESC`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	return collectDeliveries(rows, err, "list webhook deliveries")
+}
+
+func collectDeliveries(rows *sql.Rows, err error, op string) ([]models.WebhookDelivery, error) {
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, translateErr(err))
+	}
+	defer rows.Close()
+
+	res := make([]models.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan webhook delivery: %w", translateErr(err))
+		}
+		res = append(res, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, translateErr(err))
+	}
+	return res, nil
+}
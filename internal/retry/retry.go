@@ -0,0 +1,58 @@
+// Package retry holds the retry schedule and poll loop shared by the
+// background workers: webhook deliveries, chat messages, reviewer syncs and
+// the outbox relay.
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Policy is how a worker retries a failed attempt: the delay starts at
+// BaseBackoff and doubles with every failed attempt up to MaxBackoff, until
+// MaxAttempts attempts were made. Zero MaxAttempts retries forever.
+type Policy struct {
+	MaxAttempts int
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Backoff is the delay before attempt n+1 after n failed attempts.
+func (p Policy) Backoff(n int) time.Duration {
+	d := p.BaseBackoff
+	for i := 1; i < n; i++ {
+		d *= 2
+		if d >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return min(d, p.MaxBackoff)
+}
+
+// Next schedules the attempt after the attempts'th one failed at at, waiting
+// at least wait, e.g. a server's Retry-After. It reports false when the
+// attempts are used up and the job has failed for good.
+func (p Policy) Next(attempts int, at time.Time, wait time.Duration) (time.Time, bool) {
+	if p.MaxAttempts > 0 && attempts >= p.MaxAttempts {
+		return time.Time{}, false
+	}
+	return at.Add(max(p.Backoff(attempts), wait)), true
+}
+
+// Poll calls work right away and then every interval or whenever wake
+// fires, until ctx is cancelled.
+func Poll(ctx context.Context, interval time.Duration, wake <-chan struct{}, work func(context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		work(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-wake:
+		}
+	}
+}
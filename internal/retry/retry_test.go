@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	p := Policy{BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+	assert.Equal(t, 10*time.Second, p.Backoff(1))
+	assert.Equal(t, 20*time.Second, p.Backoff(2))
+	assert.Equal(t, 40*time.Second, p.Backoff(3))
+	assert.Equal(t, time.Minute, p.Backoff(4))
+	assert.Equal(t, time.Minute, p.Backoff(100))
+}
+
+func TestNext(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := Policy{MaxAttempts: 3, BaseBackoff: 10 * time.Second, MaxBackoff: time.Minute}
+
+	next, ok := p.Next(1, at, 0)
+	assert.True(t, ok)
+	assert.Equal(t, at.Add(10*time.Second), next)
+
+	next, ok = p.Next(2, at, 30*time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, at.Add(30*time.Second), next, "a longer wait wins over the backoff")
+
+	_, ok = p.Next(3, at, 0)
+	assert.False(t, ok)
+
+	p.MaxAttempts = 0
+	_, ok = p.Next(1000, at, 0)
+	assert.True(t, ok, "zero MaxAttempts retries forever")
+}
+
+func TestPollRunsOnWakeUntilCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wake := make(chan struct{})
+	calls := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		Poll(ctx, time.Hour, wake, func(context.Context) { calls <- struct{}{} })
+		close(done)
+	}()
+
+	<-calls
+	wake <- struct{}{}
+	<-calls
+	cancel()
+	<-done
+}
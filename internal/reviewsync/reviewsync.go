@@ -0,0 +1,225 @@
+// Package reviewsync pushes the reviewers the service assigns to the code
+// host a PR was imported from, so they get the host's own notifications.
+//
+// Publish marks a PR's sync pending whenever its reviewers change; Run works
+// through pending syncs in the background. A sync pushes the PR's current
+// reviewers rather than replaying individual changes: it requests reviewers
+// that were not pushed yet and removes pushed ones that were replaced, so
+// retries and concurrent changes converge on the same state.
+package reviewsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/retry"
+	"prmanager/internal/vcs"
+)
+
+type Config struct {
+	retry.Policy
+	PollInterval time.Duration
+	Lease        time.Duration
+	BatchSize    int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Policy:       retry.Policy{MaxAttempts: 10, BaseBackoff: 30 * time.Second, MaxBackoff: 30 * time.Minute},
+		PollInterval: 5 * time.Second,
+		Lease:        time.Minute,
+		BatchSize:    20,
+	}
+}
+
+type Syncer struct {
+	repo      repository.Repository
+	providers map[string]vcs.Provider
+	logger    *slog.Logger
+	cfg       Config
+	now       func() time.Time
+	wake      chan struct{}
+}
+
+// NewSyncer returns a syncer pushing to providers, keyed by provider name
+// (vcs.GitHub, vcs.GitLab). PRs from other providers are left alone.
+func NewSyncer(r repository.Repository, providers map[string]vcs.Provider, logger *slog.Logger, cfg Config) *Syncer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Syncer{
+		repo:      r,
+		providers: providers,
+		logger:    logger,
+		cfg:       cfg,
+		now:       time.Now,
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Publish schedules a sync for the PR of reviewer events. It is idempotent.
+func (s *Syncer) Publish(ctx context.Context, e events.Event) error {
+	if len(s.providers) == 0 {
+		return nil
+	}
+	var data struct {
+		PRID int `json:"pr_id"`
+	}
+	switch e.Type {
+	case events.ReviewerAssigned, events.ReviewerReassigned:
+		if err := json.Unmarshal(e.Data, &data); err != nil {
+			return fmt.Errorf("decode %s event: %w", e.Type, err)
+		}
+	default:
+		return nil
+	}
+
+	ref, err := s.repo.GetExternalRef(ctx, data.PRID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get external ref of PR %d: %w", data.PRID, err)
+	}
+	if _, ok := s.providers[ref.Provider]; !ok {
+		return nil
+	}
+	if err := s.repo.MarkReviewerSyncPending(ctx, data.PRID, s.now()); err != nil {
+		return fmt.Errorf("schedule reviewer sync of PR %d: %w", data.PRID, err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run syncs due PRs until ctx is cancelled.
+func (s *Syncer) Run(ctx context.Context) {
+	retry.Poll(ctx, s.cfg.PollInterval, s.wake, func(ctx context.Context) {
+		if _, err := s.SyncDue(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("failed to sync reviewers", "error", err)
+		}
+	})
+}
+
+// SyncDue makes one attempt for every pending sync that is due and returns
+// how many attempts were made.
+func (s *Syncer) SyncDue(ctx context.Context) (int, error) {
+	due, err := s.repo.ClaimReviewerSyncs(ctx, s.now(), s.cfg.Lease, s.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("claim reviewer syncs: %w", err)
+	}
+	for i, sync := range due {
+		if err := s.attempt(ctx, sync); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+func (s *Syncer) attempt(ctx context.Context, sync models.ReviewerSync) error {
+	ref, err := s.repo.GetExternalRef(ctx, sync.PRID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get external ref of PR %d: %w", sync.PRID, err)
+	}
+
+	pushErr := s.push(ctx, ref, &sync)
+	at := s.now()
+	sync.Attempts++
+	sync.LastAttemptAt = &at
+
+	switch next, ok := s.cfg.Next(sync.Attempts, at, 0); {
+	case pushErr == nil:
+		sync.Status = models.ReviewerSyncSynced
+		sync.LastError = ""
+		sync.NextAttemptAt = nil
+		sync.SyncedAt = &at
+	case !ok:
+		sync.Status = models.ReviewerSyncFailed
+		sync.LastError = pushErr.Error()
+		sync.NextAttemptAt = nil
+		s.logger.Warn("reviewer sync failed permanently",
+			"pr_id", sync.PRID, "provider", ref.Provider, "attempts", sync.Attempts, "error", pushErr)
+	default:
+		sync.LastError = pushErr.Error()
+		sync.NextAttemptAt = &next
+		s.logger.Info("reviewer sync failed, will retry",
+			"pr_id", sync.PRID, "provider", ref.Provider, "attempts", sync.Attempts, "next_attempt_at", next, "error", pushErr)
+	}
+
+	if err := s.repo.CompleteReviewerSync(ctx, sync); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("update reviewer sync of PR %d: %w", sync.PRID, err)
+	}
+	return nil
+}
+
+// push brings the code host in line with the PR's reviewers, updating
+// sync.Pushed with every call that succeeded.
+func (s *Syncer) push(ctx context.Context, ref models.ExternalRef, sync *models.ReviewerSync) error {
+	provider, ok := s.providers[ref.Provider]
+	if !ok {
+		return fmt.Errorf("provider %q is not configured", ref.Provider)
+	}
+	want, err := s.logins(ctx, sync.PRID, ref.Provider)
+	if err != nil {
+		return err
+	}
+
+	var add, remove []string
+	for _, l := range want {
+		if !slices.Contains(sync.Pushed, l) {
+			add = append(add, l)
+		}
+	}
+	for _, l := range sync.Pushed {
+		if !slices.Contains(want, l) {
+			remove = append(remove, l)
+		}
+	}
+
+	if err := provider.RequestReviewers(ctx, ref, add); err != nil {
+		return fmt.Errorf("request reviewers %v: %w", add, err)
+	}
+	sync.Pushed = append(sync.Pushed, add...)
+	if err := provider.RemoveReviewers(ctx, ref, remove); err != nil {
+		return fmt.Errorf("remove reviewers %v: %w", remove, err)
+	}
+	sync.Pushed = slices.DeleteFunc(sync.Pushed, func(l string) bool { return slices.Contains(remove, l) })
+	return nil
+}
+
+// logins maps the PR's reviewers to their logins on provider. Reviewers
+// without a linked identity cannot be requested there and are skipped.
+func (s *Syncer) logins(ctx context.Context, prID int, provider string) ([]string, error) {
+	reviewers, err := s.repo.GetReviewersByPR(ctx, prID)
+	if err != nil {
+		return nil, fmt.Errorf("get reviewers: %w", err)
+	}
+	res := make([]string, 0, len(reviewers))
+	for _, u := range reviewers {
+		ids, err := s.repo.ListIdentities(ctx, u.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list identities of user %d: %w", u.ID, err)
+		}
+		i := slices.IndexFunc(ids, func(id models.Identity) bool { return id.Provider == provider })
+		if i < 0 {
+			s.logger.Info("reviewer has no identity on provider, not requesting", "pr_id", prID, "user_id", u.ID, "provider", provider)
+			continue
+		}
+		res = append(res, ids[i].Login)
+	}
+	return res, nil
+}
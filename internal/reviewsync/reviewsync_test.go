@@ -0,0 +1,235 @@
+package reviewsync
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/outbox"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+	"prmanager/internal/vcs"
+	"prmanager/internal/vcs/github"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitHub keeps the requested reviewers of every pull request it is asked
+// about and fails the next fail requests with 502.
+type fakeGitHub struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	requested map[string][]string
+	fail      int
+	calls     int
+}
+
+func newFakeGitHub(t *testing.T) *fakeGitHub {
+	f := &fakeGitHub{requested: make(map[string][]string)}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeGitHub) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls++
+	if f.fail > 0 {
+		f.fail--
+		http.Error(w, `{"message":"Bad Gateway"}`, http.StatusBadGateway)
+		return
+	}
+	var body struct {
+		Reviewers []string `json:"reviewers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cur := f.requested[r.URL.Path]
+	switch r.Method {
+	case http.MethodPost:
+		for _, l := range body.Reviewers {
+			if !slices.Contains(cur, l) {
+				cur = append(cur, l)
+			}
+		}
+	case http.MethodDelete:
+		cur = slices.DeleteFunc(cur, func(l string) bool { return slices.Contains(body.Reviewers, l) })
+	}
+	f.requested[r.URL.Path] = cur
+	w.Write([]byte(`{}`))
+}
+
+func (f *fakeGitHub) reviewers(number string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	res := slices.Clone(f.requested["/repos/acme/api/pulls/"+number+"/requested_reviewers"])
+	slices.Sort(res)
+	return res
+}
+
+type fixture struct {
+	repo   repository.Repository
+	svc    *service.Service
+	relay  *outbox.Relay
+	syncer *Syncer
+	gh     *fakeGitHub
+	clock  time.Time
+	users  map[string]models.User
+}
+
+func newFixture(t *testing.T, cfg Config) *fixture {
+	t.Helper()
+	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewRepo()
+	gh := newFakeGitHub(t)
+
+	f := &fixture{repo: repo, gh: gh, clock: time.Now(), users: make(map[string]models.User)}
+	f.svc = service.NewService(repo, logger)
+	f.syncer = NewSyncer(repo, map[string]vcs.Provider{vcs.GitHub: github.NewClient(gh.URL, "token", gh.Client())}, logger, cfg)
+	f.syncer.now = func() time.Time { return f.clock }
+	f.relay = outbox.NewRelay(repo, events.Fanout(f.syncer), logger, outbox.DefaultConfig())
+
+	team, err := f.svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	for _, name := range []string{"author", "alice", "bob", "carol"} {
+		u, err := f.svc.CreateUser(ctx, &team.ID, name, true)
+		require.NoError(t, err)
+		_, err = f.svc.SetIdentity(ctx, u.ID, vcs.GitHub, "gh-"+name)
+		require.NoError(t, err)
+		f.users[name] = u
+	}
+	return f
+}
+
+// run relays every recorded event and makes one round of sync attempts.
+func (f *fixture) run(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	for {
+		n, err := f.relay.RelayDue(ctx)
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := f.syncer.SyncDue(ctx)
+	require.NoError(t, err)
+}
+
+func (f *fixture) open(t *testing.T, number int) models.PRWithReviewers {
+	t.Helper()
+	res, err := f.svc.SyncExternalPR(context.Background(), vcs.ActionOpened, vcs.PullRequest{
+		Ref:         models.ExternalRef{Provider: vcs.GitHub, Repo: "acme/api", Number: number},
+		Title:       "Add search",
+		AuthorLogin: "gh-author",
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.ExternalSyncCreated, res.Status)
+	return *res.PR
+}
+
+func logins(users []models.User) []string {
+	var res []string
+	for _, u := range users {
+		res = append(res, "gh-"+u.Name)
+	}
+	slices.Sort(res)
+	return res
+}
+
+func TestReviewersArePushedAndReplaced(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, DefaultConfig())
+
+	pr := f.open(t, 42)
+	f.run(t)
+	assert.Equal(t, logins(pr.Reviewers), f.gh.reviewers("42"))
+
+	sync, err := f.svc.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewerSyncSynced, sync.Status)
+	assert.Equal(t, 1, sync.Attempts)
+
+	old := pr.Reviewers[0]
+	reassigned, err := f.svc.ReassignReviewer(ctx, pr.ID, old.ID)
+	require.NoError(t, err)
+	f.run(t)
+	assert.Equal(t, logins(reassigned.Reviewers), f.gh.reviewers("42"))
+	assert.NotContains(t, f.gh.reviewers("42"), "gh-"+old.Name, "replaced reviewers are removed")
+
+	calls := f.gh.calls
+	f.run(t)
+	assert.Equal(t, calls, f.gh.calls, "nothing is pushed without changes")
+}
+
+func TestFailedPushesAreRetried(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.MaxAttempts = 3
+	f := newFixture(t, cfg)
+
+	f.gh.fail = 1
+	pr := f.open(t, 7)
+	f.run(t)
+	sync, err := f.svc.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewerSyncPending, sync.Status)
+	assert.Contains(t, sync.LastError, "unexpected status 502")
+	require.NotNil(t, sync.NextAttemptAt)
+	assert.Empty(t, f.gh.reviewers("7"))
+
+	f.run(t)
+	sync, err = f.svc.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sync.Attempts, "retries wait for the backoff")
+
+	f.clock = f.clock.Add(cfg.BaseBackoff)
+	f.run(t)
+	sync, err = f.svc.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewerSyncSynced, sync.Status)
+	assert.Empty(t, sync.LastError)
+	assert.Equal(t, logins(pr.Reviewers), f.gh.reviewers("7"))
+
+	f.gh.fail = 100
+	_, err = f.svc.ReassignReviewer(ctx, pr.ID, pr.Reviewers[0].ID)
+	require.NoError(t, err)
+	for range cfg.MaxAttempts {
+		f.run(t)
+		f.clock = f.clock.Add(cfg.MaxBackoff)
+	}
+	sync, err = f.svc.GetReviewerSync(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReviewerSyncFailed, sync.Status)
+	assert.Equal(t, cfg.MaxAttempts, sync.Attempts)
+}
+
+func TestPRsWithoutExternalRefAreNotSynced(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, DefaultConfig())
+
+	pr, err := f.svc.CreatePR(ctx, "manual", f.users["author"].ID)
+	require.NoError(t, err)
+	f.run(t)
+
+	_, err = f.svc.GetReviewerSync(ctx, pr.ID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	assert.Zero(t, f.gh.calls)
+}
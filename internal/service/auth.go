@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"prmanager/internal/jwtauth"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tokenPrefix marks PR Manager tokens, so they are easy to spot in logs and
+// secret scanners.
+const tokenPrefix = "prm_"
+
+// Principal is who a request acts as: the holder of an API token, or the
+// subject of a single sign-on token. TeamID is the team of UserID at the time
+// the request was authenticated. OrgID is the organization the principal is
+// bound to; an admin without one administers the whole instance.
+type Principal struct {
+	TokenID int
+	Subject string
+	Role    models.Role
+	UserID  *int
+	TeamID  *int
+	OrgID   *int
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context in which the service acts as p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal of ctx. Calls without one come from
+// the CLI, background workers or a server running without authentication,
+// and are not restricted.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IssueToken creates an API token with role. Team lead and user tokens act as
+// userID, which must be a live user; a team lead must be in a team. Tokens
+// are bound to the organization of userID, or else to the one ctx is scoped
+// to. The token itself is returned only here.
+func (s *Service) IssueToken(ctx context.Context, name string, role models.Role, userID *int) (_ models.APIToken, _ string, err error) {
+	ctx, span := s.startSpan(ctx, "IssueToken", attribute.String("role", string(role)), optionalInt("user_id", userID))
+	defer func() { endSpan(span, err) }()
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.APIToken{}, "", fmt.Errorf("%w: token name empty", ErrBadRequest)
+	}
+	switch role {
+	case models.RoleAdmin, models.RoleTeamLead, models.RoleUser:
+	default:
+		return models.APIToken{}, "", fmt.Errorf("%w: unknown role %q", ErrBadRequest, role)
+	}
+	if role != models.RoleAdmin && userID == nil {
+		return models.APIToken{}, "", fmt.Errorf("%w: %s tokens need a user", ErrBadRequest, role)
+	}
+	orgID := repository.OrgFilter(ctx)
+	if userID != nil {
+		u, err := s.getLiveUser(ctx, *userID)
+		if err != nil {
+			return models.APIToken{}, "", err
+		}
+		if role == models.RoleTeamLead && u.TeamID == nil {
+			return models.APIToken{}, "", fmt.Errorf("%w: team lead is not in a team", ErrBadRequest)
+		}
+		orgID = &u.OrgID
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return models.APIToken{}, "", fmt.Errorf("generate token: %w", err)
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	t, err := s.repo.CreateAPIToken(ctx, models.APIToken{Name: name, Role: role, UserID: userID, OrgID: orgID, Hash: hashToken(token)})
+	if err != nil {
+		s.log(ctx).Error("failed to create API token", "error", err, "name", name)
+		return models.APIToken{}, "", err
+	}
+	s.log(ctx).Info("API token issued", "token_id", t.ID, "name", name, "role", role, "user_id", userID, "org_id", orgID)
+	return t, token, nil
+}
+
+func (s *Service) ListTokens(ctx context.Context) (_ []models.APIToken, err error) {
+	ctx, span := s.startSpan(ctx, "ListTokens")
+	defer func() { endSpan(span, err) }()
+	ts, err := s.repo.ListAPITokens(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to list API tokens", "error", err)
+		return nil, err
+	}
+	return ts, nil
+}
+
+func (s *Service) RevokeToken(ctx context.Context, id int) (err error) {
+	ctx, span := s.startSpan(ctx, "RevokeToken", attribute.Int("token_id", id))
+	defer func() { endSpan(span, err) }()
+	err = s.repo.RevokeAPIToken(ctx, id, time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: token not found or already revoked", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to revoke API token", "error", err, "token_id", id)
+		return err
+	}
+	s.log(ctx).Info("API token revoked", "token_id", id)
+	return nil
+}
+
+// Authenticate returns the principal token acts as. Unknown and revoked
+// tokens, and tokens of users that were deleted since, are ErrUnauthorized.
+func (s *Service) Authenticate(ctx context.Context, token string) (_ Principal, err error) {
+	ctx, span := s.startSpan(ctx, "Authenticate")
+	defer func() { endSpan(span, err) }()
+	if !strings.HasPrefix(token, tokenPrefix) {
+		if s.jwt != nil {
+			return s.authenticateJWT(ctx, token)
+		}
+		return Principal{}, fmt.Errorf("%w: malformed token", ErrUnauthorized)
+	}
+	t, err := s.repo.GetAPITokenByHash(ctx, hashToken(token))
+	if errors.Is(err, repository.ErrNotFound) || err == nil && t.RevokedAt != nil {
+		return Principal{}, fmt.Errorf("%w: unknown or revoked token", ErrUnauthorized)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get API token", "error", err)
+		return Principal{}, err
+	}
+
+	p := Principal{TokenID: t.ID, Role: t.Role, UserID: t.UserID, OrgID: t.OrgID}
+	if t.UserID != nil {
+		u, err := s.getLiveUser(ctx, *t.UserID)
+		if errors.Is(err, ErrNotFound) {
+			return Principal{}, fmt.Errorf("%w: token user was deleted", ErrUnauthorized)
+		}
+		if err != nil {
+			return Principal{}, err
+		}
+		p.TeamID, p.OrgID = u.TeamID, &u.OrgID
+	}
+	return p, nil
+}
+
+// authenticateJWT verifies a single sign-on token and maps it to a
+// principal. Instance admins are bound to no organization. Other admins need
+// no linked user, and without one are bound to the organization of the org
+// claim; everyone else must have linked the user claim as their oidc identity
+// and is bound to that user's organization.
+func (s *Service) authenticateJWT(ctx context.Context, token string) (Principal, error) {
+	claims, err := s.jwt.Verify(ctx, token)
+	if errors.Is(err, jwtauth.ErrInvalidToken) {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+	if errors.Is(err, jwtauth.ErrKeysUnavailable) {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to verify JWT", "error", err)
+		return Principal{}, err
+	}
+
+	m := s.jwtMapping
+	p := Principal{Subject: claims.String("sub"), Role: models.RoleUser}
+	groups := claims.Strings(m.GroupsClaim)
+	member := func(of []string) bool {
+		return slices.ContainsFunc(groups, func(g string) bool { return slices.Contains(of, g) })
+	}
+	instance := member(m.InstanceAdminGroups)
+	switch {
+	case instance, member(m.AdminGroups):
+		p.Role = models.RoleAdmin
+	case member(m.TeamLeadGroups):
+		p.Role = models.RoleTeamLead
+	}
+
+	login := claims.String(m.UserClaim)
+	if login == "" && p.Role != models.RoleAdmin {
+		return Principal{}, fmt.Errorf("%w: token has no %s claim", ErrUnauthorized, m.UserClaim)
+	}
+	var u models.User
+	if login != "" {
+		u, err = s.UserByIdentity(ctx, models.OIDCIdentityProvider, login)
+		switch {
+		case errors.Is(err, ErrNotFound) && p.Role == models.RoleAdmin:
+			login = ""
+		case errors.Is(err, ErrNotFound):
+			return Principal{}, fmt.Errorf("%w: no user linked to %s %q", ErrUnauthorized, m.UserClaim, login)
+		case err != nil:
+			return Principal{}, err
+		}
+	}
+	if login != "" {
+		p.UserID, p.TeamID, p.OrgID = &u.ID, u.TeamID, &u.OrgID
+	}
+	switch {
+	case instance:
+		p.OrgID = nil
+	case p.OrgID == nil:
+		orgID := models.DefaultOrgID
+		if ref := claims.String(m.OrgClaim); ref != "" {
+			o, err := s.ResolveOrganization(ctx, ref)
+			if errors.Is(err, ErrNotFound) {
+				return Principal{}, fmt.Errorf("%w: no organization %q", ErrUnauthorized, ref)
+			}
+			if err != nil {
+				return Principal{}, err
+			}
+			orgID = o.ID
+		}
+		p.OrgID = &orgID
+	}
+	return p, nil
+}
+
+// requireAdmin returns ErrForbidden unless ctx has no principal or an admin.
+func requireAdmin(ctx context.Context) error {
+	if p, ok := PrincipalFrom(ctx); ok && p.Role != models.RoleAdmin {
+		return fmt.Errorf("%w: admin required", ErrForbidden)
+	}
+	return nil
+}
+
+// authorize returns ErrForbidden unless the principal of ctx is an admin or
+// acts as one of userIDs, or lead is set and it leads the team of one of
+// them.
+func (s *Service) authorize(ctx context.Context, lead bool, userIDs ...int) error {
+	p, ok := PrincipalFrom(ctx)
+	if !ok || p.Role == models.RoleAdmin {
+		return nil
+	}
+	if p.UserID != nil && slices.Contains(userIDs, *p.UserID) {
+		return nil
+	}
+	if lead && p.Role == models.RoleTeamLead && p.TeamID != nil {
+		for _, id := range userIDs {
+			u, err := s.repo.GetUserByID(ctx, id)
+			if errors.Is(err, repository.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				s.log(ctx).Error("failed to get user", "error", err, "user_id", id)
+				return err
+			}
+			if u.TeamID != nil && *u.TeamID == *p.TeamID {
+				return nil
+			}
+		}
+	}
+	s.log(ctx).Warn("forbidden", "token_id", p.TokenID, "role", p.Role, "user_ids", userIDs)
+	return fmt.Errorf("%w: not allowed to act for this user", ErrForbidden)
+}
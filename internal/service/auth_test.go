@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"prmanager/internal/jwtauth"
+	"prmanager/internal/jwtauth/jwtauthtest"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueAndAuthenticateToken(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	team, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	alice, err := service.CreateUser(ctx, &team.ID, "Alice", true)
+	require.NoError(t, err)
+	loner, err := service.CreateUser(ctx, nil, "Loner", true)
+	require.NoError(t, err)
+
+	_, _, err = service.IssueToken(ctx, "ci", models.RoleUser, nil)
+	assert.ErrorIs(t, err, ErrBadRequest, "user tokens need a user")
+	_, _, err = service.IssueToken(ctx, "ci", "root", nil)
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, _, err = service.IssueToken(ctx, "lead", models.RoleTeamLead, &loner.ID)
+	assert.ErrorIs(t, err, ErrBadRequest, "a team lead needs a team")
+	ghost := loner.ID + 100
+	_, _, err = service.IssueToken(ctx, "ghost", models.RoleUser, &ghost)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	tok, secret, err := service.IssueToken(ctx, "laptop", models.RoleTeamLead, &alice.ID)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(secret, "prm_"))
+	assert.NotContains(t, tok.Hash, secret, "only the hash is stored")
+
+	p, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, tok.ID, p.TokenID)
+	assert.Equal(t, models.RoleTeamLead, p.Role)
+	assert.Equal(t, &alice.ID, p.UserID)
+	assert.Equal(t, &team.ID, p.TeamID)
+
+	_, err = service.Authenticate(ctx, secret+"x")
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	_, err = service.Authenticate(ctx, "Bearer "+secret)
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	require.NoError(t, service.RevokeToken(ctx, tok.ID))
+	assert.ErrorIs(t, service.RevokeToken(ctx, tok.ID), ErrNotFound)
+	_, err = service.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, ErrUnauthorized, "revoked tokens stop working")
+
+	_, secret, err = service.IssueToken(ctx, "phone", models.RoleUser, &alice.ID)
+	require.NoError(t, err)
+	_, err = service.DeleteUser(ctx, alice.ID)
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, secret)
+	assert.ErrorIs(t, err, ErrUnauthorized, "tokens of deleted users stop working")
+
+	all, err := service.ListTokens(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestServiceAuthorization(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	backend, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	frontend, err := service.CreateTeam(ctx, "frontend")
+	require.NoError(t, err)
+	users := map[string]models.User{}
+	for _, m := range []struct {
+		name string
+		team int
+	}{{"Author", backend.ID}, {"Alice", backend.ID}, {"Bob", backend.ID}, {"Carol", backend.ID}, {"Lead", backend.ID}, {"Other", frontend.ID}} {
+		u, err := service.CreateUser(ctx, &m.team, m.name, true)
+		require.NoError(t, err)
+		users[m.name] = u
+	}
+	as := func(role models.Role, name string) context.Context {
+		u := users[name]
+		return WithPrincipal(ctx, Principal{TokenID: 1, Role: role, UserID: &u.ID, TeamID: u.TeamID})
+	}
+	author, lead, other := as(models.RoleUser, "Author"), as(models.RoleTeamLead, "Lead"), as(models.RoleUser, "Other")
+	otherLead := as(models.RoleTeamLead, "Other")
+	admin := WithPrincipal(ctx, Principal{TokenID: 2, Role: models.RoleAdmin})
+
+	_, err = service.CreatePR(other, "Sneaky", users["Author"].ID)
+	assert.ErrorIs(t, err, ErrForbidden, "users only open their own PRs")
+	_, err = service.CreatePR(lead, "On behalf", users["Author"].ID)
+	assert.ErrorIs(t, err, ErrForbidden, "not even their lead")
+	pr, err := service.CreatePR(author, "Add search", users["Author"].ID)
+	require.NoError(t, err)
+	require.Len(t, pr.Reviewers, 2)
+	reviewer := pr.Reviewers[0]
+
+	_, err = service.ListPRsAssignedToUser(other, reviewer.ID, repository.ReviewQueueFilter{})
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.ListPRsAssignedToUser(otherLead, reviewer.ID, repository.ReviewQueueFilter{})
+	assert.ErrorIs(t, err, ErrForbidden, "leads only see their own team's queues")
+	_, err = service.ListPRsAssignedToUser(lead, reviewer.ID, repository.ReviewQueueFilter{})
+	assert.NoError(t, err)
+
+	_, err = service.ReassignReviewer(author, pr.ID, reviewer.ID)
+	assert.ErrorIs(t, err, ErrForbidden, "authors cannot swap out their reviewers")
+	_, err = service.ReassignReviewer(otherLead, pr.ID, reviewer.ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.ReassignReviewer(lead, pr.ID, reviewer.ID)
+	assert.NoError(t, err)
+
+	_, err = service.MergePR(other, pr.ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+	_, err = service.MergePR(author, pr.ID)
+	assert.NoError(t, err)
+
+	_, err = service.SetDigestPrefs(lead, models.DigestPrefs{UserID: users["Alice"].ID, OptOut: true})
+	assert.ErrorIs(t, err, ErrForbidden, "preferences are personal")
+	_, err = service.SetDigestPrefs(admin, models.DigestPrefs{UserID: users["Alice"].ID, OptOut: true})
+	assert.NoError(t, err)
+	_, err = service.ListIdentities(other, users["Alice"].ID)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestAuthenticateJWT(t *testing.T) {
+	ctx := context.Background()
+	idp := jwtauthtest.NewProvider(t)
+	verifier := jwtauth.NewVerifier(jwtauth.NewRemoteKeys(idp.JWKSURL, nil), jwtauth.Config{Issuer: idp.Issuer, Audience: "pr-manager"})
+	service := NewService(memory.NewRepo(), createTestLogger(), WithJWT(verifier, JWTMapping{
+		UserClaim:           "sub",
+		GroupsClaim:         "groups",
+		OrgClaim:            "org",
+		AdminGroups:         []string{"pr-admins"},
+		InstanceAdminGroups: []string{"pr-root"},
+		TeamLeadGroups:      []string{"leads"},
+	}))
+
+	team, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	alice, err := service.CreateUser(ctx, &team.ID, "Alice", true)
+	require.NoError(t, err)
+	_, err = service.SetIdentity(ctx, alice.ID, models.OIDCIdentityProvider, "00uA1ice")
+	require.NoError(t, err)
+
+	token := func(sub string, groups ...string) string {
+		c := idp.Claims(sub, "pr-manager")
+		c["groups"] = groups
+		return idp.Token(c)
+	}
+
+	p, err := service.Authenticate(ctx, token("00uA1ice", "eng"))
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleUser, p.Role)
+	assert.Equal(t, &alice.ID, p.UserID)
+	assert.Equal(t, &team.ID, p.TeamID)
+	assert.Equal(t, "00uA1ice", p.Subject)
+
+	p, err = service.Authenticate(ctx, token("00uA1ice", "eng", "leads"))
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleTeamLead, p.Role)
+
+	_, err = service.Authenticate(ctx, token("00ua1ice"))
+	assert.ErrorIs(t, err, ErrUnauthorized, "subjects are case-sensitive")
+	_, err = service.Authenticate(ctx, token("00uStranger", "eng"))
+	assert.ErrorIs(t, err, ErrUnauthorized, "users must be linked")
+
+	p, err = service.Authenticate(ctx, token("00uStranger", "pr-admins", "leads"))
+	require.NoError(t, err, "admins need no linked user")
+	assert.Equal(t, models.RoleAdmin, p.Role)
+	assert.Nil(t, p.UserID)
+	assert.Equal(t, models.DefaultOrgID, *p.OrgID, "unlinked admins do not administer the whole instance")
+
+	acme, err := service.CreateOrganization(ctx, "acme")
+	require.NoError(t, err)
+	c := idp.Claims("00uStranger", "pr-manager")
+	c["groups"] = []string{"pr-admins"}
+	c["org"] = "acme"
+	p, err = service.Authenticate(ctx, idp.Token(c))
+	require.NoError(t, err)
+	assert.Equal(t, &acme.ID, p.OrgID)
+	c["org"] = "globex"
+	_, err = service.Authenticate(ctx, idp.Token(c))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	p, err = service.Authenticate(ctx, token("00uStranger", "pr-root"))
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, p.Role)
+	assert.Nil(t, p.OrgID, "only instance admin groups administer the whole instance")
+	p, err = service.Authenticate(ctx, token("00uA1ice", "pr-root"))
+	require.NoError(t, err)
+	assert.Equal(t, &alice.ID, p.UserID)
+	assert.Nil(t, p.OrgID)
+
+	c = idp.Claims("00uA1ice", "another-app")
+	_, err = service.Authenticate(ctx, idp.Token(c))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+
+	_, err = service.DeleteUser(ctx, alice.ID)
+	require.NoError(t, err)
+	_, err = service.Authenticate(ctx, token("00uA1ice"))
+	assert.ErrorIs(t, err, ErrUnauthorized, "deleted users cannot sign in")
+}
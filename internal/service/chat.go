@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SetTeamChannel makes the team's PR notifications go to a Slack or
+// Mattermost incoming webhook, optionally overriding its default channel.
+func (s *Service) SetTeamChannel(ctx context.Context, teamRef, webhookURL, channel string) (_ models.TeamChannel, err error) {
+	ctx, span := s.startSpan(ctx, "SetTeamChannel", attribute.String("team", teamRef))
+	defer func() { endSpan(span, err) }()
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.TeamChannel{}, fmt.Errorf("%w: webhook_url must be an absolute http(s) URL", ErrBadRequest)
+	}
+	team, err := s.resolveTeam(ctx, teamRef)
+	if err != nil {
+		return models.TeamChannel{}, err
+	}
+
+	c, err := s.repo.SetTeamChannel(ctx, models.TeamChannel{TeamID: team.ID, WebhookURL: webhookURL, Channel: strings.TrimSpace(channel)})
+	if err != nil {
+		s.log(ctx).Error("failed to set team channel", "error", err, "team_id", team.ID)
+		return models.TeamChannel{}, err
+	}
+	s.log(ctx).Info("team chat channel set", "team_id", team.ID, "channel", c.Channel)
+	return c, nil
+}
+
+func (s *Service) GetTeamChannel(ctx context.Context, teamRef string) (_ models.TeamChannel, err error) {
+	ctx, span := s.startSpan(ctx, "GetTeamChannel", attribute.String("team", teamRef))
+	defer func() { endSpan(span, err) }()
+	team, err := s.resolveTeam(ctx, teamRef)
+	if err != nil {
+		return models.TeamChannel{}, err
+	}
+	c, err := s.repo.GetTeamChannel(ctx, team.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.TeamChannel{}, fmt.Errorf("%w: team has no chat channel", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get team channel", "error", err, "team_id", team.ID)
+		return models.TeamChannel{}, err
+	}
+	return c, nil
+}
+
+func (s *Service) DeleteTeamChannel(ctx context.Context, teamRef string) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteTeamChannel", attribute.String("team", teamRef))
+	defer func() { endSpan(span, err) }()
+	team, err := s.resolveTeam(ctx, teamRef)
+	if err != nil {
+		return err
+	}
+	err = s.repo.DeleteTeamChannel(ctx, team.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: team has no chat channel", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to delete team channel", "error", err, "team_id", team.ID)
+		return err
+	}
+	s.log(ctx).Info("team chat channel removed", "team_id", team.ID)
+	return nil
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// getLiveUser returns the user unless it does not exist or was deleted.
+func (s *Service) getLiveUser(ctx context.Context, id int) (models.User, error) {
+	u, err := s.repo.GetUserByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && u.DeletedAt != nil {
+		return models.User{}, fmt.Errorf("%w: user not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get user", "error", err, "user_id", id)
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+// DeleteUser soft-deletes a user. Their open reviews are handed to other
+// members of their team first, since a deleted user can no longer review.
+func (s *Service) DeleteUser(ctx context.Context, id int) (_ models.UserUpdateResult, err error) {
+	ctx, span := s.startSpan(ctx, "DeleteUser", attribute.Int("user_id", id))
+	defer func() { endSpan(span, err) }()
+	return inTx(ctx, s, func(ctx context.Context, tx *Service) (models.UserUpdateResult, error) {
+		return tx.deleteUser(ctx, id)
+	})
+}
+
+func (s *Service) deleteUser(ctx context.Context, id int) (models.UserUpdateResult, error) {
+	s.log(ctx).Info("deleting user", "user_id", id)
+
+	u, err := s.getLiveUser(ctx, id)
+	if err != nil {
+		return models.UserUpdateResult{}, err
+	}
+
+	reassignments := make([]models.Reassignment, 0)
+	if u.TeamID != nil {
+		if reassignments, err = s.reassignOpenReviews(ctx, id, *u.TeamID); err != nil {
+			return models.UserUpdateResult{}, err
+		}
+	}
+
+	if err := s.repo.DeleteUser(ctx, id); err != nil {
+		s.log(ctx).Error("failed to delete user", "error", err, "user_id", id)
+		return models.UserUpdateResult{}, err
+	}
+	deleted, err := s.repo.GetUserByID(ctx, id)
+	if err != nil {
+		return models.UserUpdateResult{}, err
+	}
+
+	s.log(ctx).Info("user deleted", "user_id", id, "reassignments", len(reassignments))
+	return models.UserUpdateResult{User: deleted, Reassignments: reassignments}, nil
+}
+
+// DeleteTeam soft-deletes a team. Teams with live members are refused so no
+// user is left in a team that no longer exists.
+func (s *Service) DeleteTeam(ctx context.Context, ref string) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteTeam", attribute.String("team", ref))
+	defer func() { endSpan(span, err) }()
+	s.log(ctx).Info("deleting team", "team", ref)
+
+	team, err := s.resolveTeam(ctx, ref)
+	if err != nil {
+		return err
+	}
+	if err := s.ensureTeamEmpty(ctx, team.ID); err != nil {
+		return err
+	}
+	if err := s.repo.DeleteTeam(ctx, team.ID); err != nil {
+		s.log(ctx).Error("failed to delete team", "error", err, "team_id", team.ID)
+		return err
+	}
+
+	s.log(ctx).Info("team deleted", "team_id", team.ID)
+	return nil
+}
+
+// PurgeUser removes a user's personal data for good. The row is kept,
+// anonymized, so PRs they authored or reviewed remain consistent.
+func (s *Service) PurgeUser(ctx context.Context, id int) (err error) {
+	ctx, span := s.startSpan(ctx, "PurgeUser", attribute.Int("user_id", id))
+	defer func() { endSpan(span, err) }()
+	u, err := s.repo.GetUserByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: user not found", ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if u.DeletedAt == nil {
+		if _, err := s.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.PurgeUser(ctx, id); err != nil {
+		s.log(ctx).Error("failed to purge user", "error", err, "user_id", id)
+		return err
+	}
+
+	s.log(ctx).Info("user purged", "user_id", id)
+	return nil
+}
+
+// PurgeTeam removes a team row, deleted or not. Like DeleteTeam it refuses
+// teams that still have live members.
+func (s *Service) PurgeTeam(ctx context.Context, id int) (err error) {
+	ctx, span := s.startSpan(ctx, "PurgeTeam", attribute.Int("team_id", id))
+	defer func() { endSpan(span, err) }()
+	if _, err := s.repo.GetTeamByID(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("%w: team not found", ErrNotFound)
+		}
+		return err
+	}
+	if err := s.ensureTeamEmpty(ctx, id); err != nil {
+		return err
+	}
+	if err := s.repo.PurgeTeam(ctx, id); err != nil {
+		s.log(ctx).Error("failed to purge team", "error", err, "team_id", id)
+		return err
+	}
+
+	s.log(ctx).Info("team purged", "team_id", id)
+	return nil
+}
+
+func (s *Service) ensureTeamEmpty(ctx context.Context, teamID int) error {
+	members, err := s.repo.ListTeamMembers(ctx, teamID)
+	if err != nil {
+		s.log(ctx).Error("failed to list team members", "error", err, "team_id", teamID)
+		return err
+	}
+	if len(members) > 0 {
+		return fmt.Errorf("%w: move or delete its %d members first", ErrTeamNotEmpty, len(members))
+	}
+	return nil
+}
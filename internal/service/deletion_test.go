@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteUserReassignsOpenReviews(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	team, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	author, err := service.CreateUser(ctx, &team.ID, "Author", true)
+	require.NoError(t, err)
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		_, err := service.CreateUser(ctx, &team.ID, name, true)
+		require.NoError(t, err)
+	}
+	pr, err := service.CreatePR(ctx, "Add search", author.ID)
+	require.NoError(t, err)
+	gone := pr.Reviewers[0]
+
+	res, err := service.DeleteUser(ctx, gone.ID)
+	require.NoError(t, err)
+	require.NotNil(t, res.User.DeletedAt)
+	require.Len(t, res.Reassignments, 1)
+
+	got, err := service.GetPR(ctx, pr.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, ids(got.Reviewers), gone.ID)
+
+	_, err = service.DeleteUser(ctx, gone.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = service.UpdateUser(ctx, gone.ID, UserUpdate{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// deleted users are not candidates for new PRs
+	next, err := service.CreatePR(ctx, "Fix search", author.ID)
+	require.NoError(t, err)
+	assert.NotContains(t, ids(next.Reviewers), gone.ID)
+
+	user, err := service.GetUser(ctx, gone.ID)
+	require.NoError(t, err, "deleted users still render")
+	assert.Equal(t, gone.Name, user.Name)
+}
+
+func TestDeleteTeamRequiresNoMembers(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	team, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	u, err := service.CreateUser(ctx, &team.ID, "Alice", true)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.DeleteTeam(ctx, "backend"), ErrTeamNotEmpty)
+
+	_, err = service.DeleteUser(ctx, u.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteTeam(ctx, "backend"))
+
+	_, err = service.GetTeam(ctx, "backend")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, service.DeleteTeam(ctx, "backend"), ErrNotFound)
+	_, err = service.CreateUser(ctx, &team.ID, "Bob", true)
+	assert.ErrorIs(t, err, ErrBadRequest, "deleted teams take no new members")
+}
+
+func TestPurgeUserAnonymizesAndKeepsHistory(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	team, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	author, err := service.CreateUser(ctx, &team.ID, "Author", true)
+	require.NoError(t, err)
+	reviewer, err := service.CreateUser(ctx, &team.ID, "Alice", true)
+	require.NoError(t, err)
+	pr, err := service.CreatePR(ctx, "Add search", author.ID)
+	require.NoError(t, err)
+	_, err = service.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, service.PurgeUser(ctx, reviewer.ID))
+
+	got, err := service.GetPR(ctx, pr.ID)
+	require.NoError(t, err)
+	require.Len(t, got.Reviewers, 1)
+	assert.Equal(t, reviewer.ID, got.Reviewers[0].ID)
+	assert.Equal(t, repository.AnonymizedUserName, got.Reviewers[0].Name)
+
+	assert.ErrorIs(t, service.PurgeTeam(ctx, team.ID), ErrTeamNotEmpty)
+	require.NoError(t, service.PurgeUser(ctx, author.ID))
+	require.NoError(t, service.PurgeTeam(ctx, team.ID))
+	assert.ErrorIs(t, service.PurgeTeam(ctx, team.ID), ErrNotFound)
+
+	_, err = service.CreateTeam(ctx, "backend")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, service.PurgeUser(ctx, 999), ErrNotFound)
+}
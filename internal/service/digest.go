@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// GetDigestPrefs returns the user's digest preferences. Users who never set
+// any get the defaults: subscribed, at the server's send time.
+func (s *Service) GetDigestPrefs(ctx context.Context, userID int) (_ models.DigestPrefs, err error) {
+	ctx, span := s.startSpan(ctx, "GetDigestPrefs", attribute.Int("user_id", userID))
+	defer func() { endSpan(span, err) }()
+	if err := s.authorize(ctx, false, userID); err != nil {
+		return models.DigestPrefs{}, err
+	}
+	if err := s.requireUser(ctx, userID); err != nil {
+		return models.DigestPrefs{}, err
+	}
+	p, err := s.repo.GetDigestPrefs(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.DigestPrefs{UserID: userID}, nil
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get digest prefs", "error", err, "user_id", userID)
+		return models.DigestPrefs{}, err
+	}
+	return p, nil
+}
+
+// SetDigestPrefs replaces the opt-out flag, send time and time zone of
+// p.UserID. Empty SendAt and Timezone restore the server defaults.
+func (s *Service) SetDigestPrefs(ctx context.Context, p models.DigestPrefs) (_ models.DigestPrefs, err error) {
+	ctx, span := s.startSpan(ctx, "SetDigestPrefs", attribute.Int("user_id", p.UserID))
+	defer func() { endSpan(span, err) }()
+	p.SendAt = strings.TrimSpace(p.SendAt)
+	p.Timezone = strings.TrimSpace(p.Timezone)
+	if p.SendAt != "" {
+		at, err := time.Parse(models.DigestTimeLayout, p.SendAt)
+		if err != nil {
+			return models.DigestPrefs{}, fmt.Errorf("%w: send_at must be a time of day like 08:30", ErrBadRequest)
+		}
+		p.SendAt = at.Format(models.DigestTimeLayout)
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return models.DigestPrefs{}, fmt.Errorf("%w: unknown timezone %q", ErrBadRequest, p.Timezone)
+		}
+	}
+	if err := s.authorize(ctx, false, p.UserID); err != nil {
+		return models.DigestPrefs{}, err
+	}
+	if err := s.requireUser(ctx, p.UserID); err != nil {
+		return models.DigestPrefs{}, err
+	}
+
+	res, err := s.repo.SetDigestPrefs(ctx, p)
+	if err != nil {
+		s.log(ctx).Error("failed to set digest prefs", "error", err, "user_id", p.UserID)
+		return models.DigestPrefs{}, err
+	}
+	s.log(ctx).Info("digest preferences set", "user_id", p.UserID, "opt_out", res.OptOut, "send_at", res.SendAt, "timezone", res.Timezone)
+	return res, nil
+}
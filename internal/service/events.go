@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/outbox"
+	"prmanager/internal/repository"
+)
+
+// inTx runs fn with a copy of s whose repository is bound to one transaction,
+// so the state changes fn makes and the events it emits commit together. fn
+// gets the context of the transaction's span.
+func inTx[T any](ctx context.Context, s *Service, fn func(ctx context.Context, tx *Service) (T, error)) (T, error) {
+	ctx, span := s.startSpan(ctx, "transaction")
+	var res T
+	err := s.repo.WithTx(ctx, func(r repository.Repository) error {
+		tx := *s
+		tx.repo = r
+		var err error
+		res, err = fn(ctx, &tx)
+		return err
+	})
+	endSpan(span, err)
+	return res, err
+}
+
+// emit records an event about prID in the outbox. It must run inside inTx so
+// the event is only published if the change it describes is committed.
+func (s *Service) emit(ctx context.Context, typ events.Type, prID int, data any) error {
+	e, err := events.New(typ, data)
+	if err == nil {
+		err = outbox.Record(ctx, s.repo, e, outbox.PRKey(prID))
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to record event", "error", err, "type", typ, "pr_id", prID)
+	}
+	return err
+}
+
+func (s *Service) emitPRCreated(ctx context.Context, pr models.PRWithReviewers) error {
+	if err := s.emit(ctx, events.PRCreated, pr.ID, events.PRData{PR: pr}); err != nil {
+		return err
+	}
+	for _, r := range pr.Reviewers {
+		if err := s.emit(ctx, events.ReviewerAssigned, pr.ID, events.ReviewerAssignedData{PRID: pr.ID, ReviewerID: r.ID}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// drainOutbox returns the recorded events in the order the relay would
+// publish them, marking each one delivered.
+func drainOutbox(t *testing.T, r repository.Repository) []events.Event {
+	ctx := context.Background()
+	var out []events.Event
+	for {
+		claimed, err := r.ClaimOutboxEvents(ctx, time.Now().Add(time.Second), time.Minute, 100)
+		require.NoError(t, err)
+		if len(claimed) == 0 {
+			return out
+		}
+		for _, row := range claimed {
+			var e events.Event
+			require.NoError(t, json.Unmarshal(row.Payload, &e))
+			out = append(out, e)
+			require.NoError(t, r.MarkOutboxEventDelivered(ctx, row.ID, time.Now()))
+		}
+	}
+}
+
+func eventTypes(es []events.Event) []events.Type {
+	var out []events.Type
+	for _, e := range es {
+		out = append(out, e.Type)
+	}
+	return out
+}
+
+func TestLifecycleEventsAreRecorded(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepo()
+	svc := NewService(repo, createTestLogger())
+
+	team, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	author, err := svc.CreateUser(ctx, &team.ID, "Author", true)
+	require.NoError(t, err)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := svc.CreateUser(ctx, &team.ID, name, true)
+		require.NoError(t, err)
+	}
+
+	pr, err := svc.CreatePR(ctx, "Add search", author.ID)
+	require.NoError(t, err)
+	old := pr.Reviewers[0].ID
+	res, err := svc.ReassignReviewer(ctx, pr.ID, old)
+	require.NoError(t, err)
+	_, err = svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+	_, err = svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+
+	recorded := drainOutbox(t, repo)
+	assert.Equal(t, []events.Type{
+		events.PRCreated, events.ReviewerAssigned, events.ReviewerAssigned,
+		events.ReviewerReassigned, events.PRMerged,
+	}, eventTypes(recorded), "merging twice records one event")
+
+	var data events.ReviewerReassignedData
+	require.NoError(t, json.Unmarshal(recorded[3].Data, &data))
+	assert.Equal(t, pr.ID, data.PRID)
+	assert.Equal(t, old, data.OldReviewerID)
+	assert.Contains(t, ids(res.Reviewers), data.NewReviewerID)
+}
+
+// failingOutbox cannot record events, inside or outside a transaction.
+type failingOutbox struct {
+	repository.Repository
+}
+
+func (failingOutbox) CreateOutboxEvent(context.Context, models.OutboxEvent) error {
+	return errors.New("disk full")
+}
+
+func (f failingOutbox) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return f.Repository.WithTx(ctx, func(tx repository.Repository) error {
+		return fn(failingOutbox{tx})
+	})
+}
+
+func TestChangeIsRolledBackWhenEventCannotBeRecorded(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepo()
+	svc := NewService(failingOutbox{repo}, createTestLogger())
+
+	team, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	author, err := svc.CreateUser(ctx, &team.ID, "Author", true)
+	require.NoError(t, err)
+	_, err = svc.CreateUser(ctx, &team.ID, "Reviewer", true)
+	require.NoError(t, err)
+
+	_, err = svc.CreatePR(ctx, "x", author.ID)
+	require.Error(t, err)
+
+	_, err = repo.GetPRByID(ctx, 1)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "no PR without its pr.created event")
+	n, err := repo.CountAssignments(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
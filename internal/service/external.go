@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/vcs"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SetIdentity links userID to login on provider, replacing any login the user
+// had there. Code host logins and email addresses are case-insensitive and
+// stored lower-cased; chat handles and single sign-on subjects are kept as
+// given, since Slack member IDs are upper-case and subjects are opaque.
+//
+// Users may set their own email address. Every other login is trusted to say
+// who someone is, by imports, single sign-on and the slash command, so only an
+// admin may link it. Code host and chat logins are matched instance-wide, as
+// the integrations that resolve them are, so only an instance admin may link
+// those: an organization's admin could otherwise claim another tenant's login.
+func (s *Service) SetIdentity(ctx context.Context, userID int, provider, login string) (_ models.Identity, err error) {
+	ctx, span := s.startSpan(ctx, "SetIdentity", attribute.Int("user_id", userID), attribute.String("provider", provider))
+	defer func() { endSpan(span, err) }()
+	switch {
+	case provider == models.ChatIdentityProvider:
+		login = strings.TrimPrefix(strings.TrimSpace(login), "@")
+	case provider == models.OIDCIdentityProvider:
+		login = strings.TrimSpace(login)
+	case provider == models.EmailIdentityProvider:
+		login = strings.TrimSpace(login)
+		if login != "" {
+			addr, err := mail.ParseAddress(login)
+			if err != nil {
+				return models.Identity{}, fmt.Errorf("%w: login must be an email address", ErrBadRequest)
+			}
+			login = strings.ToLower(addr.Address)
+		}
+	case vcs.KnownProvider(provider):
+		login = strings.ToLower(strings.TrimSpace(login))
+	default:
+		return models.Identity{}, fmt.Errorf("%w: unknown provider %q", ErrNotFound, provider)
+	}
+	if login == "" {
+		return models.Identity{}, fmt.Errorf("%w: login is required", ErrBadRequest)
+	}
+	switch provider {
+	case models.EmailIdentityProvider:
+		err = s.authorize(ctx, false, userID)
+	case models.OIDCIdentityProvider:
+		err = requireAdmin(ctx)
+	default:
+		err = requireInstanceAdmin(ctx)
+	}
+	if err != nil {
+		return models.Identity{}, err
+	}
+	if err := s.requireUser(ctx, userID); err != nil {
+		return models.Identity{}, err
+	}
+
+	id, err := s.repo.SetIdentity(ctx, models.Identity{UserID: userID, Provider: provider, Login: login})
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return models.Identity{}, fmt.Errorf("%w: %s login %q is linked to another user", ErrConflict, provider, login)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to set identity", "error", err, "user_id", userID, "provider", provider)
+		return models.Identity{}, err
+	}
+	s.log(ctx).Info("identity linked", "user_id", userID, "provider", provider, "login", login)
+	return id, nil
+}
+
+func (s *Service) ListIdentities(ctx context.Context, userID int) (_ []models.Identity, err error) {
+	ctx, span := s.startSpan(ctx, "ListIdentities", attribute.Int("user_id", userID))
+	defer func() { endSpan(span, err) }()
+	if err := s.authorize(ctx, false, userID); err != nil {
+		return nil, err
+	}
+	if err := s.requireUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	ids, err := s.repo.ListIdentities(ctx, userID)
+	if err != nil {
+		s.log(ctx).Error("failed to list identities", "error", err, "user_id", userID)
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (s *Service) DeleteIdentity(ctx context.Context, userID int, provider string) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteIdentity", attribute.Int("user_id", userID), attribute.String("provider", provider))
+	defer func() { endSpan(span, err) }()
+	if err := s.authorize(ctx, false, userID); err != nil {
+		return err
+	}
+	if err := s.requireUser(ctx, userID); err != nil {
+		return err
+	}
+	err = s.repo.DeleteIdentity(ctx, userID, provider)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: identity not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to delete identity", "error", err, "user_id", userID, "provider", provider)
+		return err
+	}
+	s.log(ctx).Info("identity unlinked", "user_id", userID, "provider", provider)
+	return nil
+}
+
+// UserByIdentity returns the live user whose login on provider is login.
+func (s *Service) UserByIdentity(ctx context.Context, provider, login string) (_ models.User, err error) {
+	ctx, span := s.startSpan(ctx, "UserByIdentity", attribute.String("provider", provider))
+	defer func() { endSpan(span, err) }()
+	if provider != models.ChatIdentityProvider && provider != models.OIDCIdentityProvider {
+		login = strings.ToLower(login)
+	}
+	id, err := s.repo.GetIdentityByLogin(ctx, provider, login)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.User{}, fmt.Errorf("%w: no user with %s login %q", ErrNotFound, provider, login)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get identity", "error", err, "provider", provider)
+		return models.User{}, err
+	}
+	u, err := s.repo.GetUserByID(ctx, id.UserID)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && u.DeletedAt != nil {
+		return models.User{}, fmt.Errorf("%w: user not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get user", "error", err, "user_id", id.UserID)
+		return models.User{}, err
+	}
+	return u, nil
+}
+
+func (s *Service) requireUser(ctx context.Context, userID int) error {
+	u, err := s.repo.GetUserByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && u.DeletedAt != nil {
+		return fmt.Errorf("%w: user not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get user", "error", err, "user_id", userID)
+		return err
+	}
+	return nil
+}
+
+// SyncExternalPR mirrors action on an external pull request onto the PR that
+// tracks it, creating that PR when the pull request is opened. Events about
+// pull requests that cannot be tracked, e.g. because the author has no linked
+// identity, are ignored rather than failed so the code host does not retry.
+func (s *Service) SyncExternalPR(ctx context.Context, action vcs.Action, pr vcs.PullRequest) (_ models.ExternalSyncResult, err error) {
+	ctx, span := s.startSpan(ctx, "SyncExternalPR", attribute.String("provider", pr.Ref.Provider), attribute.String("repo", pr.Ref.Repo), attribute.Int("number", pr.Ref.Number), attribute.String("action", string(action)))
+	defer func() { endSpan(span, err) }()
+	sync := func(ctx context.Context, tx *Service) (models.ExternalSyncResult, error) {
+		return tx.syncExternalPR(ctx, action, pr)
+	}
+	res, err := inTx(ctx, s, sync)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		// A concurrent delivery of the same event linked the pull request
+		// first; run again to act on the PR it created.
+		res, err = inTx(ctx, s, sync)
+	}
+	return res, err
+}
+
+func (s *Service) syncExternalPR(ctx context.Context, action vcs.Action, pr vcs.PullRequest) (models.ExternalSyncResult, error) {
+	ref := pr.Ref
+	s.log(ctx).Info("syncing external PR", "provider", ref.Provider, "repo", ref.Repo, "number", ref.Number, "action", action)
+
+	tracked, err := s.repo.GetPRByExternalRef(ctx, ref.Provider, ref.Repo, ref.Number)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		s.log(ctx).Error("failed to look up external PR", "error", err, "provider", ref.Provider, "repo", ref.Repo, "number", ref.Number)
+		return models.ExternalSyncResult{}, err
+	}
+	isTracked := err == nil
+
+	switch action {
+	case vcs.ActionOpened, vcs.ActionReopened:
+		if !isTracked {
+			return s.importExternalPR(ctx, pr)
+		}
+		if tracked.Status != models.PRStatusClosed || action == vcs.ActionOpened {
+			return s.syncResult(ctx, models.ExternalSyncUnchanged, tracked)
+		}
+		res, err := s.reopenPR(ctx, tracked.ID)
+		return updated(res, err)
+
+	case vcs.ActionClosed, vcs.ActionMerged:
+		if !isTracked {
+			return ignored("pull request is not tracked"), nil
+		}
+		target, transition := models.PRStatusClosed, s.closePR
+		if action == vcs.ActionMerged {
+			target, transition = models.PRStatusMerged, s.mergePR
+		}
+		if tracked.Status == target {
+			return s.syncResult(ctx, models.ExternalSyncUnchanged, tracked)
+		}
+		if tracked.Status == models.PRStatusMerged {
+			return ignored("pr is already merged"), nil
+		}
+		res, err := transition(ctx, tracked.ID)
+		return updated(res, err)
+	}
+	return ignored(fmt.Sprintf("unsupported action %q", action)), nil
+}
+
+func (s *Service) importExternalPR(ctx context.Context, pr vcs.PullRequest) (models.ExternalSyncResult, error) {
+	if pr.AuthorLogin == "" {
+		return ignored("author of the pull request is unknown"), nil
+	}
+	login := strings.ToLower(pr.AuthorLogin)
+	id, err := s.repo.GetIdentityByLogin(ctx, pr.Ref.Provider, login)
+	if errors.Is(err, repository.ErrNotFound) {
+		s.log(ctx).Info("ignoring external PR by unknown author", "provider", pr.Ref.Provider, "login", login)
+		return ignored(fmt.Sprintf("%s login %q is not linked to a user", pr.Ref.Provider, login)), nil
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to look up identity", "error", err, "provider", pr.Ref.Provider, "login", login)
+		return models.ExternalSyncResult{}, err
+	}
+
+	author, err := s.repo.GetUserByID(ctx, id.UserID)
+	if err != nil {
+		s.log(ctx).Error("failed to get identity user", "error", err, "user_id", id.UserID)
+		return models.ExternalSyncResult{}, err
+	}
+	if !author.IsActive || author.DeletedAt != nil {
+		return ignored("author is not active"), nil
+	}
+
+	created, err := s.createPR(ctx, pr.Title, author.ID)
+	if err != nil {
+		return models.ExternalSyncResult{}, err
+	}
+	ref := pr.Ref
+	ref.PRID = created.ID
+	if err := s.repo.LinkExternalPR(ctx, ref); err != nil {
+		s.log(ctx).Warn("failed to link external PR", "error", err, "pr_id", created.ID)
+		return models.ExternalSyncResult{}, err
+	}
+	return models.ExternalSyncResult{Status: models.ExternalSyncCreated, PR: &created}, nil
+}
+
+func (s *Service) syncResult(ctx context.Context, status models.ExternalSyncStatus, pr models.PR) (models.ExternalSyncResult, error) {
+	revs, err := s.repo.GetReviewersByPR(ctx, pr.ID)
+	if err != nil {
+		s.log(ctx).Error("failed to get reviewers", "error", err, "pr_id", pr.ID)
+		return models.ExternalSyncResult{}, err
+	}
+	return models.ExternalSyncResult{Status: status, PR: &models.PRWithReviewers{PR: pr, Reviewers: revs}}, nil
+}
+
+func updated(pr models.PRWithReviewers, err error) (models.ExternalSyncResult, error) {
+	if err != nil {
+		return models.ExternalSyncResult{}, err
+	}
+	return models.ExternalSyncResult{Status: models.ExternalSyncUpdated, PR: &pr}, nil
+}
+
+func ignored(reason string) models.ExternalSyncResult {
+	return models.ExternalSyncResult{Status: models.ExternalSyncIgnored, Reason: reason}
+}
+
+// closePR moves an open PR to CLOSED. Closing a closed PR is a no-op.
+func (s *Service) closePR(ctx context.Context, prID int) (models.PRWithReviewers, error) {
+	return s.setOpenState(ctx, prID, models.PRStatusOpen, models.PRStatusClosed, events.PRClosed)
+}
+
+// reopenPR moves a closed PR back to OPEN. Reopening an open PR is a no-op.
+func (s *Service) reopenPR(ctx context.Context, prID int) (models.PRWithReviewers, error) {
+	return s.setOpenState(ctx, prID, models.PRStatusClosed, models.PRStatusOpen, events.PRReopened)
+}
+
+func (s *Service) setOpenState(ctx context.Context, prID int, from, to models.PRStatus, typ events.Type) (models.PRWithReviewers, error) {
+	pr, err := s.repo.LockPR(ctx, prID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.PRWithReviewers{}, fmt.Errorf("%w: pr not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get PR", "error", err, "pr_id", prID)
+		return models.PRWithReviewers{}, err
+	}
+	revs, err := s.repo.GetReviewersByPR(ctx, prID)
+	if err != nil {
+		s.log(ctx).Error("failed to get reviewers", "error", err, "pr_id", prID)
+		return models.PRWithReviewers{}, err
+	}
+	res := models.PRWithReviewers{PR: pr, Reviewers: revs}
+
+	switch pr.Status {
+	case to:
+		return res, nil
+	case from:
+	default:
+		return models.PRWithReviewers{}, fmt.Errorf("%w: pr is %s", ErrBadRequest, pr.Status)
+	}
+
+	if err := s.repo.SetPRStatus(ctx, prID, string(to)); err != nil {
+		s.log(ctx).Error("failed to set PR status", "error", err, "pr_id", prID, "status", to)
+		return models.PRWithReviewers{}, err
+	}
+	res.Status = to
+	s.log(ctx).Info("PR status changed", "pr_id", prID, "from", from, "status", to)
+	return res, s.emit(ctx, typ, prID, events.PRData{PR: res})
+}
+
+// GetReviewerSync reports how far pushing the PR's reviewers to its code host
+// got. PRs that were not imported, or whose reviewers were never pushed, have
+// no sync.
+func (s *Service) GetReviewerSync(ctx context.Context, prID int) (_ models.ReviewerSync, err error) {
+	ctx, span := s.startSpan(ctx, "GetReviewerSync", attribute.Int("pr_id", prID))
+	defer func() { endSpan(span, err) }()
+	if _, err := s.repo.GetPRByID(ctx, prID); errors.Is(err, repository.ErrNotFound) {
+		return models.ReviewerSync{}, fmt.Errorf("%w: pr not found", ErrNotFound)
+	} else if err != nil {
+		s.log(ctx).Error("failed to get PR", "error", err, "pr_id", prID)
+		return models.ReviewerSync{}, err
+	}
+	sync, err := s.repo.GetReviewerSync(ctx, prID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.ReviewerSync{}, fmt.Errorf("%w: pr has no reviewer sync", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get reviewer sync", "error", err, "pr_id", prID)
+		return models.ReviewerSync{}, err
+	}
+	return sync, nil
+}
@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/vcs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func githubPR(number int, login string) vcs.PullRequest {
+	return vcs.PullRequest{
+		Ref:         models.ExternalRef{Provider: vcs.GitHub, Repo: "acme/api", Number: number},
+		Title:       "Add search",
+		AuthorLogin: login,
+	}
+}
+
+func TestSyncExternalPRLifecycle(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepo()
+	svc := NewService(repo, createTestLogger())
+
+	team, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	author, err := svc.CreateUser(ctx, &team.ID, "Author", true)
+	require.NoError(t, err)
+	_, err = svc.CreateUser(ctx, &team.ID, "Reviewer", true)
+	require.NoError(t, err)
+	_, err = svc.SetIdentity(ctx, author.ID, vcs.GitHub, "  Octo-Author ")
+	require.NoError(t, err)
+
+	res, err := svc.SyncExternalPR(ctx, vcs.ActionOpened, githubPR(7, "octo-author"))
+	require.NoError(t, err)
+	require.Equal(t, models.ExternalSyncCreated, res.Status)
+	assert.Equal(t, author.ID, res.PR.AuthorID)
+	assert.Len(t, res.PR.Reviewers, 1)
+	prID := res.PR.ID
+
+	res, err = svc.SyncExternalPR(ctx, vcs.ActionOpened, githubPR(7, "octo-author"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ExternalSyncUnchanged, res.Status, "redelivered events are deduplicated")
+	assert.Equal(t, prID, res.PR.ID)
+
+	res, err = svc.SyncExternalPR(ctx, vcs.ActionClosed, githubPR(7, "octo-author"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ExternalSyncUpdated, res.Status)
+	assert.Equal(t, models.PRStatusClosed, res.PR.Status)
+
+	_, err = svc.ReassignReviewer(ctx, prID, res.PR.Reviewers[0].ID)
+	assert.EqualError(t, err, "bad request: cannot reassign closed pr")
+
+	res, err = svc.SyncExternalPR(ctx, vcs.ActionReopened, githubPR(7, "octo-author"))
+	require.NoError(t, err)
+	assert.Equal(t, models.PRStatusOpen, res.PR.Status)
+
+	res, err = svc.SyncExternalPR(ctx, vcs.ActionMerged, githubPR(7, "octo-author"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ExternalSyncUpdated, res.Status)
+	assert.Equal(t, models.PRStatusMerged, res.PR.Status)
+
+	res, err = svc.SyncExternalPR(ctx, vcs.ActionClosed, githubPR(7, "octo-author"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ExternalSyncIgnored, res.Status, "merged PRs stay merged")
+
+	assert.Equal(t, []events.Type{
+		events.PRCreated, events.ReviewerAssigned, events.PRClosed, events.PRReopened, events.PRMerged,
+	}, eventTypes(drainOutbox(t, repo)))
+}
+
+func TestSyncExternalPRIgnoresUntrackable(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewRepo()
+	svc := NewService(repo, createTestLogger())
+
+	team, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	inactive, err := svc.CreateUser(ctx, &team.ID, "Inactive", false)
+	require.NoError(t, err)
+	_, err = svc.SetIdentity(ctx, inactive.ID, vcs.GitHub, "sleepy")
+	require.NoError(t, err)
+
+	res, err := svc.SyncExternalPR(ctx, vcs.ActionOpened, githubPR(1, "stranger"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ExternalSyncIgnored, res.Status)
+	assert.Contains(t, res.Reason, "not linked")
+
+	res, err = svc.SyncExternalPR(ctx, vcs.ActionOpened, githubPR(1, "sleepy"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ExternalSyncIgnored, res.Status)
+
+	res, err = svc.SyncExternalPR(ctx, vcs.ActionMerged, githubPR(2, "sleepy"))
+	require.NoError(t, err)
+	assert.Equal(t, models.ExternalSyncIgnored, res.Status)
+	assert.Empty(t, drainOutbox(t, repo))
+}
+
+func TestSetIdentity(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(memory.NewRepo(), createTestLogger())
+
+	team, err := svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	alice, err := svc.CreateUser(ctx, &team.ID, "alice", true)
+	require.NoError(t, err)
+	bob, err := svc.CreateUser(ctx, &team.ID, "bob", true)
+	require.NoError(t, err)
+
+	_, err = svc.SetIdentity(ctx, alice.ID, vcs.GitHub, "Alice")
+	require.NoError(t, err)
+	_, err = svc.SetIdentity(ctx, bob.ID, vcs.GitHub, "ALICE")
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = svc.SetIdentity(ctx, bob.ID, vcs.GitHub, " ")
+	assert.ErrorIs(t, err, ErrBadRequest)
+	_, err = svc.SetIdentity(ctx, bob.ID, "bitbucket", "bob")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = svc.SetIdentity(ctx, bob.ID+100, vcs.GitHub, "ghost")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	ids, err := svc.ListIdentities(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	assert.Equal(t, "alice", ids[0].Login)
+
+	require.NoError(t, svc.DeleteIdentity(ctx, alice.ID, vcs.GitHub))
+	assert.ErrorIs(t, svc.DeleteIdentity(ctx, alice.ID, vcs.GitHub), ErrNotFound)
+}
+
+func TestOrgAdminCannotClaimInstanceWideLogins(t *testing.T) {
+	ctx := context.Background()
+	svc := NewService(memory.NewRepo(), createTestLogger())
+
+	acme, err := svc.CreateOrganization(ctx, "acme")
+	require.NoError(t, err)
+	acmeCtx := repository.WithOrg(ctx, acme.ID)
+	team, err := svc.CreateTeam(acmeCtx, "backend")
+	require.NoError(t, err)
+	alice, err := svc.CreateUser(acmeCtx, &team.ID, "alice", true)
+	require.NoError(t, err)
+
+	orgAdmin := WithPrincipal(acmeCtx, Principal{TokenID: 1, Role: models.RoleAdmin, OrgID: &acme.ID})
+	for _, provider := range []string{vcs.GitHub, vcs.GitLab, models.ChatIdentityProvider} {
+		_, err = svc.SetIdentity(orgAdmin, alice.ID, provider, "octocat")
+		assert.ErrorIs(t, err, ErrForbidden, provider)
+	}
+	_, err = svc.SetIdentity(orgAdmin, alice.ID, models.EmailIdentityProvider, "alice@acme.test")
+	assert.NoError(t, err, "organization admins still manage their users' email")
+
+	root := WithPrincipal(acmeCtx, Principal{TokenID: 2, Role: models.RoleAdmin})
+	_, err = svc.SetIdentity(root, alice.ID, vcs.GitHub, "octocat")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+// WithLogger returns a context whose service calls log to l, e.g. a logger
+// carrying the ID of the request being served.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// LoggerFrom returns the logger of ctx, or fallback if it has none.
+func LoggerFrom(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return fallback
+}
+
+func (s *Service) log(ctx context.Context) *slog.Logger {
+	return LoggerFrom(ctx, s.logger)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"fmt"
+
+	"prmanager/internal/jwtauth"
+)
+
+type Option func(*Service)
+
+// MovePolicy decides what happens to a user's open reviews when they move to
+// another team.
+type MovePolicy string
+
+const (
+	MovePolicyKeep     MovePolicy = "keep"
+	MovePolicyReassign MovePolicy = "reassign"
+)
+
+func ParseMovePolicy(s string) (MovePolicy, error) {
+	switch p := MovePolicy(s); p {
+	case MovePolicyKeep, MovePolicyReassign:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown move policy %q, want %q or %q", s, MovePolicyKeep, MovePolicyReassign)
+}
+
+func WithMovePolicy(p MovePolicy) Option {
+	return func(s *Service) {
+		s.movePolicy = p
+	}
+}
+
+// JWTMapping says how the claims of a single sign-on token map to a user and
+// a role. UserClaim is looked up as the user's oidc identity. A token whose
+// GroupsClaim lists one of AdminGroups acts as an admin, else one of
+// TeamLeadGroups as a team lead, else as a user.
+//
+// An admin without a linked user is bound to the organization whose name or
+// id is in OrgClaim, or to the default organization when OrgClaim is unset or
+// absent. Only a member of one of InstanceAdminGroups administers the whole
+// instance.
+type JWTMapping struct {
+	UserClaim           string
+	GroupsClaim         string
+	OrgClaim            string
+	AdminGroups         []string
+	InstanceAdminGroups []string
+	TeamLeadGroups      []string
+}
+
+// WithJWT makes Authenticate accept tokens of a single sign-on provider
+// besides API tokens.
+func WithJWT(v *jwtauth.Verifier, m JWTMapping) Option {
+	return func(s *Service) {
+		s.jwt = v
+		s.jwtMapping = m
+	}
+}
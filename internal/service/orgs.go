@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// requireInstanceAdmin returns ErrForbidden unless ctx has no principal or
+// an admin that is not bound to an organization.
+func requireInstanceAdmin(ctx context.Context) error {
+	p, ok := PrincipalFrom(ctx)
+	if !ok || p.Role == models.RoleAdmin && p.OrgID == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: instance admin required", ErrForbidden)
+}
+
+func (s *Service) CreateOrganization(ctx context.Context, name string) (_ models.Organization, err error) {
+	ctx, span := s.startSpan(ctx, "CreateOrganization")
+	defer func() { endSpan(span, err) }()
+	if err := requireInstanceAdmin(ctx); err != nil {
+		return models.Organization{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return models.Organization{}, fmt.Errorf("%w: organization name empty", ErrBadRequest)
+	}
+
+	o, err := s.repo.CreateOrganization(ctx, name)
+	if errors.Is(err, repository.ErrAlreadyExists) {
+		return models.Organization{}, fmt.Errorf("%w: organization %q already exists", ErrConflict, name)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to create organization", "error", err, "name", name)
+		return models.Organization{}, err
+	}
+	s.log(ctx).Info("organization created", "org_id", o.ID, "name", o.Name)
+	return o, nil
+}
+
+func (s *Service) ListOrganizations(ctx context.Context) (_ []models.Organization, err error) {
+	ctx, span := s.startSpan(ctx, "ListOrganizations")
+	defer func() { endSpan(span, err) }()
+	if err := requireInstanceAdmin(ctx); err != nil {
+		return nil, err
+	}
+	orgs, err := s.repo.ListOrganizations(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to list organizations", "error", err)
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// ResolveOrganization looks an organization up by id or, failing that, by
+// name.
+func (s *Service) ResolveOrganization(ctx context.Context, ref string) (_ models.Organization, err error) {
+	ctx, span := s.startSpan(ctx, "ResolveOrganization", attribute.String("org", ref))
+	defer func() { endSpan(span, err) }()
+	if id, err := strconv.Atoi(ref); err == nil {
+		o, err := s.repo.GetOrganization(ctx, id)
+		if err == nil {
+			return o, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return models.Organization{}, err
+		}
+	}
+	o, err := s.repo.GetOrganizationByName(ctx, ref)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Organization{}, fmt.Errorf("%w: organization %q not found", ErrNotFound, ref)
+	}
+	return o, err
+}
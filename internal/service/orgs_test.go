@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizations(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	acme, err := service.CreateOrganization(ctx, " acme ")
+	require.NoError(t, err)
+	assert.Equal(t, "acme", acme.Name)
+	_, err = service.CreateOrganization(ctx, "acme")
+	assert.ErrorIs(t, err, ErrConflict)
+	_, err = service.CreateOrganization(ctx, " ")
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	byID, err := service.ResolveOrganization(ctx, strconv.Itoa(acme.ID))
+	require.NoError(t, err)
+	assert.Equal(t, acme, byID)
+	byName, err := service.ResolveOrganization(ctx, "acme")
+	require.NoError(t, err)
+	assert.Equal(t, acme, byName)
+	_, err = service.ResolveOrganization(ctx, "globex")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	acmeCtx := repository.WithOrg(ctx, acme.ID)
+	team, err := service.CreateTeam(acmeCtx, "backend")
+	require.NoError(t, err)
+	alice, err := service.CreateUser(acmeCtx, &team.ID, "Alice", true)
+	require.NoError(t, err)
+	assert.Equal(t, acme.ID, alice.OrgID)
+
+	_, secret, err := service.IssueToken(ctx, "laptop", models.RoleUser, &alice.ID)
+	require.NoError(t, err)
+	p, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, &acme.ID, p.OrgID, "user tokens are bound to the user's organization")
+
+	_, secret, err = service.IssueToken(acmeCtx, "acme-admin", models.RoleAdmin, nil)
+	require.NoError(t, err)
+	orgAdmin, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Equal(t, &acme.ID, orgAdmin.OrgID)
+	_, err = service.CreateOrganization(WithPrincipal(ctx, orgAdmin), "globex")
+	assert.ErrorIs(t, err, ErrForbidden, "organization admins cannot create organizations")
+	_, err = service.ListOrganizations(WithPrincipal(ctx, orgAdmin))
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, secret, err = service.IssueToken(ctx, "root", models.RoleAdmin, nil)
+	require.NoError(t, err)
+	root, err := service.Authenticate(ctx, secret)
+	require.NoError(t, err)
+	assert.Nil(t, root.OrgID)
+	orgs, err := service.ListOrganizations(WithPrincipal(ctx, root))
+	require.NoError(t, err)
+	require.Len(t, orgs, 2)
+	assert.Equal(t, models.DefaultOrgID, orgs[0].ID)
+
+	_, _, err = service.IssueToken(repository.WithOrg(ctx, models.DefaultOrgID), "cross", models.RoleUser, &alice.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "users of other organizations are invisible")
+}
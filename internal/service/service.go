@@ -5,39 +5,64 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
-	"math/rand"
-	"time"
+	"math/rand/v2"
+	"slices"
+	"strconv"
 
+	"prmanager/internal/events"
+	"prmanager/internal/jwtauth"
 	"prmanager/internal/models"
+	"prmanager/internal/pagination"
 	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	ErrNotFound    = errors.New("not found")
-	ErrBadRequest  = errors.New("bad request")
-	ErrPRMerged    = errors.New("cannot reassign on merged PR")
-	ErrNoCandidate = errors.New("no active replacement candidate in team")
+	ErrNotFound     = errors.New("not found")
+	ErrBadRequest   = errors.New("bad request")
+	ErrPRMerged     = errors.New("cannot reassign on merged PR")
+	ErrNoCandidate  = errors.New("no active replacement candidate in team")
+	ErrTeamNotEmpty = errors.New("team still has members")
+	ErrConflict     = errors.New("conflict")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	// ErrUnavailable means a request could not be checked because a
+	// dependency is down; retrying later may succeed.
+	ErrUnavailable = errors.New("temporarily unavailable")
 )
 
 type Service struct {
 	repo   repository.Repository
-	rand   *rand.Rand
 	logger *slog.Logger
+
+	movePolicy MovePolicy
+	jwt        *jwtauth.Verifier
+	jwtMapping JWTMapping
+	tracer     trace.Tracer
 }
 
-func NewService(r repository.Repository, logger *slog.Logger) *Service {
+func NewService(r repository.Repository, logger *slog.Logger, opts ...Option) *Service {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &Service{
-		repo:   r,
-		rand:   rand.New(rand.NewSource(time.Now().UnixNano())),
-		logger: logger,
+	s := &Service{
+		repo:       r,
+		logger:     logger,
+		movePolicy: MovePolicyKeep,
+		tracer:     defaultTracer(),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-func (s *Service) CreateTeam(ctx context.Context, name string) (models.Team, error) {
-	s.logger.Info("creating team", "name", name)
+func (s *Service) CreateTeam(ctx context.Context, name string) (_ models.Team, err error) {
+	ctx, span := s.startSpan(ctx, "CreateTeam")
+	defer func() { endSpan(span, err) }()
+	s.log(ctx).Info("creating team", "name", name)
 
 	if name == "" {
 		return models.Team{}, fmt.Errorf("%w: team name empty", ErrBadRequest)
@@ -45,24 +70,26 @@ func (s *Service) CreateTeam(ctx context.Context, name string) (models.Team, err
 
 	t, err := s.repo.CreateTeam(ctx, name)
 	if err != nil {
-		s.logger.Error("failed to create team", "error", err, "name", name)
+		s.log(ctx).Error("failed to create team", "error", err, "name", name)
 		return models.Team{}, err
 	}
 
-	s.logger.Info("team created successfully", "team_id", t.ID, "name", t.Name)
+	s.log(ctx).Info("team created successfully", "team_id", t.ID, "name", t.Name)
 	return t, nil
 }
 
-func (s *Service) CreateUser(ctx context.Context, teamID *int, name string, isActive bool) (models.User, error) {
-	s.logger.Info("creating user", "name", name, "team_id", teamID, "is_active", isActive)
+func (s *Service) CreateUser(ctx context.Context, teamID *int, name string, isActive bool) (_ models.User, err error) {
+	ctx, span := s.startSpan(ctx, "CreateUser", optionalInt("team_id", teamID))
+	defer func() { endSpan(span, err) }()
+	s.log(ctx).Info("creating user", "name", name, "team_id", teamID, "is_active", isActive)
 
 	if name == "" {
 		return models.User{}, fmt.Errorf("%w: user name empty", ErrBadRequest)
 	}
 
 	if teamID != nil {
-		if _, err := s.repo.GetTeamByID(ctx, *teamID); err != nil {
-			s.logger.Warn("team not found for user creation", "team_id", *teamID)
+		if team, err := s.repo.GetTeamByID(ctx, *teamID); err != nil || team.DeletedAt != nil {
+			s.log(ctx).Warn("team not found for user creation", "team_id", *teamID)
 			return models.User{}, fmt.Errorf("%w: team not found", ErrBadRequest)
 		}
 	}
@@ -70,25 +97,151 @@ func (s *Service) CreateUser(ctx context.Context, teamID *int, name string, isAc
 	u := models.User{TeamID: teamID, Name: name, IsActive: isActive}
 	user, err := s.repo.CreateUser(ctx, u)
 	if err != nil {
-		s.logger.Error("failed to create user", "error", err, "name", name, "team_id", teamID)
+		s.log(ctx).Error("failed to create user", "error", err, "name", name, "team_id", teamID)
 		return models.User{}, err
 	}
 
-	s.logger.Info("user created successfully", "user_id", user.ID, "name", user.Name)
+	s.log(ctx).Info("user created successfully", "user_id", user.ID, "name", user.Name)
 	return user, nil
 }
 
-func (s *Service) CreatePR(ctx context.Context, title string, authorID int) (models.PRWithReviewers, error) {
-	s.logger.Info("creating PR", "title", title, "author_id", authorID)
+// UserUpdate holds the fields of a partial user update; nil fields are left
+// unchanged. SetTeam distinguishes "remove from team" (TeamID nil) from "keep
+// the current team".
+type UserUpdate struct {
+	Name     *string
+	SetTeam  bool
+	TeamID   *int
+	IsActive *bool
+}
+
+func (s *Service) UpdateUser(ctx context.Context, id int, upd UserUpdate) (_ models.UserUpdateResult, err error) {
+	ctx, span := s.startSpan(ctx, "UpdateUser", attribute.Int("user_id", id), optionalInt("team_id", upd.TeamID))
+	defer func() { endSpan(span, err) }()
+	return inTx(ctx, s, func(ctx context.Context, tx *Service) (models.UserUpdateResult, error) {
+		return tx.updateUser(ctx, id, upd)
+	})
+}
+
+func (s *Service) updateUser(ctx context.Context, id int, upd UserUpdate) (models.UserUpdateResult, error) {
+	s.log(ctx).Info("updating user", "user_id", id)
+
+	u, err := s.getLiveUser(ctx, id)
+	if err != nil {
+		return models.UserUpdateResult{}, err
+	}
+
+	if upd.Name != nil {
+		if *upd.Name == "" {
+			return models.UserUpdateResult{}, fmt.Errorf("%w: user name empty", ErrBadRequest)
+		}
+		u.Name = *upd.Name
+	}
+	if upd.IsActive != nil {
+		u.IsActive = *upd.IsActive
+	}
+
+	oldTeamID := u.TeamID
+	moved := false
+	if upd.SetTeam {
+		if upd.TeamID != nil {
+			if team, err := s.repo.GetTeamByID(ctx, *upd.TeamID); err != nil || team.DeletedAt != nil {
+				s.log(ctx).Warn("team not found for user update", "team_id", *upd.TeamID)
+				return models.UserUpdateResult{}, fmt.Errorf("%w: team not found", ErrBadRequest)
+			}
+		}
+		moved = oldTeamID != nil && (upd.TeamID == nil || *upd.TeamID != *oldTeamID)
+		u.TeamID = upd.TeamID
+	}
+
+	reassignments := make([]models.Reassignment, 0)
+	if moved && s.movePolicy == MovePolicyReassign {
+		reassignments, err = s.reassignOpenReviews(ctx, id, *oldTeamID)
+		if err != nil {
+			return models.UserUpdateResult{}, err
+		}
+	}
+
+	updated, err := s.repo.UpdateUser(ctx, u)
+	if err != nil {
+		s.log(ctx).Error("failed to update user", "error", err, "user_id", id)
+		return models.UserUpdateResult{}, err
+	}
+
+	s.log(ctx).Info("user updated successfully", "user_id", id, "moved", moved, "reassignments", len(reassignments))
+	return models.UserUpdateResult{User: updated, Reassignments: reassignments}, nil
+}
+
+// reassignOpenReviews hands every open review of userID to another member of
+// teamID. PRs without a free candidate keep their reviewer.
+func (s *Service) reassignOpenReviews(ctx context.Context, userID, teamID int) ([]models.Reassignment, error) {
+	prs, err := s.repo.ListPRsAssignedToUser(ctx, userID, repository.ReviewQueueFilter{
+		Statuses: []models.PRStatus{models.PRStatusOpen},
+		SortBy:   repository.SortByCreatedAt,
+	})
+	if err != nil {
+		s.log(ctx).Error("failed to list open reviews", "error", err, "user_id", userID)
+		return nil, err
+	}
+
+	res := make([]models.Reassignment, 0, len(prs))
+	for _, assigned := range prs {
+		// The queue was read before taking the PR's lock; recheck it.
+		pr, err := s.repo.LockPR(ctx, assigned.ID)
+		if err != nil {
+			s.log(ctx).Error("failed to lock PR", "error", err, "pr_id", assigned.ID)
+			return nil, err
+		}
+		revs, err := s.repo.GetReviewersByPR(ctx, pr.ID)
+		if err != nil {
+			s.log(ctx).Error("failed to get current reviewers", "error", err, "pr_id", pr.ID)
+			return nil, err
+		}
+		if pr.Status != models.PRStatusOpen || !slices.ContainsFunc(revs, func(u models.User) bool { return u.ID == userID }) {
+			continue
+		}
+		newUser, err := s.pickReplacement(ctx, pr, teamID, userID, revs)
+		if errors.Is(err, ErrNoCandidate) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := s.repo.ReplaceReviewer(ctx, pr.ID, userID, newUser.ID); err != nil {
+			s.log(ctx).Error("failed to replace reviewer", "error", err, "pr_id", pr.ID, "old_user", userID, "new_user", newUser.ID)
+			return nil, err
+		}
+		if err := s.emit(ctx, events.ReviewerReassigned, pr.ID, events.ReviewerReassignedData{PRID: pr.ID, OldReviewerID: userID, NewReviewerID: newUser.ID}); err != nil {
+			return nil, err
+		}
+		res = append(res, models.Reassignment{PRID: pr.ID, OldUserID: userID, NewUserID: newUser.ID})
+	}
+	return res, nil
+}
+
+func (s *Service) CreatePR(ctx context.Context, title string, authorID int) (_ models.PRWithReviewers, err error) {
+	ctx, span := s.startSpan(ctx, "CreatePR", attribute.Int("author_id", authorID))
+	defer func() { endSpan(span, err) }()
+	return inTx(ctx, s, func(ctx context.Context, tx *Service) (models.PRWithReviewers, error) {
+		return tx.createPR(ctx, title, authorID)
+	})
+}
+
+func (s *Service) createPR(ctx context.Context, title string, authorID int) (models.PRWithReviewers, error) {
+	s.log(ctx).Info("creating PR", "title", title, "author_id", authorID)
+
+	if err := s.authorize(ctx, false, authorID); err != nil {
+		return models.PRWithReviewers{}, err
+	}
 
 	author, err := s.repo.GetUserByID(ctx, authorID)
 	if err != nil {
-		s.logger.Warn("author not found", "author_id", authorID, "error", err)
+		s.log(ctx).Warn("author not found", "author_id", authorID, "error", err)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: author not found", ErrBadRequest)
 	}
 
 	if !author.IsActive {
-		s.logger.Warn("author is not active", "author_id", authorID)
+		s.log(ctx).Warn("author is not active", "author_id", authorID)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: author is not active", ErrBadRequest)
 	}
 
@@ -98,18 +251,19 @@ func (s *Service) CreatePR(ctx context.Context, title string, authorID int) (mod
 		Status:   models.PRStatusOpen,
 	})
 	if err != nil {
-		s.logger.Error("failed to create PR", "error", err, "title", title, "author_id", authorID)
+		s.log(ctx).Error("failed to create PR", "error", err, "title", title, "author_id", authorID)
 		return models.PRWithReviewers{}, err
 	}
 
 	if author.TeamID == nil {
-		s.logger.Info("PR created without reviewers (author has no team)", "pr_id", pr.ID)
-		return models.PRWithReviewers{PR: pr, Reviewers: []models.User{}}, nil
+		s.log(ctx).Info("PR created without reviewers (author has no team)", "pr_id", pr.ID)
+		res := models.PRWithReviewers{PR: pr, Reviewers: []models.User{}}
+		return res, s.emitPRCreated(ctx, res)
 	}
 
 	candidates, err := s.repo.ListActiveUsersInTeam(ctx, *author.TeamID)
 	if err != nil {
-		s.logger.Error("failed to get team members", "error", err, "team_id", *author.TeamID)
+		s.log(ctx).Error("failed to get team members", "error", err, "team_id", *author.TeamID)
 		return models.PRWithReviewers{}, err
 	}
 
@@ -133,53 +287,70 @@ func (s *Service) CreatePR(ctx context.Context, title string, authorID int) (mod
 		}
 
 		if err := s.repo.AssignReviewers(ctx, pr.ID, chosenIDs); err != nil {
-			s.logger.Error("failed to assign reviewers", "error", err, "pr_id", pr.ID, "reviewer_ids", chosenIDs)
+			s.log(ctx).Error("failed to assign reviewers", "error", err, "pr_id", pr.ID, "reviewer_ids", chosenIDs)
 			return models.PRWithReviewers{}, err
 		}
 	}
 
 	revs, err := s.repo.GetReviewersByPR(ctx, pr.ID)
 	if err != nil {
-		s.logger.Error("failed to get assigned reviewers", "error", err, "pr_id", pr.ID)
+		s.log(ctx).Error("failed to get assigned reviewers", "error", err, "pr_id", pr.ID)
 		return models.PRWithReviewers{}, err
 	}
 
-	s.logger.Info("PR created successfully",
+	s.log(ctx).Info("PR created successfully",
 		"pr_id", pr.ID,
 		"reviewers_count", len(revs),
 		"reviewer_ids", chosenIDs)
 
-	return models.PRWithReviewers{PR: pr, Reviewers: revs}, nil
+	res := models.PRWithReviewers{PR: pr, Reviewers: revs}
+	return res, s.emitPRCreated(ctx, res)
+}
+
+func (s *Service) ReassignReviewer(ctx context.Context, prID int, oldUserID int) (_ models.PRWithReviewers, err error) {
+	ctx, span := s.startSpan(ctx, "ReassignReviewer", attribute.Int("pr_id", prID), attribute.Int("user_id", oldUserID))
+	defer func() { endSpan(span, err) }()
+	return inTx(ctx, s, func(ctx context.Context, tx *Service) (models.PRWithReviewers, error) {
+		return tx.reassignReviewer(ctx, prID, oldUserID)
+	})
 }
 
-func (s *Service) ReassignReviewer(ctx context.Context, prID int, oldUserID int) (models.PRWithReviewers, error) {
-	s.logger.Info("reassigning reviewer", "pr_id", prID, "old_user_id", oldUserID)
+func (s *Service) reassignReviewer(ctx context.Context, prID int, oldUserID int) (models.PRWithReviewers, error) {
+	s.log(ctx).Info("reassigning reviewer", "pr_id", prID, "old_user_id", oldUserID)
 
-	pr, err := s.repo.GetPRByID(ctx, prID)
+	pr, err := s.repo.LockPR(ctx, prID)
 	if err != nil {
-		s.logger.Warn("PR not found for reassignment", "pr_id", prID)
+		s.log(ctx).Warn("PR not found for reassignment", "pr_id", prID)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: pr not found", ErrBadRequest)
 	}
 
+	if err := s.authorize(ctx, true, oldUserID); err != nil {
+		return models.PRWithReviewers{}, err
+	}
+
 	if pr.Status == models.PRStatusMerged {
-		s.logger.Warn("attempt to reassign reviewer on merged PR", "pr_id", prID)
+		s.log(ctx).Warn("attempt to reassign reviewer on merged PR", "pr_id", prID)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: cannot reassign merged pr", ErrPRMerged)
 	}
 
+	if pr.Status == models.PRStatusClosed {
+		return models.PRWithReviewers{}, fmt.Errorf("%w: cannot reassign closed pr", ErrBadRequest)
+	}
+
 	oldUser, err := s.repo.GetUserByID(ctx, oldUserID)
 	if err != nil {
-		s.logger.Warn("old reviewer not found", "user_id", oldUserID)
+		s.log(ctx).Warn("old reviewer not found", "user_id", oldUserID)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: user not found", ErrBadRequest)
 	}
 
 	if oldUser.TeamID == nil {
-		s.logger.Warn("reviewer has no team", "user_id", oldUserID)
+		s.log(ctx).Warn("reviewer has no team", "user_id", oldUserID)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: reviewer has no team", ErrBadRequest)
 	}
 
 	currentReviewers, err := s.repo.GetReviewersByPR(ctx, prID)
 	if err != nil {
-		s.logger.Error("failed to get current reviewers", "error", err, "pr_id", prID)
+		s.log(ctx).Error("failed to get current reviewers", "error", err, "pr_id", prID)
 		return models.PRWithReviewers{}, err
 	}
 
@@ -191,16 +362,47 @@ func (s *Service) ReassignReviewer(ctx context.Context, prID int, oldUserID int)
 		}
 	}
 	if !found {
-		s.logger.Warn("old reviewer not assigned to PR", "pr_id", prID, "user_id", oldUserID)
+		s.log(ctx).Warn("old reviewer not assigned to PR", "pr_id", prID, "user_id", oldUserID)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: reviewer is not assigned to this PR", ErrBadRequest)
 	}
 
-	candidates, err := s.repo.ListActiveUsersInTeam(ctx, *oldUser.TeamID)
+	newUser, err := s.pickReplacement(ctx, pr, *oldUser.TeamID, oldUserID, currentReviewers)
+	if err != nil {
+		return models.PRWithReviewers{}, err
+	}
+
+	if err := s.repo.ReplaceReviewer(ctx, prID, oldUserID, newUser.ID); err != nil {
+		s.log(ctx).Error("failed to replace reviewer", "error", err, "pr_id", prID, "old_user", oldUserID, "new_user", newUser.ID)
+		return models.PRWithReviewers{}, err
+	}
+
+	revs, err := s.repo.GetReviewersByPR(ctx, prID)
 	if err != nil {
-		s.logger.Error("failed to get team candidates", "error", err, "team_id", *oldUser.TeamID)
+		s.log(ctx).Error("failed to get updated reviewers", "error", err, "pr_id", prID)
 		return models.PRWithReviewers{}, err
 	}
 
+	s.log(ctx).Info("reviewer reassigned successfully",
+		"pr_id", prID,
+		"old_user_id", oldUserID,
+		"new_user_id", newUser.ID,
+		"new_user_name", newUser.Name)
+
+	res := models.PRWithReviewers{PR: pr, Reviewers: revs}
+	return res, s.emit(ctx, events.ReviewerReassigned, prID, events.ReviewerReassignedData{PRID: prID, OldReviewerID: oldUserID, NewReviewerID: newUser.ID})
+}
+
+// pickReplacement chooses a random active member of teamID who is neither the
+// author nor already reviewing pr.
+func (s *Service) pickReplacement(ctx context.Context, pr models.PR, teamID, oldUserID int, currentReviewers []models.User) (_ models.User, err error) {
+	ctx, span := s.startSpan(ctx, "pickReplacement", attribute.Int("pr_id", pr.ID), attribute.Int("team_id", teamID))
+	defer func() { endSpan(span, err) }()
+	candidates, err := s.repo.ListActiveUsersInTeam(ctx, teamID)
+	if err != nil {
+		s.log(ctx).Error("failed to get team candidates", "error", err, "team_id", teamID)
+		return models.User{}, err
+	}
+
 	filtered := make([]models.User, 0)
 	for _, u := range candidates {
 		if u.ID != pr.AuthorID && u.ID != oldUserID {
@@ -218,45 +420,37 @@ func (s *Service) ReassignReviewer(ctx context.Context, prID int, oldUserID int)
 	}
 
 	if len(filtered) == 0 {
-		s.logger.Warn("no available candidates for reassignment",
-			"pr_id", prID, "old_user_id", oldUserID, "team_id", *oldUser.TeamID)
-		return models.PRWithReviewers{}, fmt.Errorf("%w: no active candidates to reassign", ErrNoCandidate)
-	}
-
-	newIdx := s.rand.Intn(len(filtered))
-	newUser := filtered[newIdx]
-
-	if err := s.repo.ReplaceReviewer(ctx, prID, oldUserID, newUser.ID); err != nil {
-		s.logger.Error("failed to replace reviewer", "error", err, "pr_id", prID, "old_user", oldUserID, "new_user", newUser.ID)
-		return models.PRWithReviewers{}, err
+		s.log(ctx).Warn("no available candidates for reassignment",
+			"pr_id", pr.ID, "old_user_id", oldUserID, "team_id", teamID)
+		return models.User{}, fmt.Errorf("%w: no active candidates to reassign", ErrNoCandidate)
 	}
 
-	revs, err := s.repo.GetReviewersByPR(ctx, prID)
-	if err != nil {
-		s.logger.Error("failed to get updated reviewers", "error", err, "pr_id", prID)
-		return models.PRWithReviewers{}, err
-	}
-
-	s.logger.Info("reviewer reassigned successfully",
-		"pr_id", prID,
-		"old_user_id", oldUserID,
-		"new_user_id", newUser.ID,
-		"new_user_name", newUser.Name)
+	return filtered[rand.IntN(len(filtered))], nil
+}
 
-	return models.PRWithReviewers{PR: pr, Reviewers: revs}, nil
+func (s *Service) MergePR(ctx context.Context, prID int) (_ models.PRWithReviewers, err error) {
+	ctx, span := s.startSpan(ctx, "MergePR", attribute.Int("pr_id", prID))
+	defer func() { endSpan(span, err) }()
+	return inTx(ctx, s, func(ctx context.Context, tx *Service) (models.PRWithReviewers, error) {
+		return tx.mergePR(ctx, prID)
+	})
 }
 
-func (s *Service) MergePR(ctx context.Context, prID int) (models.PRWithReviewers, error) {
-	s.logger.Info("merging PR", "pr_id", prID)
+func (s *Service) mergePR(ctx context.Context, prID int) (models.PRWithReviewers, error) {
+	s.log(ctx).Info("merging PR", "pr_id", prID)
 
-	pr, err := s.repo.GetPRByID(ctx, prID)
+	pr, err := s.repo.LockPR(ctx, prID)
 	if err != nil {
-		s.logger.Warn("PR not found for merge", "pr_id", prID)
+		s.log(ctx).Warn("PR not found for merge", "pr_id", prID)
 		return models.PRWithReviewers{}, fmt.Errorf("%w: pr not found", ErrBadRequest)
 	}
 
+	if err := s.authorize(ctx, true, pr.AuthorID); err != nil {
+		return models.PRWithReviewers{}, err
+	}
+
 	if pr.Status == models.PRStatusMerged {
-		s.logger.Info("PR already merged", "pr_id", prID)
+		s.log(ctx).Info("PR already merged", "pr_id", prID)
 		revs, _ := s.repo.GetReviewersByPR(ctx, prID)
 		return models.PRWithReviewers{PR: pr, Reviewers: revs}, nil
 	}
@@ -266,43 +460,187 @@ func (s *Service) MergePR(ctx context.Context, prID int) (models.PRWithReviewers
 	}
 
 	if err := s.repo.SetPRStatus(ctx, prID, string(models.PRStatusMerged)); err != nil {
-		s.logger.Error("failed to set PR status to merged", "error", err, "pr_id", prID)
+		s.log(ctx).Error("failed to set PR status to merged", "error", err, "pr_id", prID)
 		return models.PRWithReviewers{}, err
 	}
 
 	pr.Status = models.PRStatusMerged
 	revs, err := s.repo.GetReviewersByPR(ctx, prID)
 	if err != nil {
-		s.logger.Error("failed to get reviewers after merge", "error", err, "pr_id", prID)
+		s.log(ctx).Error("failed to get reviewers after merge", "error", err, "pr_id", prID)
 		return models.PRWithReviewers{}, err
 	}
 
-	s.logger.Info("PR merged successfully", "pr_id", prID)
-	return models.PRWithReviewers{PR: pr, Reviewers: revs}, nil
+	s.log(ctx).Info("PR merged successfully", "pr_id", prID)
+	res := models.PRWithReviewers{PR: pr, Reviewers: revs}
+	return res, s.emit(ctx, events.PRMerged, prID, events.PRData{PR: res})
 }
 
-func (s *Service) ListPRsAssignedToUser(ctx context.Context, userID int) ([]models.PRWithReviewers, error) {
-	s.logger.Debug("listing PRs assigned to user", "user_id", userID)
+func (s *Service) ListPRsAssignedToUser(ctx context.Context, userID int, f repository.ReviewQueueFilter) (_ pagination.Page[models.AssignedPR], err error) {
+	ctx, span := s.startSpan(ctx, "ListPRsAssignedToUser", attribute.Int("user_id", userID))
+	defer func() { endSpan(span, err) }()
+	s.log(ctx).Debug("listing PRs assigned to user", "user_id", userID)
+
+	if err := s.authorize(ctx, true, userID); err != nil {
+		return pagination.Page[models.AssignedPR]{}, err
+	}
 
 	if _, err := s.repo.GetUserByID(ctx, userID); err != nil {
-		s.logger.Warn("user not found for PRs query", "user_id", userID)
-		return nil, fmt.Errorf("%w: user not found", ErrBadRequest)
+		s.log(ctx).Warn("user not found for PRs query", "user_id", userID)
+		return pagination.Page[models.AssignedPR]{}, fmt.Errorf("%w: user not found", ErrBadRequest)
 	}
 
-	prs, err := s.repo.ListPRsAssignedToUser(ctx, userID)
+	if f.SortBy == "" {
+		f.SortBy = repository.SortByCreatedAt
+	}
+	limit := f.Limit
+	if limit > 0 {
+		f.Limit = limit + 1
+	}
+
+	prs, err := s.repo.ListPRsAssignedToUser(ctx, userID, f)
 	if err != nil {
-		s.logger.Error("failed to list PRs for user", "error", err, "user_id", userID)
-		return nil, err
+		s.log(ctx).Error("failed to list PRs for user", "error", err, "user_id", userID)
+		return pagination.Page[models.AssignedPR]{}, err
 	}
 
-	s.logger.Debug("retrieved PRs for user", "user_id", userID, "prs_count", len(prs))
-	return prs, nil
+	page := pagination.NewPage(prs, limit, func(p models.AssignedPR) pagination.Cursor {
+		key := f.SortKey(p)
+		return pagination.Cursor{Sort: string(f.SortBy), Desc: f.Desc, Time: &key, ID: p.ID}
+	})
+	s.log(ctx).Debug("retrieved PRs for user", "user_id", userID, "prs_count", len(page.Items))
+	return page, nil
+}
+
+func (s *Service) GetPR(ctx context.Context, id int) (_ models.PRWithReviewers, err error) {
+	ctx, span := s.startSpan(ctx, "GetPR", attribute.Int("pr_id", id))
+	defer func() { endSpan(span, err) }()
+	pr, err := s.repo.GetPRByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.PRWithReviewers{}, fmt.Errorf("%w: pr not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get PR", "error", err, "pr_id", id)
+		return models.PRWithReviewers{}, err
+	}
+
+	revs, err := s.repo.GetReviewersByPR(ctx, id)
+	if err != nil {
+		s.log(ctx).Error("failed to get reviewers", "error", err, "pr_id", id)
+		return models.PRWithReviewers{}, err
+	}
+	return models.PRWithReviewers{PR: pr, Reviewers: revs}, nil
+}
+
+// resolveTeam looks a live team up by id or, failing that, by name, so teams
+// whose name happens to be numeric are still reachable.
+func (s *Service) resolveTeam(ctx context.Context, ref string) (models.Team, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		team, err := s.repo.GetTeamByID(ctx, id)
+		if err == nil && team.DeletedAt == nil {
+			return team, nil
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return models.Team{}, err
+		}
+	}
+	team, err := s.repo.GetTeamByName(ctx, ref)
+	if errors.Is(err, repository.ErrNotFound) || err == nil && team.DeletedAt != nil {
+		return models.Team{}, fmt.Errorf("%w: team not found", ErrNotFound)
+	}
+	return team, err
+}
+
+func (s *Service) GetTeam(ctx context.Context, ref string) (_ models.TeamWithMembers, err error) {
+	ctx, span := s.startSpan(ctx, "GetTeam", attribute.String("team", ref))
+	defer func() { endSpan(span, err) }()
+	team, err := s.resolveTeam(ctx, ref)
+	if err != nil {
+		return models.TeamWithMembers{}, err
+	}
+
+	members, err := s.repo.ListTeamMembers(ctx, team.ID)
+	if err != nil {
+		s.log(ctx).Error("failed to list team members", "error", err, "team_id", team.ID)
+		return models.TeamWithMembers{}, err
+	}
+	return models.TeamWithMembers{Team: team, Members: members}, nil
+}
+
+func (s *Service) ListTeams(ctx context.Context, f repository.TeamFilter) (_ pagination.Page[models.Team], err error) {
+	ctx, span := s.startSpan(ctx, "ListTeams")
+	defer func() { endSpan(span, err) }()
+	limit := f.Limit
+	if limit > 0 {
+		f.Limit = limit + 1
+	}
+	teams, err := s.repo.ListTeams(ctx, f)
+	if err != nil {
+		s.log(ctx).Error("failed to list teams", "error", err)
+		return pagination.Page[models.Team]{}, err
+	}
+	return pagination.NewPage(teams, limit, func(t models.Team) pagination.Cursor {
+		return pagination.Cursor{Sort: pagination.SortByID, ID: t.ID}
+	}), nil
+}
+
+func (s *Service) GetUser(ctx context.Context, id int) (_ models.UserWithTeam, err error) {
+	ctx, span := s.startSpan(ctx, "GetUser", attribute.Int("user_id", id))
+	defer func() { endSpan(span, err) }()
+	u, err := s.repo.GetUserByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.UserWithTeam{}, fmt.Errorf("%w: user not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get user", "error", err, "user_id", id)
+		return models.UserWithTeam{}, err
+	}
+
+	res := models.UserWithTeam{User: u}
+	if u.TeamID != nil {
+		team, err := s.repo.GetTeamByID(ctx, *u.TeamID)
+		if err != nil {
+			s.log(ctx).Error("failed to get user team", "error", err, "user_id", id)
+			return models.UserWithTeam{}, err
+		}
+		res.Team = &team
+	}
+	return res, nil
+}
+
+// ListUsers lists users, optionally restricted to the team identified by
+// teamRef (an id or a name).
+func (s *Service) ListUsers(ctx context.Context, teamRef string, f repository.UserFilter) (_ pagination.Page[models.User], err error) {
+	ctx, span := s.startSpan(ctx, "ListUsers", attribute.String("team", teamRef))
+	defer func() { endSpan(span, err) }()
+	if teamRef != "" {
+		team, err := s.resolveTeam(ctx, teamRef)
+		if err != nil {
+			return pagination.Page[models.User]{}, err
+		}
+		f.TeamID = &team.ID
+	}
+
+	limit := f.Limit
+	if limit > 0 {
+		f.Limit = limit + 1
+	}
+	users, err := s.repo.ListUsers(ctx, f)
+	if err != nil {
+		s.log(ctx).Error("failed to list users", "error", err)
+		return pagination.Page[models.User]{}, err
+	}
+	return pagination.NewPage(users, limit, func(u models.User) pagination.Cursor {
+		return pagination.Cursor{Sort: pagination.SortByID, ID: u.ID}
+	}), nil
 }
 
-func (s *Service) StatsAssignments(ctx context.Context) (int, error) {
+func (s *Service) StatsAssignments(ctx context.Context) (_ int, err error) {
+	ctx, span := s.startSpan(ctx, "StatsAssignments")
+	defer func() { endSpan(span, err) }()
 	count, err := s.repo.CountAssignments(ctx)
 	if err != nil {
-		s.logger.Error("failed to count assignments", "error", err)
+		s.log(ctx).Error("failed to count assignments", "error", err)
 		return 0, err
 	}
 	return count, nil
@@ -323,7 +661,7 @@ func (s *Service) randomSample(n, k int) []int {
 		res[i] = i
 	}
 	for i := 0; i < k; i++ {
-		r := i + s.rand.Intn(n-i)
+		r := i + rand.IntN(n-i)
 		res[i], res[r] = res[r], res[i]
 	}
 	return res[:k]
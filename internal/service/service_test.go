@@ -2,20 +2,52 @@ package service
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"slices"
 	"testing"
+	"time"
 
 	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockRepository struct {
 	mock.Mock
 }
 
+func (m *MockRepository) CreateOrganization(ctx context.Context, name string) (models.Organization, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(models.Organization), args.Error(1)
+}
+
+func (m *MockRepository) GetOrganization(ctx context.Context, id int) (models.Organization, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Organization), args.Error(1)
+}
+
+func (m *MockRepository) GetOrganizationByName(ctx context.Context, name string) (models.Organization, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(models.Organization), args.Error(1)
+}
+
+func (m *MockRepository) ListOrganizations(ctx context.Context) ([]models.Organization, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Organization), args.Error(1)
+}
+
+func (m *MockRepository) RestoreOrganization(ctx context.Context, o models.Organization) error {
+	args := m.Called(ctx, o)
+	return args.Error(0)
+}
+
 func (m *MockRepository) CreateTeam(ctx context.Context, name string) (models.Team, error) {
 	args := m.Called(ctx, name)
 	return args.Get(0).(models.Team), args.Error(1)
@@ -56,6 +88,11 @@ func (m *MockRepository) GetPRByID(ctx context.Context, id int) (models.PR, erro
 	return args.Get(0).(models.PR), args.Error(1)
 }
 
+func (m *MockRepository) LockPR(ctx context.Context, id int) (models.PR, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.PR), args.Error(1)
+}
+
 func (m *MockRepository) SetPRStatus(ctx context.Context, id int, status string) error {
 	args := m.Called(ctx, id, status)
 	return args.Error(0)
@@ -76,8 +113,13 @@ func (m *MockRepository) ReplaceReviewer(ctx context.Context, prID int, oldUserI
 	return args.Error(0)
 }
 
-func (m *MockRepository) ListPRsAssignedToUser(ctx context.Context, userID int) ([]models.PRWithReviewers, error) {
-	args := m.Called(ctx, userID)
+func (m *MockRepository) ListPRsAssignedToUser(ctx context.Context, userID int, f repository.ReviewQueueFilter) ([]models.AssignedPR, error) {
+	args := m.Called(ctx, userID, f)
+	return args.Get(0).([]models.AssignedPR), args.Error(1)
+}
+
+func (m *MockRepository) ListPRsByAuthor(ctx context.Context, authorID int, statuses []models.PRStatus) ([]models.PRWithReviewers, error) {
+	args := m.Called(ctx, authorID, statuses)
 	return args.Get(0).([]models.PRWithReviewers), args.Error(1)
 }
 
@@ -86,6 +128,401 @@ func (m *MockRepository) CountAssignments(ctx context.Context) (int, error) {
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockRepository) ForEachTeam(ctx context.Context, fn func(models.Team) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ForEachUser(ctx context.Context, fn func(models.User) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ForEachPR(ctx context.Context, fn func(models.PR) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ForEachReviewerAssignment(ctx context.Context, fn func(models.ReviewerAssignment) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ForEachIdentity(ctx context.Context, fn func(models.Identity) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ForEachExternalRef(ctx context.Context, fn func(models.ExternalRef) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ForEachReviewerSync(ctx context.Context, fn func(models.ReviewerSync) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ForEachTeamChannel(ctx context.Context, fn func(models.TeamChannel) error) error {
+	args := m.Called(ctx, fn)
+	return args.Error(0)
+}
+
+// ReadSnapshot runs fn against the mock itself; expectations apply as usual.
+func (m *MockRepository) ReadSnapshot(ctx context.Context, fn func(r repository.Repository) error) error {
+	return fn(m)
+}
+
+func (m *MockRepository) RestoreTeam(ctx context.Context, t models.Team) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestoreUser(ctx context.Context, u models.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestorePR(ctx context.Context, pr models.PR) error {
+	args := m.Called(ctx, pr)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestoreReviewerAssignment(ctx context.Context, a models.ReviewerAssignment) error {
+	args := m.Called(ctx, a)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestoreIdentity(ctx context.Context, id models.Identity) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestoreReviewerSync(ctx context.Context, s models.ReviewerSync) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestoreTeamChannel(ctx context.Context, c models.TeamChannel) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestoreTeamSLA(ctx context.Context, s models.TeamSLA) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RestoreDigestPrefs(ctx context.Context, p models.DigestPrefs) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SyncSequences(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateIdempotencyKey(ctx context.Context, rec models.IdempotencyRecord) error {
+	args := m.Called(ctx, rec)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetIdempotencyKey(ctx context.Context, key string) (models.IdempotencyRecord, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(models.IdempotencyRecord), args.Error(1)
+}
+
+func (m *MockRepository) CompleteIdempotencyKey(ctx context.Context, key string, statusCode int, body []byte) error {
+	args := m.Called(ctx, key, statusCode, body)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteIdempotencyKey(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteIdempotencyKeysBefore(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(ctx, before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) GetTeamByName(ctx context.Context, name string) (models.Team, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(models.Team), args.Error(1)
+}
+
+func (m *MockRepository) ListTeams(ctx context.Context, f repository.TeamFilter) ([]models.Team, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]models.Team), args.Error(1)
+}
+
+func (m *MockRepository) ListTeamMembers(ctx context.Context, teamID int) ([]models.User, error) {
+	args := m.Called(ctx, teamID)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockRepository) ListUsers(ctx context.Context, f repository.UserFilter) ([]models.User, error) {
+	args := m.Called(ctx, f)
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockRepository) UpdateUser(ctx context.Context, u models.User) (models.User, error) {
+	args := m.Called(ctx, u)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockRepository) DeleteTeam(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) PurgeTeam(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) PurgeUser(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateWebhook(ctx context.Context, w models.Webhook) (models.Webhook, error) {
+	args := m.Called(ctx, w)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockRepository) GetWebhook(ctx context.Context, id int) (models.Webhook, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockRepository) ListWebhooks(ctx context.Context) ([]models.Webhook, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockRepository) DeleteWebhook(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRepository) CreateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) (models.WebhookDelivery, error) {
+	args := m.Called(ctx, d)
+	return args.Get(0).(models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) UpdateWebhookDelivery(ctx context.Context, d models.WebhookDelivery) error {
+	args := m.Called(ctx, d)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListDueWebhookDeliveries(ctx context.Context, now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockRepository) ListWebhookDeliveries(ctx context.Context, webhookID int, f repository.DeliveryFilter) ([]models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookID, f)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+// WithTx runs fn against the mock itself; expectations apply as usual.
+func (m *MockRepository) WithTx(ctx context.Context, fn func(tx repository.Repository) error) error {
+	return fn(m)
+}
+
+func (m *MockRepository) CreateOutboxEvent(ctx context.Context, e models.OutboxEvent) error {
+	args := m.Called(ctx, e)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ClaimOutboxEvents(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.OutboxEvent, error) {
+	args := m.Called(ctx, now, lease, limit)
+	return args.Get(0).([]models.OutboxEvent), args.Error(1)
+}
+
+func (m *MockRepository) MarkOutboxEventDelivered(ctx context.Context, id int, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RescheduleOutboxEvent(ctx context.Context, e models.OutboxEvent) error {
+	args := m.Called(ctx, e)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeadLetterOutboxEvent(ctx context.Context, e models.OutboxEvent, at time.Time) error {
+	args := m.Called(ctx, e, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) DeleteDeliveredOutboxEvents(ctx context.Context, before time.Time) (int, error) {
+	args := m.Called(ctx, before)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockRepository) SetIdentity(ctx context.Context, id models.Identity) (models.Identity, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.Identity), args.Error(1)
+}
+
+func (m *MockRepository) GetIdentityByLogin(ctx context.Context, provider, login string) (models.Identity, error) {
+	args := m.Called(ctx, provider, login)
+	return args.Get(0).(models.Identity), args.Error(1)
+}
+
+func (m *MockRepository) ListIdentities(ctx context.Context, userID int) ([]models.Identity, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]models.Identity), args.Error(1)
+}
+
+func (m *MockRepository) DeleteIdentity(ctx context.Context, userID int, provider string) error {
+	args := m.Called(ctx, userID, provider)
+	return args.Error(0)
+}
+
+func (m *MockRepository) LinkExternalPR(ctx context.Context, ref models.ExternalRef) error {
+	args := m.Called(ctx, ref)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetExternalRef(ctx context.Context, prID int) (models.ExternalRef, error) {
+	args := m.Called(ctx, prID)
+	return args.Get(0).(models.ExternalRef), args.Error(1)
+}
+
+func (m *MockRepository) GetPRByExternalRef(ctx context.Context, provider, repo string, number int) (models.PR, error) {
+	args := m.Called(ctx, provider, repo, number)
+	return args.Get(0).(models.PR), args.Error(1)
+}
+
+func (m *MockRepository) MarkReviewerSyncPending(ctx context.Context, prID int, at time.Time) error {
+	args := m.Called(ctx, prID, at)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetReviewerSync(ctx context.Context, prID int) (models.ReviewerSync, error) {
+	args := m.Called(ctx, prID)
+	return args.Get(0).(models.ReviewerSync), args.Error(1)
+}
+
+func (m *MockRepository) ClaimReviewerSyncs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.ReviewerSync, error) {
+	args := m.Called(ctx, now, lease, limit)
+	return args.Get(0).([]models.ReviewerSync), args.Error(1)
+}
+
+func (m *MockRepository) CompleteReviewerSync(ctx context.Context, s models.ReviewerSync) error {
+	args := m.Called(ctx, s)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetTeamChannel(ctx context.Context, c models.TeamChannel) (models.TeamChannel, error) {
+	args := m.Called(ctx, c)
+	return args.Get(0).(models.TeamChannel), args.Error(1)
+}
+
+func (m *MockRepository) GetTeamChannel(ctx context.Context, teamID int) (models.TeamChannel, error) {
+	args := m.Called(ctx, teamID)
+	return args.Get(0).(models.TeamChannel), args.Error(1)
+}
+
+func (m *MockRepository) DeleteTeamChannel(ctx context.Context, teamID int) error {
+	args := m.Called(ctx, teamID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) SetTeamSLA(ctx context.Context, sla models.TeamSLA) (models.TeamSLA, error) {
+	args := m.Called(ctx, sla)
+	return args.Get(0).(models.TeamSLA), args.Error(1)
+}
+
+func (m *MockRepository) GetTeamSLA(ctx context.Context, teamID int) (models.TeamSLA, error) {
+	args := m.Called(ctx, teamID)
+	return args.Get(0).(models.TeamSLA), args.Error(1)
+}
+
+func (m *MockRepository) DeleteTeamSLA(ctx context.Context, teamID int) error {
+	args := m.Called(ctx, teamID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListTeamSLAs(ctx context.Context) ([]models.TeamSLA, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.TeamSLA), args.Error(1)
+}
+
+func (m *MockRepository) ListPendingReviews(ctx context.Context, teamID int) ([]models.PendingReview, error) {
+	args := m.Called(ctx, teamID)
+	return args.Get(0).([]models.PendingReview), args.Error(1)
+}
+
+func (m *MockRepository) MarkReviewSLAStage(ctx context.Context, prID, userID int, stage models.SLAStage, at time.Time) (bool, error) {
+	args := m.Called(ctx, prID, userID, stage, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateChatMessage(ctx context.Context, msg models.ChatMessage) (models.ChatMessage, error) {
+	args := m.Called(ctx, msg)
+	return args.Get(0).(models.ChatMessage), args.Error(1)
+}
+
+func (m *MockRepository) UpdateChatMessage(ctx context.Context, msg models.ChatMessage) error {
+	args := m.Called(ctx, msg)
+	return args.Error(0)
+}
+
+func (m *MockRepository) ListDueChatMessages(ctx context.Context, now time.Time, limit int) ([]models.ChatMessage, error) {
+	args := m.Called(ctx, now, limit)
+	return args.Get(0).([]models.ChatMessage), args.Error(1)
+}
+
+func (m *MockRepository) SetDigestPrefs(ctx context.Context, p models.DigestPrefs) (models.DigestPrefs, error) {
+	args := m.Called(ctx, p)
+	return args.Get(0).(models.DigestPrefs), args.Error(1)
+}
+
+func (m *MockRepository) GetDigestPrefs(ctx context.Context, userID int) (models.DigestPrefs, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(models.DigestPrefs), args.Error(1)
+}
+
+func (m *MockRepository) ListDigestPrefs(ctx context.Context) ([]models.DigestPrefs, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.DigestPrefs), args.Error(1)
+}
+
+func (m *MockRepository) ClaimDigest(ctx context.Context, userID int, due, at time.Time) (bool, error) {
+	args := m.Called(ctx, userID, due, at)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) CreateAPIToken(ctx context.Context, t models.APIToken) (models.APIToken, error) {
+	args := m.Called(ctx, t)
+	return args.Get(0).(models.APIToken), args.Error(1)
+}
+
+func (m *MockRepository) GetAPITokenByHash(ctx context.Context, hash string) (models.APIToken, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(models.APIToken), args.Error(1)
+}
+
+func (m *MockRepository) ListAPITokens(ctx context.Context) ([]models.APIToken, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.APIToken), args.Error(1)
+}
+
+func (m *MockRepository) RevokeAPIToken(ctx context.Context, id int, at time.Time) error {
+	args := m.Called(ctx, id, at)
+	return args.Error(0)
+}
+
 func createTestLogger() *slog.Logger {
 	return slog.New(slog.NewTextHandler(io.Discard, nil))
 }
@@ -227,6 +664,7 @@ func TestCreatePRSuccess(t *testing.T) {
 			return true
 		})).Return(nil)
 		mockRepo.On("GetReviewersByPR", mock.Anything, pr.ID).Return(candidates[:2], nil)
+		mockRepo.On("CreateOutboxEvent", mock.Anything, mock.AnythingOfType("models.OutboxEvent")).Return(nil)
 
 		result, err := service.CreatePR(context.Background(), title, authorID)
 
@@ -241,6 +679,7 @@ func TestCreatePRSuccess(t *testing.T) {
 			return len(ids) == 2
 		}))
 		mockRepo.AssertCalled(t, "GetReviewersByPR", mock.Anything, pr.ID)
+		mockRepo.AssertNumberOfCalls(t, "CreateOutboxEvent", 3)
 	})
 }
 
@@ -269,6 +708,7 @@ func TestCreatePRNoTeam(t *testing.T) {
 
 		mockRepo.On("GetUserByID", mock.Anything, authorID).Return(author, nil)
 		mockRepo.On("CreatePR", mock.Anything, mock.AnythingOfType("models.PR")).Return(pr, nil)
+		mockRepo.On("CreateOutboxEvent", mock.Anything, mock.AnythingOfType("models.OutboxEvent")).Return(nil)
 
 		result, err := service.CreatePR(context.Background(), title, authorID)
 
@@ -338,3 +778,202 @@ func TestCreatePRAuthorNotFound(t *testing.T) {
 		mockRepo.AssertNotCalled(t, "GetReviewersByPR")
 	})
 }
+
+func TestReassignReviewerWithMemoryRepo(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	team, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	author, err := service.CreateUser(ctx, &team.ID, "Author", true)
+	require.NoError(t, err)
+	for i := 1; i <= 3; i++ {
+		_, err := service.CreateUser(ctx, &team.ID, fmt.Sprintf("Reviewer %d", i), true)
+		require.NoError(t, err)
+	}
+
+	pr, err := service.CreatePR(ctx, "Add search", author.ID)
+	require.NoError(t, err)
+	require.Len(t, pr.Reviewers, 2)
+
+	old := pr.Reviewers[0].ID
+	res, err := service.ReassignReviewer(ctx, pr.ID, old)
+	require.NoError(t, err)
+	require.Len(t, res.Reviewers, 2)
+	for _, r := range res.Reviewers {
+		assert.NotEqual(t, old, r.ID)
+		assert.NotEqual(t, author.ID, r.ID)
+	}
+
+	// the only remaining candidate is the reviewer who was just replaced
+	res, err = service.ReassignReviewer(ctx, pr.ID, res.Reviewers[0].ID)
+	require.NoError(t, err)
+	assert.Contains(t, []int{res.Reviewers[0].ID, res.Reviewers[1].ID}, old)
+
+	_, err = service.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+	_, err = service.ReassignReviewer(ctx, pr.ID, res.Reviewers[0].ID)
+	assert.ErrorIs(t, err, ErrPRMerged)
+}
+
+func TestReassignReviewerNoCandidateWithMemoryRepo(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+
+	team, err := service.CreateTeam(ctx, "small")
+	require.NoError(t, err)
+	author, err := service.CreateUser(ctx, &team.ID, "Author", true)
+	require.NoError(t, err)
+	_, err = service.CreateUser(ctx, &team.ID, "Reviewer", true)
+	require.NoError(t, err)
+
+	pr, err := service.CreatePR(ctx, "Fix typo", author.ID)
+	require.NoError(t, err)
+	require.Len(t, pr.Reviewers, 1)
+
+	_, err = service.ReassignReviewer(ctx, pr.ID, pr.Reviewers[0].ID)
+	assert.ErrorIs(t, err, ErrNoCandidate)
+}
+
+func TestListPRsAssignedToUserPaginates(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, createTestLogger())
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prs := make([]models.AssignedPR, 3)
+	for i := range prs {
+		prs[i].ID = i + 1
+		prs[i].AssignedAt = base.Add(time.Duration(i) * time.Minute)
+	}
+
+	mockRepo.On("GetUserByID", mock.Anything, 7).Return(models.User{ID: 7}, nil)
+	mockRepo.On("ListPRsAssignedToUser", mock.Anything, 7, repository.ReviewQueueFilter{SortBy: repository.SortByAssignedAt, Desc: true, Limit: 3}).
+		Return(prs, nil)
+
+	page, err := service.ListPRsAssignedToUser(context.Background(), 7, repository.ReviewQueueFilter{SortBy: repository.SortByAssignedAt, Desc: true, Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 2, "the extra row only signals another page")
+	require.NotNil(t, page.NextCursor)
+
+	c, err := pagination.DecodeFor(*page.NextCursor, "assigned_at", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.ID)
+	assert.True(t, prs[1].AssignedAt.Equal(*c.Time))
+}
+
+func TestListPRsAssignedToUserLastPage(t *testing.T) {
+	mockRepo := new(MockRepository)
+	service := NewService(mockRepo, createTestLogger())
+
+	mockRepo.On("GetUserByID", mock.Anything, 7).Return(models.User{ID: 7}, nil)
+	mockRepo.On("ListPRsAssignedToUser", mock.Anything, 7, mock.Anything).Return([]models.AssignedPR{{}}, nil)
+
+	page, err := service.ListPRsAssignedToUser(context.Background(), 7, repository.ReviewQueueFilter{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+	assert.Nil(t, page.NextCursor)
+}
+
+func TestUpdateUserMovePolicies(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T, policy MovePolicy) (*Service, models.Team, models.PRWithReviewers, models.PRWithReviewers) {
+		t.Helper()
+		service := NewService(memory.NewRepo(), createTestLogger(), WithMovePolicy(policy))
+		team, err := service.CreateTeam(ctx, "backend")
+		require.NoError(t, err)
+		other, err := service.CreateTeam(ctx, "frontend")
+		require.NoError(t, err)
+		author, err := service.CreateUser(ctx, &team.ID, "Author", true)
+		require.NoError(t, err)
+		for i := 1; i <= 3; i++ {
+			_, err := service.CreateUser(ctx, &team.ID, fmt.Sprintf("Reviewer %d", i), true)
+			require.NoError(t, err)
+		}
+		open, err := service.CreatePR(ctx, "Add search", author.ID)
+		require.NoError(t, err)
+		merged, err := service.CreatePR(ctx, "Fix typo", author.ID)
+		require.NoError(t, err)
+		_, err = service.MergePR(ctx, merged.ID)
+		require.NoError(t, err)
+		return service, other, open, merged
+	}
+
+	t.Run("keep", func(t *testing.T) {
+		service, other, open, _ := setup(t, MovePolicyKeep)
+		mover := open.Reviewers[0].ID
+
+		res, err := service.UpdateUser(ctx, mover, UserUpdate{SetTeam: true, TeamID: &other.ID})
+		require.NoError(t, err)
+		assert.Equal(t, other.ID, *res.User.TeamID)
+		assert.NotNil(t, res.Reassignments)
+		assert.Empty(t, res.Reassignments)
+
+		pr, err := service.GetPR(ctx, open.ID)
+		require.NoError(t, err)
+		assert.Contains(t, ids(pr.Reviewers), mover)
+	})
+
+	t.Run("reassign", func(t *testing.T) {
+		service, other, open, merged := setup(t, MovePolicyReassign)
+		mover := open.Reviewers[0].ID
+
+		res, err := service.UpdateUser(ctx, mover, UserUpdate{SetTeam: true, TeamID: &other.ID})
+		require.NoError(t, err)
+		require.Len(t, res.Reassignments, 1, "only the open PR is reassigned")
+		assert.Equal(t, open.ID, res.Reassignments[0].PRID)
+		assert.Equal(t, mover, res.Reassignments[0].OldUserID)
+
+		pr, err := service.GetPR(ctx, open.ID)
+		require.NoError(t, err)
+		assert.NotContains(t, ids(pr.Reviewers), mover)
+		assert.Contains(t, ids(pr.Reviewers), res.Reassignments[0].NewUserID)
+
+		if slices.Contains(ids(merged.Reviewers), mover) {
+			pr, err = service.GetPR(ctx, merged.ID)
+			require.NoError(t, err)
+			assert.Contains(t, ids(pr.Reviewers), mover, "merged PRs keep their history")
+		}
+
+		// renaming without moving does not touch reviews
+		name := "Renamed"
+		res, err = service.UpdateUser(ctx, open.Reviewers[1].ID, UserUpdate{Name: &name})
+		require.NoError(t, err)
+		assert.Equal(t, "Renamed", res.User.Name)
+		assert.Empty(t, res.Reassignments)
+	})
+}
+
+func TestUpdateUserValidation(t *testing.T) {
+	ctx := context.Background()
+	service := NewService(memory.NewRepo(), createTestLogger())
+	team, err := service.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	u, err := service.CreateUser(ctx, &team.ID, "Alice", true)
+	require.NoError(t, err)
+
+	empty := ""
+	_, err = service.UpdateUser(ctx, u.ID, UserUpdate{Name: &empty})
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	missing := team.ID + 100
+	_, err = service.UpdateUser(ctx, u.ID, UserUpdate{SetTeam: true, TeamID: &missing})
+	assert.ErrorIs(t, err, ErrBadRequest)
+
+	_, err = service.UpdateUser(ctx, u.ID+100, UserUpdate{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	inactive := false
+	res, err := service.UpdateUser(ctx, u.ID, UserUpdate{SetTeam: true, IsActive: &inactive})
+	require.NoError(t, err)
+	assert.Nil(t, res.User.TeamID, "SetTeam with a nil TeamID removes the user from their team")
+	assert.False(t, res.User.IsActive)
+}
+
+func ids(users []models.User) []int {
+	res := make([]int, 0, len(users))
+	for _, u := range users {
+		res = append(res, u.ID)
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	defaultWorkdayStart = "09:00"
+	defaultWorkdayEnd   = "18:00"
+	defaultSLATimezone  = "UTC"
+)
+
+// SetTeamSLA replaces the review SLA of the team. Empty WorkdayStart,
+// WorkdayEnd and Timezone mean 09:00 to 18:00 UTC. The lead, if any, must be
+// a member of the team.
+func (s *Service) SetTeamSLA(ctx context.Context, teamRef string, sla models.TeamSLA) (_ models.TeamSLA, err error) {
+	ctx, span := s.startSpan(ctx, "SetTeamSLA", attribute.String("team", teamRef))
+	defer func() { endSpan(span, err) }()
+	if err := validateSLA(&sla); err != nil {
+		return models.TeamSLA{}, err
+	}
+	team, err := s.resolveTeam(ctx, teamRef)
+	if err != nil {
+		return models.TeamSLA{}, err
+	}
+	if sla.LeadID != nil {
+		lead, err := s.repo.GetUserByID(ctx, *sla.LeadID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return models.TeamSLA{}, fmt.Errorf("%w: lead not found", ErrBadRequest)
+		}
+		if err != nil {
+			s.log(ctx).Error("failed to get lead", "error", err, "user_id", *sla.LeadID)
+			return models.TeamSLA{}, err
+		}
+		if lead.DeletedAt != nil || lead.TeamID == nil || *lead.TeamID != team.ID {
+			return models.TeamSLA{}, fmt.Errorf("%w: lead must be a member of the team", ErrBadRequest)
+		}
+	}
+
+	sla.TeamID = team.ID
+	res, err := s.repo.SetTeamSLA(ctx, sla)
+	if err != nil {
+		s.log(ctx).Error("failed to set team SLA", "error", err, "team_id", team.ID)
+		return models.TeamSLA{}, err
+	}
+	s.log(ctx).Info("team review SLA set", "team_id", team.ID,
+		"remind_after_hours", res.RemindAfterHours,
+		"reassign_after_hours", res.ReassignAfterHours,
+		"escalate_after_hours", res.EscalateAfterHours)
+	return res, nil
+}
+
+// validateSLA checks the thresholds and working hours of sla and fills in
+// their defaults.
+func validateSLA(sla *models.TeamSLA) error {
+	thresholds := []int{sla.RemindAfterHours, sla.ReassignAfterHours, sla.EscalateAfterHours}
+	last := 0
+	for _, h := range thresholds {
+		if h < 0 {
+			return fmt.Errorf("%w: SLA thresholds must not be negative", ErrBadRequest)
+		}
+		if h == 0 {
+			continue
+		}
+		if h <= last {
+			return fmt.Errorf("%w: SLA thresholds must grow from reminder to reassignment to escalation", ErrBadRequest)
+		}
+		last = h
+	}
+	if last == 0 {
+		return fmt.Errorf("%w: set at least one of remind_after_hours, reassign_after_hours and escalate_after_hours", ErrBadRequest)
+	}
+
+	start, err := time.Parse(models.DigestTimeLayout, cmp.Or(strings.TrimSpace(sla.WorkdayStart), defaultWorkdayStart))
+	if err != nil {
+		return fmt.Errorf("%w: workday_start must be a time of day like 09:00", ErrBadRequest)
+	}
+	end, err := time.Parse(models.DigestTimeLayout, cmp.Or(strings.TrimSpace(sla.WorkdayEnd), defaultWorkdayEnd))
+	if err != nil {
+		return fmt.Errorf("%w: workday_end must be a time of day like 18:00", ErrBadRequest)
+	}
+	if !start.Before(end) {
+		return fmt.Errorf("%w: workday_start must be before workday_end", ErrBadRequest)
+	}
+	sla.WorkdayStart = start.Format(models.DigestTimeLayout)
+	sla.WorkdayEnd = end.Format(models.DigestTimeLayout)
+
+	sla.Timezone = cmp.Or(strings.TrimSpace(sla.Timezone), defaultSLATimezone)
+	if _, err := time.LoadLocation(sla.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrBadRequest, sla.Timezone)
+	}
+	return nil
+}
+
+func (s *Service) GetTeamSLA(ctx context.Context, teamRef string) (_ models.TeamSLA, err error) {
+	ctx, span := s.startSpan(ctx, "GetTeamSLA", attribute.String("team", teamRef))
+	defer func() { endSpan(span, err) }()
+	team, err := s.resolveTeam(ctx, teamRef)
+	if err != nil {
+		return models.TeamSLA{}, err
+	}
+	sla, err := s.repo.GetTeamSLA(ctx, team.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.TeamSLA{}, fmt.Errorf("%w: team has no review SLA", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get team SLA", "error", err, "team_id", team.ID)
+		return models.TeamSLA{}, err
+	}
+	return sla, nil
+}
+
+func (s *Service) DeleteTeamSLA(ctx context.Context, teamRef string) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteTeamSLA", attribute.String("team", teamRef))
+	defer func() { endSpan(span, err) }()
+	team, err := s.resolveTeam(ctx, teamRef)
+	if err != nil {
+		return err
+	}
+	err = s.repo.DeleteTeamSLA(ctx, team.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: team has no review SLA", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to delete team SLA", "error", err, "team_id", team.ID)
+		return err
+	}
+	s.log(ctx).Info("team review SLA removed", "team_id", team.ID)
+	return nil
+}
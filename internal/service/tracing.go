@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "prmanager/internal/service"
+
+// WithTracerProvider makes the service trace its methods to tp instead of
+// the global tracer provider.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Service) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// startSpan starts the span of a service method, named "Service.<name>".
+// End it with endSpan.
+func (s *Service) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "Service."+name, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it failed if err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func defaultTracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// optionalInt is the attribute for an optional ID, or an empty one.
+func optionalInt(key string, v *int) attribute.KeyValue {
+	if v == nil {
+		return attribute.KeyValue{}
+	}
+	return attribute.Int(key, *v)
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"prmanager/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestFailedCallsMarkTheirSpans(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	service := NewService(memory.NewRepo(), createTestLogger(), WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))))
+
+	_, err := service.MergePR(context.Background(), 42)
+	require.Error(t, err)
+
+	spans := exp.GetSpans()
+	require.NotEmpty(t, spans)
+	merge := spans[len(spans)-1]
+	assert.Equal(t, "Service.MergePR", merge.Name)
+	assert.Equal(t, codes.Error, merge.Status.Code)
+	require.Len(t, merge.Events, 1)
+	assert.Equal(t, "exception", merge.Events[0].Name)
+}
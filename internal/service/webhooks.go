@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/pagination"
+	"prmanager/internal/repository"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CreateWebhook subscribes url to the given event types, or to all of them
+// when eventTypes is empty. A secret is generated when none is given; it is
+// only ever returned here.
+//
+// Webhooks receive the events of every organization, so only instance admins
+// manage them.
+func (s *Service) CreateWebhook(ctx context.Context, rawURL, secret string, eventTypes []string) (_ models.Webhook, err error) {
+	ctx, span := s.startSpan(ctx, "CreateWebhook")
+	defer func() { endSpan(span, err) }()
+	if err := requireInstanceAdmin(ctx); err != nil {
+		return models.Webhook{}, err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return models.Webhook{}, fmt.Errorf("%w: url must be an absolute http(s) URL", ErrBadRequest)
+	}
+
+	subscribed := make([]string, 0, len(eventTypes))
+	for _, t := range eventTypes {
+		if !events.Type(t).Valid() {
+			return models.Webhook{}, fmt.Errorf("%w: unknown event type %q", ErrBadRequest, t)
+		}
+		if !slices.Contains(subscribed, t) {
+			subscribed = append(subscribed, t)
+		}
+	}
+
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return models.Webhook{}, fmt.Errorf("generate webhook secret: %w", err)
+		}
+		secret = hex.EncodeToString(b)
+	}
+
+	w, err := s.repo.CreateWebhook(ctx, models.Webhook{URL: rawURL, Secret: secret, Events: subscribed})
+	if err != nil {
+		s.log(ctx).Error("failed to create webhook", "error", err, "url", rawURL)
+		return models.Webhook{}, err
+	}
+	s.log(ctx).Info("webhook created", "webhook_id", w.ID, "url", w.URL, "events", w.Events)
+	return w, nil
+}
+
+func (s *Service) GetWebhook(ctx context.Context, id int) (_ models.Webhook, err error) {
+	ctx, span := s.startSpan(ctx, "GetWebhook", attribute.Int("webhook_id", id))
+	defer func() { endSpan(span, err) }()
+	if err := requireInstanceAdmin(ctx); err != nil {
+		return models.Webhook{}, err
+	}
+	w, err := s.repo.GetWebhook(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return models.Webhook{}, fmt.Errorf("%w: webhook not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to get webhook", "error", err, "webhook_id", id)
+		return models.Webhook{}, err
+	}
+	w.Secret = ""
+	return w, nil
+}
+
+func (s *Service) ListWebhooks(ctx context.Context) (_ []models.Webhook, err error) {
+	ctx, span := s.startSpan(ctx, "ListWebhooks")
+	defer func() { endSpan(span, err) }()
+	if err := requireInstanceAdmin(ctx); err != nil {
+		return nil, err
+	}
+	hooks, err := s.repo.ListWebhooks(ctx)
+	if err != nil {
+		s.log(ctx).Error("failed to list webhooks", "error", err)
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+func (s *Service) DeleteWebhook(ctx context.Context, id int) (err error) {
+	ctx, span := s.startSpan(ctx, "DeleteWebhook", attribute.Int("webhook_id", id))
+	defer func() { endSpan(span, err) }()
+	if err := requireInstanceAdmin(ctx); err != nil {
+		return err
+	}
+	err = s.repo.DeleteWebhook(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("%w: webhook not found", ErrNotFound)
+	}
+	if err != nil {
+		s.log(ctx).Error("failed to delete webhook", "error", err, "webhook_id", id)
+		return err
+	}
+	s.log(ctx).Info("webhook deleted", "webhook_id", id)
+	return nil
+}
+
+// ListWebhookDeliveries pages through the delivery log of a webhook, newest
+// first.
+func (s *Service) ListWebhookDeliveries(ctx context.Context, webhookID int, f repository.DeliveryFilter) (_ pagination.Page[models.WebhookDelivery], err error) {
+	ctx, span := s.startSpan(ctx, "ListWebhookDeliveries", attribute.Int("webhook_id", webhookID))
+	defer func() { endSpan(span, err) }()
+	if _, err := s.GetWebhook(ctx, webhookID); err != nil {
+		return pagination.Page[models.WebhookDelivery]{}, err
+	}
+
+	limit := f.Limit
+	if limit > 0 {
+		f.Limit = limit + 1
+	}
+	deliveries, err := s.repo.ListWebhookDeliveries(ctx, webhookID, f)
+	if err != nil {
+		s.log(ctx).Error("failed to list webhook deliveries", "error", err, "webhook_id", webhookID)
+		return pagination.Page[models.WebhookDelivery]{}, err
+	}
+	return pagination.NewPage(deliveries, limit, func(d models.WebhookDelivery) pagination.Cursor {
+		return pagination.Cursor{Sort: pagination.SortByID, Desc: true, ID: d.ID}
+	}), nil
+}
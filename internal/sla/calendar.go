@@ -0,0 +1,54 @@
+package sla
+
+import (
+	"fmt"
+	"time"
+
+	"prmanager/internal/models"
+)
+
+// calendar is a team's working week: Monday to Friday, start to end in loc.
+type calendar struct {
+	loc        *time.Location
+	start, end time.Time // only the time of day is used
+}
+
+func newCalendar(sla models.TeamSLA) (calendar, error) {
+	loc, err := time.LoadLocation(sla.Timezone)
+	if err != nil {
+		return calendar{}, err
+	}
+	start, err := time.Parse(models.DigestTimeLayout, sla.WorkdayStart)
+	if err != nil {
+		return calendar{}, fmt.Errorf("workday start: %w", err)
+	}
+	end, err := time.Parse(models.DigestTimeLayout, sla.WorkdayEnd)
+	if err != nil {
+		return calendar{}, fmt.Errorf("workday end: %w", err)
+	}
+	return calendar{loc: loc, start: start, end: end}, nil
+}
+
+// workingTime is how much of the time from from to to falls in working
+// hours.
+func (c calendar) workingTime(from, to time.Time) time.Duration {
+	from, to = from.In(c.loc), to.In(c.loc)
+	var total time.Duration
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, c.loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if wd := day.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			continue
+		}
+		open := time.Date(day.Year(), day.Month(), day.Day(), c.start.Hour(), c.start.Minute(), 0, 0, c.loc)
+		closed := time.Date(day.Year(), day.Month(), day.Day(), c.end.Hour(), c.end.Minute(), 0, 0, c.loc)
+		if open.Before(from) {
+			open = from
+		}
+		if closed.After(to) {
+			closed = to
+		}
+		if closed.After(open) {
+			total += closed.Sub(open)
+		}
+	}
+	return total
+}
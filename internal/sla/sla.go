@@ -0,0 +1,205 @@
+// Package sla enforces the review SLAs of teams.
+//
+// Once per PollInterval the worker measures how many working hours every
+// reviewer of an open PR has had since they were assigned, per the SLA of
+// the reviewer's team. Past the reminder threshold it emits a
+// review.reminder event, past the reassignment threshold it hands the review
+// to someone else in the team through ReassignReviewer, and past the
+// escalation threshold, if nobody could take the review over, it emits a
+// review.escalated event for the team lead. Reminders and escalations are
+// recorded on the assignment in the same transaction as their event, so
+// each is sent once per assignment even with several replicas running; an
+// escalation is recorded on every assignment of the PR, so the lead hears
+// about a PR once. A reassignment that found nobody is recorded too and not
+// retried for that assignment. A reassignment starts a new assignment with a
+// clean slate.
+package sla
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	_ "time/tzdata" // teams pick their time zone; the image may have no zoneinfo
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/outbox"
+	"prmanager/internal/repository"
+	"prmanager/internal/service"
+)
+
+// Reassigner hands a review to another member of the reviewer's team;
+// *service.Service is one.
+type Reassigner interface {
+	ReassignReviewer(ctx context.Context, prID, oldUserID int) (models.PRWithReviewers, error)
+}
+
+type Config struct {
+	PollInterval time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{PollInterval: time.Minute}
+}
+
+type Worker struct {
+	repo       repository.Repository
+	reassigner Reassigner
+	logger     *slog.Logger
+	cfg        Config
+	now        func() time.Time
+}
+
+func NewWorker(r repository.Repository, reassigner Reassigner, logger *slog.Logger, cfg Config) *Worker {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Worker{
+		repo:       r,
+		reassigner: reassigner,
+		logger:     logger,
+		cfg:        cfg,
+		now:        time.Now,
+	}
+}
+
+// Run enforces the SLAs until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Enforce(ctx); err != nil && ctx.Err() == nil {
+			w.logger.Error("failed to enforce review SLAs", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Enforce acts on every overdue review and returns how many reminders,
+// reassignments and escalations it made.
+func (w *Worker) Enforce(ctx context.Context) (int, error) {
+	now := w.now()
+	slas, err := w.repo.ListTeamSLAs(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list team SLAs: %w", err)
+	}
+
+	acted := 0
+	for _, sla := range slas {
+		cal, err := newCalendar(sla)
+		if err != nil {
+			w.logger.Warn("invalid team SLA", "team_id", sla.TeamID, "error", err)
+			continue
+		}
+		reviews, err := w.repo.ListPendingReviews(ctx, sla.TeamID)
+		if err != nil {
+			return acted, fmt.Errorf("list pending reviews of team %d: %w", sla.TeamID, err)
+		}
+		for _, r := range reviews {
+			ok, err := w.enforce(ctx, sla, r, cal.workingTime(r.AssignedAt, now), now)
+			if err != nil {
+				return acted, err
+			}
+			if ok {
+				acted++
+			}
+		}
+	}
+	return acted, nil
+}
+
+// enforce takes the step r is due for after waiting waited, and reports
+// whether it took one. Only repository failures are errors.
+func (w *Worker) enforce(ctx context.Context, sla models.TeamSLA, r models.PendingReview, waited time.Duration, now time.Time) (bool, error) {
+	due := func(hours int) bool {
+		return hours > 0 && waited >= time.Duration(hours)*time.Hour
+	}
+	log := w.logger.With("pr_id", r.PRID, "user_id", r.UserID, "team_id", sla.TeamID, "waited", waited)
+
+	// Once escalated, the review is the lead's to sort out.
+	if r.EscalatedAt != nil {
+		return false, nil
+	}
+	if due(sla.ReassignAfterHours) && r.ReassignFailedAt == nil {
+		_, err := w.reassigner.ReassignReviewer(ctx, r.PRID, r.UserID)
+		switch {
+		case err == nil:
+			log.Info("overdue review reassigned")
+			return true, nil
+		case errors.Is(err, service.ErrNoCandidate):
+			// Nobody can take it over; remind or escalate instead, and
+			// don't ask again every poll.
+			log.Info("no one to reassign overdue review to")
+			if _, err := w.repo.MarkReviewSLAStage(ctx, r.PRID, r.UserID, models.SLAReassignFailed, now); err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return false, fmt.Errorf("record failed reassignment of PR %d: %w", r.PRID, err)
+			}
+		default:
+			log.Warn("failed to reassign overdue review", "error", err)
+			return false, nil
+		}
+	}
+	if due(sla.EscalateAfterHours) {
+		return w.record(ctx, log, events.ReviewEscalated, models.SLAEscalated, sla, r, now)
+	}
+	if due(sla.RemindAfterHours) && r.RemindedAt == nil {
+		return w.record(ctx, log, events.ReviewReminder, models.SLAReminded, sla, r, now)
+	}
+	return false, nil
+}
+
+// record marks r as having reached stage and emits its event, unless another
+// replica got there first, or the reviewer was replaced or the PR closed
+// meanwhile.
+func (w *Worker) record(ctx context.Context, log *slog.Logger, typ events.Type, stage models.SLAStage, sla models.TeamSLA, r models.PendingReview, now time.Time) (bool, error) {
+	data := events.ReviewOverdueData{PRID: r.PRID, ReviewerID: r.UserID, TeamID: sla.TeamID, AssignedAt: r.AssignedAt}
+	if typ == events.ReviewEscalated {
+		data.LeadID = sla.LeadID
+	}
+	e, err := events.New(typ, data)
+	if err != nil {
+		return false, err
+	}
+
+	var marked bool
+	err = w.repo.WithTx(ctx, func(tx repository.Repository) error {
+		pr, err := tx.LockPR(ctx, r.PRID)
+		if err != nil || pr.Status != models.PRStatusOpen {
+			return err
+		}
+		if marked, err = tx.MarkReviewSLAStage(ctx, r.PRID, r.UserID, stage, now); err != nil || !marked {
+			return err
+		}
+		if stage == models.SLAEscalated {
+			// The other reviews of the PR are the lead's now too, and
+			// must not escalate it again.
+			reviewers, err := tx.GetReviewersByPR(ctx, r.PRID)
+			if err != nil {
+				return err
+			}
+			for _, u := range reviewers {
+				if _, err := tx.MarkReviewSLAStage(ctx, r.PRID, u.ID, stage, now); err != nil {
+					return err
+				}
+			}
+		}
+		return outbox.Record(ctx, tx, e, outbox.PRKey(r.PRID))
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("record %s of PR %d: %w", typ, r.PRID, err)
+	}
+	if marked {
+		log.Info("overdue review "+string(stage), "event_id", e.ID)
+	}
+	return marked, nil
+}
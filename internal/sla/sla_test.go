@@ -0,0 +1,208 @@
+package sla
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/outbox"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// monday is 09:00 UTC on a Monday.
+var monday = time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+func TestWorkingTime(t *testing.T) {
+	cal, err := newCalendar(models.TeamSLA{WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "Europe/Berlin"})
+	require.NoError(t, err)
+	berlin := cal.loc
+
+	for _, c := range []struct {
+		name     string
+		from, to time.Time
+		want     time.Duration
+	}{
+		{"within a day", time.Date(2025, 3, 3, 10, 0, 0, 0, berlin), time.Date(2025, 3, 3, 12, 30, 0, 0, berlin), 150 * time.Minute},
+		{"before opening", time.Date(2025, 3, 3, 7, 0, 0, 0, berlin), time.Date(2025, 3, 3, 10, 0, 0, 0, berlin), time.Hour},
+		{"overnight", time.Date(2025, 3, 3, 17, 0, 0, 0, berlin), time.Date(2025, 3, 4, 10, 0, 0, 0, berlin), 2 * time.Hour},
+		{"over the weekend", time.Date(2025, 3, 7, 16, 0, 0, 0, berlin), time.Date(2025, 3, 10, 11, 0, 0, 0, berlin), 4 * time.Hour},
+		{"saturday only", time.Date(2025, 3, 8, 10, 0, 0, 0, berlin), time.Date(2025, 3, 8, 17, 0, 0, 0, berlin), 0},
+		{"a full week", time.Date(2025, 3, 3, 0, 0, 0, 0, berlin), time.Date(2025, 3, 10, 0, 0, 0, 0, berlin), 45 * time.Hour},
+		{"in another zone", monday, monday.Add(3 * time.Hour), 3 * time.Hour},
+		{"across the DST switch", time.Date(2025, 3, 28, 17, 0, 0, 0, berlin), time.Date(2025, 3, 31, 10, 0, 0, 0, berlin), 2 * time.Hour},
+		{"backwards", monday.Add(time.Hour), monday, 0},
+	} {
+		assert.Equal(t, c.want, cal.workingTime(c.from, c.to), c.name)
+	}
+}
+
+type fixture struct {
+	repo   repository.Repository
+	worker *Worker
+	clock  time.Time
+	users  map[string]models.User
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := memory.NewRepo()
+	f := &fixture{repo: repo, users: make(map[string]models.User)}
+	f.worker = NewWorker(repo, service.NewService(repo, logger), logger, DefaultConfig())
+	f.worker.now = func() time.Time { return f.clock }
+	return f
+}
+
+func (f *fixture) team(t *testing.T, name string, members ...string) models.Team {
+	t.Helper()
+	ctx := context.Background()
+	team, err := f.repo.CreateTeam(ctx, name)
+	require.NoError(t, err)
+	for _, m := range members {
+		u, err := f.repo.CreateUser(ctx, models.User{TeamID: &team.ID, Name: m, IsActive: true})
+		require.NoError(t, err)
+		f.users[m] = u
+	}
+	return team
+}
+
+// pr opens a PR whose reviewers were assigned at at.
+func (f *fixture) pr(t *testing.T, author string, at time.Time, reviewers ...string) models.PR {
+	t.Helper()
+	ctx := context.Background()
+	pr, err := f.repo.CreatePR(ctx, models.PR{Title: "Add search", AuthorID: f.users[author].ID, Status: models.PRStatusOpen})
+	require.NoError(t, err)
+	for _, r := range reviewers {
+		require.NoError(t, f.repo.RestoreReviewerAssignment(ctx, models.ReviewerAssignment{PRID: pr.ID, UserID: f.users[r].ID, AssignedAt: at}))
+	}
+	return pr
+}
+
+func (f *fixture) enforceAt(t *testing.T, at time.Time) int {
+	t.Helper()
+	f.clock = at
+	n, err := f.worker.Enforce(context.Background())
+	require.NoError(t, err)
+	return n
+}
+
+type recorder map[events.Type][]events.Event
+
+func (r recorder) Publish(_ context.Context, e events.Event) error {
+	r[e.Type] = append(r[e.Type], e)
+	return nil
+}
+
+// events relays the recorded events and returns them by type.
+func (f *fixture) events(t *testing.T) recorder {
+	t.Helper()
+	rec := make(recorder)
+	relay := outbox.NewRelay(f.repo, rec, slog.New(slog.NewTextHandler(io.Discard, nil)), outbox.DefaultConfig())
+	for {
+		n, err := relay.RelayDue(context.Background())
+		require.NoError(t, err)
+		if n == 0 {
+			return rec
+		}
+	}
+}
+
+func TestOverdueReviews(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	web := f.team(t, "web", "author", "alice", "bob")
+	ops := f.team(t, "ops", "owner", "carol", "dave")
+	_, err := f.repo.SetTeamSLA(ctx, models.TeamSLA{TeamID: web.ID, RemindAfterHours: 2, ReassignAfterHours: 4, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC"})
+	require.NoError(t, err)
+	lead := f.users["dave"].ID
+	_, err = f.repo.SetTeamSLA(ctx, models.TeamSLA{TeamID: ops.ID, RemindAfterHours: 2, ReassignAfterHours: 4, EscalateAfterHours: 8, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC", LeadID: &lead})
+	require.NoError(t, err)
+
+	webPR := f.pr(t, "author", monday, "alice")
+	// carol and dave are the only possible reviewers in ops, so neither can
+	// be replaced
+	opsPR := f.pr(t, "owner", monday, "carol", "dave")
+
+	assert.Equal(t, 0, f.enforceAt(t, monday.Add(time.Hour)))
+	assert.Equal(t, 3, f.enforceAt(t, monday.Add(150*time.Minute)), "all three reviewers are reminded")
+	assert.Equal(t, 0, f.enforceAt(t, monday.Add(3*time.Hour)), "reminders are sent once")
+
+	assert.Equal(t, 1, f.enforceAt(t, monday.Add(4*time.Hour)), "alice is replaced")
+	revs, err := f.repo.GetReviewersByPR(ctx, webPR.ID)
+	require.NoError(t, err)
+	require.Len(t, revs, 1)
+	assert.Equal(t, f.users["bob"].ID, revs[0].ID)
+
+	assert.Equal(t, 0, f.enforceAt(t, monday.Add(7*time.Hour)))
+	assert.Equal(t, 1, f.enforceAt(t, monday.Add(8*time.Hour)), "the ops PR is escalated once, not once per reviewer")
+	assert.Equal(t, 0, f.enforceAt(t, monday.Add(48*time.Hour)), "escalated reviews are left to the lead")
+
+	got := f.events(t)
+	assert.Len(t, got[events.ReviewReminder], 3)
+	assert.Len(t, got[events.ReviewerReassigned], 1)
+	require.Len(t, got[events.ReviewEscalated], 1)
+	var d events.ReviewOverdueData
+	require.NoError(t, json.Unmarshal(got[events.ReviewEscalated][0].Data, &d))
+	assert.Equal(t, opsPR.ID, d.PRID)
+	assert.Equal(t, ops.ID, d.TeamID)
+	require.NotNil(t, d.LeadID)
+	assert.Equal(t, lead, *d.LeadID)
+	assert.True(t, monday.Equal(d.AssignedAt))
+}
+
+// countingReassigner counts the reassignments the worker asks for.
+type countingReassigner struct {
+	Reassigner
+	calls int
+}
+
+func (c *countingReassigner) ReassignReviewer(ctx context.Context, prID, oldUserID int) (models.PRWithReviewers, error) {
+	c.calls++
+	return c.Reassigner.ReassignReviewer(ctx, prID, oldUserID)
+}
+
+func TestFailedReassignmentIsNotRetried(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	web := f.team(t, "web", "author", "alice")
+	_, err := f.repo.SetTeamSLA(ctx, models.TeamSLA{TeamID: web.ID, RemindAfterHours: 2, ReassignAfterHours: 4, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC"})
+	require.NoError(t, err)
+	c := &countingReassigner{Reassigner: f.worker.reassigner}
+	f.worker.reassigner = c
+
+	f.pr(t, "author", monday, "alice")
+	assert.Equal(t, 1, f.enforceAt(t, monday.Add(2*time.Hour)))
+	for h := 4; h < 8; h++ {
+		assert.Equal(t, 0, f.enforceAt(t, monday.Add(time.Duration(h)*time.Hour)))
+	}
+	assert.Equal(t, 1, c.calls, "nobody could take the review over, so it is not asked again")
+}
+
+func TestClosedPRsAreNotTracked(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	web := f.team(t, "web", "author", "alice", "bob")
+	_, err := f.repo.SetTeamSLA(ctx, models.TeamSLA{TeamID: web.ID, RemindAfterHours: 1, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC"})
+	require.NoError(t, err)
+
+	pr := f.pr(t, "author", monday, "alice")
+	require.NoError(t, f.repo.SetPRStatus(ctx, pr.ID, string(models.PRStatusMerged)))
+	open := f.pr(t, "author", monday, "bob")
+	assert.Equal(t, 1, f.enforceAt(t, monday.Add(2*time.Hour)))
+	reminders := f.events(t)[events.ReviewReminder]
+	require.Len(t, reminders, 1)
+	var d events.ReviewOverdueData
+	require.NoError(t, json.Unmarshal(reminders[0].Data, &d))
+	assert.Equal(t, open.ID, d.PRID)
+	assert.Nil(t, d.LeadID)
+}
@@ -0,0 +1,84 @@
+// Package slack understands Slack slash command requests: it verifies their
+// signature and decodes the form Slack posts.
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Slack-Signature"
+	TimestampHeader = "X-Slack-Request-Timestamp"
+
+	// MaxSkew is how old a request may be before it is refused as a replay.
+	MaxSkew = 5 * time.Minute
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrStaleRequest     = errors.New("request timestamp too far from now")
+)
+
+// Sign returns the X-Slack-Signature value for body sent at timestamp, a
+// Unix time in seconds: "v0=" followed by the hex HMAC-SHA256 of
+// "v0:timestamp:body" keyed with the signing secret.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequest checks the signature and timestamp headers of a request
+// received at now against body.
+func VerifyRequest(secret string, body []byte, timestamp, signature string, now time.Time) error {
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if d := now.Sub(time.Unix(secs, 0)); d > MaxSkew || d < -MaxSkew {
+		return ErrStaleRequest
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// SlashCommand is the subset of the slash command form the service reads.
+type SlashCommand struct {
+	Command string
+	Text    string
+	UserID  string
+}
+
+func ParseSlashCommand(body []byte) (SlashCommand, error) {
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		return SlashCommand{}, fmt.Errorf("decode slash command: %w", err)
+	}
+	return SlashCommand{
+		Command: form.Get("command"),
+		Text:    strings.TrimSpace(form.Get("text")),
+		UserID:  form.Get("user_id"),
+	}, nil
+}
+
+// Response is an immediate reply to a slash command.
+type Response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// Ephemeral is a reply only the user who typed the command sees.
+func Ephemeral(text string) Response {
+	return Response{ResponseType: "ephemeral", Text: text}
+}
@@ -0,0 +1,38 @@
+package slack
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	now := time.Unix(1531420618, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	body := []byte("command=%2Freviews&text=stats")
+	sig := Sign("s3cret", ts, body)
+
+	assert.NoError(t, VerifyRequest("s3cret", body, ts, sig, now))
+	assert.NoError(t, VerifyRequest("s3cret", body, ts, sig, now.Add(MaxSkew)))
+	assert.ErrorIs(t, VerifyRequest("s3cret", body, ts, sig, now.Add(MaxSkew+time.Second)), ErrStaleRequest)
+	assert.ErrorIs(t, VerifyRequest("other", body, ts, sig, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRequest("s3cret", []byte("command=%2Freviews"), ts, sig, now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRequest("s3cret", body, ts, "", now), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRequest("s3cret", body, "yesterday", sig, now), ErrInvalidSignature)
+
+	later := strconv.FormatInt(now.Unix()+1, 10)
+	assert.ErrorIs(t, VerifyRequest("s3cret", body, later, sig, now), ErrInvalidSignature, "the timestamp is signed")
+}
+
+func TestSignMatchesSlackExample(t *testing.T) {
+	// https://api.slack.com/authentication/verifying-requests-from-slack
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&team_id=T1DC2JH3J&team_domain=testteamnow&channel_id=G8PSS9T3V&channel_name=foobar&user_id=U2CERLKJA&user_name=roadrunner&command=%2Fwebhook-collect&text=&response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2FT1DC2JH3J%2F397700885554%2F96rGlfmibIGlgcZRskXaIFfN&trigger_id=398738663015.47445629121.803a0bc887a14d10d2c447fce8b6703c")
+	assert.Equal(t, "v0=a2114d57b48eac39b9ad189dd8316235a7b4a8d21a10bd27519666489c69b503", Sign("8f742231b10e8888abcd99yyyzzz85a5", "1531420618", body))
+
+	cmd, err := ParseSlashCommand(body)
+	require.NoError(t, err)
+	assert.Equal(t, SlashCommand{Command: "/webhook-collect", UserID: "U2CERLKJA"}, cmd)
+}
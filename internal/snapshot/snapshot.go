@@ -0,0 +1,331 @@
+package snapshot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+)
+
+// Version is the format Export writes. Older snapshots are still restored:
+// version 1 predates organizations and version 2 has only the sections up to
+// reviewer_assignments; see Export.
+const (
+	Version      = 3
+	versionNoOrg = 1
+)
+
+var (
+	ErrNotEmpty           = errors.New("target database is not empty")
+	ErrUnsupportedVersion = errors.New("unsupported snapshot version")
+	ErrMalformed          = errors.New("malformed snapshot")
+
+	errStop = errors.New("stop iteration")
+)
+
+// Export writes a snapshot as a single JSON object. Sections are written in
+// dependency order (organizations, teams, users, prs, reviewer_assignments,
+// then pr_external_refs, identities, reviewer_syncs, team_channels,
+// team_slas and digest_prefs, which hang off them) so that Restore can insert
+// rows as it reads them. Version 1 snapshots, from before organizations
+// existed, have no organizations section and no org_id, and restore into the
+// default organization. All sections are read from one consistent snapshot of
+// the repository.
+//
+// Left out on purpose are API tokens and webhook subscriptions, which grant
+// access to the instance or send its events elsewhere and are set up anew on
+// the target, and the work queues: idempotency keys, outbox events, webhook
+// deliveries and chat messages. Reviewer syncs are kept, without their lease,
+// so pending pushes to code hosts resume after a restore.
+func Export(ctx context.Context, repo repository.Repository, w io.Writer) error {
+	return repo.ReadSnapshot(ctx, func(repo repository.Repository) error {
+		return export(ctx, repo, w)
+	})
+}
+
+func export(ctx context.Context, repo repository.Repository, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	header, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("encode header: %w", err)
+	}
+	fmt.Fprintf(bw, `{"version":%d,"exported_at":%s`, Version, header)
+
+	if err := writeSection(bw, "organizations", func(emit func(any) error) error {
+		orgs, err := repo.ListOrganizations(ctx)
+		if err != nil {
+			return err
+		}
+		for _, o := range orgs {
+			if err := emit(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "teams", func(emit func(any) error) error {
+		return repo.ForEachTeam(ctx, func(t models.Team) error { return emit(t) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "users", func(emit func(any) error) error {
+		return repo.ForEachUser(ctx, func(u models.User) error { return emit(u) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "prs", func(emit func(any) error) error {
+		return repo.ForEachPR(ctx, func(p models.PR) error { return emit(p) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "reviewer_assignments", func(emit func(any) error) error {
+		return repo.ForEachReviewerAssignment(ctx, func(a models.ReviewerAssignment) error { return emit(a) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "pr_external_refs", func(emit func(any) error) error {
+		return repo.ForEachExternalRef(ctx, func(ref models.ExternalRef) error { return emit(ref) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "identities", func(emit func(any) error) error {
+		return repo.ForEachIdentity(ctx, func(id models.Identity) error { return emit(id) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "reviewer_syncs", func(emit func(any) error) error {
+		return repo.ForEachReviewerSync(ctx, func(s models.ReviewerSync) error { return emit(s) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "team_channels", func(emit func(any) error) error {
+		return repo.ForEachTeamChannel(ctx, func(c models.TeamChannel) error { return emit(c) })
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "team_slas", func(emit func(any) error) error {
+		slas, err := repo.ListTeamSLAs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, sla := range slas {
+			if err := emit(sla); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, enc); err != nil {
+		return err
+	}
+	if err := writeSection(bw, "digest_prefs", func(emit func(any) error) error {
+		prefs, err := repo.ListDigestPrefs(ctx)
+		if err != nil {
+			return err
+		}
+		for _, p := range prefs {
+			if err := emit(p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, enc); err != nil {
+		return err
+	}
+
+	if _, err := bw.WriteString("}\n"); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+func writeSection(w *bufio.Writer, name string, iterate func(emit func(any) error) error, enc *json.Encoder) error {
+	fmt.Fprintf(w, `,%q:[`, name)
+	first := true
+	err := iterate(func(v any) error {
+		if !first {
+			if err := w.WriteByte(','); err != nil {
+				return err
+			}
+		}
+		first = false
+		return enc.Encode(v)
+	})
+	if err != nil {
+		return fmt.Errorf("export %s: %w", name, err)
+	}
+	if _, err := w.WriteString("]"); err != nil {
+		return fmt.Errorf("export %s: %w", name, err)
+	}
+	return nil
+}
+
+// Restore loads a snapshot produced by Export into an empty repository,
+// keeping the original IDs and timestamps. It runs in one transaction, so a
+// failed restore leaves the repository empty.
+func Restore(ctx context.Context, repo repository.Repository, r io.Reader) error {
+	return repo.WithTx(ctx, func(tx repository.Repository) error {
+		if err := restore(ctx, tx, r); err != nil {
+			return err
+		}
+		if err := tx.SyncSequences(ctx); err != nil {
+			return fmt.Errorf("restore: %w", err)
+		}
+		return nil
+	})
+}
+
+func restore(ctx context.Context, repo repository.Repository, r io.Reader) error {
+	empty, err := isEmpty(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return ErrNotEmpty
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(r))
+	if err := expectDelim(dec, '{'); err != nil {
+		return err
+	}
+
+	versionSeen := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("%w: expected object key", ErrMalformed)
+		}
+
+		if key == "version" {
+			var v int
+			if err := dec.Decode(&v); err != nil {
+				return fmt.Errorf("%w: version: %v", ErrMalformed, err)
+			}
+			if v < versionNoOrg || v > Version {
+				return fmt.Errorf("%w: %d", ErrUnsupportedVersion, v)
+			}
+			versionSeen = true
+			continue
+		}
+		if !versionSeen {
+			return fmt.Errorf("%w: version must precede data sections", ErrMalformed)
+		}
+
+		switch key {
+		case "organizations":
+			err = readSection(dec, key, func(o models.Organization) error { return repo.RestoreOrganization(ctx, o) })
+		case "teams":
+			err = readSection(dec, key, func(t models.Team) error { return repo.RestoreTeam(ctx, t) })
+		case "users":
+			err = readSection(dec, key, func(u models.User) error { return repo.RestoreUser(ctx, u) })
+		case "prs":
+			err = readSection(dec, key, func(p models.PR) error { return repo.RestorePR(ctx, p) })
+		case "reviewer_assignments":
+			err = readSection(dec, key, func(a models.ReviewerAssignment) error { return repo.RestoreReviewerAssignment(ctx, a) })
+		case "pr_external_refs":
+			err = readSection(dec, key, func(ref models.ExternalRef) error { return repo.LinkExternalPR(ctx, ref) })
+		case "identities":
+			err = readSection(dec, key, func(id models.Identity) error { return repo.RestoreIdentity(ctx, id) })
+		case "reviewer_syncs":
+			err = readSection(dec, key, func(s models.ReviewerSync) error { return repo.RestoreReviewerSync(ctx, s) })
+		case "team_channels":
+			err = readSection(dec, key, func(c models.TeamChannel) error { return repo.RestoreTeamChannel(ctx, c) })
+		case "team_slas":
+			err = readSection(dec, key, func(s models.TeamSLA) error { return repo.RestoreTeamSLA(ctx, s) })
+		case "digest_prefs":
+			err = readSection(dec, key, func(p models.DigestPrefs) error { return repo.RestoreDigestPrefs(ctx, p) })
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if !versionSeen {
+		return fmt.Errorf("%w: missing version", ErrMalformed)
+	}
+	return expectDelim(dec, '}')
+}
+
+func readSection[T any](dec *json.Decoder, name string, restore func(T) error) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for dec.More() {
+		var item T
+		if err := dec.Decode(&item); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrMalformed, name, err)
+		}
+		if err := restore(item); err != nil {
+			return fmt.Errorf("restore %s: %w", name, err)
+		}
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != want {
+		return fmt.Errorf("%w: expected %q", ErrMalformed, want)
+	}
+	return nil
+}
+
+func isEmpty(ctx context.Context, repo repository.Repository) (bool, error) {
+	err := repo.ForEachTeam(ctx, func(models.Team) error { return errStop })
+	if errors.Is(err, errStop) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check teams: %w", err)
+	}
+
+	err = repo.ForEachUser(ctx, func(models.User) error { return errStop })
+	if errors.Is(err, errStop) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check users: %w", err)
+	}
+
+	err = repo.ForEachPR(ctx, func(models.PR) error { return errStop })
+	if errors.Is(err, errStop) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check PRs: %w", err)
+	}
+
+	err = repo.ForEachReviewerAssignment(ctx, func(models.ReviewerAssignment) error { return errStop })
+	if errors.Is(err, errStop) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check reviewer assignments: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,318 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeRepo struct {
+	repository.Repository
+
+	orgs        []models.Organization
+	teams       []models.Team
+	users       []models.User
+	prs         []models.PR
+	assignments []models.ReviewerAssignment
+	refs        []models.ExternalRef
+	identities  []models.Identity
+	syncs       []models.ReviewerSync
+	channels    []models.TeamChannel
+	slas        []models.TeamSLA
+	digestPrefs []models.DigestPrefs
+	synced      int
+}
+
+func each[T any](items []T, fn func(T) error) error {
+	for _, item := range items {
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) ListOrganizations(context.Context) ([]models.Organization, error) {
+	return f.orgs, nil
+}
+
+func (f *fakeRepo) ForEachTeam(_ context.Context, fn func(models.Team) error) error {
+	for _, t := range f.teams {
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) ForEachUser(_ context.Context, fn func(models.User) error) error {
+	for _, u := range f.users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) ForEachPR(_ context.Context, fn func(models.PR) error) error {
+	for _, p := range f.prs {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) ForEachReviewerAssignment(_ context.Context, fn func(models.ReviewerAssignment) error) error {
+	for _, a := range f.assignments {
+		if err := fn(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *fakeRepo) ForEachExternalRef(_ context.Context, fn func(models.ExternalRef) error) error {
+	return each(f.refs, fn)
+}
+
+func (f *fakeRepo) ForEachIdentity(_ context.Context, fn func(models.Identity) error) error {
+	return each(f.identities, fn)
+}
+
+func (f *fakeRepo) ForEachReviewerSync(_ context.Context, fn func(models.ReviewerSync) error) error {
+	return each(f.syncs, fn)
+}
+
+func (f *fakeRepo) ForEachTeamChannel(_ context.Context, fn func(models.TeamChannel) error) error {
+	return each(f.channels, fn)
+}
+
+func (f *fakeRepo) ListTeamSLAs(context.Context) ([]models.TeamSLA, error) {
+	return f.slas, nil
+}
+
+func (f *fakeRepo) ListDigestPrefs(context.Context) ([]models.DigestPrefs, error) {
+	return f.digestPrefs, nil
+}
+
+func (f *fakeRepo) RestoreOrganization(_ context.Context, o models.Organization) error {
+	f.orgs = append(f.orgs, o)
+	return nil
+}
+
+func (f *fakeRepo) RestoreTeam(_ context.Context, t models.Team) error {
+	f.teams = append(f.teams, t)
+	return nil
+}
+
+func (f *fakeRepo) RestoreUser(_ context.Context, u models.User) error {
+	f.users = append(f.users, u)
+	return nil
+}
+
+func (f *fakeRepo) RestorePR(_ context.Context, pr models.PR) error {
+	f.prs = append(f.prs, pr)
+	return nil
+}
+
+func (f *fakeRepo) RestoreReviewerAssignment(_ context.Context, a models.ReviewerAssignment) error {
+	f.assignments = append(f.assignments, a)
+	return nil
+}
+
+func (f *fakeRepo) LinkExternalPR(_ context.Context, ref models.ExternalRef) error {
+	f.refs = append(f.refs, ref)
+	return nil
+}
+
+func (f *fakeRepo) RestoreIdentity(_ context.Context, id models.Identity) error {
+	f.identities = append(f.identities, id)
+	return nil
+}
+
+func (f *fakeRepo) RestoreReviewerSync(_ context.Context, s models.ReviewerSync) error {
+	f.syncs = append(f.syncs, s)
+	return nil
+}
+
+func (f *fakeRepo) RestoreTeamChannel(_ context.Context, c models.TeamChannel) error {
+	f.channels = append(f.channels, c)
+	return nil
+}
+
+func (f *fakeRepo) RestoreTeamSLA(_ context.Context, sla models.TeamSLA) error {
+	f.slas = append(f.slas, sla)
+	return nil
+}
+
+func (f *fakeRepo) RestoreDigestPrefs(_ context.Context, p models.DigestPrefs) error {
+	f.digestPrefs = append(f.digestPrefs, p)
+	return nil
+}
+
+func (f *fakeRepo) ReadSnapshot(_ context.Context, fn func(repository.Repository) error) error {
+	return fn(f)
+}
+
+func (f *fakeRepo) WithTx(_ context.Context, fn func(repository.Repository) error) error {
+	backup := *f
+	if err := fn(f); err != nil {
+		*f = backup
+		return err
+	}
+	return nil
+}
+
+func (f *fakeRepo) SyncSequences(context.Context) error {
+	f.synced++
+	return nil
+}
+
+func populatedRepo() *fakeRepo {
+	ts := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	teamID, lead := 7, 10
+	reminded := ts.Add(6 * time.Hour)
+	return &fakeRepo{
+		orgs: []models.Organization{
+			{ID: models.DefaultOrgID, Name: "default", CreatedAt: ts},
+			{ID: 2, Name: "acme", CreatedAt: ts},
+		},
+		teams: []models.Team{{ID: 7, OrgID: 2, Name: "backend", CreatedAt: ts}},
+		users: []models.User{
+			{ID: 10, OrgID: 2, TeamID: &teamID, Name: "Alice", IsActive: true, CreatedAt: ts},
+			{ID: 11, OrgID: 2, TeamID: &teamID, Name: "Bob", IsActive: false, CreatedAt: ts.Add(time.Hour)},
+			{ID: 12, OrgID: 2, Name: "Carol", IsActive: true, CreatedAt: ts.Add(2 * time.Hour)},
+		},
+		prs: []models.PR{
+			{ID: 100, OrgID: 2, Title: "Add search", AuthorID: 10, Status: models.PRStatusMerged, CreatedAt: ts.Add(3 * time.Hour)},
+		},
+		assignments: []models.ReviewerAssignment{
+			{PRID: 100, UserID: 11, AssignedAt: ts.Add(4 * time.Hour)},
+			{PRID: 100, UserID: 12, AssignedAt: ts.Add(5 * time.Hour), RemindedAt: &reminded},
+		},
+		refs:       []models.ExternalRef{{PRID: 100, Provider: "github", Repo: "acme/api", Number: 7, URL: "https://github.com/acme/api/pull/7"}},
+		identities: []models.Identity{{UserID: 10, Provider: "github", Login: "alice", CreatedAt: ts}},
+		syncs: []models.ReviewerSync{
+			{PRID: 100, Status: models.ReviewerSyncPending, Attempts: 2, LastError: "502", Pushed: []string{"bob"}, NextAttemptAt: &reminded},
+		},
+		channels:    []models.TeamChannel{{TeamID: 7, WebhookURL: "https://hooks.slack.com/services/T/B/x", Channel: "#backend", UpdatedAt: ts}},
+		slas:        []models.TeamSLA{{TeamID: 7, RemindAfterHours: 4, WorkdayStart: "09:00", WorkdayEnd: "18:00", Timezone: "UTC", LeadID: &lead, UpdatedAt: ts}},
+		digestPrefs: []models.DigestPrefs{{UserID: 11, OptOut: true, LastSentAt: &reminded, UpdatedAt: ts}},
+	}
+}
+
+func TestExportRestoreRoundTrip(t *testing.T) {
+	src := populatedRepo()
+
+	var buf bytes.Buffer
+	require.NoError(t, Export(context.Background(), src, &buf))
+	assert.True(t, json.Valid(buf.Bytes()))
+
+	dst := &fakeRepo{}
+	require.NoError(t, Restore(context.Background(), dst, &buf))
+
+	assert.Equal(t, src.orgs, dst.orgs)
+	assert.Equal(t, src.teams, dst.teams)
+	assert.Equal(t, src.users, dst.users)
+	assert.Equal(t, src.prs, dst.prs)
+	assert.Equal(t, src.assignments, dst.assignments)
+	assert.Equal(t, src.refs, dst.refs)
+	assert.Equal(t, src.identities, dst.identities)
+	assert.Equal(t, src.syncs, dst.syncs)
+	assert.Equal(t, src.channels, dst.channels)
+	assert.Equal(t, src.slas, dst.slas)
+	assert.Equal(t, src.digestPrefs, dst.digestPrefs)
+	assert.Equal(t, 1, dst.synced, "sequences are synced once, after all sections")
+}
+
+func TestExportEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Export(context.Background(), &fakeRepo{}, &buf))
+
+	var doc struct {
+		Version int               `json:"version"`
+		Teams   []json.RawMessage `json:"teams"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, Version, doc.Version)
+	assert.NotNil(t, doc.Teams)
+	assert.Empty(t, doc.Teams)
+}
+
+func TestRestoreRejectsNonEmptyTarget(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Export(context.Background(), populatedRepo(), &buf))
+
+	err := Restore(context.Background(), populatedRepo(), &buf)
+	assert.ErrorIs(t, err, ErrNotEmpty)
+}
+
+func TestRestoreRejectsTargetWithOnlyPRs(t *testing.T) {
+	dst := &fakeRepo{prs: populatedRepo().prs}
+	err := Restore(context.Background(), dst, strings.NewReader(`{"version":2,"teams":[]}`))
+	assert.ErrorIs(t, err, ErrNotEmpty)
+}
+
+func TestRestoreRollsBackOnFailure(t *testing.T) {
+	dst := &fakeRepo{}
+	in := `{"version":2,"teams":[{"id":1,"name":"a"}],"users":[{"id":2,"name":`
+	err := Restore(context.Background(), dst, strings.NewReader(in))
+	assert.ErrorIs(t, err, ErrMalformed)
+	assert.Empty(t, dst.teams, "rows restored before the failure are rolled back")
+	assert.Zero(t, dst.synced)
+}
+
+func TestRestoreAcceptsVersion1(t *testing.T) {
+	dst := &fakeRepo{}
+	in := `{"version":1,"teams":[{"id":3,"name":"ops","created_at":"2025-03-01T00:00:00Z"}],"users":[{"id":4,"team_id":3,"name":"Alice","is_active":true,"created_at":"2025-03-01T00:00:00Z"}]}`
+	require.NoError(t, Restore(context.Background(), dst, strings.NewReader(in)))
+	require.Len(t, dst.teams, 1)
+	assert.Zero(t, dst.teams[0].OrgID, "left to the repository, which uses the default organization")
+	require.Len(t, dst.users, 1)
+	assert.Empty(t, dst.orgs)
+}
+
+func TestRestoreAcceptsVersion2(t *testing.T) {
+	dst := &fakeRepo{}
+	in := `{"version":2,"organizations":[{"id":1,"name":"default"}],"teams":[{"id":3,"org_id":1,"name":"ops","created_at":"2025-03-01T00:00:00Z"}]}`
+	require.NoError(t, Restore(context.Background(), dst, strings.NewReader(in)))
+	require.Len(t, dst.teams, 1)
+	assert.Empty(t, dst.identities)
+}
+
+func TestRestoreRejectsUnknownVersion(t *testing.T) {
+	err := Restore(context.Background(), &fakeRepo{}, strings.NewReader(`{"version":99,"teams":[]}`))
+	assert.ErrorIs(t, err, ErrUnsupportedVersion)
+}
+
+func TestRestoreRejectsMalformedInput(t *testing.T) {
+	cases := map[string]string{
+		"not an object":   `[]`,
+		"missing version": `{"teams":[]}`,
+		"truncated":       `{"version":2,"teams":[{"id":1,"name":"a"}`,
+	}
+	for name, in := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := Restore(context.Background(), &fakeRepo{}, strings.NewReader(in))
+			assert.ErrorIs(t, err, ErrMalformed)
+		})
+	}
+}
+
+func TestRestoreSkipsUnknownSections(t *testing.T) {
+	dst := &fakeRepo{}
+	in := `{"version":2,"exported_at":"2025-03-01T00:00:00Z","future":{"a":[1,2]},"teams":[{"id":3,"name":"ops","created_at":"2025-03-01T00:00:00Z"}]}`
+	require.NoError(t, Restore(context.Background(), dst, strings.NewReader(in)))
+	require.Len(t, dst.teams, 1)
+	assert.Equal(t, 3, dst.teams[0].ID)
+}
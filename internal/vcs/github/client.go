@@ -0,0 +1,75 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"prmanager/internal/models"
+	"prmanager/internal/vcs"
+)
+
+const DefaultAPIURL = "https://api.github.com"
+
+// Client requests and removes reviewers through GitHub's REST API.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+var _ vcs.Provider = (*Client)(nil)
+
+// NewClient returns a client for the API at baseURL (DefaultAPIURL when
+// empty, or a GitHub Enterprise /api/v3 URL) authenticating with token.
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if baseURL == "" {
+		baseURL = DefaultAPIURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, http: httpClient}
+}
+
+func (c *Client) RequestReviewers(ctx context.Context, ref models.ExternalRef, logins []string) error {
+	return c.reviewers(ctx, http.MethodPost, ref, logins)
+}
+
+func (c *Client) RemoveReviewers(ctx context.Context, ref models.ExternalRef, logins []string) error {
+	return c.reviewers(ctx, http.MethodDelete, ref, logins)
+}
+
+func (c *Client) reviewers(ctx context.Context, method string, ref models.ExternalRef, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(map[string][]string{"reviewers": logins})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/repos/%s/pulls/%d/requested_reviewers", c.baseURL, ref.Repo, ref.Number)
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &vcs.APIError{StatusCode: resp.StatusCode, Body: string(b)}
+	}
+	return nil
+}
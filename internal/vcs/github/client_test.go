@@ -0,0 +1,102 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/vcs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI mimics the requested_reviewers endpoints of one pull request.
+type fakeAPI struct {
+	mu        sync.Mutex
+	requested []string
+	auth      []string
+	fail      int
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	if r.URL.Path != "/repos/acme/api/pulls/42/requested_reviewers" {
+		http.NotFound(w, r)
+		return
+	}
+	if f.fail > 0 {
+		f.fail--
+		http.Error(w, `{"message":"Server Error"}`, http.StatusBadGateway)
+		return
+	}
+	var body struct {
+		Reviewers []string `json:"reviewers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch r.Method {
+	case http.MethodPost:
+		for _, l := range body.Reviewers {
+			if !contains(f.requested, l) {
+				f.requested = append(f.requested, l)
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		kept := f.requested[:0]
+		for _, l := range f.requested {
+			if !contains(body.Reviewers, l) {
+				kept = append(kept, l)
+			}
+		}
+		f.requested = kept
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+	w.Write([]byte(`{}`))
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func TestClientRequestsAndRemovesReviewers(t *testing.T) {
+	api := &fakeAPI{}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.URL, "ghp_test", srv.Client())
+	ref := models.ExternalRef{Provider: vcs.GitHub, Repo: "acme/api", Number: 42}
+
+	require.NoError(t, c.RequestReviewers(ctx, ref, []string{"alice", "bob"}))
+	require.NoError(t, c.RemoveReviewers(ctx, ref, []string{"alice"}))
+	require.NoError(t, c.RequestReviewers(ctx, ref, nil), "nothing to request makes no call")
+	assert.Equal(t, []string{"bob"}, api.requested)
+	assert.Equal(t, []string{"Bearer ghp_test", "Bearer ghp_test"}, api.auth)
+
+	api.fail = 1
+	err := c.RequestReviewers(ctx, ref, []string{"carol"})
+	var apiErr *vcs.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusBadGateway, apiErr.StatusCode)
+
+	err = c.RequestReviewers(ctx, models.ExternalRef{Repo: "acme/web", Number: 1}, []string{"carol"})
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
@@ -0,0 +1,99 @@
+// Package github understands GitHub's pull_request webhooks: it verifies
+// their signature and translates them into vcs actions.
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"prmanager/internal/models"
+	"prmanager/internal/vcs"
+)
+
+const (
+	EventHeader     = "X-GitHub-Event"
+	SignatureHeader = "X-Hub-Signature-256"
+	DeliveryHeader  = "X-GitHub-Delivery"
+
+	EventPing        = "ping"
+	EventPullRequest = "pull_request"
+)
+
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// VerifySignature checks header, the X-Hub-Signature-256 value GitHub sends,
+// against the HMAC-SHA256 of body keyed with secret.
+func VerifySignature(secret string, body []byte, header string) error {
+	sig, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return ErrInvalidSignature
+	}
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// PullRequestEvent is the subset of the pull_request webhook payload the
+// integration reads.
+type PullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Number  int    `json:"number"`
+		Title   string `json:"title"`
+		HTMLURL string `json:"html_url"`
+		Draft   bool   `json:"draft"`
+		Merged  bool   `json:"merged"`
+		User    struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	} `json:"pull_request"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+// Translate maps the event onto a vcs action. Drafts are not tracked until
+// they are marked ready for review, and a closed pull request that was merged
+// counts as merged. ok is false for actions that do not change the PR.
+func (e PullRequestEvent) Translate() (action vcs.Action, pr vcs.PullRequest, ok bool) {
+	switch e.Action {
+	case "opened":
+		action, ok = vcs.ActionOpened, !e.PullRequest.Draft
+	case "ready_for_review":
+		action, ok = vcs.ActionOpened, true
+	case "reopened":
+		action, ok = vcs.ActionReopened, true
+	case "closed":
+		action, ok = vcs.ActionClosed, true
+		if e.PullRequest.Merged {
+			action = vcs.ActionMerged
+		}
+	}
+
+	number := e.PullRequest.Number
+	if number == 0 {
+		number = e.Number
+	}
+	pr = vcs.PullRequest{
+		Ref: models.ExternalRef{
+			Provider: vcs.GitHub,
+			Repo:     e.Repository.FullName,
+			Number:   number,
+			URL:      e.PullRequest.HTMLURL,
+		},
+		Title:       e.PullRequest.Title,
+		AuthorLogin: e.PullRequest.User.Login,
+	}
+	return action, pr, ok
+}
@@ -0,0 +1,74 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"prmanager/internal/vcs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func fixture(t *testing.T, name string) PullRequestEvent {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	var e PullRequestEvent
+	require.NoError(t, json.Unmarshal(b, &e))
+	return e
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+
+	assert.NoError(t, VerifySignature("s3cret", body, sign("s3cret", body)))
+	assert.ErrorIs(t, VerifySignature("other", body, sign("s3cret", body)), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature("s3cret", []byte(`{"action":"closed"}`), sign("s3cret", body)), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature("s3cret", body, ""), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature("s3cret", body, "sha1=abc"), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifySignature("s3cret", body, "sha256=not-hex"), ErrInvalidSignature)
+}
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		fixture string
+		action  vcs.Action
+		ok      bool
+	}{
+		{"opened.json", vcs.ActionOpened, true},
+		{"opened_draft.json", vcs.ActionOpened, false},
+		{"ready_for_review.json", vcs.ActionOpened, true},
+		{"closed.json", vcs.ActionClosed, true},
+		{"closed_merged.json", vcs.ActionMerged, true},
+		{"reopened.json", vcs.ActionReopened, true},
+		{"synchronize.json", "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.fixture, func(t *testing.T) {
+			action, pr, ok := fixture(t, c.fixture).Translate()
+			assert.Equal(t, c.ok, ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, c.action, action)
+			assert.Equal(t, vcs.GitHub, pr.Ref.Provider)
+			assert.Equal(t, "acme/api", pr.Ref.Repo)
+			assert.Equal(t, 42, pr.Ref.Number)
+			assert.Equal(t, "https://github.com/acme/api/pull/42", pr.Ref.URL)
+			assert.Equal(t, "Add rate limiting to the public API", pr.Title)
+			assert.Equal(t, "Octo-Alice", pr.AuthorLogin)
+		})
+	}
+}
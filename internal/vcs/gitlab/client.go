@@ -0,0 +1,164 @@
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"prmanager/internal/models"
+	"prmanager/internal/vcs"
+)
+
+const DefaultURL = "https://gitlab.com"
+
+// Client requests and removes merge request reviewers through GitLab's REST
+// API. GitLab only lets the whole reviewer list be replaced, so both methods
+// read the current reviewers first.
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+var _ vcs.Provider = (*Client)(nil)
+
+// NewClient returns a client for the instance at baseURL (DefaultURL when
+// empty) authenticating with a personal, group or project access token.
+func NewClient(baseURL, token string, httpClient *http.Client) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), token: token, http: httpClient}
+}
+
+type apiUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+func (c *Client) RequestReviewers(ctx context.Context, ref models.ExternalRef, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	current, err := c.reviewers(ctx, ref)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(current)+len(logins))
+	for _, u := range current {
+		ids = append(ids, u.ID)
+	}
+	for _, login := range logins {
+		id, err := c.userID(ctx, login)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == len(current) {
+		return nil
+	}
+	return c.setReviewers(ctx, ref, ids)
+}
+
+func (c *Client) RemoveReviewers(ctx context.Context, ref models.ExternalRef, logins []string) error {
+	if len(logins) == 0 {
+		return nil
+	}
+	current, err := c.reviewers(ctx, ref)
+	if err != nil {
+		return err
+	}
+	ids := make([]int, 0, len(current))
+	for _, u := range current {
+		if !slices.ContainsFunc(logins, func(l string) bool { return strings.EqualFold(l, u.Username) }) {
+			ids = append(ids, u.ID)
+		}
+	}
+	if len(ids) == len(current) {
+		return nil
+	}
+	return c.setReviewers(ctx, ref, ids)
+}
+
+func (c *Client) mergeRequestPath(ref models.ExternalRef) string {
+	return fmt.Sprintf("/api/v4/projects/%s/merge_requests/%d", url.PathEscape(ref.Repo), ref.Number)
+}
+
+func (c *Client) reviewers(ctx context.Context, ref models.ExternalRef) ([]apiUser, error) {
+	var mr struct {
+		Reviewers []apiUser `json:"reviewers"`
+	}
+	if err := c.do(ctx, http.MethodGet, c.mergeRequestPath(ref), nil, &mr); err != nil {
+		return nil, err
+	}
+	return mr.Reviewers, nil
+}
+
+func (c *Client) userID(ctx context.Context, username string) (int, error) {
+	var users []apiUser
+	if err := c.do(ctx, http.MethodGet, "/api/v4/users?username="+url.QueryEscape(username), nil, &users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("gitlab user %q not found", username)
+	}
+	return users[0].ID, nil
+}
+
+// Username returns the username of the GitLab user with the given id.
+func (c *Client) Username(ctx context.Context, id int) (string, error) {
+	var u apiUser
+	if err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/v4/users/%d", id), nil, &u); err != nil {
+		return "", err
+	}
+	return u.Username, nil
+}
+
+func (c *Client) setReviewers(ctx context.Context, ref models.ExternalRef, ids []int) error {
+	return c.do(ctx, http.MethodPut, c.mergeRequestPath(ref), map[string][]int{"reviewer_ids": ids}, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &vcs.APIError{StatusCode: resp.StatusCode, Body: string(b)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,125 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"prmanager/internal/models"
+	"prmanager/internal/vcs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPI mimics the users lookup and one merge request of GitLab's API.
+type fakeAPI struct {
+	mu        sync.Mutex
+	users     map[string]int
+	reviewers []int
+	puts      int
+	tokens    []string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tokens = append(f.tokens, r.Header.Get("PRIVATE-TOKEN"))
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v4/users":
+		res := []apiUser{}
+		if id, ok := f.users[r.URL.Query().Get("username")]; ok {
+			res = append(res, apiUser{ID: id, Username: r.URL.Query().Get("username")})
+		}
+		json.NewEncoder(w).Encode(res)
+
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v4/users/"):
+		for name, id := range f.users {
+			if r.URL.Path == fmt.Sprintf("/api/v4/users/%d", id) {
+				json.NewEncoder(w).Encode(apiUser{ID: id, Username: name})
+				return
+			}
+		}
+		http.Error(w, `{"message":"404 User Not Found"}`, http.StatusNotFound)
+
+	case r.URL.EscapedPath() == "/api/v4/projects/payments%2Fbilling/merge_requests/17":
+		if r.Method == http.MethodPut {
+			var body struct {
+				ReviewerIDs []int `json:"reviewer_ids"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			f.reviewers = body.ReviewerIDs
+			f.puts++
+		}
+		mr := struct {
+			IID       int       `json:"iid"`
+			Reviewers []apiUser `json:"reviewers"`
+		}{IID: 17, Reviewers: []apiUser{}}
+		for _, rid := range f.reviewers {
+			for name, id := range f.users {
+				if rid == id {
+					mr.Reviewers = append(mr.Reviewers, apiUser{ID: id, Username: name})
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(mr)
+
+	default:
+		http.Error(w, `{"message":"404 Not found"}`, http.StatusNotFound)
+	}
+}
+
+func TestClientRequestsAndRemovesReviewers(t *testing.T) {
+	api := &fakeAPI{users: map[string]int{"dana": 21, "kim.lee": 34, "sam": 55}, reviewers: []int{55}}
+	srv := httptest.NewServer(api)
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewClient(srv.URL, "glpat-test", srv.Client())
+	ref := models.ExternalRef{Provider: vcs.GitLab, Repo: "payments/billing", Number: 17}
+
+	require.NoError(t, c.RequestReviewers(ctx, ref, []string{"kim.lee", "dana"}))
+	assert.Equal(t, []int{55, 34, 21}, api.reviewers, "reviewers added by hand are kept")
+
+	require.NoError(t, c.RequestReviewers(ctx, ref, []string{"dana"}))
+	assert.Equal(t, 1, api.puts, "requesting present reviewers changes nothing")
+
+	require.NoError(t, c.RemoveReviewers(ctx, ref, []string{"Kim.Lee"}))
+	assert.Equal(t, []int{55, 21}, api.reviewers)
+	require.NoError(t, c.RemoveReviewers(ctx, ref, []string{"nobody"}))
+	assert.Equal(t, 2, api.puts)
+	for _, tok := range api.tokens {
+		assert.Equal(t, "glpat-test", tok)
+	}
+
+	assert.ErrorContains(t, c.RequestReviewers(ctx, ref, []string{"ghost"}), `gitlab user "ghost" not found`)
+
+	err := c.RequestReviewers(ctx, models.ExternalRef{Repo: "payments/ledger", Number: 1}, []string{"dana"})
+	var apiErr *vcs.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
+
+func TestClientUsername(t *testing.T) {
+	srv := httptest.NewServer(&fakeAPI{users: map[string]int{"Dana.Builder": 21}})
+	defer srv.Close()
+	c := NewClient(srv.URL, "glpat-test", srv.Client())
+
+	login, err := c.Username(context.Background(), 21)
+	require.NoError(t, err)
+	assert.Equal(t, "Dana.Builder", login)
+
+	_, err = c.Username(context.Background(), 99)
+	var apiErr *vcs.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+}
@@ -0,0 +1,96 @@
+// Package gitlab understands GitLab's Merge Request Hook: it verifies the
+// shared token and translates merge request events into vcs actions.
+package gitlab
+
+import (
+	"crypto/subtle"
+	"errors"
+
+	"prmanager/internal/models"
+	"prmanager/internal/vcs"
+)
+
+const (
+	EventHeader = "X-Gitlab-Event"
+	TokenHeader = "X-Gitlab-Token"
+	UUIDHeader  = "X-Gitlab-Event-UUID"
+
+	EventMergeRequest = "Merge Request Hook"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// VerifyToken checks the X-Gitlab-Token header against the secret token
+// configured on the GitLab webhook.
+func VerifyToken(secret, header string) error {
+	if header == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(header)) != 1 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// MergeRequestEvent is the subset of the Merge Request Hook payload the
+// integration reads. User is whoever triggered the event, which is not
+// necessarily the merge request's author: GitLab only sends the author's id.
+type MergeRequestEvent struct {
+	ObjectKind string `json:"object_kind"`
+	User       struct {
+		ID       int    `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	ObjectAttributes struct {
+		IID      int    `json:"iid"`
+		AuthorID int    `json:"author_id"`
+		Title    string `json:"title"`
+		URL      string `json:"url"`
+		Action   string `json:"action"`
+		Draft    bool   `json:"draft"`
+	} `json:"object_attributes"`
+	Changes struct {
+		Draft *struct {
+			Previous bool `json:"previous"`
+			Current  bool `json:"current"`
+		} `json:"draft"`
+	} `json:"changes"`
+}
+
+// Translate maps the event onto a vcs action. Drafts are not tracked until
+// they are marked ready, which GitLab reports as an "update" that clears the
+// draft flag. ok is false for actions that do not change the PR.
+//
+// The author's login is only known when the author triggered the event;
+// otherwise pr.AuthorLogin is empty and has to be looked up by
+// ObjectAttributes.AuthorID, see Client.Username.
+func (e MergeRequestEvent) Translate() (action vcs.Action, pr vcs.PullRequest, ok bool) {
+	attrs := e.ObjectAttributes
+	switch attrs.Action {
+	case "open":
+		action, ok = vcs.ActionOpened, !attrs.Draft
+	case "update":
+		d := e.Changes.Draft
+		action, ok = vcs.ActionOpened, d != nil && d.Previous && !d.Current
+	case "reopen":
+		action, ok = vcs.ActionReopened, true
+	case "close":
+		action, ok = vcs.ActionClosed, true
+	case "merge":
+		action, ok = vcs.ActionMerged, true
+	}
+
+	pr = vcs.PullRequest{
+		Ref: models.ExternalRef{
+			Provider: vcs.GitLab,
+			Repo:     e.Project.PathWithNamespace,
+			Number:   attrs.IID,
+			URL:      attrs.URL,
+		},
+		Title: attrs.Title,
+	}
+	if e.User.ID == attrs.AuthorID {
+		pr.AuthorLogin = e.User.Username
+	}
+	return action, pr, ok
+}
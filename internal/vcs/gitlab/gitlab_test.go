@@ -0,0 +1,64 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"prmanager/internal/vcs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixture(t *testing.T, name string) MergeRequestEvent {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	var e MergeRequestEvent
+	require.NoError(t, json.Unmarshal(b, &e))
+	return e
+}
+
+func TestVerifyToken(t *testing.T) {
+	assert.NoError(t, VerifyToken("s3cret", "s3cret"))
+	assert.ErrorIs(t, VerifyToken("s3cret", "s3cret "), ErrInvalidToken)
+	assert.ErrorIs(t, VerifyToken("s3cret", ""), ErrInvalidToken)
+	assert.ErrorIs(t, VerifyToken("", ""), ErrInvalidToken)
+}
+
+func TestTranslate(t *testing.T) {
+	cases := []struct {
+		fixture string
+		action  vcs.Action
+		ok      bool
+		login   string
+	}{
+		{"open.json", vcs.ActionOpened, true, "Dana.Builder"},
+		{"open_draft.json", vcs.ActionOpened, false, ""},
+		{"ready.json", vcs.ActionOpened, true, "Dana.Builder"},
+		{"ready_by_reviewer.json", vcs.ActionOpened, true, ""},
+		{"update.json", vcs.ActionOpened, false, ""},
+		{"close.json", vcs.ActionClosed, true, ""},
+		{"reopen.json", vcs.ActionReopened, true, "Dana.Builder"},
+		{"reopen_by_reviewer.json", vcs.ActionReopened, true, ""},
+		{"merge.json", vcs.ActionMerged, true, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.fixture, func(t *testing.T) {
+			action, pr, ok := fixture(t, c.fixture).Translate()
+			assert.Equal(t, c.ok, ok)
+			if !ok {
+				return
+			}
+			assert.Equal(t, c.action, action)
+			assert.Equal(t, vcs.GitLab, pr.Ref.Provider)
+			assert.Equal(t, "payments/billing", pr.Ref.Repo)
+			assert.Equal(t, 17, pr.Ref.Number, "merge requests are identified by their IID")
+			assert.Equal(t, "https://gitlab.example.com/payments/billing/-/merge_requests/17", pr.Ref.URL)
+			assert.Equal(t, "Retry failed invoice webhooks", pr.Title)
+			assert.Equal(t, c.login, pr.AuthorLogin, "only the author's own events name them")
+		})
+	}
+}
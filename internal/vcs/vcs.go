@@ -0,0 +1,63 @@
+// Package vcs is the provider-neutral view of pull requests on code hosts.
+// Integrations translate their webhooks into an Action on a PullRequest and
+// the service mirrors it onto the PR it tracks.
+package vcs
+
+import (
+	"context"
+	"fmt"
+
+	"prmanager/internal/models"
+)
+
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// Providers lists the code hosts users can link an identity for.
+var Providers = []string{GitHub, GitLab}
+
+func KnownProvider(p string) bool {
+	for _, known := range Providers {
+		if p == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Action string
+
+const (
+	ActionOpened   Action = "opened"
+	ActionClosed   Action = "closed"
+	ActionMerged   Action = "merged"
+	ActionReopened Action = "reopened"
+)
+
+// PullRequest identifies a pull request by its Ref and carries what is needed
+// to create a PR for it. AuthorLogin is the author's login on Ref.Provider,
+// empty when the event does not say who the author is.
+type PullRequest struct {
+	Ref         models.ExternalRef
+	Title       string
+	AuthorLogin string
+}
+
+// Provider pushes review requests to a code host, so reviewers picked by the
+// service get the host's own notifications. Both methods are idempotent.
+type Provider interface {
+	RequestReviewers(ctx context.Context, ref models.ExternalRef, logins []string) error
+	RemoveReviewers(ctx context.Context, ref models.ExternalRef, logins []string) error
+}
+
+// APIError is a non-2xx answer from a code host's API.
+type APIError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
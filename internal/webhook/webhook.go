@@ -0,0 +1,217 @@
+// Package webhook delivers domain events to subscribed HTTP endpoints.
+//
+// Publish records one delivery per subscribed webhook; Run sends them in the
+// background, signing every body with the webhook secret and retrying failed
+// attempts with exponential backoff until MaxAttempts is reached. Every
+// delivery stays in the repository as the delivery log.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/repository"
+	"prmanager/internal/retry"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+type Config struct {
+	retry.Policy
+	PollInterval time.Duration
+	Timeout      time.Duration
+	BatchSize    int
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Policy:       retry.Policy{MaxAttempts: 8, BaseBackoff: 10 * time.Second, MaxBackoff: time.Hour},
+		PollInterval: 5 * time.Second,
+		Timeout:      10 * time.Second,
+		BatchSize:    50,
+	}
+}
+
+type Dispatcher struct {
+	repo   repository.Repository
+	client *http.Client
+	logger *slog.Logger
+	cfg    Config
+	now    func() time.Time
+	wake   chan struct{}
+}
+
+func NewDispatcher(r repository.Repository, logger *slog.Logger, cfg Config) *Dispatcher {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Dispatcher{
+		repo:   r,
+		client: &http.Client{Timeout: cfg.Timeout},
+		logger: logger,
+		cfg:    cfg,
+		now:    time.Now,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Sign returns the signature header value for body: "sha256=" followed by the
+// hex HMAC-SHA256 of the body keyed with the webhook secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Subscribed reports whether w wants events of type t. A webhook without an
+// explicit event list receives everything.
+func Subscribed(w models.Webhook, t events.Type) bool {
+	return len(w.Events) == 0 || slices.Contains(w.Events, string(t))
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, e events.Event) error {
+	hooks, err := d.repo.ListWebhooks(ctx)
+	if err != nil {
+		return fmt.Errorf("list webhooks: %w", err)
+	}
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	queued := false
+	for _, w := range hooks {
+		if !Subscribed(w, e.Type) {
+			continue
+		}
+		next := d.now()
+		_, err := d.repo.CreateWebhookDelivery(ctx, models.WebhookDelivery{
+			WebhookID:     w.ID,
+			EventID:       e.ID,
+			EventType:     string(e.Type),
+			Payload:       body,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: &next,
+		})
+		if errors.Is(err, repository.ErrInvalidReference) {
+			continue // deleted since it was listed
+		}
+		if err != nil {
+			return fmt.Errorf("queue delivery for webhook %d: %w", w.ID, err)
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Run delivers due webhooks until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	retry.Poll(ctx, d.cfg.PollInterval, d.wake, func(ctx context.Context) {
+		if _, err := d.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Error("failed to deliver webhooks", "error", err)
+		}
+	})
+}
+
+// DeliverDue makes one attempt for every delivery whose next attempt is due
+// and returns how many attempts were made.
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	due, err := d.repo.ListDueWebhookDeliveries(ctx, d.now(), d.cfg.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("list due deliveries: %w", err)
+	}
+	for i, del := range due {
+		if err := d.attempt(ctx, del); err != nil {
+			return i, err
+		}
+	}
+	return len(due), nil
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, del models.WebhookDelivery) error {
+	w, err := d.repo.GetWebhook(ctx, del.WebhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get webhook %d: %w", del.WebhookID, err)
+	}
+
+	code, sendErr := d.send(ctx, w, del)
+	at := d.now()
+	del.Attempts++
+	del.LastAttemptAt = &at
+	del.ResponseCode = code
+
+	switch next, ok := d.cfg.Next(del.Attempts, at, 0); {
+	case sendErr == nil:
+		del.Status = models.DeliverySucceeded
+		del.LastError = ""
+		del.NextAttemptAt = nil
+	case !ok:
+		del.Status = models.DeliveryFailed
+		del.LastError = sendErr.Error()
+		del.NextAttemptAt = nil
+		d.logger.Warn("webhook delivery failed permanently",
+			"delivery_id", del.ID, "webhook_id", w.ID, "attempts", del.Attempts, "error", sendErr)
+	default:
+		del.LastError = sendErr.Error()
+		del.NextAttemptAt = &next
+		d.logger.Info("webhook delivery failed, will retry",
+			"delivery_id", del.ID, "webhook_id", w.ID, "attempts", del.Attempts, "next_attempt_at", next, "error", sendErr)
+	}
+
+	if err := d.repo.UpdateWebhookDelivery(ctx, del); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("update delivery %d: %w", del.ID, err)
+	}
+	return nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, w models.Webhook, del models.WebhookDelivery) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderDelivery, strconv.Itoa(del.ID))
+	req.Header.Set(HeaderSignature, Sign(w.Secret, del.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	code := resp.StatusCode
+	if code < 200 || code > 299 {
+		return &code, fmt.Errorf("receiver responded %d", code)
+	}
+	return &code, nil
+}
@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"prmanager/internal/events"
+	"prmanager/internal/models"
+	"prmanager/internal/outbox"
+	"prmanager/internal/repository"
+	"prmanager/internal/repository/memory"
+	"prmanager/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver is an httptest endpoint that answers with the queued status codes
+// (200 once they run out) and records every request.
+type receiver struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	codes    []int
+	requests []received
+}
+
+func newReceiver(t *testing.T, codes ...int) *receiver {
+	rc := &receiver{codes: codes}
+	rc.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rc.mu.Lock()
+		defer rc.mu.Unlock()
+		rc.requests = append(rc.requests, received{header: r.Header.Clone(), body: body})
+		code := http.StatusOK
+		if len(rc.codes) > 0 {
+			code, rc.codes = rc.codes[0], rc.codes[1:]
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(rc.Close)
+	return rc
+}
+
+func (rc *receiver) received() []received {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]received(nil), rc.requests...)
+}
+
+type fixture struct {
+	repo  repository.Repository
+	svc   *service.Service
+	relay *outbox.Relay
+	d     *Dispatcher
+	clock time.Time
+}
+
+func newFixture(t *testing.T, cfg Config) *fixture {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	f := &fixture{repo: memory.NewRepo(), clock: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	f.d = NewDispatcher(f.repo, logger, cfg)
+	f.d.now = func() time.Time { return f.clock }
+	f.relay = outbox.NewRelay(f.repo, f.d, logger, outbox.DefaultConfig())
+	f.svc = service.NewService(f.repo, logger)
+	return f
+}
+
+// deliverDue moves all recorded events from the outbox into the delivery
+// queue, then makes one attempt for every due delivery.
+func (f *fixture) deliverDue(t *testing.T) int {
+	ctx := context.Background()
+	for {
+		n, err := f.relay.RelayDue(ctx)
+		require.NoError(t, err)
+		if n == 0 {
+			break
+		}
+	}
+	n, err := f.d.DeliverDue(ctx)
+	require.NoError(t, err)
+	return n
+}
+
+func testConfig() Config {
+	cfg := DefaultConfig()
+	cfg.MaxAttempts = 3
+	cfg.BaseBackoff = time.Second
+	cfg.MaxBackoff = time.Minute
+	return cfg
+}
+
+// createPR makes a PR by an author with one teammate, so it emits pr.created
+// and a single reviewer.assigned.
+func (f *fixture) createPR(t *testing.T) models.PRWithReviewers {
+	ctx := context.Background()
+	team, err := f.svc.CreateTeam(ctx, "backend")
+	require.NoError(t, err)
+	author, err := f.svc.CreateUser(ctx, &team.ID, "alice", true)
+	require.NoError(t, err)
+	_, err = f.svc.CreateUser(ctx, &team.ID, "bob", true)
+	require.NoError(t, err)
+	pr, err := f.svc.CreatePR(ctx, "Add login", author.ID)
+	require.NoError(t, err)
+	return pr
+}
+
+func (f *fixture) deliveries(t *testing.T, webhookID int) []models.WebhookDelivery {
+	ds, err := f.repo.ListWebhookDeliveries(context.Background(), webhookID, repository.DeliveryFilter{})
+	require.NoError(t, err)
+	return ds
+}
+
+func TestDeliversSignedEvents(t *testing.T) {
+	ctx := context.Background()
+	rc := newReceiver(t)
+	f := newFixture(t, testConfig())
+	hook, err := f.svc.CreateWebhook(ctx, rc.URL, "s3cret", nil)
+	require.NoError(t, err)
+
+	pr := f.createPR(t)
+	_, err = f.svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, f.deliverDue(t))
+
+	reqs := rc.received()
+	require.Len(t, reqs, 3)
+	var types []string
+	for _, req := range reqs {
+		assert.Equal(t, Sign("s3cret", req.body), req.header.Get(HeaderSignature))
+		assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+
+		var e events.Event
+		require.NoError(t, json.Unmarshal(req.body, &e))
+		assert.NotEmpty(t, e.ID)
+		assert.Equal(t, string(e.Type), req.header.Get(HeaderEvent))
+		types = append(types, string(e.Type))
+	}
+	assert.ElementsMatch(t, []string{"pr.created", "reviewer.assigned", "pr.merged"}, types)
+
+	var created events.Event
+	require.NoError(t, json.Unmarshal(reqs[0].body, &created))
+	var data events.PRData
+	require.NoError(t, json.Unmarshal(created.Data, &data))
+	assert.Equal(t, pr.ID, data.PR.ID)
+
+	for _, d := range f.deliveries(t, hook.ID) {
+		assert.Equal(t, models.DeliverySucceeded, d.Status)
+		assert.Equal(t, 1, d.Attempts)
+		require.NotNil(t, d.ResponseCode)
+		assert.Equal(t, http.StatusOK, *d.ResponseCode)
+	}
+
+	assert.Zero(t, f.deliverDue(t), "delivered events are not sent again")
+}
+
+func TestRetriesWithExponentialBackoff(t *testing.T) {
+	ctx := context.Background()
+	rc := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway)
+	f := newFixture(t, testConfig())
+	hook, err := f.svc.CreateWebhook(ctx, rc.URL, "s", []string{"pr.created"})
+	require.NoError(t, err)
+	f.createPR(t)
+
+	f.deliverDue(t)
+	d := f.deliveries(t, hook.ID)[0]
+	assert.Equal(t, models.DeliveryPending, d.Status)
+	assert.Equal(t, 1, d.Attempts)
+	assert.Equal(t, http.StatusInternalServerError, *d.ResponseCode)
+	assert.Contains(t, d.LastError, "500")
+	assert.Equal(t, f.clock.Add(time.Second), *d.NextAttemptAt)
+
+	assert.Zero(t, f.deliverDue(t), "not due before the backoff elapses")
+
+	f.clock = f.clock.Add(time.Second)
+	f.deliverDue(t)
+	d = f.deliveries(t, hook.ID)[0]
+	assert.Equal(t, 2, d.Attempts)
+	assert.Equal(t, f.clock.Add(2*time.Second), *d.NextAttemptAt, "backoff doubles")
+
+	f.clock = f.clock.Add(2 * time.Second)
+	f.deliverDue(t)
+	d = f.deliveries(t, hook.ID)[0]
+	assert.Equal(t, models.DeliverySucceeded, d.Status)
+	assert.Equal(t, 3, d.Attempts)
+	assert.Empty(t, d.LastError)
+	assert.Nil(t, d.NextAttemptAt)
+
+	reqs := rc.received()
+	require.Len(t, reqs, 3)
+	assert.Equal(t, reqs[0].body, reqs[2].body, "retries resend the same event")
+}
+
+func TestGivesUpAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	rc := newReceiver(t, 500, 500, 500, 500)
+	f := newFixture(t, testConfig())
+	hook, err := f.svc.CreateWebhook(ctx, rc.URL, "s", []string{"pr.created"})
+	require.NoError(t, err)
+	f.createPR(t)
+
+	for range 5 {
+		f.deliverDue(t)
+		f.clock = f.clock.Add(time.Hour)
+	}
+
+	d := f.deliveries(t, hook.ID)[0]
+	assert.Equal(t, models.DeliveryFailed, d.Status)
+	assert.Equal(t, 3, d.Attempts)
+	assert.Nil(t, d.NextAttemptAt)
+	assert.Len(t, rc.received(), 3)
+}
+
+func TestOnlySubscribedEventsAreQueued(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, testConfig())
+	merged, err := f.svc.CreateWebhook(ctx, "http://example.invalid/merged", "s", []string{"pr.merged"})
+	require.NoError(t, err)
+
+	pr := f.createPR(t)
+	f.deliverDue(t)
+	assert.Empty(t, f.deliveries(t, merged.ID))
+
+	_, err = f.svc.MergePR(ctx, pr.ID)
+	require.NoError(t, err)
+	f.deliverDue(t)
+	ds := f.deliveries(t, merged.ID)
+	require.Len(t, ds, 1)
+	assert.Equal(t, "pr.merged", ds[0].EventType)
+}